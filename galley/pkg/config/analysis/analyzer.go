@@ -15,6 +15,7 @@
 package analysis
 
 import (
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/processing/transformer"
 	"istio.io/istio/galley/pkg/config/scope"
 	"istio.io/istio/pkg/config/schema/collection"
@@ -57,11 +58,29 @@ func (c *CombinedAnalyzer) Analyze(ctx Context) {
 			scope.Analysis.Debugf("Analyzer %q has been cancelled...", c.Metadata().Name)
 			return
 		}
-		a.Analyze(ctx)
+		if name := a.Metadata().Name; name != "" {
+			a.Analyze(&analyzerContext{Context: ctx, name: name})
+		} else {
+			a.Analyze(ctx)
+		}
 		scope.Analysis.Debugf("Completed analyzer %q...", a.Metadata().Name)
 	}
 }
 
+// analyzerContext records the name of the analyzer on the messages it reports.
+type analyzerContext struct {
+	Context
+	name string
+}
+
+// Report implements Context
+func (c *analyzerContext) Report(col collection.Name, m diag.Message) {
+	if m.Analyzer == "" {
+		m.Analyzer = c.name
+	}
+	c.Context.Report(col, m)
+}
+
 // RemoveSkipped removes analyzers that should be skipped, meaning they meet one of the following criteria:
 // 1. The analyzer requires disabled input collections. The names of removed analyzers are returned.
 // Transformer information is used to determine, based on the disabled input collections, which output collections
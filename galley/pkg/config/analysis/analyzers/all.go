@@ -21,13 +21,17 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deployment"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/envoyfilter"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/gateway"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/multicluster"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/schema"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/service"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/serviceentry"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/sidecar"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/virtualservice"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/workloadentry"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/workloadgroup"
 )
 
 // All returns all analyzers
@@ -36,6 +40,10 @@ func All() []analysis.Analyzer {
 		// Please keep this list sorted alphabetically by pkg.name for convenience
 		&annotations.K8sAnalyzer{},
 		&authz.AuthorizationPoliciesAnalyzer{},
+		&authz.PortAnalyzer{},
+		&authz.PrincipalAnalyzer{},
+		&authz.ProviderAnalyzer{},
+		&authz.ShadowedRuleAnalyzer{},
 		&deployment.ServiceAssociationAnalyzer{},
 		&deprecation.FieldAnalyzer{},
 		&gateway.IngressGatewayPortAnalyzer{},
@@ -44,6 +52,8 @@ func All() []analysis.Analyzer {
 		&injection.ImageAnalyzer{},
 		&multicluster.MeshNetworksAnalyzer{},
 		&service.PortNameAnalyzer{},
+		&serviceentry.AddressAnalyzer{},
+		&serviceentry.HostConflictAnalyzer{},
 		&sidecar.DefaultSelectorAnalyzer{},
 		&sidecar.SelectorAnalyzer{},
 		&virtualservice.ConflictingMeshGatewayHostsAnalyzer{},
@@ -52,7 +62,12 @@ func All() []analysis.Analyzer {
 		&virtualservice.GatewayAnalyzer{},
 		&virtualservice.RegexAnalyzer{},
 		&virtualservice.MatchesAnalyzer{},
+		&virtualservice.ShadowingAnalyzer{},
+		&workloadentry.SelectorAnalyzer{},
+		&workloadentry.ServiceAccountAnalyzer{},
+		&workloadgroup.PortAnalyzer{},
 		&destinationrule.CaCertificateAnalyzer{},
+		&destinationrule.TLSDisableAnalyzer{},
 	}
 
 	analyzers = append(analyzers, schema.AllValidationAnalyzers()...)
@@ -60,6 +75,15 @@ func All() []analysis.Analyzer {
 	return analyzers
 }
 
+// IstioctlOnly returns the analyzers that only istioctl analyze runs, in addition to All().
+// They generate the proxy configuration of workloads, which is too expensive to do on every
+// change in Istiod.
+func IstioctlOnly() []analysis.Analyzer {
+	return []analysis.Analyzer{
+		&envoyfilter.PatchAnalyzer{},
+	}
+}
+
 // AllCombined returns all analyzers combined as one
 func AllCombined() *analysis.CombinedAnalyzer {
 	return analysis.Combine("all", All()...)
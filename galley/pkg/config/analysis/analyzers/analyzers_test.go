@@ -30,12 +30,16 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deployment"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/envoyfilter"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/gateway"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/multicluster"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/service"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/serviceentry"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/sidecar"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/virtualservice"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/workloadentry"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/workloadgroup"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/local"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
@@ -89,6 +93,17 @@ var testGrid = []testCase{
 			{msg.Deprecated, "Sidecar no-selector.default"},
 		},
 	},
+	{
+		name:       "envoyFilterPatches",
+		inputFiles: []string{"testdata/envoyfilter-patches.yaml"},
+		analyzer:   &envoyfilter.PatchAnalyzer{},
+		expected: []message{
+			{msg.EnvoyFilterPatchNoMatch, "EnvoyFilter no-match.default"},
+			{msg.EnvoyFilterOrderDependent, "EnvoyFilter timeout-a.default"},
+			{msg.EnvoyFilterOrderDependent, "EnvoyFilter timeout-b.default"},
+			{msg.EnvoyFilterInvalidConfig, "EnvoyFilter invalid-dns-refresh.default"},
+		},
+	},
 	{
 		name:       "gatewayNoWorkload",
 		inputFiles: []string{"testdata/gateway-no-workload.yaml"},
@@ -153,6 +168,7 @@ var testGrid = []testCase{
 		analyzer:   &injection.Analyzer{},
 		expected: []message{
 			{msg.NamespaceNotInjected, "Namespace bar"},
+			{msg.NamespaceNotInjected, "Namespace baz"},
 			{msg.PodMissingProxy, "Pod noninjectedpod.default"},
 			{msg.NamespaceMultipleInjectionLabels, "Namespace busted"},
 		},
@@ -247,6 +263,7 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "VirtualService reviews-bogusport.default"},
 			{msg.VirtualServiceDestinationPortSelectorRequired, "VirtualService reviews-2port-missing.default"},
 			{msg.ReferencedResourceNotFound, "VirtualService cross-namespace-details.istio-system"},
+			{msg.ReferencedResourceNotFound, "VirtualService reviews-partial-fqdn.default"},
 		},
 	},
 	{
@@ -326,6 +343,105 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "AuthorizationPolicy httpbin-bogus-not-ns.httpbin"},
 		},
 	},
+	{
+		name:       "authorizationpolicies principals",
+		inputFiles: []string{"testdata/authorizationpolicies-references.yaml"},
+		analyzer:   &authz.PrincipalAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyPrincipalNotFound, "AuthorizationPolicy principals.httpbin"},
+		},
+	},
+	{
+		name:       "authorizationpolicies ports",
+		inputFiles: []string{"testdata/authorizationpolicies-references.yaml"},
+		analyzer:   &authz.PortAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyPortNotExposed, "AuthorizationPolicy ports.httpbin"},
+		},
+	},
+	{
+		name:           "authorizationpolicies providers",
+		inputFiles:     []string{"testdata/authorizationpolicies-references.yaml"},
+		meshConfigFile: "testdata/mesh-with-extension-providers.yaml",
+		analyzer:       &authz.ProviderAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyUndefinedProvider, "AuthorizationPolicy ext-authz-undefined.httpbin"},
+		},
+	},
+	{
+		name:       "authorizationpolicies shadowed rules",
+		inputFiles: []string{"testdata/authorizationpolicies-references.yaml"},
+		analyzer:   &authz.ShadowedRuleAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyShadowedRule, "AuthorizationPolicy allow-admin.httpbin"},
+		},
+	},
+	{
+		name:       "serviceentry host conflicts",
+		inputFiles: []string{"testdata/serviceentry.yaml"},
+		analyzer:   &serviceentry.HostConflictAnalyzer{},
+		expected: []message{
+			{msg.ServiceEntryHostConflict, "ServiceEntry reviews-tcp.default"},
+			{msg.ServiceEntryHostConflict, "ServiceEntry api-tls.default"},
+			{msg.ServiceEntryHostConflict, "ServiceEntry api-http.default"},
+			{msg.ServiceEntryHostConflict, "ServiceEntry cache-a.team-a"},
+			{msg.ServiceEntryHostConflict, "ServiceEntry cache-b.team-b"},
+		},
+	},
+	{
+		name:       "serviceentry addresses",
+		inputFiles: []string{"testdata/serviceentry.yaml"},
+		analyzer:   &serviceentry.AddressAnalyzer{},
+		expected: []message{
+			{msg.ServiceEntryAddressRequired, "ServiceEntry postgres.default"},
+		},
+	},
+	{
+		name:       "workloadentry selectors",
+		inputFiles: []string{"testdata/workloadentry.yaml"},
+		analyzer:   &workloadentry.SelectorAnalyzer{},
+		expected: []message{
+			{msg.WorkloadEntryNotSelected, "WorkloadEntry vm-c.default"},
+			{msg.WorkloadEntryNotSelected, "WorkloadEntry vm-a.other"},
+		},
+	},
+	{
+		name:       "workloadentry service accounts",
+		inputFiles: []string{"testdata/workloadentry.yaml"},
+		analyzer:   &workloadentry.ServiceAccountAnalyzer{},
+		expected: []message{
+			{msg.ReferencedResourceNotFound, "WorkloadEntry vm-b.default"},
+		},
+	},
+	{
+		name:       "workloadgroup ports",
+		inputFiles: []string{"testdata/workloadentry.yaml"},
+		analyzer:   &workloadgroup.PortAnalyzer{},
+		expected: []message{
+			{msg.WorkloadGroupPortConflict, "WorkloadGroup vm-a.default"},
+			{msg.WorkloadGroupPortConflict, "WorkloadGroup vm-a.default"},
+		},
+	},
+	{
+		name:       "virtualservice shadowing",
+		inputFiles: []string{"testdata/virtualservice_shadowing.yaml"},
+		analyzer:   &virtualservice.ShadowingAnalyzer{},
+		expected: []message{
+			{msg.VirtualServiceShadowedMatch, "VirtualService shadowed.default"},
+			{msg.VirtualServiceShadowedMatch, "VirtualService shadowed.default"},
+			{msg.VirtualServiceUnreachableRule, "VirtualService shadowed.default"},
+			{msg.VirtualServiceShadowedMatch, "VirtualService shadowed.default"},
+			{msg.VirtualServiceUnreachableRule, "VirtualService shadowed.default"},
+			{msg.VirtualServiceShadowedMatch, "VirtualService shadowed.default"},
+			{msg.VirtualServiceUnreachableRule, "VirtualService shadowed.default"},
+			{msg.VirtualServiceShadowedMatch, "VirtualService shadowed.default"},
+			{msg.VirtualServiceUnreachableRule, "VirtualService shadowed.default"},
+			{msg.VirtualServiceRouteWeights, "VirtualService weights.default"},
+			{msg.VirtualServiceRouteWeights, "VirtualService weights.default"},
+			{msg.VirtualServiceShadowedMatch, "VirtualService gw-second.default"},
+			{msg.VirtualServiceUnreachableRule, "VirtualService gw-second.default"},
+		},
+	},
 	{
 		name: "destinationrule with no cacert, simple at destinationlevel",
 		inputFiles: []string{
@@ -396,6 +512,17 @@ var testGrid = []testCase{
 		analyzer: &destinationrule.CaCertificateAnalyzer{},
 		expected: []message{},
 	},
+	{
+		name: "destinationrule with tls mode disable",
+		inputFiles: []string{
+			"testdata/destinationrule-tls-disable.yaml",
+		},
+		analyzer: &destinationrule.TLSDisableAnalyzer{},
+		expected: []message{
+			{msg.DestinationRuleTLSDisabledStrictMTLS, "DestinationRule reviews.bookinfo"},
+			{msg.DestinationRuleTLSDisabledStrictMTLS, "DestinationRule reviews.bookinfo"},
+		},
+	},
 	{
 		name: "dupmatches",
 		inputFiles: []string{
@@ -481,7 +608,7 @@ func TestAnalyzers(t *testing.T) {
 	t.Run("CheckMetadataInputs", func(t *testing.T) {
 		g := NewWithT(t)
 	outer:
-		for _, a := range All() {
+		for _, a := range append(All(), IstioctlOnly()...) {
 			analyzerName := a.Metadata().Name
 
 			// Skip this check for explicitly ignored analyzers
@@ -507,12 +634,84 @@ func TestAnalyzers(t *testing.T) {
 	})
 }
 
-// Verify that all of the analyzers tested here are also registered in All()
+// Verify the fixes suggested by the analyzers, which the test grid doesn't compare
+func TestFixes(t *testing.T) {
+	type fixedMessage struct {
+		origin string
+		patch  []diag.PatchOperation
+	}
+
+	cases := []struct {
+		tc       testCase
+		expected []fixedMessage
+	}{
+		{
+			tc: testCase{
+				name:       "istioInjection",
+				inputFiles: []string{"testdata/injection.yaml"},
+				analyzer:   &injection.Analyzer{},
+			},
+			expected: []fixedMessage{
+				{"Namespace bar", []diag.PatchOperation{
+					diag.Add("/metadata/labels", map[string]string{"istio-injection": "enabled"})}},
+				{"Namespace baz", []diag.PatchOperation{diag.Add("/metadata/labels/istio-injection", "enabled")}},
+			},
+		},
+		{
+			tc: testCase{
+				name:       "portNameNotFollowConvention",
+				inputFiles: []string{"testdata/service-no-port-name.yaml"},
+				analyzer:   &service.PortNameAnalyzer{},
+			},
+			expected: []fixedMessage{
+				{"Service my-service1.my-namespace1", []diag.PatchOperation{diag.Add("/spec/ports/0/name", "http")}},
+				{"Service my-service2.my-namespace2", []diag.PatchOperation{diag.Add("/spec/ports/0/name", "http-foo")}},
+			},
+		},
+		{
+			tc: testCase{
+				name:       "virtualServiceDestinationHosts",
+				inputFiles: []string{"testdata/virtualservice_destinationhosts.yaml"},
+				analyzer:   &virtualservice.DestinationHostAnalyzer{},
+			},
+			expected: []fixedMessage{
+				{"VirtualService reviews-partial-fqdn.default", []diag.PatchOperation{
+					diag.Replace("/spec/http/0/route/0/destination/host", "reviews.default.svc.cluster.local")}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			sa, err := setupAnalyzerForCase(c.tc, nil)
+			if err != nil {
+				t.Fatalf("Error setting up analysis for testcase %s: %v", c.tc.name, err)
+			}
+			result, err := runAnalyzer(sa)
+			if err != nil {
+				t.Fatalf("Error running analysis on testcase %s: %v", c.tc.name, err)
+			}
+
+			var got []fixedMessage
+			for _, m := range result.Messages {
+				for _, f := range m.Fixes {
+					got = append(got, fixedMessage{m.Resource.Origin.FriendlyName(), f.Patch})
+				}
+			}
+			g.Expect(got).To(ConsistOf(c.expected))
+		})
+	}
+}
+
+// Verify that all of the analyzers tested here are also registered in All() or IstioctlOnly()
 func TestAnalyzersInAll(t *testing.T) {
 	g := NewWithT(t)
 
 	var allNames []string
-	for _, a := range All() {
+	for _, a := range append(All(), IstioctlOnly()...) {
 		allNames = append(allNames, a.Metadata().Name)
 	}
 
@@ -525,7 +724,7 @@ func TestAnalyzersHaveUniqueNames(t *testing.T) {
 	g := NewWithT(t)
 
 	existingNames := make(map[string]struct{})
-	for _, a := range All() {
+	for _, a := range append(All(), IstioctlOnly()...) {
 		n := a.Metadata().Name
 		_, ok := existingNames[n]
 		// TODO (Nino-K): remove this condition once metadata is clean up
@@ -542,7 +741,7 @@ func TestAnalyzersHaveUniqueNames(t *testing.T) {
 func TestAnalyzersHaveDescription(t *testing.T) {
 	g := NewWithT(t)
 
-	for _, a := range All() {
+	for _, a := range append(All(), IstioctlOnly()...) {
 		g.Expect(a.Metadata().Description).ToNot(Equal(""))
 	}
 }
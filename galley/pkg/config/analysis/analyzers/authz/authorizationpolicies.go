@@ -34,10 +34,7 @@ import (
 // AuthorizationPoliciesAnalyzer checks the validity of authorization policies
 type AuthorizationPoliciesAnalyzer struct{}
 
-var (
-	_          analysis.Analyzer = &AuthorizationPoliciesAnalyzer{}
-	meshConfig *v1alpha1.MeshConfig
-)
+var _ analysis.Analyzer = &AuthorizationPoliciesAnalyzer{}
 
 func (a *AuthorizationPoliciesAnalyzer) Metadata() analysis.Metadata {
 	return analysis.Metadata{
@@ -106,10 +103,7 @@ func meshWidePolicy(ns string, c analysis.Context) bool {
 }
 
 func fetchMeshConfig(c analysis.Context) *v1alpha1.MeshConfig {
-	if meshConfig != nil {
-		return meshConfig
-	}
-
+	var meshConfig *v1alpha1.MeshConfig
 	c.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
 		meshConfig = r.Message.(*v1alpha1.MeshConfig)
 		return r.Metadata.FullName.Name != util.MeshConfigName
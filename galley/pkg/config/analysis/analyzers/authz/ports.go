@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// PortAnalyzer checks that the ports of authorization policies are exposed by the workloads
+// the policies select.
+type PortAnalyzer struct{}
+
+var _ analysis.Analyzer = &PortAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *PortAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.PortAnalyzer",
+		Description: "Checks that authorization policy ports are exposed by the workloads the policies select",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			collections.K8SCoreV1Namespaces.Name(),
+			collections.K8SCoreV1Pods.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *PortAnalyzer) Analyze(c analysis.Context) {
+	rootNamespace := fetchMeshConfig(c).GetRootNamespace()
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		var exposed map[string]bool
+		for i, rule := range ap.Rules {
+			for j, to := range rule.To {
+				for k, port := range to.GetOperation().GetPorts() {
+					if exposed == nil {
+						exposed = exposedPorts(c, selectedPods(c, r, rootNamespace))
+					}
+					// Workloads that declare no ports may still listen on any of them.
+					if len(exposed) == 0 || exposed[port] {
+						continue
+					}
+					m := msg.NewAuthorizationPolicyPortNotExposed(r, port)
+					if line, ok := util.ErrorLine(r, fmt.Sprintf(util.AuthorizationPolicyPort, i, j, k)); ok {
+						m.Line = line
+					}
+					c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// selectedPods returns the in-mesh pods the authorization policy applies to.
+func selectedPods(c analysis.Context, r *resource.Instance, rootNamespace string) []*resource.Instance {
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+	apNs := r.Metadata.FullName.Namespace
+	selector := k8s_labels.Everything()
+	if ap.Selector != nil {
+		selector = k8s_labels.SelectorFromSet(ap.Selector.MatchLabels)
+	}
+
+	var pods []*resource.Instance
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(rp *resource.Instance) bool {
+		if rp.Metadata.FullName.Namespace != apNs && apNs.String() != rootNamespace {
+			return true
+		}
+		if selector.Matches(k8s_labels.Set(rp.Message.(*v1.Pod).Labels)) && util.PodInMesh(rp, c) {
+			pods = append(pods, rp)
+		}
+		return true
+	})
+	return pods
+}
+
+// exposedPorts returns the ports the pods declare, and those services selecting the pods forward to.
+func exposedPorts(c analysis.Context, pods []*resource.Instance) map[string]bool {
+	ports := make(map[string]bool)
+	for _, rp := range pods {
+		pod := rp.Message.(*v1.Pod)
+		for _, container := range pod.Spec.Containers {
+			for _, cp := range container.Ports {
+				ports[strconv.Itoa(int(cp.ContainerPort))] = true
+			}
+		}
+
+		c.ForEach(collections.K8SCoreV1Services.Name(), func(rs *resource.Instance) bool {
+			svc := rs.Message.(*v1.ServiceSpec)
+			if rs.Metadata.FullName.Namespace != rp.Metadata.FullName.Namespace || len(svc.Selector) == 0 ||
+				!k8s_labels.SelectorFromSet(svc.Selector).Matches(k8s_labels.Set(pod.Labels)) {
+				return true
+			}
+			for _, sp := range svc.Ports {
+				switch {
+				case sp.TargetPort.Type == intstr.Int && sp.TargetPort.IntVal != 0:
+					ports[strconv.Itoa(int(sp.TargetPort.IntVal))] = true
+				case sp.TargetPort.Type == intstr.String && sp.TargetPort.StrVal != "":
+					// Named target ports refer to container ports, which are already included.
+				default:
+					ports[strconv.Itoa(int(sp.Port))] = true
+				}
+			}
+			return true
+		})
+	}
+	return ports
+}
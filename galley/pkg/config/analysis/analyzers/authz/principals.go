@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// PrincipalAnalyzer checks that the principals of authorization policies refer to service
+// accounts that workloads in the mesh run as.
+type PrincipalAnalyzer struct{}
+
+var _ analysis.Analyzer = &PrincipalAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *PrincipalAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.PrincipalAnalyzer",
+		Description: "Checks that authorization policy principals refer to service accounts workloads run as",
+		Inputs: collection.Names{
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			collections.K8SCoreV1Namespaces.Name(),
+			collections.K8SCoreV1Pods.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *PrincipalAnalyzer) Analyze(c analysis.Context) {
+	serviceAccounts := initServiceAccounts(c)
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		for i, rule := range ap.Rules {
+			for j, from := range rule.From {
+				if from.Source == nil {
+					continue
+				}
+				for _, field := range []struct {
+					name       string
+					principals []string
+				}{
+					{"principals", from.Source.Principals},
+					{"notPrincipals", from.Source.NotPrincipals},
+				} {
+					for k, principal := range field.principals {
+						ns, sa, ok := parsePrincipal(principal)
+						if !ok || serviceAccounts[ns+"/"+sa] {
+							continue
+						}
+						m := msg.NewAuthorizationPolicyPrincipalNotFound(r, principal, sa, ns)
+						if line, ok := util.ErrorLine(r, fmt.Sprintf(util.AuthorizationPolicyPrincipal, i, j, field.name, k)); ok {
+							m.Line = line
+						}
+						c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// parsePrincipal returns the namespace and service account of a principal of the form
+// <trust domain>/ns/<namespace>/sa/<service account>. Principals of other forms, or using
+// wildcards for either, are not parsed.
+func parsePrincipal(principal string) (string, string, bool) {
+	parts := strings.Split(principal, "/")
+	if len(parts) != 5 || parts[1] != "ns" || parts[3] != "sa" {
+		return "", "", false
+	}
+	ns, sa := parts[2], parts[4]
+	if ns == "" || sa == "" || strings.Contains(ns, "*") || strings.Contains(sa, "*") {
+		return "", "", false
+	}
+	return ns, sa, true
+}
+
+// Build a set of the service accounts, as namespace/name, in-mesh Pods run as
+func initServiceAccounts(c analysis.Context) map[string]bool {
+	serviceAccounts := make(map[string]bool)
+
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		if !util.PodInMesh(r, c) {
+			return true
+		}
+		p := r.Message.(*v1.Pod)
+		sa := p.Spec.ServiceAccountName
+		if sa == "" {
+			sa = "default"
+		}
+		serviceAccounts[r.Metadata.FullName.Namespace.String()+"/"+sa] = true
+		return true
+	})
+
+	return serviceAccounts
+}
@@ -0,0 +1,42 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePrincipal(t *testing.T) {
+	assert := assert.New(t)
+
+	ns, sa, ok := parsePrincipal("cluster.local/ns/foo/sa/bar")
+	assert.True(ok)
+	assert.Equal("foo", ns)
+	assert.Equal("bar", sa)
+
+	_, _, ok = parsePrincipal("*/ns/foo/sa/bar")
+	assert.True(ok)
+
+	_, _, ok = parsePrincipal("cluster.local/ns/foo/sa/*")
+	assert.False(ok)
+	_, _, ok = parsePrincipal("cluster.local/ns/*/sa/bar")
+	assert.False(ok)
+	_, _, ok = parsePrincipal("*")
+	assert.False(ok)
+	_, _, ok = parsePrincipal("spiffe://cluster.local/ns/foo/sa/bar")
+	assert.False(ok)
+}
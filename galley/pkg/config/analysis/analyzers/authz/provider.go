@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ProviderAnalyzer checks that authorization policies with the CUSTOM action refer to an
+// extension provider defined in the mesh config.
+type ProviderAnalyzer struct{}
+
+var _ analysis.Analyzer = &ProviderAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ProviderAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.ProviderAnalyzer",
+		Description: "Checks that authorization policies with the CUSTOM action refer to a defined extension provider",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ProviderAnalyzer) Analyze(c analysis.Context) {
+	providers := make(map[string]bool)
+	for _, p := range fetchMeshConfig(c).GetExtensionProviders() {
+		providers[p.GetName()] = true
+	}
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		// A CUSTOM policy without a provider is rejected by validation.
+		if ap.Action != v1beta1.AuthorizationPolicy_CUSTOM || ap.GetProvider().GetName() == "" {
+			return true
+		}
+		if !providers[ap.GetProvider().GetName()] {
+			m := msg.NewAuthorizationPolicyUndefinedProvider(r, ap.GetProvider().GetName())
+			if line, ok := util.ErrorLine(r, util.AuthorizationPolicyProvider); ok {
+				m.Line = line
+			}
+			c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+		}
+		return true
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ShadowedRuleAnalyzer checks for ALLOW authorization policy rules that never take effect, as a
+// DENY policy applying to the same workloads denies all the requests they match.
+type ShadowedRuleAnalyzer struct{}
+
+var _ analysis.Analyzer = &ShadowedRuleAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ShadowedRuleAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.ShadowedRuleAnalyzer",
+		Description: "Checks for ALLOW authorization policy rules that are fully shadowed by DENY policy rules",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ShadowedRuleAnalyzer) Analyze(c analysis.Context) {
+	rootNamespace := fetchMeshConfig(c).GetRootNamespace()
+
+	var denyPolicies []*resource.Instance
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		if r.Message.(*v1beta1.AuthorizationPolicy).Action == v1beta1.AuthorizationPolicy_DENY {
+			denyPolicies = append(denyPolicies, r)
+		}
+		return true
+	})
+	if len(denyPolicies) == 0 {
+		return
+	}
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		if ap.Action != v1beta1.AuthorizationPolicy_ALLOW {
+			return true
+		}
+	rules:
+		for i, rule := range ap.Rules {
+			for _, rd := range denyPolicies {
+				if !appliesToAll(rd, r, rootNamespace) {
+					continue
+				}
+				for j, denyRule := range rd.Message.(*v1beta1.AuthorizationPolicy).Rules {
+					if !ruleCovers(denyRule, rule) {
+						continue
+					}
+					denyName := fmt.Sprintf("%s.%s", rd.Metadata.FullName.Name, rd.Metadata.FullName.Namespace)
+					m := msg.NewAuthorizationPolicyShadowedRule(r, i, j, denyName)
+					if line, ok := util.ErrorLine(r, fmt.Sprintf(util.MetadataName)); ok {
+						m.Line = line
+					}
+					c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+					continue rules
+				}
+			}
+		}
+		return true
+	})
+}
+
+// appliesToAll returns true if the policy applies to every workload the other policy applies to.
+func appliesToAll(r, other *resource.Instance, rootNamespace string) bool {
+	ns := r.Metadata.FullName.Namespace
+	if ns != other.Metadata.FullName.Namespace && ns.String() != rootNamespace {
+		return false
+	}
+	selector := r.Message.(*v1beta1.AuthorizationPolicy).GetSelector().GetMatchLabels()
+	if len(selector) == 0 {
+		return true
+	}
+	return k8s_labels.SelectorFromSet(selector).Matches(
+		k8s_labels.Set(other.Message.(*v1beta1.AuthorizationPolicy).GetSelector().GetMatchLabels()))
+}
+
+// ruleCovers returns true if the rule matches all the requests the other rule matches. Only
+// conditions that are identical are compared, so a rule may cover another without this
+// reporting it, but never the reverse.
+func ruleCovers(rule, other *v1beta1.Rule) bool {
+	if len(rule.From) > 0 {
+		if len(other.From) == 0 {
+			return false
+		}
+		for _, of := range other.From {
+			if !containsFrom(rule.From, of) {
+				return false
+			}
+		}
+	}
+	if len(rule.To) > 0 {
+		if len(other.To) == 0 {
+			return false
+		}
+		for _, ot := range other.To {
+			if !containsTo(rule.To, ot) {
+				return false
+			}
+		}
+	}
+	// All the conditions must be met, so the rule must not add any to those of the other rule.
+	for _, w := range rule.When {
+		if !containsCondition(other.When, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFrom(from []*v1beta1.Rule_From, f *v1beta1.Rule_From) bool {
+	for _, e := range from {
+		if proto.Equal(e, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTo(to []*v1beta1.Rule_To, t *v1beta1.Rule_To) bool {
+	for _, e := range to {
+		if proto.Equal(e, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCondition(when []*v1beta1.Condition, c *v1beta1.Condition) bool {
+	for _, e := range when {
+		if proto.Equal(e, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"istio.io/api/security/v1beta1"
+)
+
+func TestRuleCovers(t *testing.T) {
+	assert := assert.New(t)
+
+	from := func(ns ...string) *v1beta1.Rule_From {
+		return &v1beta1.Rule_From{Source: &v1beta1.Source{Namespaces: ns}}
+	}
+	to := func(paths ...string) *v1beta1.Rule_To {
+		return &v1beta1.Rule_To{Operation: &v1beta1.Operation{Paths: paths}}
+	}
+	when := func(key string, values ...string) *v1beta1.Condition {
+		return &v1beta1.Condition{Key: key, Values: values}
+	}
+
+	// An empty rule matches all requests.
+	assert.True(ruleCovers(&v1beta1.Rule{}, &v1beta1.Rule{From: []*v1beta1.Rule_From{from("foo")}}))
+	assert.False(ruleCovers(&v1beta1.Rule{From: []*v1beta1.Rule_From{from("foo")}}, &v1beta1.Rule{}))
+
+	// Any of the sources or operations of the rule may match.
+	assert.True(ruleCovers(
+		&v1beta1.Rule{To: []*v1beta1.Rule_To{to("/a"), to("/b")}},
+		&v1beta1.Rule{From: []*v1beta1.Rule_From{from("foo")}, To: []*v1beta1.Rule_To{to("/b")}}))
+	assert.False(ruleCovers(
+		&v1beta1.Rule{To: []*v1beta1.Rule_To{to("/a")}},
+		&v1beta1.Rule{To: []*v1beta1.Rule_To{to("/a"), to("/b")}}))
+	assert.False(ruleCovers(
+		&v1beta1.Rule{To: []*v1beta1.Rule_To{to("/a")}},
+		&v1beta1.Rule{To: []*v1beta1.Rule_To{to("/a", "/b")}}))
+
+	// All the conditions of the rule must match.
+	assert.True(ruleCovers(
+		&v1beta1.Rule{When: []*v1beta1.Condition{when("request.headers[x]", "a")}},
+		&v1beta1.Rule{When: []*v1beta1.Condition{when("request.headers[x]", "a"), when("source.ip", "10.0.0.1")}}))
+	assert.False(ruleCovers(
+		&v1beta1.Rule{When: []*v1beta1.Condition{when("request.headers[x]", "a"), when("source.ip", "10.0.0.1")}},
+		&v1beta1.Rule{When: []*v1beta1.Condition{when("request.headers[x]", "a")}}))
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// TLSDisableAnalyzer checks for TLS settings with mode DISABLE to services whose workloads require mutual TLS
+// with a STRICT PeerAuthentication, which reject the plaintext traffic.
+type TLSDisableAnalyzer struct{}
+
+var _ analysis.Analyzer = &TLSDisableAnalyzer{}
+
+func (a *TLSDisableAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.TLSDisableAnalyzer",
+		Description: "Checks if TLS mode DISABLE is set for services whose workloads require mutual TLS",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioSecurityV1Beta1Peerauthentications.Name(),
+			collections.K8SCoreV1Services.Name(),
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+		},
+	}
+}
+
+func (a *TLSDisableAnalyzer) Analyze(ctx analysis.Context) {
+	rootNamespace := resource.Namespace(fetchMeshConfig(ctx).RootNamespace)
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		a.analyzeDestinationRule(r, ctx, rootNamespace)
+		return true
+	})
+}
+
+func (a *TLSDisableAnalyzer) analyzeDestinationRule(r *resource.Instance, ctx analysis.Context, rootNamespace resource.Namespace) {
+	dr := r.Message.(*v1alpha3.DestinationRule)
+	svcName := util.GetResourceNameFromHost(r.Metadata.FullName.Namespace, dr.GetHost())
+	svc := ctx.Find(collections.K8SCoreV1Services.Name(), svcName)
+	if svc == nil {
+		return
+	}
+	pa := strictPeerAuthentication(ctx, rootNamespace, svcName.Namespace, svc.Message.(*corev1.ServiceSpec).Selector)
+	if pa == nil {
+		return
+	}
+
+	report := func(policy *v1alpha3.TrafficPolicy, subset string) {
+		scope := ""
+		if subset != "" {
+			scope = fmt.Sprintf(" in subset %s", subset)
+		}
+		if isTLSDisabled(policy.GetTls()) {
+			ctx.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+				msg.NewDestinationRuleTLSDisabledStrictMTLS(r, scope, dr.GetHost(), pa.Metadata.FullName.String()))
+		}
+		for _, p := range policy.GetPortLevelSettings() {
+			if isTLSDisabled(p.GetTls()) {
+				ctx.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+					msg.NewDestinationRuleTLSDisabledStrictMTLS(r, fmt.Sprintf(" for port %d%s", p.GetPort().GetNumber(), scope),
+						dr.GetHost(), pa.Metadata.FullName.String()))
+			}
+		}
+	}
+	report(dr.GetTrafficPolicy(), "")
+	for _, s := range dr.GetSubsets() {
+		report(s.GetTrafficPolicy(), s.GetName())
+	}
+}
+
+// isTLSDisabled returns whether the settings disable TLS. DISABLE is the default mode, so settings that are
+// not set do not disable TLS.
+func isTLSDisabled(settings *v1alpha3.ClientTLSSettings) bool {
+	return settings != nil && settings.GetMode() == v1alpha3.ClientTLSSettings_DISABLE
+}
+
+// strictPeerAuthentication returns the PeerAuthentication that requires mutual TLS for all the ports of the
+// workloads with the selector in the namespace, if it is known. Like Istiod, the workload PeerAuthentication
+// takes precedence over the one of the namespace, which takes precedence over the one of the mesh, and
+// PeerAuthentications with mode UNSET inherit the mode of the next one.
+func strictPeerAuthentication(ctx analysis.Context, rootNamespace, namespace resource.Namespace,
+	selector map[string]string) *resource.Instance {
+	var workload, namespaceWide, meshWide *resource.Instance
+	ctx.ForEach(collections.IstioSecurityV1Beta1Peerauthentications.Name(), func(r *resource.Instance) bool {
+		pa := r.Message.(*v1beta1.PeerAuthentication)
+		ns := r.Metadata.FullName.Namespace
+		switch {
+		case ns == namespace && len(pa.GetSelector().GetMatchLabels()) > 0:
+			// The workloads of the service are only known to match the selectors included in the service selector.
+			if len(selector) > 0 && k8slabels.SelectorFromSet(pa.GetSelector().GetMatchLabels()).Matches(k8slabels.Set(selector)) {
+				workload = r
+			}
+		case ns == namespace && pa.GetSelector() == nil:
+			namespaceWide = r
+		case ns == rootNamespace && pa.GetSelector() == nil:
+			meshWide = r
+		}
+		return true
+	})
+
+	for _, r := range []*resource.Instance{workload, namespaceWide, meshWide} {
+		if r == nil {
+			continue
+		}
+		pa := r.Message.(*v1beta1.PeerAuthentication)
+		if r == workload {
+			// Ports may be excluded from the mode of the workload.
+			for _, m := range pa.GetPortLevelMtls() {
+				if m.GetMode() != v1beta1.PeerAuthentication_MutualTLS_STRICT {
+					return nil
+				}
+			}
+		}
+		switch pa.GetMtls().GetMode() {
+		case v1beta1.PeerAuthentication_MutualTLS_STRICT:
+			return r
+		case v1beta1.PeerAuthentication_MutualTLS_UNSET:
+			continue
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// fetchMeshConfig returns the MeshConfig named istio, if not the last instance found, or the default.
+func fetchMeshConfig(ctx analysis.Context) *meshconfig.MeshConfig {
+	var meshConfig *meshconfig.MeshConfig
+	ctx.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		meshConfig = r.Message.(*meshconfig.MeshConfig)
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+	if meshConfig == nil {
+		def := mesh.DefaultMeshConfig()
+		meshConfig = &def
+	}
+	return meshConfig
+}
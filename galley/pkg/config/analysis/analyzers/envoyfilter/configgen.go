@@ -0,0 +1,376 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoyfilter
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// configInputs are the collections, besides EnvoyFilters, that the configuration patched by
+// EnvoyFilters is generated from.
+var configInputs = collection.Names{
+	collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+	collections.IstioNetworkingV1Alpha3Gateways.Name(),
+	collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+	collections.IstioNetworkingV1Alpha3Sidecars.Name(),
+	collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+	collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+}
+
+// Kinds of generated resources.
+const (
+	listenerKind = "listener"
+	clusterKind  = "cluster"
+	routeKind    = "route"
+)
+
+type resourceKey struct {
+	kind string
+	name string
+}
+
+// generated is the configuration generated for a workload.
+type generated map[resourceKey]proto.Message
+
+// workload is a set of pods that share the same namespace, labels and proxy type, and so have
+// the same configuration generated for them.
+type workload struct {
+	// name identifies the workload in messages, using its first pod.
+	name      string
+	namespace string
+	labels    labels.Instance
+	ip        string
+	proxyType model.NodeType
+}
+
+// newProxy returns a proxy for the workload. A new proxy is needed for each generation, as
+// setting it up stores the push context state in it.
+func (w *workload) newProxy() *model.Proxy {
+	return &model.Proxy{
+		Type:            w.proxyType,
+		ID:              w.name,
+		ConfigNamespace: w.namespace,
+		IPAddresses:     []string{w.ip},
+		Metadata: &model.NodeMetadata{
+			Namespace: w.namespace,
+			Labels:    w.labels,
+		},
+	}
+}
+
+// meshState holds the inputs used to generate the configuration of workloads.
+type meshState struct {
+	meshConfig *meshconfig.MeshConfig
+	configs    []config.Config
+	services   []*model.Service
+	// specs holds the Kubernetes spec of each of services.
+	specs     []*v1.ServiceSpec
+	instances []*model.ServiceInstance
+	workloads map[string]*workload
+}
+
+func newMeshState(c analysis.Context) *meshState {
+	m := &meshState{
+		meshConfig: fetchMeshConfig(c),
+		workloads:  map[string]*workload{},
+	}
+	for _, col := range configInputs {
+		c.ForEach(col, func(r *resource.Instance) bool {
+			m.configs = append(m.configs, toConfig(r))
+			return true
+		})
+	}
+	c.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		spec := r.Message.(*v1.ServiceSpec)
+		svc := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        r.Metadata.FullName.Name.String(),
+				Namespace:   r.Metadata.FullName.Namespace.String(),
+				Labels:      r.Metadata.Labels,
+				Annotations: r.Metadata.Annotations,
+			},
+			Spec: *spec,
+		}
+		m.services = append(m.services, kube.ConvertService(svc, constants.DefaultKubernetesDomain, ""))
+		m.specs = append(m.specs, spec)
+		return true
+	})
+	return m
+}
+
+// fetchMeshConfig returns the MeshConfig named istio, if not the last instance found, or the default.
+func fetchMeshConfig(c analysis.Context) *meshconfig.MeshConfig {
+	var meshConfig *meshconfig.MeshConfig
+	c.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		meshConfig = r.Message.(*meshconfig.MeshConfig)
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+	if meshConfig == nil {
+		def := mesh.DefaultMeshConfig()
+		meshConfig = &def
+	}
+	return meshConfig
+}
+
+func toConfig(r *resource.Instance) config.Config {
+	created := r.Metadata.CreateTime
+	if created.IsZero() {
+		// Resources read from files have no creation time. Give them all the same one, so they are
+		// ordered by name rather than by the time they are added to the config store.
+		created = time.Unix(0, 0)
+	}
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind:  r.Metadata.Schema.GroupVersionKind(),
+			Name:              r.Metadata.FullName.Name.String(),
+			Namespace:         r.Metadata.FullName.Namespace.String(),
+			Labels:            r.Metadata.Labels,
+			Annotations:       r.Metadata.Annotations,
+			CreationTimestamp: created,
+		},
+		Spec: r.Message,
+	}
+}
+
+// workloadFor returns the workload the pod belongs to, registering the pod as an endpoint of the
+// services selecting it if this is the first pod of the workload.
+func (m *meshState) workloadFor(r *resource.Instance) *workload {
+	pod := r.Message.(*v1.Pod)
+	ns := r.Metadata.FullName.Namespace.String()
+	proxyType := model.SidecarProxy
+	for _, container := range pod.Spec.Containers {
+		if container.Name == util.IstioProxyName && isRouter(container.Args) {
+			proxyType = model.Router
+		}
+	}
+	key := fmt.Sprintf("%s/%s/%s", ns, proxyType, labels.Instance(pod.Labels))
+	if w, f := m.workloads[key]; f {
+		return w
+	}
+
+	ip := pod.Status.PodIP
+	if net.ParseIP(ip) == nil {
+		// Pods that are not running have no address, so use one from the reserved range to
+		// still generate the inbound configuration.
+		ip = fmt.Sprintf("240.240.%d.%d", len(m.workloads)/256%256, len(m.workloads)%256)
+	}
+	w := &workload{
+		name:      fmt.Sprintf("%s.%s", pod.Name, ns),
+		namespace: ns,
+		labels:    pod.Labels,
+		ip:        ip,
+		proxyType: proxyType,
+	}
+	m.workloads[key] = w
+
+	for i, svc := range m.services {
+		spec := m.specs[i]
+		if svc.Attributes.Namespace != ns || len(spec.Selector) == 0 ||
+			!labels.Instance(spec.Selector).SubsetOf(pod.Labels) {
+			continue
+		}
+		for _, sp := range spec.Ports {
+			port, f := svc.Ports.GetByPort(int(sp.Port))
+			if !f {
+				continue
+			}
+			m.instances = append(m.instances, &model.ServiceInstance{
+				Service:     svc,
+				ServicePort: port,
+				Endpoint: &model.IstioEndpoint{
+					Address:         ip,
+					EndpointPort:    targetPort(pod, sp),
+					ServicePortName: port.Name,
+					Labels:          pod.Labels,
+					Namespace:       ns,
+					ServiceAccount:  pod.Spec.ServiceAccountName,
+					TLSMode:         model.IstioMutualTLSModeLabel,
+					WorkloadName:    pod.Name,
+				},
+			})
+		}
+	}
+	return w
+}
+
+func isRouter(args []string) bool {
+	for _, arg := range args {
+		if arg == string(model.Router) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetPort returns the container port traffic to the service port is forwarded to.
+func targetPort(pod *v1.Pod, sp v1.ServicePort) uint32 {
+	switch sp.TargetPort.Type {
+	case intstr.Int:
+		if sp.TargetPort.IntVal != 0 {
+			return uint32(sp.TargetPort.IntVal)
+		}
+	case intstr.String:
+		for _, container := range pod.Spec.Containers {
+			for _, cp := range container.Ports {
+				if cp.Name == sp.TargetPort.StrVal {
+					return uint32(cp.ContainerPort)
+				}
+			}
+		}
+	}
+	return uint32(sp.Port)
+}
+
+// generate returns the configuration generated for each of the workloads when the given
+// EnvoyFilters are applied.
+func (m *meshState) generate(envoyFilters []config.Config, workloads []*workload) (map[*workload]generated, error) {
+	out := make(map[*workload]generated, len(workloads))
+	configs := make([]config.Config, 0, len(m.configs)+len(envoyFilters))
+	configs = append(configs, m.configs...)
+	configs = append(configs, envoyFilters...)
+	cg, stop, err := v1alpha3.NewConfigGen(v1alpha3.TestOptions{
+		Configs:    configs,
+		Services:   m.services,
+		Instances:  m.instances,
+		MeshConfig: m.meshConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	for _, w := range workloads {
+		proxy := cg.SetupProxy(w.newProxy())
+		g := generated{}
+		for _, l := range cg.Listeners(proxy) {
+			g[resourceKey{listenerKind, l.Name}] = l
+		}
+		for _, c := range cg.Clusters(proxy) {
+			g[resourceKey{clusterKind, c.Name}] = c
+		}
+		for _, r := range cg.Routes(proxy) {
+			g[resourceKey{routeKind, r.Name}] = r
+		}
+		out[w] = g
+	}
+	return out, nil
+}
+
+// changed returns the resources of a that are missing from, or differ from those in, b.
+func changed(a, b generated) []resourceKey {
+	var out []resourceKey
+	for k, m := range a {
+		if o, f := b[k]; !f || !proto.Equal(m, o) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// equal returns true if a and b have the same resources.
+func equal(a, b generated) bool {
+	return len(a) == len(b) && len(changed(a, b)) == 0
+}
+
+type validator interface {
+	Validate() error
+}
+
+// validate runs the proto validation Envoy applies to the resource. As the validation does not
+// look into typed configs, those of the listener filters are validated too.
+func validate(m proto.Message) error {
+	if v, ok := m.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	l, ok := m.(*listener.Listener)
+	if !ok {
+		return nil
+	}
+	for _, lf := range l.ListenerFilters {
+		if err := validateTypedConfig(lf.GetTypedConfig()); err != nil {
+			return fmt.Errorf("listener filter %v: %v", lf.Name, err)
+		}
+	}
+	chains := l.FilterChains
+	if l.DefaultFilterChain != nil {
+		chains = append(chains, l.DefaultFilterChain)
+	}
+	for _, fc := range chains {
+		for _, f := range fc.Filters {
+			if err := validateTypedConfig(f.GetTypedConfig()); err != nil {
+				return fmt.Errorf("filter %v: %v", f.Name, err)
+			}
+			if f.GetTypedConfig() == nil || !ptypes.Is(f.GetTypedConfig(), &hcm.HttpConnectionManager{}) {
+				continue
+			}
+			h := &hcm.HttpConnectionManager{}
+			if err := ptypes.UnmarshalAny(f.GetTypedConfig(), h); err != nil {
+				continue
+			}
+			for _, hf := range h.HttpFilters {
+				if err := validateTypedConfig(hf.GetTypedConfig()); err != nil {
+					return fmt.Errorf("http filter %v: %v", hf.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateTypedConfig validates the message held in the typed config. Types that are not known
+// are skipped, as Envoy may be built with extensions that are not.
+func validateTypedConfig(a *any.Any) error {
+	if a == nil {
+		return nil
+	}
+	if _, err := protoregistry.GlobalTypes.FindMessageByURL(a.TypeUrl); err != nil {
+		return nil
+	}
+	var d ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(a, &d); err != nil {
+		return err
+	}
+	if v, ok := d.Message.(validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
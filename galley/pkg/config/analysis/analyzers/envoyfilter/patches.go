@@ -0,0 +1,317 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoyfilter
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// PatchAnalyzer generates the configuration of the workloads each EnvoyFilter selects, and checks
+// that every patch:
+// * matches some of the generated listeners, clusters or routes
+// * does not give a different result when applied in another order relative to other EnvoyFilters
+// * does not produce configuration that Envoy would reject
+type PatchAnalyzer struct{}
+
+var _ analysis.Analyzer = &PatchAnalyzer{}
+
+// removablePatches are the targets a REMOVE patch applies to. A patch to one of these that has no
+// visible effect, as a later patch overrides it, is checked for a match by removing its target.
+var removablePatches = map[networking.EnvoyFilter_ApplyTo]bool{
+	networking.EnvoyFilter_LISTENER:       true,
+	networking.EnvoyFilter_FILTER_CHAIN:   true,
+	networking.EnvoyFilter_NETWORK_FILTER: true,
+	networking.EnvoyFilter_HTTP_FILTER:    true,
+	networking.EnvoyFilter_CLUSTER:        true,
+	networking.EnvoyFilter_VIRTUAL_HOST:   true,
+	networking.EnvoyFilter_HTTP_ROUTE:     true,
+}
+
+// Metadata implements Analyzer
+func (a *PatchAnalyzer) Metadata() analysis.Metadata {
+	inputs := collection.Names{
+		collections.IstioNetworkingV1Alpha3Envoyfilters.Name(),
+		collections.IstioMeshV1Alpha1MeshConfig.Name(),
+		collections.K8SCoreV1Namespaces.Name(),
+		collections.K8SCoreV1Pods.Name(),
+		collections.K8SCoreV1Services.Name(),
+	}
+	return analysis.Metadata{
+		Name: "envoyfilter.PatchAnalyzer",
+		Description: "Checks that EnvoyFilter patches match, do not depend on the order EnvoyFilters are applied in " +
+			"and produce valid configuration for the workloads they select",
+		Inputs: append(inputs, configInputs...),
+	}
+}
+
+// Analyze implements Analyzer
+func (a *PatchAnalyzer) Analyze(c analysis.Context) {
+	var filters []*resource.Instance
+	c.ForEach(collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), func(r *resource.Instance) bool {
+		filters = append(filters, r)
+		return true
+	})
+	if len(filters) == 0 {
+		return
+	}
+
+	m := newMeshState(c)
+	selected := make([][]*workload, len(filters))
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		if !util.PodInMesh(r, c) {
+			return true
+		}
+		for i, f := range filters {
+			if !selects(f, r, m.meshConfig.RootNamespace) {
+				continue
+			}
+			selected[i] = appendWorkload(selected[i], m.workloadFor(r))
+		}
+		return true
+	})
+	var workloads []*workload
+	for _, ws := range selected {
+		for _, w := range ws {
+			workloads = appendWorkload(workloads, w)
+		}
+	}
+	if len(workloads) == 0 {
+		return
+	}
+
+	envoyFilters := make([]config.Config, 0, len(filters))
+	for _, f := range filters {
+		envoyFilters = append(envoyFilters, toConfig(f))
+	}
+	full, err := m.generate(envoyFilters, workloads)
+	if err != nil {
+		scope.Analysis.Warnf("Unable to generate configuration to analyze EnvoyFilters: %v", err)
+		return
+	}
+
+	for i, f := range filters {
+		if c.Canceled() || len(selected[i]) == 0 {
+			continue
+		}
+		a.analyzePatches(c, m, envoyFilters, i, f, selected[i], full)
+		for j := i + 1; j < len(filters); j++ {
+			a.analyzeOrder(c, m, envoyFilters, i, j, filters, selected, full)
+		}
+	}
+}
+
+// analyzePatches checks each of the patches of the filter selected[i] against the configuration
+// generated without it.
+func (a *PatchAnalyzer) analyzePatches(c analysis.Context, ms *meshState, envoyFilters []config.Config,
+	i int, r *resource.Instance, selected []*workload, full map[*workload]generated) {
+	ef := r.Message.(*networking.EnvoyFilter)
+	for pi, patch := range ef.ConfigPatches {
+		without := withPatches(envoyFilters, i, func(spec *networking.EnvoyFilter) {
+			spec.ConfigPatches = append(spec.ConfigPatches[:pi:pi], spec.ConfigPatches[pi+1:]...)
+		})
+		generatedWithout, err := ms.generate(without, selected)
+		if err != nil {
+			scope.Analysis.Warnf("Unable to generate configuration to analyze EnvoyFilter %v: %v", r.Metadata.FullName, err)
+			return
+		}
+
+		matched := false
+		var invalid *invalidResource
+		for _, w := range selected {
+			if !equal(full[w], generatedWithout[w]) {
+				matched = true
+			}
+			if invalid == nil {
+				invalid = findInvalid(w, changed(full[w], generatedWithout[w]), full[w], generatedWithout[w])
+			}
+		}
+		if !matched && removablePatches[patch.ApplyTo] && patch.Patch != nil && patch.Patch.Operation != networking.EnvoyFilter_Patch_REMOVE {
+			removed := withPatches(envoyFilters, i, func(spec *networking.EnvoyFilter) {
+				spec.ConfigPatches[pi].Patch.Operation = networking.EnvoyFilter_Patch_REMOVE
+			})
+			generatedRemoved, err := ms.generate(removed, selected)
+			if err != nil {
+				scope.Analysis.Warnf("Unable to generate configuration to analyze EnvoyFilter %v: %v", r.Metadata.FullName, err)
+				return
+			}
+			for _, w := range selected {
+				if !equal(generatedRemoved[w], generatedWithout[w]) {
+					matched = true
+				}
+			}
+		}
+
+		if !matched {
+			m := msg.NewEnvoyFilterPatchNoMatch(r, pi, patch.ApplyTo.String())
+			if line, ok := util.ErrorLine(r, fmt.Sprintf(util.EnvoyFilterConfigPatch, pi)); ok {
+				m.Line = line
+			}
+			c.Report(collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), m)
+		}
+		if invalid != nil {
+			m := msg.NewEnvoyFilterInvalidConfig(r, pi, invalid.key.kind, invalid.key.name, invalid.workload, invalid.err)
+			if line, ok := util.ErrorLine(r, fmt.Sprintf(util.EnvoyFilterConfigPatch, pi)); ok {
+				m.Line = line
+			}
+			c.Report(collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), m)
+		}
+	}
+}
+
+// analyzeOrder checks whether swapping the order of filters i and j changes the configuration of
+// the workloads both select. Filters in different namespaces are always applied root namespace
+// first, so only filters in the same namespace, with patches to the same targets, are compared.
+func (a *PatchAnalyzer) analyzeOrder(c analysis.Context, ms *meshState, envoyFilters []config.Config,
+	i, j int, filters []*resource.Instance, selected [][]*workload, full map[*workload]generated) {
+	if filters[i].Metadata.FullName.Namespace != filters[j].Metadata.FullName.Namespace ||
+		!sharePatchTargets(filters[i].Message.(*networking.EnvoyFilter), filters[j].Message.(*networking.EnvoyFilter)) {
+		return
+	}
+	var shared []*workload
+	for _, w := range selected[i] {
+		for _, o := range selected[j] {
+			if w == o {
+				shared = append(shared, w)
+			}
+		}
+	}
+	if len(shared) == 0 {
+		return
+	}
+
+	swapped := swapOrder(envoyFilters, i, j)
+	generatedSwapped, err := ms.generate(swapped, shared)
+	if err != nil {
+		scope.Analysis.Warnf("Unable to generate configuration to analyze EnvoyFilter %v: %v", filters[i].Metadata.FullName, err)
+		return
+	}
+	for _, w := range shared {
+		if equal(full[w], generatedSwapped[w]) {
+			continue
+		}
+		for _, pair := range [][2]*resource.Instance{{filters[i], filters[j]}, {filters[j], filters[i]}} {
+			other := fmt.Sprintf("%s.%s", pair[1].Metadata.FullName.Name, pair[1].Metadata.FullName.Namespace)
+			m := msg.NewEnvoyFilterOrderDependent(pair[0], w.name, other)
+			if line, ok := util.ErrorLine(pair[0], fmt.Sprintf(util.MetadataName)); ok {
+				m.Line = line
+			}
+			c.Report(collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), m)
+		}
+		return
+	}
+}
+
+// selects returns true if the EnvoyFilter applies to the pod, as decided by the push context.
+func selects(r *resource.Instance, pod *resource.Instance, rootNamespace string) bool {
+	ns := r.Metadata.FullName.Namespace
+	if ns != pod.Metadata.FullName.Namespace && ns.String() != rootNamespace {
+		return false
+	}
+	ef := r.Message.(*networking.EnvoyFilter)
+	if ef.WorkloadSelector == nil || len(ef.WorkloadSelector.Labels) == 0 {
+		return true
+	}
+	return labels.Instance(ef.WorkloadSelector.Labels).SubsetOf(pod.Message.(*v1.Pod).Labels)
+}
+
+func appendWorkload(workloads []*workload, w *workload) []*workload {
+	for _, o := range workloads {
+		if o == w {
+			return workloads
+		}
+	}
+	return append(workloads, w)
+}
+
+// withPatches returns a copy of the EnvoyFilters, with the spec of filter i changed by fn.
+func withPatches(envoyFilters []config.Config, i int, fn func(spec *networking.EnvoyFilter)) []config.Config {
+	out := append([]config.Config{}, envoyFilters...)
+	spec := envoyFilters[i].Spec.(*networking.EnvoyFilter).DeepCopy()
+	fn(spec)
+	out[i].Spec = spec
+	return out
+}
+
+// swapOrder returns a copy of the EnvoyFilters, with the creation time of filters i and j swapped.
+// Filters created at the same time are ordered by name, so the first of them is made later instead.
+func swapOrder(envoyFilters []config.Config, i, j int) []config.Config {
+	out := append([]config.Config{}, envoyFilters...)
+	ti, tj := out[i].CreationTimestamp, out[j].CreationTimestamp
+	if ti.Equal(tj) {
+		first := i
+		if out[j].Name+"."+out[j].Namespace < out[i].Name+"."+out[i].Namespace {
+			first = j
+		}
+		out[first].CreationTimestamp = ti.Add(time.Nanosecond)
+		return out
+	}
+	out[i].CreationTimestamp, out[j].CreationTimestamp = tj, ti
+	return out
+}
+
+// sharePatchTargets returns true if both EnvoyFilters patch the same kind of object.
+func sharePatchTargets(a, b *networking.EnvoyFilter) bool {
+	for _, pa := range a.ConfigPatches {
+		for _, pb := range b.ConfigPatches {
+			if pa.ApplyTo == pb.ApplyTo {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type invalidResource struct {
+	workload string
+	key      resourceKey
+	err      error
+}
+
+// findInvalid returns the first of the changed resources that is invalid with the patch applied,
+// but was valid, or missing, without it.
+func findInvalid(w *workload, changes []resourceKey, with, without generated) *invalidResource {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].kind != changes[j].kind {
+			return changes[i].kind < changes[j].kind
+		}
+		return changes[i].name < changes[j].name
+	})
+	for _, k := range changes {
+		err := validate(with[k])
+		if err == nil {
+			continue
+		}
+		if prev, f := without[k]; f && validate(prev) != nil {
+			continue
+		}
+		return &invalidResource{workload: w.name, key: k, err: err}
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"istio.io/api/label"
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
@@ -78,6 +79,15 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 				m.Line = line
 			}
 
+			description := fmt.Sprintf("Label the namespace with %s=%s", util.InjectionLabelName, util.InjectionLabelEnableValue)
+			if len(r.Metadata.Labels) == 0 {
+				m.Fixes = append(m.Fixes, diag.NewFix(description, diag.Add(diag.PatchPath("metadata", "labels"),
+					map[string]string{util.InjectionLabelName: util.InjectionLabelEnableValue})))
+			} else {
+				m.Fixes = append(m.Fixes, diag.NewFix(description, diag.Add(
+					diag.PatchPath("metadata", "labels", util.InjectionLabelName), util.InjectionLabelEnableValue)))
+			}
+
 			c.Report(collections.K8SCoreV1Namespaces.Name(), m)
 			return true
 		}
@@ -16,13 +16,17 @@ package service
 
 import (
 	"fmt"
+	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	configKube "istio.io/istio/pkg/config/kube"
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -33,6 +37,17 @@ type PortNameAnalyzer struct{}
 
 var _ analysis.Analyzer = &PortNameAnalyzer{}
 
+// wellKnownPorts are the protocols of the ports that are commonly used by a single protocol.
+var wellKnownPorts = map[int32]protocol.Instance{
+	80:    protocol.HTTP,
+	443:   protocol.HTTPS,
+	3306:  protocol.MySQL,
+	6379:  protocol.Redis,
+	8080:  protocol.HTTP,
+	8443:  protocol.HTTPS,
+	27017: protocol.Mongo,
+}
+
 // Metadata implements Analyzer
 func (s *PortNameAnalyzer) Metadata() analysis.Metadata {
 	return analysis.Metadata{
@@ -76,7 +91,41 @@ func (s *PortNameAnalyzer) analyzeService(r *resource.Instance, c analysis.Conte
 				m.Line = line
 			}
 
+			if name, ok := suggestPortName(svc, port); ok {
+				m.Fixes = append(m.Fixes, diag.NewFix(
+					fmt.Sprintf("Name the port %s", name),
+					diag.Add(diag.PatchPath("spec", "ports", i, "name"), name)))
+			}
+
 			c.Report(collections.K8SCoreV1Services.Name(), m)
 		}
 	}
 }
+
+// suggestPortName returns a name for the port that follows the naming convention, if its protocol can be
+// inferred from the name of its target port or from its number.
+func suggestPortName(svc *v1.ServiceSpec, port v1.ServicePort) (string, bool) {
+	p := protocol.Unsupported
+	if port.TargetPort.Type == intstr.String {
+		p = configKube.ConvertProtocol(port.Port, port.TargetPort.StrVal, port.Protocol, nil)
+	}
+	if p.IsUnsupported() {
+		var ok bool
+		if p, ok = wellKnownPorts[port.Port]; !ok {
+			return "", false
+		}
+	}
+
+	name := strings.ToLower(string(p))
+	if port.Name != "" {
+		name += "-" + port.Name
+	}
+
+	// Port names must be unique within the service.
+	for _, other := range svc.Ports {
+		if other.Name == name {
+			return "", false
+		}
+	}
+	return name, true
+}
@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// AddressAnalyzer checks that ServiceEntries with resolution NONE set addresses for their TCP ports.
+// Without addresses, the proxy cannot tell the traffic for such a ServiceEntry apart from any
+// other traffic on the same port, so the ServiceEntry matches all of it.
+type AddressAnalyzer struct{}
+
+var _ analysis.Analyzer = &AddressAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *AddressAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "serviceentry.AddressAnalyzer",
+		Description: "Checks that ServiceEntries with resolution NONE set addresses for their TCP ports",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *AddressAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		if se.Resolution != v1alpha3.ServiceEntry_NONE || len(se.Addresses) > 0 {
+			return true
+		}
+
+		for i, p := range se.Ports {
+			// HTTP traffic is matched by host, and TLS traffic by SNI, so only plain TCP
+			// protocols need an address to be told apart.
+			proto := protocol.Parse(p.Protocol)
+			if !proto.IsTCP() || proto.IsTLS() {
+				continue
+			}
+
+			m := msg.NewServiceEntryAddressRequired(r, int(p.Number), string(proto))
+
+			if line, ok := util.ErrorLine(r, fmt.Sprintf(util.ServiceEntryPort, i)); ok {
+				m.Line = line
+			}
+
+			c.Report(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), m)
+		}
+
+		return true
+	})
+}
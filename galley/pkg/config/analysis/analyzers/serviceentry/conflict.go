@@ -0,0 +1,204 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/kube"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// HostConflictAnalyzer checks that ServiceEntry hosts do not collide with Kubernetes services or
+// other ServiceEntries that define the same host with different ports or protocols.
+type HostConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &HostConflictAnalyzer{}
+
+// hostDefinition is a single definition of a host, by either a ServiceEntry or a service.
+type hostDefinition struct {
+	r *resource.Instance
+	// kind is the kind of the defining resource, used in messages.
+	kind string
+	// namespaces are the namespaces the definition is visible in, or util.ExportToAllNamespaces.
+	namespaces map[string]bool
+	// ports maps port numbers to protocols. Protocols that are detected at runtime are
+	// recorded as protocol.Unsupported.
+	ports map[uint32]protocol.Instance
+}
+
+// Metadata implements Analyzer
+func (a *HostConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "serviceentry.HostConflictAnalyzer",
+		Description: "Checks that ServiceEntry hosts are not defined by services or other ServiceEntries " +
+			"with different ports or protocols",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *HostConflictAnalyzer) Analyze(c analysis.Context) {
+	definitions := initHostDefinitions(c)
+
+	c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		self := newServiceEntryDefinition(r)
+
+		for i, h := range se.GetHosts() {
+			host := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h)
+			for _, d := range definitions[host] {
+				if d.kind == self.kind && d.r.Metadata.FullName == r.Metadata.FullName {
+					continue
+				}
+				if !overlaps(self, d) || compatible(self, d) {
+					continue
+				}
+
+				m := msg.NewServiceEntryHostConflict(r, h, fmt.Sprintf("%s %s", d.kind, name(d.r)))
+
+				if line, ok := util.ErrorLine(r, fmt.Sprintf(util.ServiceEntryHost, i)); ok {
+					m.Line = line
+				}
+
+				c.Report(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), m)
+			}
+		}
+
+		return true
+	})
+}
+
+// initHostDefinitions returns the definitions of each host, keyed by FQDN.
+func initHostDefinitions(c analysis.Context) map[string][]*hostDefinition {
+	result := make(map[string][]*hostDefinition)
+
+	c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		d := newServiceEntryDefinition(r)
+
+		for _, h := range se.GetHosts() {
+			host := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h)
+			result[host] = append(result[host], d)
+		}
+		return true
+	})
+
+	c.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		svc := r.Message.(*v1.ServiceSpec)
+
+		var exportTo []string
+		if e, ok := r.Metadata.Annotations[annotation.NetworkingExportTo.Name]; ok {
+			exportTo = strings.Split(e, ",")
+		}
+
+		d := &hostDefinition{
+			r:          r,
+			kind:       "Service",
+			namespaces: visibleIn(r.Metadata.FullName.Namespace, exportTo),
+			ports:      make(map[uint32]protocol.Instance),
+		}
+		for _, p := range svc.Ports {
+			d.ports[uint32(p.Port)] = kube.ConvertProtocol(p.Port, p.Name, p.Protocol, p.AppProtocol)
+		}
+
+		host := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, r.Metadata.FullName.Name.String())
+		result[host] = append(result[host], d)
+		return true
+	})
+
+	return result
+}
+
+func newServiceEntryDefinition(r *resource.Instance) *hostDefinition {
+	se := r.Message.(*v1alpha3.ServiceEntry)
+
+	d := &hostDefinition{
+		r:          r,
+		kind:       "ServiceEntry",
+		namespaces: visibleIn(r.Metadata.FullName.Namespace, se.ExportTo),
+		ports:      make(map[uint32]protocol.Instance),
+	}
+	for _, p := range se.Ports {
+		d.ports[p.Number] = protocol.Parse(p.Protocol)
+	}
+	return d
+}
+
+// visibleIn returns the namespaces a resource in the given namespace is exported to. Without
+// exportTo, it is exported to all namespaces.
+func visibleIn(namespace resource.Namespace, exportTo []string) map[string]bool {
+	if len(exportTo) == 0 {
+		return map[string]bool{util.ExportToAllNamespaces: true}
+	}
+	namespaces := make(map[string]bool, len(exportTo))
+	for _, e := range exportTo {
+		e = strings.TrimSpace(e)
+		if e == util.ExportToNamespaceLocal {
+			e = namespace.String()
+		}
+		namespaces[e] = true
+	}
+	return namespaces
+}
+
+// overlaps returns true if there is a namespace that both definitions are visible in.
+func overlaps(a, b *hostDefinition) bool {
+	if a.namespaces[util.ExportToAllNamespaces] || b.namespaces[util.ExportToAllNamespaces] {
+		return true
+	}
+	for ns := range a.namespaces {
+		if b.namespaces[ns] {
+			return true
+		}
+	}
+	return false
+}
+
+// compatible returns true if both definitions have the same ports and, where both protocols are
+// explicitly known, the same protocols.
+func compatible(a, b *hostDefinition) bool {
+	if len(a.ports) != len(b.ports) {
+		return false
+	}
+	for number, pa := range a.ports {
+		pb, ok := b.ports[number]
+		if !ok {
+			return false
+		}
+		if pa != pb && !pa.IsUnsupported() && !pb.IsUnsupported() {
+			return false
+		}
+	}
+	return true
+}
+
+func name(r *resource.Instance) string {
+	return fmt.Sprintf("%s.%s", r.Metadata.FullName.Name, r.Metadata.FullName.Namespace)
+}
@@ -48,6 +48,10 @@ const (
 	// Required parameters: http index, allowOrigins index.
 	AllowOriginsRegexMatch = "{.spec.http[%d].corsPolicy.allowOrigins[%d].regex}"
 
+	// Path for applyTo in EnvoyFilter config patches.
+	// Required parameters: config patch index.
+	EnvoyFilterConfigPatch = "{.spec.configPatches[%d].applyTo}"
+
 	// Path for workload selector.
 	// Required parameters: selector label.
 	WorkloadSelector = "{.spec.workloadSelector.labels.%s}"
@@ -76,6 +80,18 @@ const (
 	// Required parameters: rule index, from index, namespace index.
 	AuthorizationPolicyNameSpace = "{.spec.rules[%d].from[%d].source.namespaces[%d]}"
 
+	// Path for principal in authorizationPolicy.
+	// Required parameters: rule index, from index, principals field, principal index.
+	AuthorizationPolicyPrincipal = "{.spec.rules[%d].from[%d].source.%s[%d]}"
+
+	// Path for port in authorizationPolicy.
+	// Required parameters: rule index, to index, port index.
+	AuthorizationPolicyPort = "{.spec.rules[%d].to[%d].operation.ports[%d]}"
+
+	// Path for the provider name in authorizationPolicy.
+	// Required parameters: none.
+	AuthorizationPolicyProvider = "{.spec.provider.name}"
+
 	// Path for annotation.
 	// Required parameters: annotation name.
 	Annotation = "{.metadata.annotations.%s}"
@@ -87,6 +103,34 @@ const (
 	// Path for credentialName.
 	// Required parameters: server index.
 	CredentialName = "{.spec.servers[%d].tls.credentialName}"
+
+	// Path for host in ServiceEntry.
+	// Required parameters: host index.
+	ServiceEntryHost = "{.spec.hosts[%d]}"
+
+	// Path for port number in ServiceEntry.
+	// Required parameters: port index.
+	ServiceEntryPort = "{.spec.ports[%d].number}"
+
+	// Path for serviceAccount in WorkloadEntry.
+	// Required parameters: none.
+	WorkloadEntryServiceAccount = "{.spec.serviceAccount}"
+
+	// Path for template port in WorkloadGroup.
+	// Required parameters: port name.
+	WorkloadGroupTemplatePort = "{.spec.template.ports.%s}"
+
+	// Path for HTTP route in VirtualService.
+	// Required parameters: http index.
+	HTTPRoute = "{.spec.http[%d]}"
+
+	// Path for match of HTTP route in VirtualService.
+	// Required parameters: http index, match index.
+	HTTPMatch = "{.spec.http[%d].match[%d]}"
+
+	// Path for the destinations of a route in VirtualService.
+	// Required parameters: route rule, route rule index.
+	RouteDestinations = "{.spec.%s[%d].route}"
 )
 
 // ErrorLine returns the line number of the input path key in the resource
@@ -99,6 +143,29 @@ func ErrorLine(r *resource.Instance, path string) (line int, found bool) {
 	return line, true
 }
 
+// ErrorLineOfNode returns the first line of the input path key in the resource. Unlike ErrorLine, the
+// path may point to a map or a list, such as the match of a route, rather than to a leaf field.
+func ErrorLineOfNode(r *resource.Instance, path string) (line int, found bool) {
+	fieldMap := r.Origin.FieldMap()
+	if line, ok := fieldMap[path]; ok {
+		return line, true
+	}
+
+	prefix := strings.TrimSuffix(path, "}")
+	for key, l := range fieldMap {
+		if len(key) <= len(prefix) || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if next := key[len(prefix)]; next != '.' && next != '[' {
+			continue
+		}
+		if !found || l < line {
+			line, found = l, true
+		}
+	}
+	return line, found
+}
+
 // ExtractLabelFromSelectorString returns the label of the match in the k8s labels.Selector
 func ExtractLabelFromSelectorString(s string) string {
 	equalIndex := strings.Index(s, "=")
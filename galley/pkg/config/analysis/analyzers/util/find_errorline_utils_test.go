@@ -60,6 +60,32 @@ func TestErrorLine(t *testing.T) {
 	g.Expect(err2).To(Equal(false))
 }
 
+func TestErrorLineOfNode(t *testing.T) {
+	g := NewWithT(t)
+	r := &resource.Instance{Origin: &rt.Origin{FieldsMap: map[string]int{
+		"{.spec.http[0].match[0].uri.prefix}":       3,
+		"{.spec.http[0].match[1].uri.prefix}":       5,
+		"{.spec.http[0].match[1].headers.x.exact}":  6,
+		"{.spec.http[0].match[10].uri.prefix}":      4,
+		"{.spec.http[1].route[0].destination.host}": 9,
+	}}}
+
+	line, found := ErrorLineOfNode(r, fmt.Sprintf(HTTPMatch, 0, 1))
+	g.Expect(found).To(BeTrue())
+	g.Expect(line).To(Equal(5))
+
+	line, found = ErrorLineOfNode(r, fmt.Sprintf(RouteDestinations, "http", 1))
+	g.Expect(found).To(BeTrue())
+	g.Expect(line).To(Equal(9))
+
+	line, found = ErrorLineOfNode(r, "{.spec.http[0].match[1].uri.prefix}")
+	g.Expect(found).To(BeTrue())
+	g.Expect(line).To(Equal(5))
+
+	_, found = ErrorLineOfNode(r, fmt.Sprintf(HTTPMatch, 0, 2))
+	g.Expect(found).To(BeFalse())
+}
+
 func TestConstants(t *testing.T) {
 	g := NewWithT(t)
 
@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+
+	"istio.io/api/networking/v1alpha3"
+)
+
+// matchCovers returns true if all the requests that match b also match a. It errs on the side of
+// returning false when this can not be decided, such as for most pairs of regular expressions.
+func matchCovers(a, b *v1alpha3.HTTPMatchRequest) bool {
+	if a.Uri != nil && b.IgnoreUriCase && !a.IgnoreUriCase {
+		return false
+	}
+	// All paths start with a slash, so a prefix match of "/" matches all requests.
+	bURI := b.Uri
+	if bURI == nil {
+		bURI = &v1alpha3.StringMatch{MatchType: &v1alpha3.StringMatch_Prefix{Prefix: "/"}}
+	}
+	if !stringMatchCovers(a.Uri, bURI, a.IgnoreUriCase) ||
+		!stringMatchCovers(a.Scheme, b.Scheme, false) ||
+		!stringMatchCovers(a.Method, b.Method, false) ||
+		!stringMatchCovers(a.Authority, b.Authority, false) {
+		return false
+	}
+
+	if !stringMatchesCover(a.Headers, b.Headers) || !stringMatchesCover(a.QueryParams, b.QueryParams) {
+		return false
+	}
+
+	// b must exclude at least the same headers as a, in the same way.
+	for name, am := range a.WithoutHeaders {
+		bm, ok := b.WithoutHeaders[name]
+		if !ok || !proto.Equal(am, bm) {
+			return false
+		}
+	}
+
+	if a.Port != 0 && a.Port != b.Port {
+		return false
+	}
+
+	for k, v := range a.SourceLabels {
+		if bv, ok := b.SourceLabels[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	if len(a.Gateways) > 0 {
+		if len(b.Gateways) == 0 {
+			return false
+		}
+		for _, gw := range b.Gateways {
+			if !contains(a.Gateways, gw) {
+				return false
+			}
+		}
+	}
+
+	return a.SourceNamespace == "" || a.SourceNamespace == b.SourceNamespace
+}
+
+func stringMatchesCover(a, b map[string]*v1alpha3.StringMatch) bool {
+	for name, am := range a {
+		bm, ok := b[name]
+		if !ok {
+			return false
+		}
+		// A StringMatch without a match type only requires the value to be present.
+		if am.GetMatchType() != nil && !stringMatchCovers(am, bm, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringMatchCovers returns true if all the values that match b also match a. A nil StringMatch
+// matches all values.
+func stringMatchCovers(a, b *v1alpha3.StringMatch, ignoreCase bool) bool {
+	if a == nil {
+		return true
+	}
+	if b == nil {
+		return matchesAll(a)
+	}
+
+	fold := func(s string) string {
+		if ignoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+
+	// Normalize b to either a literal value or a literal prefix of all the values it matches.
+	var value, prefix string
+	var isValue bool
+	switch m := b.MatchType.(type) {
+	case *v1alpha3.StringMatch_Exact:
+		value, isValue = m.Exact, true
+	case *v1alpha3.StringMatch_Prefix:
+		prefix = m.Prefix
+	case *v1alpha3.StringMatch_Regex:
+		if ar, ok := a.MatchType.(*v1alpha3.StringMatch_Regex); ok && ar.Regex == m.Regex {
+			return true
+		}
+		re, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return false
+		}
+		prefix, isValue = re.LiteralPrefix()
+		value = prefix
+	default:
+		return matchesAll(a)
+	}
+
+	switch m := a.MatchType.(type) {
+	case *v1alpha3.StringMatch_Exact:
+		return isValue && fold(value) == fold(m.Exact)
+	case *v1alpha3.StringMatch_Prefix:
+		if isValue {
+			return strings.HasPrefix(fold(value), fold(m.Prefix))
+		}
+		return strings.HasPrefix(fold(prefix), fold(m.Prefix))
+	case *v1alpha3.StringMatch_Regex:
+		if ignoreCase {
+			return false
+		}
+		if isValue {
+			re, err := regexp.Compile("^(?:" + m.Regex + ")$")
+			return err == nil && re.MatchString(value)
+		}
+		if p, ok := regexPrefix(m.Regex); ok {
+			return strings.HasPrefix(prefix, p)
+		}
+		return false
+	}
+	return false
+}
+
+// matchesAll returns true if the StringMatch matches all values.
+func matchesAll(m *v1alpha3.StringMatch) bool {
+	switch t := m.GetMatchType().(type) {
+	case *v1alpha3.StringMatch_Prefix:
+		return t.Prefix == ""
+	case *v1alpha3.StringMatch_Regex:
+		p, ok := regexPrefix(t.Regex)
+		return ok && p == ""
+	}
+	return false
+}
+
+// regexPrefix returns p if the regex is equivalent to matching all values with the literal prefix p.
+func regexPrefix(regex string) (string, bool) {
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+
+	isAnything := func(re *syntax.Regexp) bool {
+		return re.Op == syntax.OpStar && len(re.Sub) == 1 &&
+			(re.Sub[0].Op == syntax.OpAnyChar || re.Sub[0].Op == syntax.OpAnyCharNotNL)
+	}
+
+	if isAnything(re) {
+		return "", true
+	}
+	if re.Op == syntax.OpConcat && len(re.Sub) == 2 && re.Sub[0].Op == syntax.OpLiteral &&
+		re.Sub[0].Flags&syntax.FoldCase == 0 && isAnything(re.Sub[1]) {
+		return string(re.Sub[0].Rune), true
+	}
+	return "", false
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"istio.io/api/networking/v1alpha3"
+)
+
+func exact(s string) *v1alpha3.StringMatch {
+	return &v1alpha3.StringMatch{MatchType: &v1alpha3.StringMatch_Exact{Exact: s}}
+}
+
+func prefix(s string) *v1alpha3.StringMatch {
+	return &v1alpha3.StringMatch{MatchType: &v1alpha3.StringMatch_Prefix{Prefix: s}}
+}
+
+func regex(s string) *v1alpha3.StringMatch {
+	return &v1alpha3.StringMatch{MatchType: &v1alpha3.StringMatch_Regex{Regex: s}}
+}
+
+func TestStringMatchCovers(t *testing.T) {
+	cases := []struct {
+		name       string
+		a          *v1alpha3.StringMatch
+		b          *v1alpha3.StringMatch
+		ignoreCase bool
+		want       bool
+	}{
+		{"nil covers all", nil, exact("/a"), false, true},
+		{"exact covers nothing else", exact("/a"), exact("/b"), false, false},
+		{"exact covers itself", exact("/a"), exact("/a"), false, true},
+		{"prefix covers longer prefix", prefix("/api"), prefix("/api/v1"), false, true},
+		{"prefix does not cover shorter prefix", prefix("/api/v1"), prefix("/api"), false, false},
+		{"prefix covers exact", prefix("/api"), exact("/api/v1"), false, true},
+		{"prefix ignoring case", prefix("/API"), exact("/api/v1"), true, true},
+		{"prefix covers regex with literal prefix", prefix("/api"), regex("/api/v[0-9]+"), false, true},
+		{"prefix does not cover regex with alternatives", prefix("/api"), regex("/api|/other"), false, false},
+		{"regex covers matching exact", regex("/api/v[0-9]+"), exact("/api/v2"), false, true},
+		{"regex does not cover other exact", regex("/api/v[0-9]+"), exact("/api/vx"), false, false},
+		{"regex is anchored", regex("/api"), exact("/api/v1"), false, false},
+		{"prefix regex covers prefix", regex("/static/.*"), prefix("/static/img"), false, true},
+		{"prefix regex does not cover other prefix", regex("/static/.*"), prefix("/img"), false, false},
+		{"regex covers itself", regex("/a+"), regex("/a+"), false, true},
+		{"undecidable regexes", regex("/a+"), regex("/aa+"), false, false},
+		{"match all regex covers presence", regex(".*"), &v1alpha3.StringMatch{}, false, true},
+		{"prefix does not cover presence", prefix("a"), nil, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, stringMatchCovers(tc.a, tc.b, tc.ignoreCase))
+		})
+	}
+}
+
+func TestMatchCovers(t *testing.T) {
+	assert := assert.New(t)
+
+	// A prefix of "/" matches all requests.
+	assert.True(matchCovers(&v1alpha3.HTTPMatchRequest{Uri: prefix("/")}, &v1alpha3.HTTPMatchRequest{}))
+	assert.False(matchCovers(&v1alpha3.HTTPMatchRequest{Uri: prefix("/a")}, &v1alpha3.HTTPMatchRequest{}))
+
+	// A match without headers covers matches with headers, but not the other way around.
+	withHeader := &v1alpha3.HTTPMatchRequest{
+		Uri:     prefix("/api"),
+		Headers: map[string]*v1alpha3.StringMatch{"end-user": exact("jason")},
+	}
+	assert.True(matchCovers(&v1alpha3.HTTPMatchRequest{Uri: prefix("/api")}, withHeader))
+	assert.False(matchCovers(withHeader, &v1alpha3.HTTPMatchRequest{Uri: prefix("/api")}))
+
+	// A case sensitive match does not cover a case insensitive one.
+	assert.False(matchCovers(
+		&v1alpha3.HTTPMatchRequest{Uri: prefix("/api")},
+		&v1alpha3.HTTPMatchRequest{Uri: prefix("/api"), IgnoreUriCase: true}))
+
+	// Matches restricted to gateways only cover matches restricted to the same gateways.
+	assert.True(matchCovers(
+		&v1alpha3.HTTPMatchRequest{Gateways: []string{"a", "b"}},
+		&v1alpha3.HTTPMatchRequest{Gateways: []string{"a"}}))
+	assert.False(matchCovers(
+		&v1alpha3.HTTPMatchRequest{Gateways: []string{"a"}},
+		&v1alpha3.HTTPMatchRequest{}))
+
+	// Ports must match.
+	assert.False(matchCovers(&v1alpha3.HTTPMatchRequest{Port: 80}, &v1alpha3.HTTPMatchRequest{Port: 8080}))
+}
@@ -16,10 +16,12 @@ package virtualservice
 
 import (
 	"fmt"
+	"strings"
 
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
@@ -76,6 +78,11 @@ func (a *DestinationHostAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 				m.Line = line
 			}
 
+			path := diag.PatchPath("spec", d.RouteRule, d.ServiceIndex, "route", d.DestinationIndex, "destination", "host")
+			if f, ok := hostFix(r.Metadata.FullName.Namespace, d.Destination.GetHost(), path, serviceEntryHosts); ok {
+				m.Fixes = append(m.Fixes, f)
+			}
+
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
 			continue
 		}
@@ -93,6 +100,11 @@ func (a *DestinationHostAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 				m.Line = line
 			}
 
+			path := diag.PatchPath("spec", "http", d.ServiceIndex, "mirror", "host")
+			if f, ok := hostFix(r.Metadata.FullName.Namespace, d.Destination.GetHost(), path, serviceEntryHosts); ok {
+				m.Fixes = append(m.Fixes, f)
+			}
+
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
 			continue
 		}
@@ -100,6 +112,30 @@ func (a *DestinationHostAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 	}
 }
 
+// hostFix returns a fix that replaces the host with the FQDN of the only host visible from the namespace that
+// it may be a prefix of, such as reviews.bookinfo.svc.cluster.local for reviews.bookinfo.
+func hostFix(sourceNs resource.Namespace, host, path string,
+	serviceEntryHosts map[util.ScopedFqdn]*v1alpha3.ServiceEntry) (diag.Fix, bool) {
+	var fqdn string
+	for h := range serviceEntryHosts {
+		if !h.InScopeOf(sourceNs.String()) {
+			continue
+		}
+		_, candidate := h.GetScopeAndFqdn()
+		if !strings.HasPrefix(candidate, host+".") || candidate == fqdn {
+			continue
+		}
+		if fqdn != "" {
+			return diag.Fix{}, false
+		}
+		fqdn = candidate
+	}
+	if fqdn == "" {
+		return diag.Fix{}, false
+	}
+	return diag.NewFix(fmt.Sprintf("Use the FQDN %s", fqdn), diag.Replace(path, fqdn)), true
+}
+
 func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *AnnotatedDestination, s *v1alpha3.ServiceEntry) {
 	if d.Destination.GetPort() == nil {
 		// If destination port isn't specified, it's only a problem if the service being referenced exposes multiple ports.
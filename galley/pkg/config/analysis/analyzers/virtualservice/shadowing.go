@@ -0,0 +1,335 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ShadowingAnalyzer checks for HTTP matches that never take effect because all the requests they
+// match are matched by an earlier rule, either of the same VirtualService or of another
+// VirtualService that is merged with it on a gateway. It also checks that destination weights
+// sum to 100.
+type ShadowingAnalyzer struct{}
+
+var _ analysis.Analyzer = &ShadowingAnalyzer{}
+
+// boundVirtualService is a VirtualService that is bound to a gateway host.
+type boundVirtualService struct {
+	r  *resource.Instance
+	vs *v1alpha3.VirtualService
+}
+
+// gatewayHost identifies the virtual host of a gateway that VirtualServices are merged into.
+type gatewayHost struct {
+	gateway string
+	host    string
+}
+
+// shadowedMatch identifies a match that is reported, to avoid reporting it more than once.
+type shadowedMatch struct {
+	vs    resource.FullName
+	rule  int
+	match int
+}
+
+// Metadata implements Analyzer
+func (a *ShadowingAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "virtualservice.ShadowingAnalyzer",
+		Description: "Checks virtual services for matches that are shadowed by earlier rules, " +
+			"and for destination weights that do not sum to 100",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ShadowingAnalyzer) Analyze(c analysis.Context) {
+	reported := make(map[shadowedMatch]bool)
+	merged := make(map[gatewayHost][]*boundVirtualService)
+
+	c.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+
+		a.analyzeRules(c, r, vs.Http, reported)
+		a.analyzeWeights(c, r, vs)
+
+		// VirtualServices that are bound to the same host of the same gateway are merged, with
+		// the rules of the oldest VirtualService first.
+		for _, gw := range vs.Gateways {
+			if gw == util.MeshGateway {
+				continue
+			}
+			for _, h := range vs.Hosts {
+				key := gatewayHost{
+					gateway: resource.NewShortOrFullName(r.Metadata.FullName.Namespace, gw).String(),
+					host:    util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h),
+				}
+				merged[key] = append(merged[key], &boundVirtualService{r: r, vs: vs})
+			}
+		}
+
+		return true
+	})
+
+	keys := make([]gatewayHost, 0, len(merged))
+	for key, vss := range merged {
+		if len(vss) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].gateway != keys[j].gateway {
+			return keys[i].gateway < keys[j].gateway
+		}
+		return keys[i].host < keys[j].host
+	})
+
+	for _, key := range keys {
+		vss := merged[key]
+		sort.SliceStable(vss, func(i, j int) bool {
+			mi, mj := vss[i].r.Metadata, vss[j].r.Metadata
+			if !mi.CreateTime.Equal(mj.CreateTime) {
+				return mi.CreateTime.Before(mj.CreateTime)
+			}
+			return mi.FullName.String() < mj.FullName.String()
+		})
+		a.analyzeMergedRules(c, vss, key.gateway, reported)
+	}
+}
+
+// analyzeRules reports the HTTP matches of a VirtualService that are shadowed by earlier rules of
+// the same VirtualService.
+func (a *ShadowingAnalyzer) analyzeRules(c analysis.Context, r *resource.Instance, routes []*v1alpha3.HTTPRoute,
+	reported map[shadowedMatch]bool) {
+	for i, route := range routes {
+		if len(route.Match) == 0 {
+			for j := 0; j < i; j++ {
+				if len(routes[j].Match) == 0 {
+					// Rules without matches after the first are reported by the MatchesAnalyzer.
+					break
+				}
+				if coversAll(routes[j]) {
+					a.reportUnreachable(c, r, route, i, routes[j], j, r)
+					break
+				}
+			}
+			continue
+		}
+
+		shadowed := 0
+		duplicates := 0
+		for mi, match := range route.Match {
+			for j := 0; j < i && !reported[shadowedMatch{r.Metadata.FullName, i, mi}]; j++ {
+				covered, duplicate := ruleCovers(routes[j], match)
+				if !covered {
+					continue
+				}
+				shadowed++
+				if duplicate {
+					// Duplicates are reported by the MatchesAnalyzer.
+					duplicates++
+					break
+				}
+				a.reportShadowed(c, r, route, i, match, mi, routes[j], j, r, reported)
+			}
+		}
+		if shadowed == len(route.Match) && duplicates < shadowed {
+			a.reportUnreachable(c, r, route, i, nil, -1, nil)
+		}
+	}
+}
+
+// analyzeMergedRules reports the HTTP matches of VirtualServices that are shadowed by the rules of
+// VirtualServices merged before them on the gateway.
+func (a *ShadowingAnalyzer) analyzeMergedRules(c analysis.Context, vss []*boundVirtualService, gateway string,
+	reported map[shadowedMatch]bool) {
+	for n, bound := range vss {
+		for i, route := range bound.vs.Http {
+			matches := route.Match
+			if len(matches) == 0 {
+				// A rule without matches matches all requests.
+				matches = []*v1alpha3.HTTPMatchRequest{{}}
+			}
+
+			for mi, match := range matches {
+				if !appliesTo(bound.r, match, gateway) {
+					continue
+				}
+				key := shadowedMatch{bound.r.Metadata.FullName, i, mi}
+				for _, earlier := range vss[:n] {
+					if reported[key] {
+						break
+					}
+					for j, er := range earlier.vs.Http {
+						if !appliesToAny(earlier.r, er, gateway) {
+							continue
+						}
+						if covered, _ := ruleCovers(er, match); !covered {
+							continue
+						}
+						if len(route.Match) == 0 {
+							a.reportUnreachable(c, bound.r, route, i, er, j, earlier.r)
+							reported[key] = true
+						} else {
+							a.reportShadowed(c, bound.r, route, i, match, mi, er, j, earlier.r, reported)
+						}
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func (a *ShadowingAnalyzer) analyzeWeights(c analysis.Context, r *resource.Instance, vs *v1alpha3.VirtualService) {
+	// The destinations of each rule are consecutive, so their weights are summed up per rule
+	var rules []*weightedRule
+	for _, d := range getRouteDestinations(vs) {
+		if n := len(rules); n == 0 || rules[n-1].ruleType != d.RouteRule || rules[n-1].index != d.ServiceIndex {
+			rules = append(rules, &weightedRule{ruleType: d.RouteRule, index: d.ServiceIndex})
+		}
+		rule := rules[len(rules)-1]
+		rule.count++
+		rule.total += d.Weight
+	}
+
+	for _, rule := range rules {
+		// A single destination receives all the traffic, so its weight may be omitted but should
+		// not be set to anything other than 100.
+		if rule.total == 100 || (rule.count == 1 && rule.total == 0) {
+			continue
+		}
+		var route interface{}
+		if rule.ruleType == "http" {
+			route = vs.Http[rule.index]
+		}
+		m := msg.NewVirtualServiceRouteWeights(r, rule.ruleType, routeName(route, rule.index), int(rule.total))
+
+		if line, ok := util.ErrorLineOfNode(r, fmt.Sprintf(util.RouteDestinations, rule.ruleType, rule.index)); ok {
+			m.Line = line
+		}
+
+		c.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
+	}
+}
+
+// weightedRule is the number of destinations of a route rule, and the sum of their weights.
+type weightedRule struct {
+	ruleType string
+	index    int
+	count    int
+	total    int32
+}
+
+func (a *ShadowingAnalyzer) reportShadowed(c analysis.Context, r *resource.Instance, route *v1alpha3.HTTPRoute, i int,
+	match *v1alpha3.HTTPMatchRequest, mi int, shadowing *v1alpha3.HTTPRoute, j int, shadowingVS *resource.Instance,
+	reported map[shadowedMatch]bool) {
+	reported[shadowedMatch{r.Metadata.FullName, i, mi}] = true
+
+	m := msg.NewVirtualServiceShadowedMatch(r, requestName(match, mi), routeName(route, i),
+		routeName(shadowing, j), vsName(shadowingVS))
+
+	if line, ok := util.ErrorLineOfNode(r, fmt.Sprintf(util.HTTPMatch, i, mi)); ok {
+		m.Line = line
+	}
+
+	c.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
+}
+
+// reportUnreachable reports a rule that never takes effect. If the rule is shadowed by a single
+// rule, that rule is given; otherwise the matches of the rule are shadowed by several rules.
+func (a *ShadowingAnalyzer) reportUnreachable(c analysis.Context, r *resource.Instance, route *v1alpha3.HTTPRoute, i int,
+	shadowing *v1alpha3.HTTPRoute, j int, shadowingVS *resource.Instance) {
+	reason := "all matches are shadowed by earlier rules"
+	if shadowing != nil {
+		reason = fmt.Sprintf("all requests are matched by HTTP rule %s of VirtualService %s first",
+			routeName(shadowing, j), vsName(shadowingVS))
+	}
+
+	m := msg.NewVirtualServiceUnreachableRule(r, routeName(route, i), reason)
+
+	if line, ok := util.ErrorLineOfNode(r, fmt.Sprintf(util.HTTPRoute, i)); ok {
+		m.Line = line
+	}
+
+	c.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
+}
+
+// ruleCovers returns whether all the requests that match the match are matched by the rule, and
+// whether the rule has a match that duplicates it.
+func ruleCovers(rule *v1alpha3.HTTPRoute, match *v1alpha3.HTTPMatchRequest) (covered bool, duplicate bool) {
+	if len(rule.Match) == 0 {
+		return true, false
+	}
+	for _, m := range rule.Match {
+		if asJSON(m) == asJSON(match) {
+			return true, true
+		}
+	}
+	for _, m := range rule.Match {
+		if matchCovers(m, match) {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// coversAll returns true if the rule matches all requests.
+func coversAll(rule *v1alpha3.HTTPRoute) bool {
+	covered, _ := ruleCovers(rule, &v1alpha3.HTTPMatchRequest{})
+	return covered
+}
+
+// appliesTo returns true if the match applies to requests through the gateway.
+func appliesTo(r *resource.Instance, match *v1alpha3.HTTPMatchRequest, gateway string) bool {
+	if len(match.Gateways) == 0 {
+		return true
+	}
+	for _, gw := range match.Gateways {
+		if resource.NewShortOrFullName(r.Metadata.FullName.Namespace, gw).String() == gateway {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesToAny returns true if any match of the rule applies to requests through the gateway.
+func appliesToAny(r *resource.Instance, rule *v1alpha3.HTTPRoute, gateway string) bool {
+	if len(rule.Match) == 0 {
+		return true
+	}
+	for _, m := range rule.Match {
+		if appliesTo(r, m, gateway) {
+			return true
+		}
+	}
+	return false
+}
+
+func vsName(r *resource.Instance) string {
+	return fmt.Sprintf("%s.%s", r.Metadata.FullName.Name, r.Metadata.FullName.Namespace)
+}
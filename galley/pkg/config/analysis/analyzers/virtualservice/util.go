@@ -24,6 +24,7 @@ type AnnotatedDestination struct {
 	ServiceIndex     int
 	DestinationIndex int
 	Destination      *v1alpha3.Destination
+	Weight           int32
 }
 
 func getRouteDestinations(vs *v1alpha3.VirtualService) []*AnnotatedDestination {
@@ -35,6 +36,7 @@ func getRouteDestinations(vs *v1alpha3.VirtualService) []*AnnotatedDestination {
 				ServiceIndex:     i,
 				DestinationIndex: j,
 				Destination:      rd.GetDestination(),
+				Weight:           rd.GetWeight(),
 			})
 		}
 	}
@@ -45,6 +47,7 @@ func getRouteDestinations(vs *v1alpha3.VirtualService) []*AnnotatedDestination {
 				ServiceIndex:     i,
 				DestinationIndex: j,
 				Destination:      rd.GetDestination(),
+				Weight:           rd.GetWeight(),
 			})
 		}
 	}
@@ -55,6 +58,7 @@ func getRouteDestinations(vs *v1alpha3.VirtualService) []*AnnotatedDestination {
 				ServiceIndex:     i,
 				DestinationIndex: j,
 				Destination:      rd.GetDestination(),
+				Weight:           rd.GetWeight(),
 			})
 		}
 	}
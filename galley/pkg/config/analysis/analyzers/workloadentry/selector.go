@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workloadentry
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// SelectorAnalyzer checks that WorkloadEntries are selected by at least one ServiceEntry workload
+// selector or service selector in their namespace.
+type SelectorAnalyzer struct{}
+
+var _ analysis.Analyzer = &SelectorAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *SelectorAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "workloadentry.SelectorAnalyzer",
+		Description: "Checks that WorkloadEntries are selected by at least one ServiceEntry or service",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Workloadentries.Name(),
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *SelectorAnalyzer) Analyze(c analysis.Context) {
+	selectors := make(map[resource.Namespace][]labels.Selector)
+
+	c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		if se.WorkloadSelector == nil || len(se.WorkloadSelector.Labels) == 0 {
+			return true
+		}
+		ns := r.Metadata.FullName.Namespace
+		selectors[ns] = append(selectors[ns], labels.SelectorFromSet(se.WorkloadSelector.Labels))
+		return true
+	})
+
+	c.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		svc := r.Message.(*v1.ServiceSpec)
+		if len(svc.Selector) == 0 {
+			return true
+		}
+		ns := r.Metadata.FullName.Namespace
+		selectors[ns] = append(selectors[ns], labels.SelectorFromSet(svc.Selector))
+		return true
+	})
+
+	c.ForEach(collections.IstioNetworkingV1Alpha3Workloadentries.Name(), func(r *resource.Instance) bool {
+		we := r.Message.(*v1alpha3.WorkloadEntry)
+		weLabels := labels.Set(we.Labels)
+
+		for _, sel := range selectors[r.Metadata.FullName.Namespace] {
+			if sel.Matches(weLabels) {
+				return true
+			}
+		}
+
+		m := msg.NewWorkloadEntryNotSelected(r)
+
+		if line, ok := util.ErrorLine(r, util.MetadataName); ok {
+			m.Line = line
+		}
+
+		c.Report(collections.IstioNetworkingV1Alpha3Workloadentries.Name(), m)
+
+		return true
+	})
+}
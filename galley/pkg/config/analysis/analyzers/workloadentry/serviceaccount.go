@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workloadentry
+
+import (
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// defaultServiceAccount is created by Kubernetes in every namespace, so it is not required to
+// be part of the analyzed resources.
+const defaultServiceAccount = "default"
+
+// ServiceAccountAnalyzer checks that the service accounts WorkloadEntries run as exist in their namespace.
+type ServiceAccountAnalyzer struct{}
+
+var _ analysis.Analyzer = &ServiceAccountAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ServiceAccountAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "workloadentry.ServiceAccountAnalyzer",
+		Description: "Checks that the service accounts of WorkloadEntries exist in their namespace",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Workloadentries.Name(),
+			collections.K8SCoreV1Serviceaccounts.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ServiceAccountAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Workloadentries.Name(), func(r *resource.Instance) bool {
+		we := r.Message.(*v1alpha3.WorkloadEntry)
+		if we.ServiceAccount == "" || we.ServiceAccount == defaultServiceAccount {
+			return true
+		}
+
+		name := resource.NewFullName(r.Metadata.FullName.Namespace, resource.LocalName(we.ServiceAccount))
+		if c.Exists(collections.K8SCoreV1Serviceaccounts.Name(), name) {
+			return true
+		}
+
+		m := msg.NewReferencedResourceNotFound(r, "service account", we.ServiceAccount)
+
+		if line, ok := util.ErrorLine(r, util.WorkloadEntryServiceAccount); ok {
+			m.Line = line
+		}
+
+		c.Report(collections.IstioNetworkingV1Alpha3Workloadentries.Name(), m)
+
+		return true
+	})
+}
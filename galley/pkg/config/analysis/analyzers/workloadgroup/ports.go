@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workloadgroup
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// PortAnalyzer checks that the ports of WorkloadGroup templates are consistent with the ports of
+// the ServiceEntries that select the group.
+type PortAnalyzer struct{}
+
+var _ analysis.Analyzer = &PortAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *PortAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "workloadgroup.PortAnalyzer",
+		Description: "Checks that WorkloadGroup template ports do not conflict with the ports of selecting ServiceEntries",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Workloadgroups.Name(),
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *PortAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Workloadgroups.Name(), func(r *resource.Instance) bool {
+		wg := r.Message.(*v1alpha3.WorkloadGroup)
+		if wg.Template == nil || len(wg.Template.Ports) == 0 {
+			return true
+		}
+
+		// The labels of the WorkloadEntries created from the group.
+		wgLabels := labels.Set{}
+		for k, v := range wg.GetMetadata().GetLabels() {
+			wgLabels[k] = v
+		}
+		for k, v := range wg.Template.Labels {
+			wgLabels[k] = v
+		}
+
+		portNames := make([]string, 0, len(wg.Template.Ports))
+		for name := range wg.Template.Ports {
+			portNames = append(portNames, name)
+		}
+		sort.Strings(portNames)
+
+		c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(rs *resource.Instance) bool {
+			se := rs.Message.(*v1alpha3.ServiceEntry)
+			if rs.Metadata.FullName.Namespace != r.Metadata.FullName.Namespace ||
+				se.WorkloadSelector == nil || len(se.WorkloadSelector.Labels) == 0 ||
+				!labels.SelectorFromSet(se.WorkloadSelector.Labels).Matches(wgLabels) {
+				return true
+			}
+
+			sePorts := make(map[string]*v1alpha3.Port, len(se.Ports))
+			for _, p := range se.Ports {
+				sePorts[p.Name] = p
+			}
+
+			for _, name := range portNames {
+				target := wg.Template.Ports[name]
+
+				var reason string
+				if p, ok := sePorts[name]; !ok {
+					reason = "the ServiceEntry has no port with this name"
+				} else if p.TargetPort != 0 && p.TargetPort != target {
+					reason = fmt.Sprintf("the ServiceEntry sets targetPort %d, but the template maps the port to %d",
+						p.TargetPort, target)
+				} else {
+					continue
+				}
+
+				m := msg.NewWorkloadGroupPortConflict(r, name,
+					fmt.Sprintf("%s.%s", rs.Metadata.FullName.Name, rs.Metadata.FullName.Namespace), reason)
+
+				if line, ok := util.ErrorLine(r, fmt.Sprintf(util.WorkloadGroupTemplatePort, name)); ok {
+					m.Line = line
+				}
+
+				c.Report(collections.IstioNetworkingV1Alpha3Workloadgroups.Name(), m)
+			}
+
+			return true
+		})
+
+		return true
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fix is a machine-applicable suggestion that resolves the issue reported by a Message.
+type Fix struct {
+	// Description of the change made by the fix.
+	Description string `json:"description"`
+
+	// Patch is the JSON patch (RFC 6902) that makes the change to the resource of the message.
+	Patch []PatchOperation `json:"patch"`
+}
+
+// PatchOperation is an operation of a JSON patch.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Operations of a JSON patch supported by fixes.
+const (
+	AddOp     = "add"
+	ReplaceOp = "replace"
+	RemoveOp  = "remove"
+)
+
+// NewFix returns a new Fix that applies the given operations.
+func NewFix(description string, ops ...PatchOperation) Fix {
+	return Fix{
+		Description: description,
+		Patch:       ops,
+	}
+}
+
+// Add returns an operation that adds the value at the path, replacing the value of an existing map key.
+func Add(path string, value interface{}) PatchOperation {
+	return PatchOperation{Op: AddOp, Path: path, Value: value}
+}
+
+// Replace returns an operation that replaces the existing value at the path.
+func Replace(path string, value interface{}) PatchOperation {
+	return PatchOperation{Op: ReplaceOp, Path: path, Value: value}
+}
+
+// Remove returns an operation that removes the existing value at the path.
+func Remove(path string) PatchOperation {
+	return PatchOperation{Op: RemoveOp, Path: path}
+}
+
+// PatchPath returns the JSON pointer (RFC 6901) to the field with the given path elements, such as
+// PatchPath("spec", "ports", 0, "name") for "/spec/ports/0/name".
+func PatchPath(elems ...interface{}) string {
+	var b strings.Builder
+	for _, e := range elems {
+		b.WriteString("/")
+		b.WriteString(pointerEscaper.Replace(fmt.Sprint(e)))
+	}
+	return b.String()
+}
+
+// SplitPatchPath returns the elements of a JSON pointer, which is the reverse of PatchPath.
+func SplitPatchPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid patch path %q: must start with /", path)
+	}
+	elems := strings.Split(path[1:], "/")
+	for i, e := range elems {
+		elems[i] = pointerUnescaper.Replace(e)
+	}
+	return elems, nil
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
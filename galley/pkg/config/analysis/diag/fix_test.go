@@ -0,0 +1,49 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPatchPath(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(PatchPath("spec", "ports", 0, "name")).To(Equal("/spec/ports/0/name"))
+	g.Expect(PatchPath("metadata", "labels", "sidecar.istio.io/inject")).To(Equal("/metadata/labels/sidecar.istio.io~1inject"))
+	g.Expect(PatchPath("metadata", "annotations", "a~b")).To(Equal("/metadata/annotations/a~0b"))
+	g.Expect(PatchPath()).To(Equal(""))
+}
+
+func TestSplitPatchPath(t *testing.T) {
+	g := NewWithT(t)
+
+	elems, err := SplitPatchPath("/metadata/labels/sidecar.istio.io~1inject")
+	g.Expect(err).To(BeNil())
+	g.Expect(elems).To(Equal([]string{"metadata", "labels", "sidecar.istio.io/inject"}))
+
+	elems, err = SplitPatchPath(PatchPath("a~1b", 3))
+	g.Expect(err).To(BeNil())
+	g.Expect(elems).To(Equal([]string{"a~1b", "3"}))
+
+	elems, err = SplitPatchPath("")
+	g.Expect(err).To(BeNil())
+	g.Expect(elems).To(BeEmpty())
+
+	_, err = SplitPatchPath("spec")
+	g.Expect(err).NotTo(BeNil())
+}
@@ -63,6 +63,12 @@ type Message struct {
 
 	// Line is the line number of the error place in the message
 	Line int
+
+	// Analyzer is the name of the analyzer that reported the message, if known
+	Analyzer string
+
+	// Fixes are the suggested changes to the resource that resolve the issue, if any
+	Fixes []Fix
 }
 
 // Unstructured returns this message as a JSON-style unstructured map
@@ -89,6 +95,10 @@ func (m *Message) Unstructured(includeOrigin bool) map[string]interface{} {
 	}
 	result["documentation_url"] = fmt.Sprintf("%s/%s/%s", url.ConfigAnalysis, strings.ToLower(m.Type.Code()), docQueryString)
 
+	if len(m.Fixes) > 0 {
+		result["fixes"] = m.Fixes
+	}
+
 	return result
 }
 
@@ -72,6 +72,18 @@ func TestMessage_JSON(t *testing.T) {
 		`,"level":"Error","message":"Cheese type not found: \"Feta\"","origin":"toppings/cheese","reference":"path/to/file"}`))
 }
 
+func TestMessageWithFixes_JSON(t *testing.T) {
+	g := NewWithT(t)
+	mt := NewMessageType(Error, "IST0042", "Cheese type not found: %q")
+	m := NewMessage(mt, nil, "Feta")
+	m.Fixes = []Fix{NewFix("Use cheddar", Replace("/spec/cheese", "cheddar"))}
+
+	j, _ := json.Marshal(&m)
+	g.Expect(string(j)).To(Equal(`{"code":"IST0042","documentation_url":"` + url.ConfigAnalysis + `/ist0042/"` +
+		`,"fixes":[{"description":"Use cheddar","patch":[{"op":"replace","path":"/spec/cheese","value":"cheddar"}]}]` +
+		`,"level":"Error","message":"Cheese type not found: \"Feta\""}`))
+}
+
 func TestMessage_ReplaceLine(t *testing.T) {
 	testCases := []string{"test.yaml", "test.yaml:1", "test.yaml:10", "test.yaml: 10", "test", "test:10", "123:10", "123"}
 	result := make([]string, 0)
@@ -0,0 +1,312 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fix applies the fixes suggested by analysis messages to the YAML files that define their resources.
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v3"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+)
+
+const separator = "---"
+
+// Filename returns the name of the file that defines the resource of the message, or "" if the resource
+// does not come from a file.
+func Filename(m diag.Message) string {
+	if m.Resource == nil || m.Resource.Origin == nil {
+		return ""
+	}
+	p, ok := m.Resource.Origin.Reference().(*rt.Position)
+	if !ok || p.Filename == "-" {
+		return ""
+	}
+	return p.Filename
+}
+
+// ApplyToFile applies the fixes of the messages to the file with the given name, which must define their
+// resources. It returns the messages whose fix was applied.
+func ApplyToFile(filename string, messages []diag.Message) ([]diag.Message, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fixed, applied, err := Apply(content, messages)
+	if len(applied) > 0 {
+		if werr := ioutil.WriteFile(filename, fixed, info.Mode()); werr != nil {
+			return nil, werr
+		}
+	}
+	return applied, err
+}
+
+// Apply applies the first fix of each of the messages to the document of the YAML content that defines
+// the resource of the message. Only the documents that are changed are rewritten. It returns the updated
+// content and the messages whose fix was applied, along with the errors of the others.
+func Apply(content []byte, messages []diag.Message) ([]byte, []diag.Message, error) {
+	docs := split(string(content))
+
+	var errs error
+	var applied []diag.Message
+	changed := make(map[int]bool)
+	for _, m := range messages {
+		if len(m.Fixes) == 0 || m.Resource == nil {
+			continue
+		}
+		i := find(docs, m)
+		if i < 0 {
+			errs = multierror.Append(errs, fmt.Errorf("%s: resource not found", m.Resource.Origin.FriendlyName()))
+			continue
+		}
+		if err := apply(docs[i].node, m.Fixes[0]); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %v", m.Resource.Origin.FriendlyName(), err))
+			continue
+		}
+		changed[i] = true
+		applied = append(applied, m)
+	}
+
+	var b bytes.Buffer
+	for i, d := range docs {
+		b.WriteString(d.separator)
+		if !changed[i] {
+			b.WriteString(d.text)
+			continue
+		}
+		if err := encode(&b, d.node); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return b.Bytes(), applied, errs
+}
+
+// document is a document of a multi-document YAML file.
+type document struct {
+	// separator is the separator line that precedes the document, if any.
+	separator string
+	// text of the document.
+	text string
+	// node is the parsed document, or nil if it could not be parsed.
+	node *yaml.Node
+}
+
+func split(content string) []*document {
+	d := &document{}
+	docs := []*document{d}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.HasPrefix(line, separator) && strings.TrimRightFunc(line[len(separator):], unicode.IsSpace) == "" {
+			d = &document{separator: line}
+			docs = append(docs, d)
+			continue
+		}
+		d.text += line
+	}
+
+	for _, d := range docs {
+		n := &yaml.Node{}
+		if err := yaml.Unmarshal([]byte(d.text), n); err == nil && n.Kind == yaml.DocumentNode &&
+			len(n.Content) == 1 && n.Content[0].Kind == yaml.MappingNode {
+			d.node = n
+		}
+	}
+	return docs
+}
+
+// encode writes the document with the indentation used by kubectl and most manifests.
+func encode(w io.Writer, doc *yaml.Node) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// find returns the index of the document that defines the resource of the message, or -1.
+func find(docs []*document, m diag.Message) int {
+	md := m.Resource.Metadata
+	if md.Schema == nil {
+		return -1
+	}
+	for i, d := range docs {
+		if d.node == nil {
+			continue
+		}
+		obj := d.node.Content[0]
+		apiVersion := scalar(obj, "apiVersion")
+		group := ""
+		if strings.Contains(apiVersion, "/") {
+			group = apiVersion[:strings.Index(apiVersion, "/")]
+		}
+		if group != md.Schema.Group() || scalar(obj, "kind") != md.Schema.Kind() {
+			continue
+		}
+		meta := value(obj, "metadata")
+		if meta == nil || scalar(meta, "name") != md.FullName.Name.String() {
+			continue
+		}
+		// Resources without a namespace are analyzed in the default namespace.
+		if ns := scalar(meta, "namespace"); ns != "" && ns != md.FullName.Namespace.String() {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// apply applies the operations of the fix to the document.
+func apply(doc *yaml.Node, f diag.Fix) error {
+	for _, op := range f.Patch {
+		if err := applyOperation(doc.Content[0], op); err != nil {
+			return fmt.Errorf("unable to %s %s: %v", op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+func applyOperation(root *yaml.Node, op diag.PatchOperation) error {
+	elems, err := diag.SplitPatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return fmt.Errorf("the whole resource cannot be patched")
+	}
+
+	parent := root
+	for _, e := range elems[:len(elems)-1] {
+		if parent, err = child(parent, e); err != nil {
+			return err
+		}
+	}
+	last := elems[len(elems)-1]
+
+	var v *yaml.Node
+	if op.Op != diag.RemoveOp {
+		v = &yaml.Node{}
+		if err := v.Encode(op.Value); err != nil {
+			return err
+		}
+	}
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		i := keyIndex(parent, last)
+		switch {
+		case op.Op == diag.AddOp && i < 0:
+			k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}
+			parent.Content = append(parent.Content, k, v)
+		case i < 0:
+			return fmt.Errorf("%q not found", last)
+		case op.Op == diag.RemoveOp:
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+		default:
+			parent.Content[i+1] = withComments(v, parent.Content[i+1])
+		}
+
+	case yaml.SequenceNode:
+		i := len(parent.Content)
+		if last != "-" || op.Op != diag.AddOp {
+			if i, err = strconv.Atoi(last); err != nil || i < 0 || i > len(parent.Content) {
+				return fmt.Errorf("invalid index %q", last)
+			}
+		}
+		switch {
+		case op.Op == diag.AddOp:
+			parent.Content = append(parent.Content[:i], append([]*yaml.Node{v}, parent.Content[i:]...)...)
+		case i == len(parent.Content):
+			return fmt.Errorf("invalid index %q", last)
+		case op.Op == diag.RemoveOp:
+			parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+		default:
+			parent.Content[i] = withComments(v, parent.Content[i])
+		}
+
+	default:
+		return fmt.Errorf("%q is not in a map or a list", last)
+	}
+
+	return nil
+}
+
+// child returns the value of the key of a map, or the item at the index of a list.
+func child(n *yaml.Node, e string) (*yaml.Node, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		if c := value(n, e); c != nil {
+			return c, nil
+		}
+		return nil, fmt.Errorf("%q not found", e)
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(e)
+		if err != nil || i < 0 || i >= len(n.Content) {
+			return nil, fmt.Errorf("invalid index %q", e)
+		}
+		return n.Content[i], nil
+	default:
+		return nil, fmt.Errorf("%q is not in a map or a list", e)
+	}
+}
+
+func keyIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func value(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	if i := keyIndex(n, key); i >= 0 {
+		return n.Content[i+1]
+	}
+	return nil
+}
+
+func scalar(n *yaml.Node, key string) string {
+	if v := value(n, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// withComments returns the node, with the comments of the node it replaces.
+func withComments(n, old *yaml.Node) *yaml.Node {
+	n.HeadComment = old.HeadComment
+	n.LineComment = old.LineComment
+	n.FootComment = old.FootComment
+	return n
+}
@@ -0,0 +1,191 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fix
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const content = `# Services of the app
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - name: web # the port of the app
+    port: 80
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: bookinfo
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: details
+  namespace: default
+spec:
+  hosts:
+  - details
+  http:
+  - route:
+    - destination:
+        host: details
+`
+
+var msgType = diag.NewMessageType(diag.Warning, "TEST0001", "test")
+
+func message(s collection.Schema, name resource.FullName, fixes ...diag.Fix) diag.Message {
+	r := &resource.Instance{
+		Metadata: resource.Metadata{Schema: s.Resource(), FullName: name},
+		Origin: &rt.Origin{
+			Collection: s.Name(),
+			Kind:       s.Resource().Kind(),
+			FullName:   name,
+			Ref:        &rt.Position{Filename: "test.yaml", Line: 1},
+		},
+	}
+	m := diag.NewMessage(msgType, r)
+	m.Fixes = fixes
+	return m
+}
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+
+	messages := []diag.Message{
+		message(collections.K8SCoreV1Services, resource.NewFullName("default", "details"),
+			diag.NewFix("rename", diag.Replace("/spec/ports/0/name", "http-web"))),
+		message(collections.K8SCoreV1Namespaces, resource.NewFullName("", "bookinfo"),
+			diag.NewFix("label", diag.Add("/metadata/labels", map[string]string{"istio-injection": "enabled"}))),
+		// Messages without fixes are ignored.
+		message(collections.IstioNetworkingV1Alpha3Virtualservices, resource.NewFullName("default", "details")),
+		// Resources of other kinds or namespaces are not found.
+		message(collections.IstioNetworkingV1Alpha3Virtualservices, resource.NewFullName("other", "details"),
+			diag.NewFix("host", diag.Replace("/spec/http/0/route/0/destination/host", "details.default.svc.cluster.local"))),
+		message(collections.IstioNetworkingV1Alpha3Destinationrules, resource.NewFullName("default", "details"),
+			diag.NewFix("host", diag.Replace("/spec/host", "details.default.svc.cluster.local"))),
+	}
+
+	fixed, applied, err := Apply([]byte(content), messages)
+	assert.Error(err)
+	assert.Len(applied, 2)
+	assert.Equal(`# Services of the app
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+    - name: http-web # the port of the app
+      port: 80
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: bookinfo
+  labels:
+    istio-injection: enabled
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: details
+  namespace: default
+spec:
+  hosts:
+  - details
+  http:
+  - route:
+    - destination:
+        host: details
+`, string(fixed))
+}
+
+func TestApplyOperation(t *testing.T) {
+	cases := []struct {
+		name string
+		op   diag.PatchOperation
+		want string
+		err  bool
+	}{
+		{name: "add key", op: diag.Add("/a/c", 2), want: "a:\n  b: [1, 2]\n  c: 2\n"},
+		{name: "add existing key", op: diag.Add("/a/b", "x"), want: "a:\n  b: x\n"},
+		{name: "add item", op: diag.Add("/a/b/1", 3), want: "a:\n  b: [1, 3, 2]\n"},
+		{name: "append item", op: diag.Add("/a/b/-", 3), want: "a:\n  b: [1, 2, 3]\n"},
+		{name: "replace item", op: diag.Replace("/a/b/0", 3), want: "a:\n  b: [3, 2]\n"},
+		{name: "remove key", op: diag.Remove("/a/b"), want: "a: {}\n"},
+		{name: "remove item", op: diag.Remove("/a/b/1"), want: "a:\n  b: [1]\n"},
+		{name: "replace missing key", op: diag.Replace("/a/c", 1), err: true},
+		{name: "remove missing item", op: diag.Remove("/a/b/2"), err: true},
+		{name: "missing parent", op: diag.Add("/x/y", 1), err: true},
+		{name: "scalar parent", op: diag.Add("/a/b/0/c", 1), err: true},
+		{name: "whole document", op: diag.Replace("", 1), err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			docs := split("a:\n  b: [1, 2]\n")
+			err := applyOperation(docs[0].node.Content[0], c.op)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var out bytes.Buffer
+			assert.NoError(t, encode(&out, docs[0].node))
+			assert.Equal(t, c.want, out.String())
+		})
+	}
+}
+
+func TestApplyToFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "fix")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "test.yaml")
+	assert.NoError(ioutil.WriteFile(name, []byte(content), 0600))
+
+	m := message(collections.K8SCoreV1Services, resource.NewFullName("default", "details"),
+		diag.NewFix("rename", diag.Replace("/spec/ports/0/name", "http-web")))
+	m.Resource.Origin.(*rt.Origin).Ref = &rt.Position{Filename: name, Line: 2}
+	assert.Equal(name, Filename(m))
+
+	applied, err := ApplyToFile(name, []diag.Message{m})
+	assert.NoError(err)
+	assert.Len(applied, 1)
+
+	fixed, err := ioutil.ReadFile(name)
+	assert.NoError(err)
+	assert.Contains(string(fixed), "- name: http-web # the port of the app")
+}
@@ -104,6 +104,7 @@ func TestAnalyzersRun(t *testing.T) {
 
 	result, err := sa.Analyze(cancel)
 	g.Expect(err).To(BeNil())
+	m.Analyzer = a.Metadata().Name
 	g.Expect(result.Messages).To(ConsistOf(m))
 	g.Expect(collectionAccessed).To(Equal(basicmeta.K8SCollection1.Name()))
 	g.Expect(result.ExecutedAnalyzers).To(ConsistOf(a.Metadata().Name))
@@ -131,6 +132,7 @@ func TestFilterOutputByNamespace(t *testing.T) {
 
 	result, err := sa.Analyze(cancel)
 	g.Expect(err).To(BeNil())
+	msg1.Analyzer = a.Metadata().Name
 	g.Expect(result.Messages).To(ConsistOf(msg1))
 }
 
@@ -142,6 +142,13 @@ func All() []*diag.MessageType {
 	}
 }
 
+// descriptors holds the name and description of all known message types, keyed by code.
+var descriptors = map[string]Descriptor{
+	{{- range .Messages}}
+	"{{.Code}}": {Name: "{{.Name}}", Description: {{printf "%q" .Description}}},
+	{{- end}}
+}
+
 {{range .Messages}}
 // New{{.Name}} returns a new diag.Message based on {{.Name}}.
 func New{{.Name}}(r *resource.Instance{{range .Args}}, {{.Name}} {{.Type}}{{end}}) diag.Message {
@@ -128,6 +128,62 @@ var (
 	// SchemaWarning defines a diag.MessageType for message "SchemaWarning".
 	// Description: The resource has a schema validation warning.
 	SchemaWarning = diag.NewMessageType(diag.Warning, "IST0133", "Schema validation warning: %v")
+
+	// EnvoyFilterPatchNoMatch defines a diag.MessageType for message "EnvoyFilterPatchNoMatch".
+	// Description: An EnvoyFilter patch does not match any configuration generated for the workloads it selects.
+	EnvoyFilterPatchNoMatch = diag.NewMessageType(diag.Warning, "IST0134", "EnvoyFilter patch %v (applyTo %v) does not match any configuration generated for the workloads it selects.")
+
+	// EnvoyFilterOrderDependent defines a diag.MessageType for message "EnvoyFilterOrderDependent".
+	// Description: The configuration produced by an EnvoyFilter depends on the order it is applied relative to another EnvoyFilter.
+	EnvoyFilterOrderDependent = diag.NewMessageType(diag.Warning, "IST0135", "The configuration generated for workload %v depends on the order in which this EnvoyFilter and EnvoyFilter %v are applied; the order is determined by creation time.")
+
+	// EnvoyFilterInvalidConfig defines a diag.MessageType for message "EnvoyFilterInvalidConfig".
+	// Description: An EnvoyFilter patch produces configuration that Envoy would reject.
+	EnvoyFilterInvalidConfig = diag.NewMessageType(diag.Error, "IST0136", "EnvoyFilter patch %v produces an invalid %v %v for workload %v: %v")
+
+	// AuthorizationPolicyPrincipalNotFound defines a diag.MessageType for message "AuthorizationPolicyPrincipalNotFound".
+	// Description: An AuthorizationPolicy principal refers to a service account that no workload runs as.
+	AuthorizationPolicyPrincipalNotFound = diag.NewMessageType(diag.Warning, "IST0137", "The principal %v refers to service account %v in namespace %v, which no workload in the mesh runs as.")
+
+	// AuthorizationPolicyPortNotExposed defines a diag.MessageType for message "AuthorizationPolicyPortNotExposed".
+	// Description: An AuthorizationPolicy port is not exposed by any of the workloads the policy selects.
+	AuthorizationPolicyPortNotExposed = diag.NewMessageType(diag.Warning, "IST0138", "The port %v is not exposed by any of the workloads the policy selects.")
+
+	// AuthorizationPolicyUndefinedProvider defines a diag.MessageType for message "AuthorizationPolicyUndefinedProvider".
+	// Description: An AuthorizationPolicy with the CUSTOM action refers to an extension provider that is not defined in the mesh config.
+	AuthorizationPolicyUndefinedProvider = diag.NewMessageType(diag.Error, "IST0139", "The CUSTOM action refers to extension provider %v, which is not defined in the mesh config.")
+
+	// AuthorizationPolicyShadowedRule defines a diag.MessageType for message "AuthorizationPolicyShadowedRule".
+	// Description: An ALLOW AuthorizationPolicy rule never takes effect, as all the requests it matches are denied by a DENY policy.
+	AuthorizationPolicyShadowedRule = diag.NewMessageType(diag.Warning, "IST0140", "ALLOW rule %v never takes effect, as all the requests it matches are denied by rule %v of DENY policy %v.")
+
+	// ServiceEntryHostConflict defines a diag.MessageType for message "ServiceEntryHostConflict".
+	// Description: A ServiceEntry host is also defined by another ServiceEntry or a Kubernetes service with different ports or protocols.
+	ServiceEntryHostConflict = diag.NewMessageType(diag.Warning, "IST0141", "The host %v is also defined by %v with different ports or protocols.")
+
+	// ServiceEntryAddressRequired defines a diag.MessageType for message "ServiceEntryAddressRequired".
+	// Description: A ServiceEntry with resolution NONE and no addresses matches all outbound traffic on its TCP ports.
+	ServiceEntryAddressRequired = diag.NewMessageType(diag.Warning, "IST0142", "Port %v uses protocol %v, so the ServiceEntry must set addresses when resolution is NONE; otherwise it matches all outbound traffic on this port.")
+
+	// WorkloadEntryNotSelected defines a diag.MessageType for message "WorkloadEntryNotSelected".
+	// Description: A WorkloadEntry is not selected by any ServiceEntry or Kubernetes service.
+	WorkloadEntryNotSelected = diag.NewMessageType(diag.Warning, "IST0143", "The WorkloadEntry labels do not match the workload selector of any ServiceEntry or the selector of any service in its namespace.")
+
+	// WorkloadGroupPortConflict defines a diag.MessageType for message "WorkloadGroupPortConflict".
+	// Description: A WorkloadGroup template port conflicts with the ports of a ServiceEntry that selects the group.
+	WorkloadGroupPortConflict = diag.NewMessageType(diag.Warning, "IST0144", "The template port %v conflicts with ServiceEntry %v: %v.")
+
+	// VirtualServiceShadowedMatch defines a diag.MessageType for message "VirtualServiceShadowedMatch".
+	// Description: A VirtualService match never takes effect, as all the requests it matches are matched by an earlier rule.
+	VirtualServiceShadowedMatch = diag.NewMessageType(diag.Warning, "IST0145", "Match %v of HTTP rule %v never takes effect, as all the requests it matches are matched by HTTP rule %v of VirtualService %v first.")
+
+	// VirtualServiceRouteWeights defines a diag.MessageType for message "VirtualServiceRouteWeights".
+	// Description: The destination weights of a VirtualService route do not sum to 100.
+	VirtualServiceRouteWeights = diag.NewMessageType(diag.Warning, "IST0146", "The destination weights of %v rule %v sum to %v instead of 100.")
+
+	// DestinationRuleTLSDisabledStrictMTLS defines a diag.MessageType for message "DestinationRuleTLSDisabledStrictMTLS".
+	// Description: A DestinationRule disables TLS to workloads that require mutual TLS with a STRICT PeerAuthentication.
+	DestinationRuleTLSDisabledStrictMTLS = diag.NewMessageType(diag.Warning, "IST0147", "TLS mode DISABLE%v sends plaintext traffic to host %v, whose workloads reject it as they require mutual TLS with PeerAuthentication %v.")
 )
 
 // All returns a list of all known message types.
@@ -163,9 +219,71 @@ func All() []*diag.MessageType {
 		VirtualServiceIneffectiveMatch,
 		VirtualServiceHostNotFoundInGateway,
 		SchemaWarning,
+		EnvoyFilterPatchNoMatch,
+		EnvoyFilterOrderDependent,
+		EnvoyFilterInvalidConfig,
+		AuthorizationPolicyPrincipalNotFound,
+		AuthorizationPolicyPortNotExposed,
+		AuthorizationPolicyUndefinedProvider,
+		AuthorizationPolicyShadowedRule,
+		ServiceEntryHostConflict,
+		ServiceEntryAddressRequired,
+		WorkloadEntryNotSelected,
+		WorkloadGroupPortConflict,
+		VirtualServiceShadowedMatch,
+		VirtualServiceRouteWeights,
+		DestinationRuleTLSDisabledStrictMTLS,
 	}
 }
 
+// descriptors holds the name and description of all known message types, keyed by code.
+var descriptors = map[string]Descriptor{
+	"IST0001": {Name: "InternalError", Description: "There was an internal error in the toolchain. This is almost always a bug in the implementation."},
+	"IST0002": {Name: "Deprecated", Description: "A feature that the configuration is depending on is now deprecated."},
+	"IST0101": {Name: "ReferencedResourceNotFound", Description: "A resource being referenced does not exist."},
+	"IST0102": {Name: "NamespaceNotInjected", Description: "A namespace is not enabled for Istio injection."},
+	"IST0103": {Name: "PodMissingProxy", Description: "A pod is missing the Istio proxy."},
+	"IST0104": {Name: "GatewayPortNotOnWorkload", Description: "Unhandled gateway port"},
+	"IST0105": {Name: "IstioProxyImageMismatch", Description: "The image of the Istio proxy running on the pod does not match the image defined in the injection configuration."},
+	"IST0106": {Name: "SchemaValidationError", Description: "The resource has a schema validation error."},
+	"IST0107": {Name: "MisplacedAnnotation", Description: "An Istio annotation is applied to the wrong kind of resource."},
+	"IST0108": {Name: "UnknownAnnotation", Description: "An Istio annotation is not recognized for any kind of resource"},
+	"IST0109": {Name: "ConflictingMeshGatewayVirtualServiceHosts", Description: "Conflicting hosts on VirtualServices associated with mesh gateway"},
+	"IST0110": {Name: "ConflictingSidecarWorkloadSelectors", Description: "A Sidecar resource selects the same workloads as another Sidecar resource"},
+	"IST0111": {Name: "MultipleSidecarsWithoutWorkloadSelectors", Description: "More than one sidecar resource in a namespace has no workload selector"},
+	"IST0112": {Name: "VirtualServiceDestinationPortSelectorRequired", Description: "A VirtualService routes to a service with more than one port exposed, but does not specify which to use."},
+	"IST0113": {Name: "MTLSPolicyConflict", Description: "A DestinationRule and Policy are in conflict with regards to mTLS."},
+	"IST0116": {Name: "DeploymentAssociatedToMultipleServices", Description: "The resulting pods of a service mesh deployment can't be associated with multiple services using the same port but different protocols."},
+	"IST0117": {Name: "DeploymentRequiresServiceAssociated", Description: "The resulting pods of a service mesh deployment must be associated with at least one service."},
+	"IST0118": {Name: "PortNameIsNotUnderNamingConvention", Description: "Port name is not under naming convention. Protocol detection is applied to the port."},
+	"IST0119": {Name: "JwtFailureDueToInvalidServicePortPrefix", Description: "Authentication policy with JWT targets Service with invalid port specification."},
+	"IST0122": {Name: "InvalidRegexp", Description: "Invalid Regex"},
+	"IST0123": {Name: "NamespaceMultipleInjectionLabels", Description: "A namespace has both new and legacy injection labels"},
+	"IST0125": {Name: "InvalidAnnotation", Description: "An Istio annotation that is not valid"},
+	"IST0126": {Name: "UnknownMeshNetworksServiceRegistry", Description: "A service registry in Mesh Networks is unknown"},
+	"IST0127": {Name: "NoMatchingWorkloadsFound", Description: "There aren't workloads matching the resource labels"},
+	"IST0128": {Name: "NoServerCertificateVerificationDestinationLevel", Description: "No caCertificates are set in DestinationRule, this results in no verification of presented server certificate."},
+	"IST0129": {Name: "NoServerCertificateVerificationPortLevel", Description: "No caCertificates are set in DestinationRule, this results in no verification of presented server certificate for traffic to a given port."},
+	"IST0130": {Name: "VirtualServiceUnreachableRule", Description: "A VirtualService rule will never be used because a previous rule uses the same match."},
+	"IST0131": {Name: "VirtualServiceIneffectiveMatch", Description: "A VirtualService rule match duplicates a match in a previous rule."},
+	"IST0132": {Name: "VirtualServiceHostNotFoundInGateway", Description: "Host defined in VirtualService not found in Gateway."},
+	"IST0133": {Name: "SchemaWarning", Description: "The resource has a schema validation warning."},
+	"IST0134": {Name: "EnvoyFilterPatchNoMatch", Description: "An EnvoyFilter patch does not match any configuration generated for the workloads it selects."},
+	"IST0135": {Name: "EnvoyFilterOrderDependent", Description: "The configuration produced by an EnvoyFilter depends on the order it is applied relative to another EnvoyFilter."},
+	"IST0136": {Name: "EnvoyFilterInvalidConfig", Description: "An EnvoyFilter patch produces configuration that Envoy would reject."},
+	"IST0137": {Name: "AuthorizationPolicyPrincipalNotFound", Description: "An AuthorizationPolicy principal refers to a service account that no workload runs as."},
+	"IST0138": {Name: "AuthorizationPolicyPortNotExposed", Description: "An AuthorizationPolicy port is not exposed by any of the workloads the policy selects."},
+	"IST0139": {Name: "AuthorizationPolicyUndefinedProvider", Description: "An AuthorizationPolicy with the CUSTOM action refers to an extension provider that is not defined in the mesh config."},
+	"IST0140": {Name: "AuthorizationPolicyShadowedRule", Description: "An ALLOW AuthorizationPolicy rule never takes effect, as all the requests it matches are denied by a DENY policy."},
+	"IST0141": {Name: "ServiceEntryHostConflict", Description: "A ServiceEntry host is also defined by another ServiceEntry or a Kubernetes service with different ports or protocols."},
+	"IST0142": {Name: "ServiceEntryAddressRequired", Description: "A ServiceEntry with resolution NONE and no addresses matches all outbound traffic on its TCP ports."},
+	"IST0143": {Name: "WorkloadEntryNotSelected", Description: "A WorkloadEntry is not selected by any ServiceEntry or Kubernetes service."},
+	"IST0144": {Name: "WorkloadGroupPortConflict", Description: "A WorkloadGroup template port conflicts with the ports of a ServiceEntry that selects the group."},
+	"IST0145": {Name: "VirtualServiceShadowedMatch", Description: "A VirtualService match never takes effect, as all the requests it matches are matched by an earlier rule."},
+	"IST0146": {Name: "VirtualServiceRouteWeights", Description: "The destination weights of a VirtualService route do not sum to 100."},
+	"IST0147": {Name: "DestinationRuleTLSDisabledStrictMTLS", Description: "A DestinationRule disables TLS to workloads that require mutual TLS with a STRICT PeerAuthentication."},
+}
+
 // NewInternalError returns a new diag.Message based on InternalError.
 func NewInternalError(r *resource.Instance, detail string) diag.Message {
 	return diag.NewMessage(
@@ -471,3 +589,149 @@ func NewSchemaWarning(r *resource.Instance, err error) diag.Message {
 		err,
 	)
 }
+
+// NewEnvoyFilterPatchNoMatch returns a new diag.Message based on EnvoyFilterPatchNoMatch.
+func NewEnvoyFilterPatchNoMatch(r *resource.Instance, patchno int, applyTo string) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterPatchNoMatch,
+		r,
+		patchno,
+		applyTo,
+	)
+}
+
+// NewEnvoyFilterOrderDependent returns a new diag.Message based on EnvoyFilterOrderDependent.
+func NewEnvoyFilterOrderDependent(r *resource.Instance, workload string, envoyfilter string) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterOrderDependent,
+		r,
+		workload,
+		envoyfilter,
+	)
+}
+
+// NewEnvoyFilterInvalidConfig returns a new diag.Message based on EnvoyFilterInvalidConfig.
+func NewEnvoyFilterInvalidConfig(r *resource.Instance, patchno int, kind string, resourcename string, workload string, err error) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterInvalidConfig,
+		r,
+		patchno,
+		kind,
+		resourcename,
+		workload,
+		err,
+	)
+}
+
+// NewAuthorizationPolicyPrincipalNotFound returns a new diag.Message based on AuthorizationPolicyPrincipalNotFound.
+func NewAuthorizationPolicyPrincipalNotFound(r *resource.Instance, principal string, serviceAccount string, namespace string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyPrincipalNotFound,
+		r,
+		principal,
+		serviceAccount,
+		namespace,
+	)
+}
+
+// NewAuthorizationPolicyPortNotExposed returns a new diag.Message based on AuthorizationPolicyPortNotExposed.
+func NewAuthorizationPolicyPortNotExposed(r *resource.Instance, port string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyPortNotExposed,
+		r,
+		port,
+	)
+}
+
+// NewAuthorizationPolicyUndefinedProvider returns a new diag.Message based on AuthorizationPolicyUndefinedProvider.
+func NewAuthorizationPolicyUndefinedProvider(r *resource.Instance, provider string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyUndefinedProvider,
+		r,
+		provider,
+	)
+}
+
+// NewAuthorizationPolicyShadowedRule returns a new diag.Message based on AuthorizationPolicyShadowedRule.
+func NewAuthorizationPolicyShadowedRule(r *resource.Instance, ruleno int, denyruleno int, denypolicy string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyShadowedRule,
+		r,
+		ruleno,
+		denyruleno,
+		denypolicy,
+	)
+}
+
+// NewServiceEntryHostConflict returns a new diag.Message based on ServiceEntryHostConflict.
+func NewServiceEntryHostConflict(r *resource.Instance, host string, conflict string) diag.Message {
+	return diag.NewMessage(
+		ServiceEntryHostConflict,
+		r,
+		host,
+		conflict,
+	)
+}
+
+// NewServiceEntryAddressRequired returns a new diag.Message based on ServiceEntryAddressRequired.
+func NewServiceEntryAddressRequired(r *resource.Instance, port int, protocol string) diag.Message {
+	return diag.NewMessage(
+		ServiceEntryAddressRequired,
+		r,
+		port,
+		protocol,
+	)
+}
+
+// NewWorkloadEntryNotSelected returns a new diag.Message based on WorkloadEntryNotSelected.
+func NewWorkloadEntryNotSelected(r *resource.Instance) diag.Message {
+	return diag.NewMessage(
+		WorkloadEntryNotSelected,
+		r,
+	)
+}
+
+// NewWorkloadGroupPortConflict returns a new diag.Message based on WorkloadGroupPortConflict.
+func NewWorkloadGroupPortConflict(r *resource.Instance, portName string, serviceEntry string, reason string) diag.Message {
+	return diag.NewMessage(
+		WorkloadGroupPortConflict,
+		r,
+		portName,
+		serviceEntry,
+		reason,
+	)
+}
+
+// NewVirtualServiceShadowedMatch returns a new diag.Message based on VirtualServiceShadowedMatch.
+func NewVirtualServiceShadowedMatch(r *resource.Instance, match string, rule string, shadowingRule string, virtualService string) diag.Message {
+	return diag.NewMessage(
+		VirtualServiceShadowedMatch,
+		r,
+		match,
+		rule,
+		shadowingRule,
+		virtualService,
+	)
+}
+
+// NewVirtualServiceRouteWeights returns a new diag.Message based on VirtualServiceRouteWeights.
+func NewVirtualServiceRouteWeights(r *resource.Instance, ruleType string, rule string, total int) diag.Message {
+	return diag.NewMessage(
+		VirtualServiceRouteWeights,
+		r,
+		ruleType,
+		rule,
+		total,
+	)
+}
+
+// NewDestinationRuleTLSDisabledStrictMTLS returns a new diag.Message based on DestinationRuleTLSDisabledStrictMTLS.
+func NewDestinationRuleTLSDisabledStrictMTLS(r *resource.Instance, scope string, host string, peerAuthentication string) diag.Message {
+	return diag.NewMessage(
+		DestinationRuleTLSDisabledStrictMTLS,
+		r,
+		scope,
+		host,
+		peerAuthentication,
+	)
+}
@@ -18,3 +18,17 @@ package msg
 //go:generate go run "$REPO_ROOT/galley/pkg/config/analysis/msg/generate.main.go" messages.yaml messages.gen.go
 
 //go:generate goimports -w -local istio.io "$REPO_ROOT/galley/pkg/config/analysis/msg/messages.gen.go"
+
+// Descriptor holds the metadata of a message type, as defined in messages.yaml.
+type Descriptor struct {
+	// Name of the message type, e.g. "ReferencedResourceNotFound".
+	Name string
+	// Description of the message type.
+	Description string
+}
+
+// Describe returns the metadata of the message type with the given code.
+func Describe(code string) (Descriptor, bool) {
+	d, ok := descriptors[code]
+	return d, ok
+}
@@ -0,0 +1,251 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report publishes analysis messages as Kubernetes events and metrics, in addition to the status
+// of the resources that is updated by the source.
+package report
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/processing/snapshotter"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+)
+
+const (
+	// component is the source of the events.
+	component = "istio-analysis"
+
+	// republishInterval is how often the messages that are still reported are published again, so that their
+	// events outlive the TTL of events in the API server, which is one hour by default.
+	republishInterval = 50 * time.Minute
+)
+
+// EventPublisher publishes analysis messages as events on the resources they are reported for. A message is
+// published when it is first reported, and again every republishInterval for as long as it is reported.
+type EventPublisher struct {
+	recorder    record.EventRecorder
+	broadcaster record.EventBroadcaster
+	threshold   diag.Level
+	limiter     *rate.Limiter
+	stop        chan struct{}
+
+	mu sync.Mutex
+	// reported are the events of the messages reported by the last update, by key.
+	reported map[string]*event
+	// published is when each reported message was last published.
+	published map[string]time.Time
+	// queue holds the keys of the messages waiting to be published, in the order they are reported.
+	queue  []string
+	queued map[string]struct{}
+	wake   chan struct{}
+}
+
+// event is an event to create for a message.
+type event struct {
+	ref       *v1.ObjectReference
+	eventType string
+	reason    string
+	message   string
+}
+
+var _ snapshotter.StatusUpdater = &EventPublisher{}
+
+// NewEventPublisher returns a new EventPublisher that creates events with the client for the messages at or
+// above the threshold. At most burst events are created at once, and qps events per second after that.
+// Messages that exceed the rate limit are queued until the limit allows them to be published.
+func NewEventPublisher(client kubernetes.Interface, threshold diag.Level, qps float64, burst int) *EventPublisher {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	p := newEventPublisher(broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component}), threshold, qps, burst)
+	p.broadcaster = broadcaster
+	go p.run()
+	return p
+}
+
+func newEventPublisher(recorder record.EventRecorder, threshold diag.Level, qps float64, burst int) *EventPublisher {
+	return &EventPublisher{
+		recorder:  recorder,
+		threshold: threshold,
+		limiter:   rate.NewLimiter(rate.Limit(qps), burst),
+		stop:      make(chan struct{}),
+		reported:  make(map[string]*event),
+		published: make(map[string]time.Time),
+		queued:    make(map[string]struct{}),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Update implements snapshotter.StatusUpdater
+func (p *EventPublisher) Update(messages diag.Messages) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reported := make(map[string]*event)
+	for _, m := range messages {
+		if !m.Type.Level().IsWorseThanOrEqualTo(p.threshold) {
+			continue
+		}
+		ref, ok := objectReference(m.Resource)
+		if !ok {
+			continue
+		}
+
+		text := fmt.Sprintf(m.Type.Template(), m.Parameters...)
+		key := fmt.Sprintf("%s/%s/%s/%s/%s", ref.Kind, ref.Namespace, ref.Name, m.Type.Code(), text)
+		reported[key] = &event{ref: ref, eventType: eventType(m.Type.Level()), reason: m.Type.Code(), message: text}
+		if _, ok := p.published[key]; !ok {
+			p.enqueue(key)
+		}
+	}
+	p.reported = reported
+
+	// Forget the messages that are resolved, so that they are published again if they are reported again.
+	for key := range p.published {
+		if _, ok := reported[key]; !ok {
+			delete(p.published, key)
+		}
+	}
+}
+
+// Stop stops publishing events.
+func (p *EventPublisher) Stop() {
+	close(p.stop)
+	if p.broadcaster != nil {
+		p.broadcaster.Shutdown()
+	}
+}
+
+// run publishes the queued messages as the rate limit allows, and queues the messages that are due to be
+// published again, until the publisher is stopped.
+func (p *EventPublisher) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.wake:
+		case now := <-ticker.C:
+			p.requeue(now)
+		}
+		p.publishQueued(ctx)
+	}
+}
+
+// requeue queues the reported messages that were last published more than republishInterval before now.
+func (p *EventPublisher) requeue(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, published := range p.published {
+		if now.Sub(published) >= republishInterval {
+			p.enqueue(key)
+		}
+	}
+}
+
+// publishQueued publishes the queued messages that are still reported, waiting for the rate limit, until the
+// queue is empty or the context is done.
+func (p *EventPublisher) publishQueued(ctx context.Context) {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		key := p.queue[0]
+		_, reported := p.reported[key]
+		p.mu.Unlock()
+
+		// Messages resolved while they were queued are dropped without waiting for the rate limit.
+		if reported {
+			if err := p.limiter.Wait(ctx); err != nil {
+				return
+			}
+		}
+
+		p.mu.Lock()
+		p.queue = p.queue[1:]
+		delete(p.queued, key)
+		if e, ok := p.reported[key]; ok {
+			p.recorder.Event(e.ref, e.eventType, e.reason, e.message)
+			p.published[key] = time.Now()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// enqueue queues the message with the key to be published, unless it is already queued. The caller must hold mu.
+func (p *EventPublisher) enqueue(key string) {
+	if _, ok := p.queued[key]; ok {
+		return
+	}
+	p.queue = append(p.queue, key)
+	p.queued[key] = struct{}{}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// eventType returns the type of the events for messages of the level. Kubernetes events are either normal or
+// warnings, so errors are published as warnings.
+func eventType(l diag.Level) string {
+	if l.IsWorseThanOrEqualTo(diag.Warning) {
+		return v1.EventTypeWarning
+	}
+	return v1.EventTypeNormal
+}
+
+// objectReference returns the reference of the Kubernetes object of the resource, if known.
+func objectReference(r *resource.Instance) (*v1.ObjectReference, bool) {
+	if r == nil || r.Metadata.Schema == nil {
+		return nil, false
+	}
+	s := r.Metadata.Schema
+
+	apiVersion := s.Version()
+	if s.Group() != "" {
+		apiVersion = s.Group() + "/" + apiVersion
+	}
+	ref := &v1.ObjectReference{
+		APIVersion: apiVersion,
+		Kind:       s.Kind(),
+		Namespace:  r.Metadata.FullName.Namespace.String(),
+		Name:       r.Metadata.FullName.Name.String(),
+	}
+	if o, ok := r.Origin.(*rt.Origin); ok {
+		ref.UID = o.UID
+	}
+	return ref, true
+}
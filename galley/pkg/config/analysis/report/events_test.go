@@ -0,0 +1,193 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+var (
+	errorType   = diag.NewMessageType(diag.Error, "TEST0001", "Error in %s")
+	warningType = diag.NewMessageType(diag.Warning, "TEST0002", "Warning in %s")
+	infoType    = diag.NewMessageType(diag.Info, "TEST0003", "Info in %s")
+)
+
+func instance(s collection.Schema, namespace, name string) *resource.Instance {
+	fullName := resource.NewFullName(resource.Namespace(namespace), resource.LocalName(name))
+	return &resource.Instance{
+		Metadata: resource.Metadata{Schema: s.Resource(), FullName: fullName},
+		Origin: &rt.Origin{
+			Collection: s.Name(),
+			Kind:       s.Resource().Kind(),
+			FullName:   fullName,
+			UID:        types.UID("uid-" + name),
+		},
+	}
+}
+
+func events(recorder *record.FakeRecorder) []string {
+	var result []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			result = append(result, e)
+		default:
+			return result
+		}
+	}
+}
+
+func TestEventPublisher(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(100)
+	p := newEventPublisher(recorder, diag.Warning, 100, 100)
+
+	vs := instance(collections.IstioNetworkingV1Alpha3Virtualservices, "default", "vs")
+	svc := instance(collections.K8SCoreV1Services, "default", "svc")
+
+	p.Update(diag.Messages{
+		diag.NewMessage(errorType, vs, "vs"),
+		diag.NewMessage(warningType, svc, "svc"),
+		diag.NewMessage(infoType, svc, "svc"),
+		diag.NewMessage(errorType, nil, "nothing"),
+	})
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(ConsistOf(
+		"Warning TEST0001 Error in vs",
+		"Warning TEST0002 Warning in svc",
+	))
+
+	// Messages that are still reported are not published again.
+	p.Update(diag.Messages{
+		diag.NewMessage(errorType, vs, "vs"),
+	})
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(BeEmpty())
+
+	// Messages that are resolved are published again if they are reported again.
+	p.Update(diag.Messages{
+		diag.NewMessage(errorType, vs, "vs"),
+		diag.NewMessage(warningType, svc, "svc"),
+	})
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(ConsistOf("Warning TEST0002 Warning in svc"))
+}
+
+func TestEventPublisherRateLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(100)
+	p := newEventPublisher(recorder, diag.Info, 0.001, 2)
+
+	msgs := diag.Messages{
+		diag.NewMessage(infoType, instance(collections.K8SCoreV1Services, "default", "a"), "a"),
+		diag.NewMessage(infoType, instance(collections.K8SCoreV1Services, "default", "b"), "b"),
+		diag.NewMessage(infoType, instance(collections.K8SCoreV1Services, "default", "c"), "c"),
+	}
+
+	p.Update(msgs)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	p.publishQueued(ctx)
+	g.Expect(events(recorder)).To(Equal([]string{"Normal TEST0003 Info in a", "Normal TEST0003 Info in b"}))
+
+	// The messages over the limit stay queued, and are published when the limit allows it.
+	p.limiter.SetLimit(rate.Inf)
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(Equal([]string{"Normal TEST0003 Info in c"}))
+}
+
+func TestEventPublisherRepublish(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(100)
+	p := newEventPublisher(recorder, diag.Info, 100, 100)
+
+	a := diag.NewMessage(infoType, instance(collections.K8SCoreV1Services, "default", "a"), "a")
+	b := diag.NewMessage(infoType, instance(collections.K8SCoreV1Services, "default", "b"), "b")
+	p.Update(diag.Messages{a, b})
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(ConsistOf("Normal TEST0003 Info in a", "Normal TEST0003 Info in b"))
+
+	// Messages are not published again before the interval.
+	p.requeue(time.Now())
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(BeEmpty())
+
+	// Messages that are still reported are published again after the interval.
+	p.Update(diag.Messages{a})
+	p.requeue(time.Now().Add(republishInterval))
+	p.publishQueued(context.Background())
+	g.Expect(events(recorder)).To(Equal([]string{"Normal TEST0003 Info in a"}))
+}
+
+func TestEventPublisherStop(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(100)
+	p := newEventPublisher(recorder, diag.Info, 100, 100)
+	done := make(chan struct{})
+	go func() {
+		p.run()
+		close(done)
+	}()
+
+	p.Update(diag.Messages{diag.NewMessage(infoType, instance(collections.K8SCoreV1Services, "default", "a"), "a")})
+	g.Eventually(recorder.Events).Should(Receive(Equal("Normal TEST0003 Info in a")))
+
+	p.Stop()
+	g.Eventually(done).Should(BeClosed())
+}
+
+func TestObjectReference(t *testing.T) {
+	g := NewWithT(t)
+
+	ref, ok := objectReference(instance(collections.IstioNetworkingV1Alpha3Virtualservices, "default", "vs"))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(ref).To(Equal(&v1.ObjectReference{
+		APIVersion: "networking.istio.io/v1alpha3",
+		Kind:       "VirtualService",
+		Namespace:  "default",
+		Name:       "vs",
+		UID:        "uid-vs",
+	}))
+
+	ref, ok = objectReference(instance(collections.K8SCoreV1Namespaces, "", "ns"))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(ref).To(Equal(&v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Name:       "ns",
+		UID:        "uid-ns",
+	}))
+
+	_, ok = objectReference(nil)
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"sync"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/processing/snapshotter"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	// CodeTag holds the code of the messages.
+	CodeTag = monitoring.MustCreateLabel("code")
+
+	// NamespaceTag holds the namespace of the resources of the messages.
+	NamespaceTag = monitoring.MustCreateLabel("namespace")
+
+	// LevelTag holds the level of the messages.
+	LevelTag = monitoring.MustCreateLabel("level")
+
+	analysisMessages = monitoring.NewGauge(
+		"galley/analysis/messages",
+		"The number of analysis messages currently reported",
+		monitoring.WithLabels(CodeTag, NamespaceTag, LevelTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(analysisMessages)
+}
+
+type metricKey struct {
+	code      string
+	namespace string
+	level     string
+}
+
+// MetricPublisher publishes the number of analysis messages by code, namespace and level as a gauge.
+type MetricPublisher struct {
+	threshold diag.Level
+
+	mu       sync.Mutex
+	recorded map[metricKey]struct{}
+}
+
+var _ snapshotter.StatusUpdater = &MetricPublisher{}
+
+// NewMetricPublisher returns a new MetricPublisher for the messages at or above the threshold.
+func NewMetricPublisher(threshold diag.Level) *MetricPublisher {
+	return &MetricPublisher{
+		threshold: threshold,
+		recorded:  make(map[metricKey]struct{}),
+	}
+}
+
+// Update implements snapshotter.StatusUpdater
+func (p *MetricPublisher) Update(messages diag.Messages) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	counts := make(map[metricKey]int)
+	for _, m := range messages {
+		if !m.Type.Level().IsWorseThanOrEqualTo(p.threshold) {
+			continue
+		}
+		k := metricKey{code: m.Type.Code(), level: m.Type.Level().String()}
+		if m.Resource != nil && m.Resource.Origin != nil {
+			k.namespace = m.Resource.Origin.Namespace().String()
+		}
+		counts[k]++
+	}
+
+	// Reset the gauges of the messages that are no longer reported.
+	for k := range p.recorded {
+		if _, ok := counts[k]; !ok {
+			recordCount(k, 0)
+			delete(p.recorded, k)
+		}
+	}
+	for k, n := range counts {
+		recordCount(k, n)
+		p.recorded[k] = struct{}{}
+	}
+}
+
+func recordCount(k metricKey, n int) {
+	analysisMessages.
+		With(CodeTag.Value(k.code)).
+		With(NamespaceTag.Value(k.namespace)).
+		With(LevelTag.Value(k.level)).
+		Record(float64(n))
+}
@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opencensus.io/stats/view"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func gaugeValues(t *testing.T) map[string]float64 {
+	rows, err := view.RetrieveData(analysisMessages.Name())
+	if err != nil {
+		t.Fatalf("failed to retrieve %s: %v", analysisMessages.Name(), err)
+	}
+	result := make(map[string]float64)
+	for _, row := range rows {
+		key := ""
+		for _, tag := range row.Tags {
+			key += tag.Key.Name() + "=" + tag.Value + ","
+		}
+		result[key] = row.Data.(*view.LastValueData).Value
+	}
+	return result
+}
+
+func TestMetricPublisher(t *testing.T) {
+	g := NewWithT(t)
+
+	p := NewMetricPublisher(diag.Warning)
+
+	vs := instance(collections.IstioNetworkingV1Alpha3Virtualservices, "default", "vs")
+	svc := instance(collections.K8SCoreV1Services, "default", "svc")
+	ns := instance(collections.K8SCoreV1Namespaces, "", "foo")
+
+	p.Update(diag.Messages{
+		diag.NewMessage(errorType, vs, "vs"),
+		diag.NewMessage(errorType, svc, "svc"),
+		diag.NewMessage(warningType, ns, "foo"),
+		diag.NewMessage(infoType, svc, "svc"),
+	})
+	g.Expect(gaugeValues(t)).To(Equal(map[string]float64{
+		"code=TEST0001,level=Error,namespace=default,": 2,
+		"code=TEST0002,level=Warning,namespace=foo,":   1,
+	}))
+
+	// Messages that are no longer reported are reset.
+	p.Update(diag.Messages{
+		diag.NewMessage(errorType, vs, "vs"),
+	})
+	g.Expect(gaugeValues(t)).To(Equal(map[string]float64{
+		"code=TEST0001,level=Error,namespace=default,": 1,
+		"code=TEST0002,level=Warning,namespace=foo,":   0,
+	}))
+}
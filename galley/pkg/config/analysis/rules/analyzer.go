@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Analyzer is an analyzer that is defined by a Rule.
+type Analyzer struct {
+	rule       *Rule
+	collection collection.Name
+	inputs     collection.Names
+	msgType    *diag.MessageType
+	program    cel.Program
+}
+
+var _ analysis.Analyzer = &Analyzer{}
+
+func newAnalyzer(r *Rule) (*Analyzer, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	level, err := r.level()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Analyzer{
+		rule: r,
+		// The message is not a format string, so escape any verbs in it.
+		msgType: diag.NewMessageType(level, r.Code, strings.ReplaceAll(r.Message, "%", "%%")),
+	}
+
+	if a.collection, err = resolveCollection(r.Kind); err != nil {
+		return nil, err
+	}
+	a.inputs = collection.Names{a.collection}
+	for _, in := range r.Inputs {
+		c, err := resolveCollection(in)
+		if err != nil {
+			return nil, err
+		}
+		if c != a.collection {
+			a.inputs = append(a.inputs, c)
+		}
+	}
+
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("resource", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("resources", decls.NewMapType(decls.String, decls.NewListType(decls.Dyn))),
+	))
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(r.Expression)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %v", iss.Err())
+	}
+	if t := ast.ResultType(); !reflect.DeepEqual(t, decls.Bool) && !reflect.DeepEqual(t, decls.Dyn) {
+		return nil, fmt.Errorf("expression must evaluate to a bool")
+	}
+	if a.program, err = env.Program(ast); err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+
+	return a, nil
+}
+
+// Metadata implements Analyzer
+func (a *Analyzer) Metadata() analysis.Metadata {
+	description := a.rule.Description
+	if description == "" {
+		description = a.rule.Message
+	}
+	return analysis.Metadata{
+		Name:        NamePrefix + a.rule.Name,
+		Description: description,
+		Inputs:      a.inputs,
+	}
+}
+
+// MessageType returns the type of the messages reported by the analyzer.
+func (a *Analyzer) MessageType() *diag.MessageType {
+	return a.msgType
+}
+
+// Analyze implements Analyzer
+func (a *Analyzer) Analyze(c analysis.Context) {
+	resources := make(map[string]interface{}, len(a.inputs))
+	for _, in := range a.inputs {
+		var objects []interface{}
+		c.ForEach(in, func(r *resource.Instance) bool {
+			if o, err := toObject(r); err == nil {
+				objects = append(objects, o)
+			}
+			return true
+		})
+		resources[in.String()] = objects
+	}
+
+	c.ForEach(a.collection, func(r *resource.Instance) bool {
+		o, err := toObject(r)
+		if err != nil {
+			scope.Analysis.Warnf("Unable to evaluate rule %s for %v: %v", a.rule.Name, r.Metadata.FullName, err)
+			return true
+		}
+
+		out, _, err := a.program.Eval(map[string]interface{}{
+			"resource":  o,
+			"resources": resources,
+		})
+		if err != nil {
+			scope.Analysis.Warnf("Unable to evaluate rule %s for %v: %v", a.rule.Name, r.Metadata.FullName, err)
+			return true
+		}
+		if out != types.False {
+			if out != types.True {
+				scope.Analysis.Warnf("Rule %s evaluated to %v instead of a bool for %v", a.rule.Name, out, r.Metadata.FullName)
+			}
+			return true
+		}
+
+		m := diag.NewMessage(a.msgType, r)
+
+		if line, ok := util.ErrorLine(r, util.MetadataName); ok {
+			m.Line = line
+		}
+
+		c.Report(a.collection, m)
+
+		return true
+	})
+}
+
+// toObject returns the resource in its YAML form, as decoded JSON.
+func toObject(r *resource.Instance) (map[string]interface{}, error) {
+	var js []byte
+	var err error
+	// Kubernetes types are also gogo protos, but their YAML form is their JSON encoding.
+	if strings.HasPrefix(reflect.TypeOf(r.Message).Elem().PkgPath(), "k8s.io/") {
+		js, err = json.Marshal(r.Message)
+	} else {
+		js, err = config.ToJSON(r.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(js, &spec); err != nil {
+		return nil, err
+	}
+
+	// Some Kubernetes resources, such as pods, are stored as whole objects.
+	if _, ok := spec["metadata"]; ok {
+		return spec, nil
+	}
+
+	metadata := map[string]interface{}{
+		"name": r.Metadata.FullName.Name.String(),
+	}
+	if r.Metadata.FullName.Namespace != "" {
+		metadata["namespace"] = r.Metadata.FullName.Namespace.String()
+	}
+	if len(r.Metadata.Labels) > 0 {
+		metadata["labels"] = toInterfaceMap(r.Metadata.Labels)
+	}
+	if len(r.Metadata.Annotations) > 0 {
+		metadata["annotations"] = toInterfaceMap(r.Metadata.Annotations)
+	}
+
+	return map[string]interface{}{
+		"metadata": metadata,
+		"spec":     spec,
+	}, nil
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
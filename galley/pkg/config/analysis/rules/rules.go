@@ -0,0 +1,233 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rules provides analyzers that are defined by declarative rules, rather than compiled into
+// analyzers.All(). A rule is a CEL expression that must hold for each resource of a kind, for example:
+//
+//	rules:
+//	- name: virtualservice-timeout
+//	  description: No VirtualService may set a timeout above 60s
+//	  kind: VirtualService
+//	  code: ACME0001
+//	  level: Warning
+//	  message: The VirtualService sets a timeout above 60s.
+//	  expression: >-
+//	    !has(resource.spec.http) ||
+//	    resource.spec.http.all(h, !has(h.timeout) || duration(h.timeout) <= duration("60s"))
+//
+// The expression is evaluated with the resource as the `resource` variable, in its YAML form with
+// `metadata` and `spec` fields, and must evaluate to true. Other resources are available through
+// the `resources` variable, which maps the collections listed as `inputs` (and the collection of
+// the rule itself) to lists of resources.
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// NamePrefix is the prefix of the names of the analyzers that are defined by rules.
+const NamePrefix = "rules."
+
+var fileExtensions = []string{".json", ".yaml", ".yml"}
+
+// Rule is a declarative analyzer.
+type Rule struct {
+	// Name of the rule. The name of the analyzer is the name of the rule, prefixed by NamePrefix.
+	Name string `json:"name"`
+
+	// Description of the rule.
+	Description string `json:"description"`
+
+	// Kind of the resources that the rule is evaluated for, such as VirtualService. A collection
+	// name, such as istio/networking/v1alpha3/virtualservices, may be used instead.
+	Kind string `json:"kind"`
+
+	// Inputs are the kinds or collection names of other resources the rule refers to.
+	Inputs []string `json:"inputs,omitempty"`
+
+	// Code of the messages reported by the rule. It must not be used by built-in messages.
+	Code string `json:"code"`
+
+	// Level of the messages reported by the rule: Info, Warning or Error. Defaults to Warning.
+	Level string `json:"level,omitempty"`
+
+	// Message reported for the resources the expression does not hold for.
+	Message string `json:"message"`
+
+	// Expression is a CEL expression that must evaluate to true for each resource.
+	Expression string `json:"expression"`
+}
+
+// ruleFile is the format of a rule file.
+type ruleFile struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// Load returns the analyzers defined by the rule files in dir.
+func Load(dir string) ([]analysis.Analyzer, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rules directory %s: %v", dir, err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && isRuleFile(e.Name()) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+
+	var errs error
+	var analyzers []analysis.Analyzer
+	names := make(map[string]string)
+	codes := make(map[string]string)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("unable to read rule file %s: %v", path, err))
+			continue
+		}
+
+		parsed, err := Parse(data)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid rule file %s: %v", path, err))
+			continue
+		}
+
+		for _, a := range parsed {
+			if other, ok := names[a.rule.Name]; ok {
+				errs = multierror.Append(errs, fmt.Errorf("rule %q in %s is also defined in %s", a.rule.Name, path, other))
+				continue
+			}
+			if other, ok := codes[a.rule.Code]; ok {
+				errs = multierror.Append(errs, fmt.Errorf("code %s of rule %q in %s is also used in %s",
+					a.rule.Code, a.rule.Name, path, other))
+				continue
+			}
+			names[a.rule.Name] = path
+			codes[a.rule.Code] = path
+			analyzers = append(analyzers, a)
+		}
+	}
+
+	return analyzers, errs
+}
+
+// Parse returns the analyzers defined by the contents of a rule file.
+func Parse(data []byte) ([]*Analyzer, error) {
+	var f ruleFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, err
+	}
+
+	var errs error
+	var analyzers []*Analyzer
+	for i, r := range f.Rules {
+		a, err := newAnalyzer(r)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("rule %d (%s): %v", i, r.Name, err))
+			continue
+		}
+		analyzers = append(analyzers, a)
+	}
+	return analyzers, errs
+}
+
+func (r *Rule) validate() error {
+	var errs error
+	if r.Name == "" {
+		errs = multierror.Append(errs, fmt.Errorf("name is required"))
+	}
+	if r.Kind == "" {
+		errs = multierror.Append(errs, fmt.Errorf("kind is required"))
+	}
+	if r.Code == "" {
+		errs = multierror.Append(errs, fmt.Errorf("code is required"))
+	}
+	for _, mt := range msg.All() {
+		if mt.Code() == r.Code {
+			errs = multierror.Append(errs, fmt.Errorf("code %s is used by a built-in message", r.Code))
+		}
+	}
+	if r.Message == "" {
+		errs = multierror.Append(errs, fmt.Errorf("message is required"))
+	}
+	if r.Expression == "" {
+		errs = multierror.Append(errs, fmt.Errorf("expression is required"))
+	}
+	return errs
+}
+
+func (r *Rule) level() (diag.Level, error) {
+	if r.Level == "" {
+		return diag.Warning, nil
+	}
+	level, ok := diag.GetUppercaseStringToLevelMap()[strings.ToUpper(r.Level)]
+	if !ok {
+		return diag.Level{}, fmt.Errorf("invalid level %q, must be one of %v", r.Level, diag.GetAllLevelStrings())
+	}
+	return level, nil
+}
+
+// resolveCollection returns the collection of the resources of the kind, or the named collection.
+func resolveCollection(kind string) (collection.Name, error) {
+	if strings.Contains(kind, "/") {
+		s, ok := collections.All.Find(kind)
+		if !ok {
+			return "", fmt.Errorf("unknown collection %s", kind)
+		}
+		return s.Name(), nil
+	}
+
+	// Istio resources have both a k8s/ and an istio/ collection, of which analyzers use the latter.
+	var found collection.Name
+	for _, s := range collections.All.All() {
+		if s.Resource().Kind() != kind {
+			continue
+		}
+		if strings.HasPrefix(s.Name().String(), "istio/") {
+			return s.Name(), nil
+		}
+		if found == "" {
+			found = s.Name()
+		}
+	}
+	if found == "" {
+		return "", fmt.Errorf("unknown kind %s", kind)
+	}
+	return found, nil
+}
+
+func isRuleFile(name string) bool {
+	for _, ext := range fileExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
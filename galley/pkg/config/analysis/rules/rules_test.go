@@ -0,0 +1,226 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const timeoutRule = `
+rules:
+- name: virtualservice-timeout
+  kind: VirtualService
+  code: TEST0001
+  level: Error
+  message: The VirtualService sets a timeout above 60s.
+  expression: >-
+    !has(resource.spec.http) ||
+    resource.spec.http.all(h, !has(h.timeout) || duration(h.timeout) <= duration("60s"))
+- name: gateway-exists
+  kind: istio/networking/v1alpha3/virtualservices
+  inputs:
+  - Gateway
+  code: TEST0002
+  message: The VirtualService does not refer to a gateway in its namespace.
+  expression: >-
+    !has(resource.spec.gateways) || resource.spec.gateways.all(g, g == "mesh" ||
+    resources["istio/networking/v1alpha3/gateways"].exists(gw,
+    gw.metadata.name == g && gw.metadata.namespace == resource.metadata.namespace))
+`
+
+const resources = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: gw
+  namespace: default
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - port:
+      number: 80
+      name: http
+      protocol: HTTP
+    hosts:
+    - "*"
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: fast
+  namespace: default
+spec:
+  hosts:
+  - fast
+  gateways:
+  - gw
+  - mesh
+  http:
+  - timeout: 5s
+    route:
+    - destination:
+        host: fast
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: slow
+  namespace: default
+spec:
+  hosts:
+  - slow
+  gateways:
+  - missing
+  http:
+  - timeout: 2m
+    route:
+    - destination:
+        host: slow
+`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	analyzers, err := Parse([]byte(timeoutRule))
+	assert.NoError(err)
+	assert.Len(analyzers, 2)
+
+	m := analyzers[0].Metadata()
+	assert.Equal("rules.virtualservice-timeout", m.Name)
+	assert.Equal("The VirtualService sets a timeout above 60s.", m.Description)
+	assert.Equal(collections.IstioNetworkingV1Alpha3Virtualservices.Name().String(), m.Inputs[0].String())
+	assert.Equal(diag.Error, analyzers[0].MessageType().Level())
+	assert.Equal("TEST0001", analyzers[0].MessageType().Code())
+
+	m = analyzers[1].Metadata()
+	assert.Len(m.Inputs, 2)
+	assert.Equal(collections.IstioNetworkingV1Alpha3Gateways.Name().String(), m.Inputs[1].String())
+	assert.Equal(diag.Warning, analyzers[1].MessageType().Level())
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules string
+		err   string
+	}{
+		{
+			name:  "unknown field",
+			rules: "rules:\n- name: a\n  kind: Gateway\n  code: X1\n  message: m\n  expression: 'true'\n  foo: bar",
+			err:   "unknown field",
+		},
+		{
+			name:  "missing fields",
+			rules: "rules:\n- name: a",
+			err:   "kind is required",
+		},
+		{
+			name:  "built-in code",
+			rules: "rules:\n- name: a\n  kind: Gateway\n  code: IST0101\n  message: m\n  expression: 'true'",
+			err:   "used by a built-in message",
+		},
+		{
+			name:  "unknown kind",
+			rules: "rules:\n- name: a\n  kind: Foo\n  code: X1\n  message: m\n  expression: 'true'",
+			err:   "unknown kind Foo",
+		},
+		{
+			name:  "invalid level",
+			rules: "rules:\n- name: a\n  kind: Gateway\n  code: X1\n  level: Fatal\n  message: m\n  expression: 'true'",
+			err:   "invalid level",
+		},
+		{
+			name:  "invalid expression",
+			rules: "rules:\n- name: a\n  kind: Gateway\n  code: X1\n  message: m\n  expression: 'resource.spec.'",
+			err:   "invalid expression",
+		},
+		{
+			name:  "not a bool",
+			rules: "rules:\n- name: a\n  kind: Gateway\n  code: X1\n  message: m\n  expression: '1 + 1'",
+			err:   "must evaluate to a bool",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse([]byte(c.rules))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), c.err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rules")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte(timeoutRule), 0644))
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a rule"), 0644))
+
+	analyzers, err := Load(dir)
+	assert.NoError(err)
+	assert.Len(analyzers, 2)
+
+	// The same rules in another file conflict with the first.
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "b.yaml"), []byte(timeoutRule), 0644))
+	_, err = Load(dir)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "also defined in")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	assert := assert.New(t)
+
+	parsed, err := Parse([]byte(timeoutRule))
+	assert.NoError(err)
+	var analyzers []analysis.Analyzer
+	for _, a := range parsed {
+		analyzers = append(analyzers, a)
+	}
+
+	sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("rules", analyzers...), "", "istio-system", nil, true, 10*time.Second)
+	assert.NoError(sa.AddReaderKubeSource([]local.ReaderSource{{Name: "resources.yaml", Reader: strings.NewReader(resources)}}))
+
+	result, err := sa.Analyze(make(chan struct{}))
+	assert.NoError(err)
+
+	var got []string
+	for _, m := range result.Messages {
+		got = append(got, m.Type.Code()+" "+m.Resource.Origin.FriendlyName())
+	}
+	assert.ElementsMatch([]string{
+		"TEST0001 VirtualService slow.default",
+		"TEST0002 VirtualService slow.default",
+	}, got)
+}
@@ -27,6 +27,18 @@ type StatusUpdater interface {
 	Update(messages diag.Messages)
 }
 
+// CombinedStatusUpdater is a StatusUpdater that updates resource statuses with each of its StatusUpdaters.
+type CombinedStatusUpdater []StatusUpdater
+
+var _ StatusUpdater = CombinedStatusUpdater{}
+
+// Update implements StatusUpdater
+func (c CombinedStatusUpdater) Update(m diag.Messages) {
+	for _, u := range c {
+		u.Update(m)
+	}
+}
+
 // InMemoryStatusUpdater is an in-memory implementation of StatusUpdater
 type InMemoryStatusUpdater struct {
 	WaitTimeout time.Duration
@@ -103,3 +103,18 @@ func TestInMemoryStatusUpdaterCancelled(t *testing.T) {
 	g.Expect(err).To(Not(BeNil()))
 	g.Expect(err.Error()).To(ContainSubstring("cancelled"))
 }
+
+func TestCombinedStatusUpdater(t *testing.T) {
+	g := NewWithT(t)
+
+	su1 := &InMemoryStatusUpdater{}
+	su2 := &InMemoryStatusUpdater{}
+
+	msgs := diag.Messages{
+		diag.NewMessage(diag.NewMessageType(diag.Error, "test", "test"), nil),
+	}
+
+	CombinedStatusUpdater{su1, su2}.Update(msgs)
+	g.Expect(su1.Get()).To(Equal(msgs))
+	g.Expect(su2.Get()).To(Equal(msgs))
+}
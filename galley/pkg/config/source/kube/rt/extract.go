@@ -40,6 +40,7 @@ func ToResource(object metav1.Object, schema collection.Schema, item proto.Messa
 			Version:    version,
 			Ref:        source,
 			FieldsMap:  fieldMap,
+			UID:        object.GetUID(),
 		}
 	}
 
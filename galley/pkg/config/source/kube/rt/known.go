@@ -234,6 +234,49 @@ func (p *Provider) initKnownAdapters() {
 			isBuiltIn: true,
 		},
 
+		asTypesKey("", "ServiceAccount"): {
+			extractObject: defaultExtractObject,
+			extractResource: func(o interface{}) (proto.Message, error) {
+				if obj, ok := o.(*v1.ServiceAccount); ok {
+					return obj, nil
+				}
+				return nil, fmt.Errorf("unable to convert to v1.ServiceAccount: %T", o)
+			},
+			newInformer: func() (cache.SharedIndexInformer, error) {
+				client, err := p.interfaces.KubeClient()
+				if err != nil {
+					return nil, err
+				}
+
+				mlw := listwatch.MultiNamespaceListerWatcher(p.namespaces,
+					func(namespace string) cache.ListerWatcher {
+						return &cache.ListWatch{
+							ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+								return client.CoreV1().ServiceAccounts(namespace).List(context.TODO(), opts)
+							},
+							WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+								return client.CoreV1().ServiceAccounts(namespace).Watch(context.TODO(), opts)
+							},
+						}
+					})
+
+				informer := cache.NewSharedIndexInformer(mlw, &v1.ServiceAccount{}, p.resyncPeriod,
+					cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+				return informer, nil
+			},
+			parseJSON: func(input []byte) (interface{}, error) {
+				out := &v1.ServiceAccount{}
+				if _, _, err := deserializer.Decode(input, nil, out); err != nil {
+					return nil, err
+				}
+				return out, nil
+			},
+			getStatus: noStatus,
+			isEqual:   resourceVersionsMatch,
+			isBuiltIn: true,
+		},
+
 		asTypesKey("", "Endpoints"): {
 			extractObject: defaultExtractObject,
 			extractResource: func(o interface{}) (proto.Message, error) {
@@ -19,6 +19,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -32,6 +34,9 @@ type Origin struct {
 	Version    resource.Version
 	Ref        resource.Reference
 	FieldsMap  map[string]int
+
+	// UID of the object, if it comes from the API server
+	UID types.UID
 }
 
 var _ resource.Origin = &Origin{}
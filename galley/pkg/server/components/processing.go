@@ -15,7 +15,12 @@
 package components
 
 import (
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/report"
+	"istio.io/istio/galley/pkg/config/analysis/rules"
 	"istio.io/istio/galley/pkg/config/processing"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/processor"
@@ -41,8 +46,9 @@ type Processing struct {
 
 	k kube.Interfaces
 
-	runtime *processing.Runtime
-	stopCh  chan struct{}
+	runtime        *processing.Runtime
+	eventPublisher *report.EventPublisher
+	stopCh         chan struct{}
 }
 
 // NewProcessing returns a new processing component.
@@ -83,11 +89,38 @@ func (p *Processing) Start() (err error) {
 	var distributor snapshotter.Distributor = snapshotter.NewMCPDistributor(p.mcpCache)
 
 	if p.args.EnableConfigAnalysis {
-		combinedAnalyzer := analyzers.AllCombined()
+		allAnalyzers := analyzers.All()
+		if p.args.AnalysisRulesDir != "" {
+			var ruleAnalyzers []analysis.Analyzer
+			if ruleAnalyzers, err = rules.Load(p.args.AnalysisRulesDir); err != nil {
+				return
+			}
+			allAnalyzers = append(allAnalyzers, ruleAnalyzers...)
+		}
+
+		combinedAnalyzer := analysis.Combine("all", allAnalyzers...)
 		combinedAnalyzer.RemoveSkipped(colsInSnapshots, kubeResources.DisabledCollectionNames(), transformProviders)
 
+		updaters := snapshotter.CombinedStatusUpdater{updater}
+		if p.args.EnableAnalysisMetrics {
+			updaters = append(updaters, report.NewMetricPublisher(p.args.AnalysisMetricThreshold))
+		}
+		if p.args.EnableAnalysisEvents {
+			var k kube.Interfaces
+			if k, err = p.getKubeInterfaces(); err != nil {
+				return
+			}
+			var client kubernetes.Interface
+			if client, err = k.KubeClient(); err != nil {
+				return
+			}
+			p.eventPublisher = report.NewEventPublisher(client, p.args.AnalysisEventThreshold,
+				p.args.AnalysisEventQPS, p.args.AnalysisEventBurst)
+			updaters = append(updaters, p.eventPublisher)
+		}
+
 		distributor = snapshotter.NewAnalyzingDistributor(snapshotter.AnalyzingDistributorSettings{
-			StatusUpdater:     updater,
+			StatusUpdater:     updaters,
 			Analyzer:          combinedAnalyzer,
 			Distributor:       distributor,
 			AnalysisSnapshots: p.args.Snapshots,
@@ -160,4 +193,9 @@ func (p *Processing) Stop() {
 		p.runtime.Stop()
 		p.runtime = nil
 	}
+
+	if p.eventPublisher != nil {
+		p.eventPublisher.Stop()
+		p.eventPublisher = nil
+	}
 }
@@ -21,6 +21,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/util/kuberesource"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/schema/snapshots"
@@ -58,6 +59,25 @@ type Args struct { // nolint:maligned
 	// Enable Config Analysis service, that will analyze and update CRD status. UseOldProcessor must be set to false.
 	EnableConfigAnalysis bool
 
+	// AnalysisRulesDir is the directory of rule files defining additional analyzers for Config Analysis.
+	AnalysisRulesDir string
+
+	// Enable publishing Config Analysis messages as Kubernetes events on the resources they are reported for.
+	EnableAnalysisEvents bool
+
+	// AnalysisEventThreshold is the minimum level of the messages that are published as events.
+	AnalysisEventThreshold diag.Level
+
+	// AnalysisEventQPS and AnalysisEventBurst limit the rate at which events are published.
+	AnalysisEventQPS   float64
+	AnalysisEventBurst int
+
+	// Enable publishing the number of Config Analysis messages as metrics.
+	EnableAnalysisMetrics bool
+
+	// AnalysisMetricThreshold is the minimum level of the messages that are counted by the metrics.
+	AnalysisMetricThreshold diag.Level
+
 	Snapshots       []string
 	TriggerSnapshot string
 }
@@ -65,15 +85,19 @@ type Args struct { // nolint:maligned
 // DefaultArgs allocates an Args struct initialized with Galley's default configuration.
 func DefaultArgs() *Args {
 	return &Args{
-		ResyncPeriod:          0,
-		KubeConfig:            "",
-		WatchedNamespaces:     metav1.NamespaceAll,
-		MeshConfigFile:        defaultMeshConfigFile,
-		DomainSuffix:          constants.DefaultKubernetesDomain,
-		ExcludedResourceKinds: kuberesource.DefaultExcludedResourceKinds(),
-		EnableConfigAnalysis:  false,
-		Snapshots:             []string{snapshots.Default},
-		TriggerSnapshot:       snapshots.Default,
+		ResyncPeriod:            0,
+		KubeConfig:              "",
+		WatchedNamespaces:       metav1.NamespaceAll,
+		MeshConfigFile:          defaultMeshConfigFile,
+		DomainSuffix:            constants.DefaultKubernetesDomain,
+		ExcludedResourceKinds:   kuberesource.DefaultExcludedResourceKinds(),
+		EnableConfigAnalysis:    false,
+		AnalysisEventThreshold:  diag.Warning,
+		AnalysisEventQPS:        5,
+		AnalysisEventBurst:      50,
+		AnalysisMetricThreshold: diag.Info,
+		Snapshots:               []string{snapshots.Default},
+		TriggerSnapshot:         snapshots.Default,
 	}
 }
 
@@ -87,6 +111,11 @@ func (a *Args) String() string {
 	_, _ = fmt.Fprintf(buf, "MeshConfigFile: %s\n", a.MeshConfigFile)
 	_, _ = fmt.Fprintf(buf, "DomainSuffix: %s\n", a.DomainSuffix)
 	_, _ = fmt.Fprintf(buf, "ExcludedResourceKinds: %v\n", a.ExcludedResourceKinds)
+	_, _ = fmt.Fprintf(buf, "AnalysisRulesDir: %s\n", a.AnalysisRulesDir)
+	_, _ = fmt.Fprintf(buf, "EnableAnalysisEvents: %v\n", a.EnableAnalysisEvents)
+	_, _ = fmt.Fprintf(buf, "AnalysisEventThreshold: %v\n", a.AnalysisEventThreshold)
+	_, _ = fmt.Fprintf(buf, "EnableAnalysisMetrics: %v\n", a.EnableAnalysisMetrics)
+	_, _ = fmt.Fprintf(buf, "AnalysisMetricThreshold: %v\n", a.AnalysisMetricThreshold)
 
 	return buf.String()
 }
@@ -147,11 +147,12 @@ See also 'istioctl experimental remove-from-mesh deployment' which does the reve
 			if err != nil {
 				return err
 			}
-			var sidecarTemplate, valuesConfig string
+			var injectConfig inject.Config
+			var valuesConfig string
 			ns := handlers.HandleNamespace(namespace, defaultNamespace)
 			writer := cmd.OutOrStdout()
 
-			meshConfig, err := setupParameters(&sidecarTemplate, &valuesConfig)
+			meshConfig, err := setupParameters(&injectConfig, &valuesConfig)
 			if err != nil {
 				return err
 			}
@@ -159,7 +160,7 @@ See also 'istioctl experimental remove-from-mesh deployment' which does the reve
 			if err != nil {
 				return fmt.Errorf("deployment %q does not exist", args[0])
 			}
-			return injectSideCarIntoDeployment(client, dep, sidecarTemplate, valuesConfig,
+			return injectSideCarIntoDeployment(client, dep, &injectConfig, valuesConfig,
 				args[0], ns, opts.Revision, meshConfig, writer, func(warning string) {
 					fmt.Fprintln(cmd.ErrOrStderr(), warning)
 				})
@@ -201,11 +202,12 @@ See also 'istioctl experimental remove-from-mesh service' which does the reverse
 			if err != nil {
 				return err
 			}
-			var sidecarTemplate, valuesConfig string
+			var injectConfig inject.Config
+			var valuesConfig string
 			ns := handlers.HandleNamespace(namespace, defaultNamespace)
 			writer := cmd.OutOrStdout()
 
-			meshConfig, err := setupParameters(&sidecarTemplate, &valuesConfig)
+			meshConfig, err := setupParameters(&injectConfig, &valuesConfig)
 			if err != nil {
 				return err
 			}
@@ -217,7 +219,7 @@ See also 'istioctl experimental remove-from-mesh service' which does the reverse
 				_, _ = fmt.Fprintf(writer, "No deployments found for service %s.%s\n", args[0], ns)
 				return nil
 			}
-			return injectSideCarIntoDeployments(client, matchingDeployments, sidecarTemplate, valuesConfig,
+			return injectSideCarIntoDeployments(client, matchingDeployments, &injectConfig, valuesConfig,
 				args[0], ns, opts.Revision, meshConfig, writer, func(warning string) {
 					fmt.Fprintln(cmd.ErrOrStderr(), warning)
 				})
@@ -228,11 +230,11 @@ See also 'istioctl experimental remove-from-mesh service' which does the reverse
 	return cmd
 }
 
-func injectSideCarIntoDeployments(client kubernetes.Interface, deps []appsv1.Deployment, sidecarTemplate, valuesConfig,
-	name, namespace string, revision string, meshConfig *meshconfig.MeshConfig, writer io.Writer, warningHandler func(string)) error {
+func injectSideCarIntoDeployments(client kubernetes.Interface, deps []appsv1.Deployment, injectConfig *inject.Config,
+	valuesConfig, name, namespace string, revision string, meshConfig *meshconfig.MeshConfig, writer io.Writer, warningHandler func(string)) error {
 	var errs error
 	for _, dep := range deps {
-		err := injectSideCarIntoDeployment(client, &dep, sidecarTemplate, valuesConfig,
+		err := injectSideCarIntoDeployment(client, &dep, injectConfig, valuesConfig,
 			name, namespace, revision, meshConfig, writer, warningHandler)
 		if err != nil {
 			errs = multierror.Append(errs, err)
@@ -286,7 +288,7 @@ See also 'istioctl experimental remove-from-mesh external-service' which does th
 	return cmd
 }
 
-func setupParameters(sidecarTemplate, valuesConfig *string) (*meshconfig.MeshConfig, error) {
+func setupParameters(injectConfig *inject.Config, valuesConfig *string) (*meshconfig.MeshConfig, error) {
 	var meshConfig *meshconfig.MeshConfig
 	var err error
 	if meshConfigFile != "" {
@@ -303,13 +305,15 @@ func setupParameters(sidecarTemplate, valuesConfig *string) (*meshconfig.MeshCon
 		if err != nil {
 			return nil, err
 		}
-		var injectConfig inject.Config
-		if err := yaml.Unmarshal(injectionConfig, &injectConfig); err != nil {
+		if err := yaml.Unmarshal(injectionConfig, injectConfig); err != nil {
 			return nil, multierror.Append(err, fmt.Errorf("loading --injectConfigFile"))
 		}
-		*sidecarTemplate = injectConfig.Template
-	} else if *sidecarTemplate, err = getInjectConfigFromConfigMap(kubeconfig); err != nil {
-		return nil, err
+	} else {
+		cfg, err := getInjectorConfigFromConfigMap(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		*injectConfig = *cfg
 	}
 	if valuesFile != "" {
 		valuesConfigBytes, err := ioutil.ReadFile(valuesFile) // nolint: vetshadow
@@ -323,12 +327,12 @@ func setupParameters(sidecarTemplate, valuesConfig *string) (*meshconfig.MeshCon
 	return meshConfig, err
 }
 
-func injectSideCarIntoDeployment(client kubernetes.Interface, dep *appsv1.Deployment, sidecarTemplate, valuesConfig,
-	svcName, svcNamespace string, revision string, meshConfig *meshconfig.MeshConfig, writer io.Writer, warningHandler func(string)) error {
+func injectSideCarIntoDeployment(client kubernetes.Interface, dep *appsv1.Deployment, injectConfig *inject.Config,
+	valuesConfig, svcName, svcNamespace string, revision string, meshConfig *meshconfig.MeshConfig, writer io.Writer, warningHandler func(string)) error {
 	var errs error
 	log.Debugf("updating deployment %s.%s with Istio sidecar injected",
 		dep.Name, dep.Namespace)
-	newDep, err := inject.IntoObject(sidecarTemplate, valuesConfig, revision, meshConfig, dep, warningHandler)
+	newDep, err := inject.IntoObject(injectConfig, valuesConfig, revision, meshConfig, dep, warningHandler)
 	if err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("failed to inject sidecar to deployment resource %s.%s for service %s.%s due to %v",
 			dep.Name, dep.Namespace, svcName, svcNamespace, err))
@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -29,8 +30,10 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/fix"
 	"istio.io/istio/galley/pkg/config/analysis/local"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/analysis/rules"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
 	"istio.io/istio/istioctl/pkg/util/formatting"
@@ -74,6 +77,9 @@ var (
 	suppress          []string
 	analysisTimeout   time.Duration
 	recursive         bool
+	rulesDir          string
+	fixes             bool
+	fixInPlace        bool
 
 	termEnvVar = env.RegisterStringVar("TERM", "", "Specifies terminal type.  Use 'dumb' to suppress color output")
 
@@ -104,6 +110,12 @@ func Analyze() *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Analyze the current live cluster with additional analyzers defined by the rules in a directory
+  istioctl analyze --rules-dir my-rules/
+
+  # Analyze yaml files and apply the suggested fixes to them
+  istioctl analyze --use-kube=false --fix --in-place a.yaml b.yaml
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -115,8 +127,21 @@ func Analyze() *cobra.Command {
 				}
 			}
 
+			if fixInPlace && !fixes {
+				return CommandParseError{fmt.Errorf("--in-place requires --fix")}
+			}
+
+			allAnalyzers := append(analyzers.All(), analyzers.IstioctlOnly()...)
+			if rulesDir != "" {
+				ruleAnalyzers, err := rules.Load(rulesDir)
+				if err != nil {
+					return err
+				}
+				allAnalyzers = append(allAnalyzers, ruleAnalyzers...)
+			}
+
 			if listAnalyzers {
-				fmt.Print(AnalyzersAsString(analyzers.All()))
+				fmt.Print(AnalyzersAsString(allAnalyzers))
 				return nil
 			}
 
@@ -135,7 +160,7 @@ func Analyze() *cobra.Command {
 				selectedNamespace = ""
 			}
 
-			sa := local.NewSourceAnalyzer(schema.MustGet(), analyzers.AllCombined(),
+			sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("all", allAnalyzers...),
 				resource.Namespace(selectedNamespace), resource.Namespace(istioNamespace), nil, true, analysisTimeout)
 
 			// Check for suppressions and add them to our SourceAnalyzer
@@ -154,6 +179,12 @@ func Analyze() *cobra.Command {
 						break
 					}
 				}
+				for _, a := range allAnalyzers {
+					if ra, ok := a.(*rules.Analyzer); ok && ra.MessageType().Code() == parts[0] {
+						codeIsValid = true
+						break
+					}
+				}
 
 				if !codeIsValid {
 					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Supplied message code '%s' is an unknown message code and will not have any effect.\n", parts[0])
@@ -236,6 +267,15 @@ func Analyze() *cobra.Command {
 			}
 			fmt.Fprintln(cmd.OutOrStdout(), output)
 
+			// Fixes are part of the messages in the other formats
+			if fixes {
+				if fixInPlace {
+					applyFixes(cmd, outputMessages)
+				} else if msgOutputFormat == formatting.LogFormat {
+					printFixes(cmd, outputMessages)
+				}
+			}
+
 			// An extra message on success
 			if len(outputMessages) == 0 {
 				if parseErrors == 0 {
@@ -293,9 +333,58 @@ func Analyze() *cobra.Command {
 		"The duration to wait before failing")
 	analysisCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false,
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
+	analysisCmd.PersistentFlags().StringVar(&rulesDir, "rules-dir", "",
+		"Directory of rule files defining additional analyzers. Each rule is a CEL expression that must hold for every resource of a kind.")
+	analysisCmd.PersistentFlags().BoolVar(&fixes, "fix", false,
+		"Print the fixes suggested for the messages as JSON patches, which can be applied with 'kubectl patch --type=json'.")
+	analysisCmd.PersistentFlags().BoolVar(&fixInPlace, "in-place", false,
+		"With --fix, apply the suggested fixes to the analyzed files instead of printing them. "+
+			"The documents of the files that are changed are reformatted.")
 	return analysisCmd
 }
 
+func printFixes(cmd *cobra.Command, messages diag.Messages) {
+	for _, m := range messages {
+		for _, f := range m.Fixes {
+			patch, err := json.Marshal(f.Patch)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Fix [%v]%s %s:\n%s\n", m.Type.Code(), m.Origin(), f.Description, patch)
+		}
+	}
+}
+
+// applyFixes applies the first fix suggested for each message to the file that defines its resource.
+func applyFixes(cmd *cobra.Command, messages diag.Messages) {
+	var filenames []string
+	byFile := make(map[string][]diag.Message)
+	for _, m := range messages {
+		if len(m.Fixes) == 0 {
+			continue
+		}
+		filename := fix.Filename(m)
+		if filename == "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Unable to fix [%v]%s: the resource is not defined in a file\n", m.Type.Code(), m.Origin())
+			continue
+		}
+		if _, ok := byFile[filename]; !ok {
+			filenames = append(filenames, filename)
+		}
+		byFile[filename] = append(byFile[filename], m)
+	}
+
+	for _, filename := range filenames {
+		applied, err := fix.ApplyToFile(filename, byFile[filename])
+		for _, m := range applied {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Fixed [%v]%s: %s\n", m.Type.Code(), m.Origin(), m.Fixes[0].Description)
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Unable to apply all fixes to %s: %v\n", filename, err)
+		}
+	}
+}
+
 func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error) {
 	var readers []local.ReaderSource
 	for _, f := range args {
@@ -303,7 +392,7 @@ func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error
 
 		// Handle "-" as stdin as a special case.
 		if f == "-" {
-			if isatty.IsTerminal(os.Stdin.Fd()) && !isJSONorYAMLOutputFormat() {
+			if isatty.IsTerminal(os.Stdin.Fd()) && !isMachineReadableOutputFormat() {
 				fmt.Fprint(cmd.OutOrStdout(), "Reading from stdin:\n")
 			}
 			r = os.Stdin
@@ -446,6 +535,6 @@ func analyzeTargetAsString() string {
 }
 
 // TODO: Refactor output writer so that it is smart enough to know when to output what.
-func isJSONorYAMLOutputFormat() bool {
-	return msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat
+func isMachineReadableOutputFormat() bool {
+	return msgOutputFormat != formatting.LogFormat
 }
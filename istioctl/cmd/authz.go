@@ -19,22 +19,54 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	klabels "k8s.io/apimachinery/pkg/labels"
 
 	"istio.io/istio/istioctl/pkg/authz"
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/kube"
 	"istio.io/pkg/log"
 )
 
 var (
-	configDumpFile string
+	configDumpFile      string
+	authzPolicyFile     string
+	authzMeshConfigFile string
+	authzLabels         string
+	authzTCP            bool
+	authzRequest        authzRequestFlags
 )
 
-var (
-	checkCmd = &cobra.Command{
+// authzRequestFlags describe the request evaluated by the check command.
+type authzRequestFlags struct {
+	sourcePrincipal  string
+	sourceNamespace  string
+	sourceIP         string
+	remoteIP         string
+	destinationIP    string
+	port             uint32
+	sni              string
+	host             string
+	method           string
+	path             string
+	headers          []string
+	requestPrincipal string
+	claims           []string
+}
+
+// authzRequestFlagNames are the flags that turn the check command into a request evaluation.
+var authzRequestFlagNames = []string{
+	"source-principal", "source-namespace", "source-ip", "remote-ip", "destination-ip", "port", "sni",
+	"host", "method", "path", "header", "request-principal", "claim",
+}
+
+func checkCmd() *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "check [<type>/]<name>[.<namespace>]",
 		Short: "Check AuthorizationPolicy applied in the pod.",
 		Long: `Check prints the AuthorizationPolicy applied to a pod by directly checking
@@ -42,7 +74,14 @@ the Envoy configuration of the pod. The command is especially useful for inspect
 the policy propagation from Istiod to Envoy and the final AuthorizationPolicy list merged 
 from multiple sources (mesh-level, namespace-level and workload-level).
 
-The command also supports reading from a standalone config dump file with flag -f.`,
+The command also supports reading from a standalone config dump file with flag -f.
+
+When a request is described with flags like --path or --source-principal, the command
+evaluates it against the RBAC filters of the inbound filter chain handling it, and prints
+whether it is allowed or denied along with the policy and rule that decided it. Instead of
+a pod or a config dump, the request can also be evaluated against a file or directory of
+AuthorizationPolicy resources given with --policy-file, compiled offline for the workload in
+--namespace with --labels, the same way Istiod does.`,
 		Example: `  # Check AuthorizationPolicy applied to pod httpbin-88ddbcfdd-nt5jb:
   istioctl x authz check httpbin-88ddbcfdd-nt5jb
 
@@ -50,15 +89,35 @@ The command also supports reading from a standalone config dump file with flag -
   istioctl proxy-status deployment/productpage-v1
 
   # Check AuthorizationPolicy from Envoy config dump file:
-  istioctl x authz check -f httpbin_config_dump.json`,
+  istioctl x authz check -f httpbin_config_dump.json
+
+  # Check whether a GET request from the sleep service account is allowed by pod httpbin-88ddbcfdd-nt5jb:
+  istioctl x authz check httpbin-88ddbcfdd-nt5jb --port 80 --method GET --path /status \
+    --source-principal cluster.local/ns/default/sa/sleep
+
+  # Check a request against AuthorizationPolicy files for the workload labeled app=httpbin in namespace foo:
+  istioctl x authz check --policy-file policies/ -n foo --labels app=httpbin --port 80 --path /admin \
+    --request-principal issuer.example.com/alice --claim groups=admin`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("check requires only <pod-name>[.<pod-namespace>]")
 			}
+			if authzPolicyFile != "" && (configDumpFile != "" || len(args) > 0) {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--policy-file cannot be used with a pod name or --file")
+			}
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if authzPolicyFile != "" {
+				decision, err := evaluatePolicyFiles(authzPolicyFile)
+				if err != nil {
+					return fmt.Errorf("failed to evaluate AuthorizationPolicy from %s: %v", authzPolicyFile, err)
+				}
+				authz.PrintDecision(cmd.OutOrStdout(), decision)
+				return nil
+			}
 			var configDump *configdump.Wrapper
 			var err error
 			if configDumpFile != "" {
@@ -89,11 +148,136 @@ The command also supports reading from a standalone config dump file with flag -
 			if err != nil {
 				return err
 			}
-			analyzer.Print(cmd.OutOrStdout())
+			if !requestFlagsChanged(cmd) {
+				analyzer.Print(cmd.OutOrStdout())
+				return nil
+			}
+			req, err := authzRequest.request()
+			if err != nil {
+				return err
+			}
+			decision, err := analyzer.Evaluate(req)
+			if err != nil {
+				return err
+			}
+			authz.PrintDecision(cmd.OutOrStdout(), decision)
 			return nil
 		},
 	}
-)
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&configDumpFile, "file", "f", "",
+		"The json file with Envoy config dump to be checked")
+	flags.StringVar(&authzPolicyFile, "policy-file", "",
+		"A file or directory of AuthorizationPolicy resources to evaluate the request against")
+	flags.StringVar(&authzMeshConfigFile, "meshConfigFile", "",
+		"Mesh configuration filename, used with --policy-file. Defaults to the default mesh configuration")
+	flags.StringVarP(&authzLabels, "labels", "l", "",
+		"Labels of the workload, used with --policy-file, e.g. app=httpbin,version=v1")
+	flags.BoolVar(&authzTCP, "tcp", false,
+		"Evaluate the policies built for TCP rather than HTTP, used with --policy-file")
+	flags.StringVar(&authzRequest.sourcePrincipal, "source-principal", "",
+		"Peer identity of the request, e.g. cluster.local/ns/default/sa/sleep. Empty for plain text requests")
+	flags.StringVar(&authzRequest.sourceNamespace, "source-namespace", "",
+		"Namespace of the peer. Implies the default service account of the namespace without --source-principal")
+	flags.StringVar(&authzRequest.sourceIP, "source-ip", "", "IP address of the peer")
+	flags.StringVar(&authzRequest.remoteIP, "remote-ip", "", "Original client IP address of the request. Defaults to --source-ip")
+	flags.StringVar(&authzRequest.destinationIP, "destination-ip", "", "Destination IP address of the request")
+	flags.Uint32Var(&authzRequest.port, "port", 0, "Destination port of the request")
+	flags.StringVar(&authzRequest.sni, "sni", "", "SNI of the connection")
+	flags.StringVar(&authzRequest.host, "host", "", "Host header of the request")
+	flags.StringVar(&authzRequest.method, "method", "", "Method of the request")
+	flags.StringVar(&authzRequest.path, "path", "", "Path of the request, optionally with a query string")
+	flags.StringArrayVar(&authzRequest.headers, "header", nil, "Header of the request, in the form name=value")
+	flags.StringVar(&authzRequest.requestPrincipal, "request-principal", "",
+		"Principal of the request JWT, in the form issuer/subject. Defaults to the iss and sub claims")
+	flags.StringArrayVar(&authzRequest.claims, "claim", nil,
+		"Claim of the request JWT, in the form name=value. Repeat the flag for list claims")
+
+	return cmd
+}
+
+func requestFlagsChanged(cmd *cobra.Command) bool {
+	for _, name := range authzRequestFlagNames {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluatePolicyFiles evaluates the request against the AuthorizationPolicy resources in the file
+// or directory.
+func evaluatePolicyFiles(path string) (*authz.Decision, error) {
+	ns := handlers.HandleNamespace(namespace, defaultNamespace)
+	configs, _, err := readSimulationConfigs([]string{path}, ns, constants.DefaultKubernetesDomain)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no Istio configuration found")
+	}
+	meshConfig := mesh.DefaultMeshConfig()
+	if authzMeshConfigFile != "" {
+		m, err := mesh.ReadMeshConfig(authzMeshConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mesh config: %v", err)
+		}
+		meshConfig = *m
+	}
+	workloadLabels, err := klabels.ConvertSelectorToLabelsMap(authzLabels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labels %q: %v", authzLabels, err)
+	}
+	req, err := authzRequest.request()
+	if err != nil {
+		return nil, err
+	}
+	return authz.EvaluatePolicies(configs, &meshConfig, authz.Workload{
+		Namespace: ns,
+		Labels:    workloadLabels,
+		TCP:       authzTCP,
+	}, req)
+}
+
+func (f *authzRequestFlags) request() (*authz.Request, error) {
+	req := &authz.Request{
+		SourcePrincipal:  f.sourcePrincipal,
+		SourceIP:         f.sourceIP,
+		RemoteIP:         f.remoteIP,
+		DestinationIP:    f.destinationIP,
+		DestinationPort:  f.port,
+		SNI:              f.sni,
+		Host:             f.host,
+		Method:           f.method,
+		Path:             f.path,
+		Headers:          map[string]string{},
+		RequestPrincipal: f.requestPrincipal,
+		Claims:           map[string][]string{},
+	}
+	if f.sourceNamespace != "" {
+		if f.sourcePrincipal == "" {
+			req.SourcePrincipal = fmt.Sprintf("%s/ns/%s/sa/default", constants.DefaultKubernetesDomain, f.sourceNamespace)
+		} else if !strings.Contains(f.sourcePrincipal, "/ns/"+f.sourceNamespace+"/") {
+			return nil, fmt.Errorf("source principal %q is not in namespace %q", f.sourcePrincipal, f.sourceNamespace)
+		}
+	}
+	for _, h := range f.headers {
+		parts := strings.SplitN(h, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid header %q, expected name=value", h)
+		}
+		req.Headers[parts[0]] = parts[1]
+	}
+	for _, c := range f.claims {
+		parts := strings.SplitN(c, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid claim %q, expected name=value", c)
+		}
+		req.Claims[parts[0]] = append(req.Claims[parts[0]], parts[1])
+	}
+	return req, nil
+}
 
 func getConfigDumpFromFile(filename string) (*configdump.Wrapper, error) {
 	file, err := os.Open(filename)
@@ -152,12 +336,7 @@ func AuthZ() *cobra.Command {
 		Short: "Inspect Istio AuthorizationPolicy",
 	}
 
-	cmd.AddCommand(checkCmd)
+	cmd.AddCommand(checkCmd())
 	cmd.Long += "\n\n" + ExperimentalMsg
 	return cmd
 }
-
-func init() {
-	checkCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"The json file with Envoy config dump to be checked")
-}
@@ -13,3 +13,66 @@
 // limitations under the License.
 
 package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAuthzCheckRequest(t *testing.T) {
+	cases := []execTestCase{
+		{
+			args: []string{"x", "authz", "check", "--policy-file", "testdata/authz/policies.yaml", "-n", "foo", "-l", "app=httpbin",
+				"--port", "80", "--method", "GET", "--path", "/status", "--source-principal", "cluster.local/ns/foo/sa/sleep"},
+			expectedString: "DECISION: ALLOW (allowed by ALLOW policy)\nPOLICY: allow-sleep.foo\nRULE: 0\n",
+		},
+		{
+			args: []string{"x", "authz", "check", "--policy-file", "testdata/authz/policies.yaml", "-n", "foo", "-l", "app=httpbin",
+				"--port", "80", "--method", "POST", "--path", "/status", "--source-namespace", "foo"},
+			expectedString: "DECISION: DENY (no ALLOW policy matched)",
+		},
+		{
+			args: []string{"x", "authz", "check", "--policy-file", "testdata/authz/policies.yaml", "-n", "foo", "-l", "app=httpbin",
+				"--method", "GET", "--path", "/admin/users", "--source-principal", "cluster.local/ns/foo/sa/sleep"},
+			expectedString: "DECISION: DENY (denied by DENY policy)\nPOLICY: deny-admin.foo\nRULE: 0\n",
+		},
+		{
+			args: []string{"x", "authz", "check", "--policy-file", "testdata/authz/policies.yaml", "-n", "foo", "-l", "app=httpbin",
+				"--method", "POST", "--path", "/admin/users", "--claim", "iss=issuer.example.com", "--claim", "sub=alice",
+				"--claim", "groups=dev", "--claim", "groups=admin"},
+			expectedString: "DECISION: ALLOW (allowed by ALLOW policy)\nPOLICY: allow-sleep.foo\nRULE: 1\n",
+		},
+		{
+			// The policies do not select other workloads.
+			args: []string{"x", "authz", "check", "--policy-file", "testdata/authz/policies.yaml", "-n", "foo", "-l", "app=reviews",
+				"--method", "POST", "--path", "/admin"},
+			expectedString: "DECISION: ALLOW (no policy denied the request)",
+		},
+		{
+			args: []string{"x", "authz", "check", "--policy-file", "testdata/authz/policies.yaml", "-n", "foo",
+				"--source-namespace", "bar", "--source-principal", "cluster.local/ns/foo/sa/sleep"},
+			expectedString: `source principal "cluster.local/ns/foo/sa/sleep" is not in namespace "bar"`,
+			wantException:  true,
+		},
+		{
+			// Policy files are not parsed as a config dump.
+			args: []string{"x", "authz", "check", "-f", "testdata/authz/policies.yaml", "--policy-file", "",
+				"--method", "GET"},
+			expectedString: "failed to get config dump from file testdata/authz/policies.yaml",
+			wantException:  true,
+		},
+		{
+			args: []string{"x", "authz", "check", "-f", "testdata/authz/policies.yaml",
+				"--policy-file", "testdata/authz/policies.yaml"},
+			expectedString: "--policy-file cannot be used with a pod name or --file",
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/security/pkg/server/ca/audit"
+)
+
+func caCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Interact with the Istiod certificate authority",
+		Long:  "A group of commands used to inspect the certificate authority running in Istiod",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			if len(args) != 0 {
+				return fmt.Errorf("unknown subcommand %q", args[0])
+			}
+			return nil
+		},
+	}
+	cmd.AddCommand(caIssuedCmd())
+	return cmd
+}
+
+func caIssuedCmd() *cobra.Command {
+	var (
+		opts         clioptions.ControlPlaneOptions
+		identity     string
+		serial       string
+		since        time.Duration
+		limit        int
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "issued",
+		Short: "Lists certificates recently issued by Istiod",
+		Long: `Lists the certificates recently issued by each Istiod instance, newest first.
+Only the records kept in memory by Istiod are shown; see CA_AUDIT_LOG_FILE and CA_AUDIT_SINK_ADDRESS
+for a complete record.`,
+		Example: `  # List certificates issued in the last hour
+  istioctl experimental ca issued --since 1h
+
+  # List certificates issued to a service account
+  istioctl experimental ca issued --identity ns/default/sa/sleep`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if outputFormat != jsonOutput && outputFormat != summaryOutput {
+				return fmt.Errorf("unknown output format %q, must be %s or %s", outputFormat, summaryOutput, jsonOutput)
+			}
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			q := url.Values{}
+			if identity != "" {
+				q.Set("identity", identity)
+			}
+			if serial != "" {
+				q.Set("serial", serial)
+			}
+			if since > 0 {
+				q.Set("since", time.Now().Add(-since).Format(time.RFC3339))
+			}
+			if limit > 0 {
+				q.Set("limit", strconv.Itoa(limit))
+			}
+			path := audit.DebugPath
+			if len(q) > 0 {
+				path += "?" + q.Encode()
+			}
+			results, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, path)
+			if err != nil {
+				return err
+			}
+			records, err := mergeIssuedRecords(results)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(records) > limit {
+				records = records[:limit]
+			}
+			if outputFormat == jsonOutput {
+				out, err := json.MarshalIndent(records, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(c.OutOrStdout(), string(out))
+				return err
+			}
+			return printIssuedRecords(c.OutOrStdout(), records)
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	cmd.Flags().StringVar(&identity, "identity", "", "Only show certificates whose SANs or caller identity contain this string")
+	cmd.Flags().StringVar(&serial, "serial", "", "Only show the certificate with this hex encoded serial number")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only show certificates issued within this duration")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum number of certificates to show. Zero shows all of them")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|short")
+	return cmd
+}
+
+type issuedRecord struct {
+	Istiod string `json:"istiod"`
+	*audit.Record
+}
+
+// mergeIssuedRecords combines the responses of all Istiod instances, newest first.
+func mergeIssuedRecords(results map[string][]byte) ([]issuedRecord, error) {
+	var records []issuedRecord
+	for istiod, body := range results {
+		var rs []*audit.Record
+		if err := json.Unmarshal(body, &rs); err != nil {
+			return nil, fmt.Errorf("failed to parse issued certificates from %s: %v", istiod, err)
+		}
+		for _, r := range rs {
+			records = append(records, issuedRecord{Istiod: istiod, Record: r})
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.After(records[j].Time)
+	})
+	return records, nil
+}
+
+func printIssuedRecords(w io.Writer, records []issuedRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ISSUED\tSERIAL\tSANS\tCALLER\tPEER\tEXPIRES\tISSUER KEY\tISTIOD")
+	for _, r := range records {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Format(time.RFC3339), r.SerialNumber, strings.Join(r.SANs, ","),
+			strings.Join(r.CallerIdentities, ","), r.PeerAddress, r.NotAfter.Format(time.RFC3339),
+			r.IssuerKeyID, r.Istiod)
+	}
+	return tw.Flush()
+}
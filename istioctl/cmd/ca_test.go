@@ -0,0 +1,54 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCAIssued(t *testing.T) {
+	istiodResults := map[string][]byte{
+		"istiod-1": []byte(`[{"time":"2020-11-20T10:00:00Z","serialNumber":"0a","sans":["spiffe://cluster.local/ns/default/sa/sleep"]}]`),
+		"istiod-2": []byte(`[{"time":"2020-11-20T11:00:00Z","serialNumber":"0b","sans":["spiffe://cluster.local/ns/default/sa/httpbin"]}]`),
+	}
+	cases := []execTestCase{
+		{
+			execClientConfig: istiodResults,
+			args:             strings.Split("x ca issued", " "),
+			expectedString:   "2020-11-20T11:00:00Z 0b     spiffe://cluster.local/ns/default/sa/httpbin",
+		},
+		{
+			execClientConfig: istiodResults,
+			args:             strings.Split("x ca issued -o json", " "),
+			expectedString:   `"istiod": "istiod-1"`,
+		},
+		{
+			execClientConfig: map[string][]byte{"istiod-1": []byte("not json")},
+			args:             strings.Split("x ca issued", " "),
+			wantException:    true,
+		},
+		{
+			args:          strings.Split("x ca issued -o yaml", " "),
+			wantException: true,
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
@@ -48,9 +48,13 @@ func configCmd() *cobra.Command {
 		Short: "Configure istioctl defaults",
 		Args:  cobra.NoArgs,
 		Example: `  # list configuration parameters
-  istioctl config list`,
+  istioctl config list
+
+  # list recent config versions known to Istiod
+  istioctl x config history`,
 	}
 	configCmd.AddCommand(listCommand())
+	configCmd.AddCommand(historyCommand())
 	return configCmd
 }
 
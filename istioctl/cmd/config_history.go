@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// configHistoryResponse is the response of /debug/config_history when a version or proxy is requested.
+type configHistoryResponse struct {
+	Version string            `json:"version"`
+	ProxyID string            `json:"proxyID,omitempty"`
+	Configs []json.RawMessage `json:"configs"`
+}
+
+func historyCommand() *cobra.Command {
+	var (
+		version      string
+		proxy        string
+		outputFormat string
+		opts         clioptions.ControlPlaneOptions
+	)
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent config versions known to Istiod, or show the config at a version",
+		Long: `List the recent versions of the config distributed by Istiod, with the resources added, updated or
+deleted in each one. Versions are retained for PILOT_DISTRIBUTION_HISTORY_RETENTION, and require
+PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING to be enabled in Istiod.
+
+With --version, or with --proxy to use the version a proxy is running, the full config at that version is shown.`,
+		Example: `  # list recent config versions and the changes in each
+  istioctl x config history
+
+  # show the config as of a version
+  istioctl x config history --version IZYRkqgxIEU=
+
+  # show the config as of the version the productpage pod is running
+  istioctl x config history --proxy productpage-v1-7bd4d78d58-6jrhr.default`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			if outputFormat != summaryOutput && outputFormat != jsonOutput {
+				return fmt.Errorf("unknown output format %q, must be short or json", outputFormat)
+			}
+			if version != "" && proxy != "" {
+				return fmt.Errorf("--version and --proxy cannot be used together")
+			}
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			path := "/debug/config_history"
+			if proxy != "" {
+				podName, ns := handlers.InferPodInfo(proxy, handlers.HandleNamespace(namespace, defaultNamespace))
+				path += fmt.Sprintf("?proxyID=%s.%s", podName, ns)
+			} else if version != "" {
+				path += "?version=" + version
+			}
+			responses, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, path)
+			if err != nil {
+				return fmt.Errorf("unable to query Istiod for config history: %v", err)
+			}
+			if proxy != "" || version != "" {
+				return printConfigVersion(c.OutOrStdout(), responses, outputFormat)
+			}
+			return printConfigHistory(c.OutOrStdout(), responses, outputFormat)
+		},
+	}
+	historyCmd.PersistentFlags().StringVar(&version, "version", "",
+		"Show the config as of this version")
+	historyCmd.PersistentFlags().StringVar(&proxy, "proxy", "",
+		"Show the config as of the version this pod's proxy is running, as <pod-name[.namespace]>")
+	historyCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput,
+		"Output format: one of short|json. The short format is a table for the history, and YAML for a version")
+	opts.AttachControlPlaneFlags(historyCmd)
+	return historyCmd
+}
+
+// unmarshalIstiodResponses decodes the response of each Istiod into a new value from newValue. Istiod
+// instances which cannot answer, for example because the proxy is not connected to them, are skipped.
+func unmarshalIstiodResponses(responses map[string][]byte, newValue func() interface{}) ([]interface{}, error) {
+	istiods := make([]string, 0, len(responses))
+	for istiod := range responses {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	var values []interface{}
+	var failures []string
+	for _, istiod := range istiods {
+		v := newValue()
+		if err := json.Unmarshal(responses[istiod], v); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", istiod, strings.TrimSpace(string(responses[istiod]))))
+			continue
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no Istiod returned config history:\n%s", strings.Join(failures, "\n"))
+	}
+	return values, nil
+}
+
+func printConfigHistory(w io.Writer, responses map[string][]byte, outputFormat string) error {
+	values, err := unmarshalIstiodResponses(responses, func() interface{} { return &[]model.ConfigHistoryEntry{} })
+	if err != nil {
+		return err
+	}
+	// Each Istiod records the changes it saw, so merge them and drop the duplicates.
+	seen := map[string]bool{}
+	var entries []model.ConfigHistoryEntry
+	for _, v := range values {
+		for _, e := range *v.(*[]model.ConfigHistoryEntry) {
+			key := strings.Join([]string{e.Version, string(e.Op), e.Kind, e.Namespace, e.Name, e.ResourceVersion}, "/")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	if outputFormat == jsonOutput {
+		if entries == nil {
+			entries = []model.ConfigHistoryEntry{}
+		}
+		return printIndentedJSON(w, entries)
+	}
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(w, "No config changes are retained")
+		return err
+	}
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintf(tw, "VERSION\tTIME\tCHANGE\tKIND\tNAME\n")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s/%s\n", e.Version, e.Time.UTC().Format(time.RFC3339), e.Op, e.Kind, e.Namespace, e.Name)
+	}
+	return tw.Flush()
+}
+
+func printConfigVersion(w io.Writer, responses map[string][]byte, outputFormat string) error {
+	values, err := unmarshalIstiodResponses(responses, func() interface{} { return &configHistoryResponse{} })
+	if err != nil {
+		return err
+	}
+	// Every Istiod which retains the version has the same config for it.
+	resp := values[0].(*configHistoryResponse)
+	if outputFormat == jsonOutput {
+		return printIndentedJSON(w, resp)
+	}
+	if resp.ProxyID != "" {
+		fmt.Fprintf(w, "# config version %s, as running on %s\n", resp.Version, resp.ProxyID)
+	} else {
+		fmt.Fprintf(w, "# config version %s\n", resp.Version)
+	}
+	for _, cfg := range resp.Configs {
+		out, err := yaml.JSONToYAML(cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "---\n%s", out)
+	}
+	return nil
+}
+
+func printIndentedJSON(w io.Writer, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
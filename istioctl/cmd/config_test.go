@@ -51,3 +51,66 @@ xds-port                15012            default
 		})
 	}
 }
+
+func TestConfigHistory(t *testing.T) {
+	history := []byte(`[
+  {"version": "IZYRkqgxIEU=", "time": "2020-11-20T10:00:05Z", "op": "deleted", "kind": "VirtualService",
+   "name": "reviews", "namespace": "default", "resourceVersion": "12"},
+  {"version": "pSUHtvKYZBs=", "time": "2020-11-20T10:00:00Z", "op": "added", "kind": "VirtualService",
+   "name": "reviews", "namespace": "default", "resourceVersion": "12"}
+]`)
+	version := []byte(`{"version": "pSUHtvKYZBs=", "proxyID": "productpage.default", "configs": [
+  {"apiVersion": "networking.istio.io/v1alpha3", "kind": "VirtualService",
+   "metadata": {"name": "reviews", "namespace": "default"}, "spec": {"hosts": ["reviews"]}}
+]}`)
+	cases := []execTestCase{
+		{
+			execClientConfig: map[string][]byte{
+				"istiod-1": history,
+				"istiod-2": history,
+			},
+			args: strings.Split("experimental config history", " "),
+			expectedOutput: `VERSION        TIME                   CHANGE    KIND             NAME
+IZYRkqgxIEU=   2020-11-20T10:00:05Z   deleted   VirtualService   default/reviews
+pSUHtvKYZBs=   2020-11-20T10:00:00Z   added     VirtualService   default/reviews
+`,
+		},
+		{
+			execClientConfig: map[string][]byte{
+				"istiod-1": version,
+				"istiod-2": []byte("Proxy not connected to this Pilot instance"),
+			},
+			args: strings.Split("experimental config history --proxy productpage.default", " "),
+			expectedOutput: `# config version pSUHtvKYZBs=, as running on productpage.default
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews
+`,
+		},
+		{
+			execClientConfig: map[string][]byte{
+				"istiod-1": []byte("Pilot Version tracking is disabled."),
+			},
+			args:           strings.Split("experimental config history --version pSUHtvKYZBs=", " "),
+			expectedString: "no Istiod returned config history",
+			wantException:  true,
+		},
+		{
+			args:           strings.Split("experimental config history --version a --proxy b", " "),
+			expectedString: "--version and --proxy cannot be used together",
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
@@ -174,6 +174,8 @@ func describe() *cobra.Command {
 
 	describeCmd.AddCommand(podDescribeCmd())
 	describeCmd.AddCommand(svcDescribeCmd())
+	describeCmd.AddCommand(gatewayDescribeCmd())
+	describeCmd.AddCommand(virtualServiceDescribeCmd())
 	return describeCmd
 }
 
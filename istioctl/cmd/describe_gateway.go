@@ -0,0 +1,312 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/secrets/kube"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+)
+
+func gatewayDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "gateway <gateway>",
+		Aliases: []string{"gw"},
+		Short:   "Describe a Gateway and the VirtualServices bound to it [kube-only]",
+		Long: `Analyzes a Gateway and reports the pods it selects, its external address, each server
+with the resolution status of its TLS credential, and the VirtualServices bound to it,
+including those that reference the Gateway but fail to bind to any of its hosts.`,
+		Example: `  istioctl experimental describe gateway bookinfo-gateway.istio-system`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting gateway name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gwName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+
+			configClient, err := configStoreFactory()
+			if err != nil {
+				return err
+			}
+			gw, err := configClient.NetworkingV1alpha3().Gateways(ns).Get(context.TODO(), gwName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+			vsList, err := configClient.NetworkingV1alpha3().VirtualServices(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+
+			writer := cmd.OutOrStdout()
+			fmt.Fprintf(writer, "Gateway: %s\n", kname(gw.ObjectMeta))
+			pods, err := printGatewayPods(writer, client, gw)
+			if err != nil {
+				return err
+			}
+			printGatewayServers(writer, client, gw, pods)
+			printGatewayVirtualServices(writer, gw, vsList.Items)
+			return nil
+		},
+	}
+
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
+
+// printGatewayPods prints the pods selected by the gateway and their external addresses, and
+// returns the pods.
+func printGatewayPods(writer io.Writer, client kubernetes.Interface, gw *clientnetworking.Gateway) ([]v1.Pod, error) {
+	selector := k8s_labels.SelectorFromSet(gw.Spec.Selector)
+	fmt.Fprintf(writer, "Selector: %s\n", selector)
+	if len(gw.Spec.Selector) == 0 {
+		fmt.Fprintf(writer, "WARNING: Gateway has no selector and applies to no pods\n")
+		return nil, nil
+	}
+	// Without PILOT_SCOPE_GATEWAY_TO_NAMESPACE, the gateway selects pods in any namespace.
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		fmt.Fprintf(writer, "WARNING: No pods match the selector of the Gateway\n")
+		return nil, nil
+	}
+	fmt.Fprintf(writer, "Pods:\n")
+	for _, pod := range pods.Items {
+		fmt.Fprintf(writer, "   %s (%s)\n", kname(pod.ObjectMeta), pod.Status.Phase)
+	}
+
+	// Services selecting the gateway pods provide its external address. The Services are listed once
+	// per namespace and matched against the labels of each pod.
+	printed := map[string]bool{}
+	services := map[string][]v1.Service{}
+	for _, pod := range pods.Items {
+		svcs, f := services[pod.Namespace]
+		if !f {
+			list, err := client.CoreV1().Services(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			svcs = list.Items
+			services[pod.Namespace] = svcs
+		}
+		for _, svc := range svcs {
+			if len(svc.Spec.Selector) == 0 || printed[kname(svc.ObjectMeta)] ||
+				!k8s_labels.SelectorFromSet(svc.Spec.Selector).Matches(k8s_labels.Set(pod.Labels)) {
+				continue
+			}
+			printed[kname(svc.ObjectMeta)] = true
+			fmt.Fprintf(writer, "External address: %s (Service %s, %s)\n",
+				getGatewayAddress(svc, pod), kname(svc.ObjectMeta), svc.Spec.Type)
+		}
+	}
+	if len(printed) == 0 {
+		fmt.Fprintf(writer, "WARNING: No Kubernetes Services select the Gateway pods\n")
+	}
+	return pods.Items, nil
+}
+
+func getGatewayAddress(svc v1.Service, pod v1.Pod) string {
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
+	}
+	return getIngressIP(svc, pod)
+}
+
+func printGatewayServers(writer io.Writer, client kubernetes.Interface, gw *clientnetworking.Gateway, pods []v1.Pod) {
+	fmt.Fprintf(writer, "Servers:\n")
+	for _, server := range gw.Spec.Servers {
+		port := server.GetPort()
+		fmt.Fprintf(writer, "   %s %d (%s): hosts %s\n", port.GetProtocol(), port.GetNumber(), port.GetName(),
+			strings.Join(server.Hosts, ", "))
+		tls := server.GetTls()
+		if tls == nil {
+			continue
+		}
+		fmt.Fprintf(writer, "      TLS %s", tls.GetMode())
+		if tls.GetHttpsRedirect() {
+			fmt.Fprintf(writer, " (HTTPS redirect)")
+		}
+		switch {
+		case tls.GetCredentialName() != "":
+			fmt.Fprintf(writer, ", credential %s\n", tls.GetCredentialName())
+			for _, ns := range podNamespaces(pods) {
+				fmt.Fprintf(writer, "         %s\n", resolveCredential(client, tls, ns))
+			}
+		case tls.GetServerCertificate() != "":
+			fmt.Fprintf(writer, ", certificate file %s\n", tls.GetServerCertificate())
+		default:
+			fmt.Fprintf(writer, "\n")
+		}
+	}
+}
+
+func podNamespaces(pods []v1.Pod) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, pod := range pods {
+		if !seen[pod.Namespace] {
+			seen[pod.Namespace] = true
+			out = append(out, pod.Namespace)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// resolveCredential reports whether the credential of a server resolves to a Secret with a key
+// and certificate, and a CA certificate for MUTUAL TLS, in the namespace of the gateway pods.
+func resolveCredential(client kubernetes.Interface, tls *v1alpha3.ServerTLSSettings, ns string) string {
+	name := tls.GetCredentialName()
+	scrt, err := client.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("Secret %s.%s: NOT FOUND", name, ns)
+		}
+		return fmt.Sprintf("Secret %s.%s: %v", name, ns, err)
+	}
+	hasKeyAndCert := (len(scrt.Data[kube.GenericScrtCert]) > 0 && len(scrt.Data[kube.GenericScrtKey]) > 0) ||
+		(len(scrt.Data[kube.TLSSecretCert]) > 0 && len(scrt.Data[kube.TLSSecretKey]) > 0)
+	if !hasKeyAndCert {
+		return fmt.Sprintf("Secret %s.%s: found, but MISSING key or certificate", name, ns)
+	}
+	if tls.GetMode() != v1alpha3.ServerTLSSettings_MUTUAL {
+		return fmt.Sprintf("Secret %s.%s: found", name, ns)
+	}
+	if hasCaCert(scrt) {
+		return fmt.Sprintf("Secret %s.%s: found, with CA certificate", name, ns)
+	}
+	caName := name + kube.GatewaySdsCaSuffix
+	if caScrt, err := client.CoreV1().Secrets(ns).Get(context.TODO(), caName, metav1.GetOptions{}); err == nil && hasCaCert(caScrt) {
+		return fmt.Sprintf("Secret %s.%s: found, with CA certificate in %s.%s", name, ns, caName, ns)
+	}
+	return fmt.Sprintf("Secret %s.%s: found, but MISSING CA certificate for MUTUAL TLS", name, ns)
+}
+
+func hasCaCert(scrt *v1.Secret) bool {
+	return len(scrt.Data[kube.GenericScrtCaCert]) > 0 || len(scrt.Data[kube.TLSSecretCaCert]) > 0
+}
+
+func printGatewayVirtualServices(writer io.Writer, gw *clientnetworking.Gateway, vss []clientnetworking.VirtualService) {
+	gwName := gw.Namespace + "/" + gw.Name
+	var bound, unbound []string
+	for _, vs := range vss {
+		if !referencesGateway(vs, gwName) {
+			continue
+		}
+		hosts := gatewayBoundHosts(gw, vs)
+		if len(hosts) > 0 {
+			bound = append(bound, fmt.Sprintf("%s: hosts %s", kname(vs.ObjectMeta), strings.Join(hosts, ", ")))
+		} else {
+			unbound = append(unbound, fmt.Sprintf("%s: no host of [%s] matches a server host visible to namespace %s",
+				kname(vs.ObjectMeta), strings.Join(vs.Spec.Hosts, ", "), vs.Namespace))
+		}
+	}
+	sort.Strings(bound)
+	sort.Strings(unbound)
+
+	if len(bound) == 0 {
+		fmt.Fprintf(writer, "WARNING: No VirtualServices are bound to the Gateway\n")
+	} else {
+		fmt.Fprintf(writer, "VirtualServices:\n")
+		for _, s := range bound {
+			fmt.Fprintf(writer, "   %s\n", s)
+		}
+	}
+	if len(unbound) > 0 {
+		fmt.Fprintf(writer, "VirtualServices referencing the Gateway that fail to bind:\n")
+		for _, s := range unbound {
+			fmt.Fprintf(writer, "   %s\n", s)
+		}
+	}
+}
+
+// referencesGateway returns true if the VirtualService lists the gateway, given as namespace/name,
+// in its gateways.
+func referencesGateway(vs clientnetworking.VirtualService, gwName string) bool {
+	for _, ref := range vs.Spec.Gateways {
+		if model.ResolveGatewayName(ref, config.Meta{Namespace: vs.Namespace}) == gwName {
+			return true
+		}
+	}
+	return false
+}
+
+// gatewayBoundHosts returns the hosts of the VirtualService that match a host of a server of the
+// gateway, like Istiod does when building the gateway routes. Server hosts of the form
+// namespace/host only bind VirtualServices in that namespace.
+func gatewayBoundHosts(gw *clientnetworking.Gateway, vs clientnetworking.VirtualService) []string {
+	var out []string
+	for _, vsHost := range vs.Spec.Hosts {
+	servers:
+		for _, server := range gw.Spec.Servers {
+			for _, serverHost := range server.Hosts {
+				if gatewayHostMatches(serverHost, gw.Namespace, vs.Namespace, vsHost) {
+					out = append(out, vsHost)
+					break servers
+				}
+			}
+		}
+	}
+	return out
+}
+
+func gatewayHostMatches(serverHost, gwNamespace, vsNamespace, vsHost string) bool {
+	if i := strings.Index(serverHost, "/"); i >= 0 {
+		ns := serverHost[:i]
+		if ns == "." {
+			ns = gwNamespace
+		}
+		if ns != "*" && ns != vsNamespace {
+			return false
+		}
+		serverHost = serverHost[i+1:]
+	}
+	return host.Name(serverHost).Matches(resolveVirtualServiceHost(vsHost, vsNamespace))
+}
+
+// resolveVirtualServiceHost resolves a short host name of a VirtualService to a FQDN, like Istiod does.
+func resolveVirtualServiceHost(h, namespace string) host.Name {
+	return model.ResolveShortnameToFQDN(h, config.Meta{Namespace: namespace, Domain: constants.DefaultKubernetesDomain})
+}
@@ -16,21 +16,28 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
 	"istio.io/istio/pilot/test/util"
 )
 
 // execAndK8sConfigTestCase lets a test case hold some Envoy, Istio, and Kubernetes configuration
 type execAndK8sConfigTestCase struct {
-	k8sConfigs []runtime.Object // Canned K8s configuration
-	namespace  string
+	k8sConfigs   []runtime.Object // Canned K8s configuration
+	istioConfigs []runtime.Object // Canned Istio configuration
+	namespace    string
 
 	args []string
 
@@ -77,11 +84,194 @@ func TestDescribe(t *testing.T) {
 	}
 }
 
+func TestDescribeGateway(t *testing.T) {
+	gwLabels := map[string]string{"istio": "ingressgateway"}
+	k8sConfigs := []runtime.Object{
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway-1", Namespace: "istio-system", Labels: gwLabels},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+			Spec:       v1.ServiceSpec{Selector: gwLabels, Type: v1.ServiceTypeLoadBalancer},
+			Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+			}},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "productpage", Namespace: "default"},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bookinfo-cert", Namespace: "istio-system"},
+			Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+		},
+	}
+	istioConfigs := []runtime.Object{
+		&clientnetworking.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: "bookinfo-gateway", Namespace: "istio-system"},
+			Spec: v1alpha3.Gateway{
+				Selector: gwLabels,
+				Servers: []*v1alpha3.Server{
+					{
+						Port:  &v1alpha3.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+						Hosts: []string{"default/*.example.com"},
+					},
+					{
+						Port:  &v1alpha3.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+						Hosts: []string{"bookinfo.example.com"},
+						Tls:   &v1alpha3.ServerTLSSettings{Mode: v1alpha3.ServerTLSSettings_SIMPLE, CredentialName: "bookinfo-cert"},
+					},
+					{
+						Port:  &v1alpha3.Port{Number: 8443, Name: "mtls", Protocol: "HTTPS"},
+						Hosts: []string{"./*"},
+						Tls:   &v1alpha3.ServerTLSSettings{Mode: v1alpha3.ServerTLSSettings_MUTUAL, CredentialName: "missing-cert"},
+					},
+				},
+			},
+		},
+		&clientnetworking.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: "bookinfo", Namespace: "default"},
+			Spec: v1alpha3.VirtualService{
+				Hosts:    []string{"bookinfo.example.com"},
+				Gateways: []string{"istio-system/bookinfo-gateway"},
+				Http: []*v1alpha3.HTTPRoute{{
+					Route: []*v1alpha3.HTTPRouteDestination{{
+						Destination: &v1alpha3.Destination{Host: "productpage", Subset: "v1"},
+					}},
+				}},
+			},
+		},
+		&clientnetworking.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "foo"},
+			Spec: v1alpha3.VirtualService{
+				Hosts:    []string{"other.example.com"},
+				Gateways: []string{"bookinfo-gateway.istio-system.svc.cluster.local", "missing"},
+			},
+		},
+		&clientnetworking.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "productpage", Namespace: "default"},
+			Spec: v1alpha3.DestinationRule{
+				Host:    "productpage",
+				Subsets: []*v1alpha3.Subset{{Name: "v2"}},
+			},
+		},
+	}
+
+	cases := []execAndK8sConfigTestCase{
+		{
+			k8sConfigs:   k8sConfigs,
+			istioConfigs: istioConfigs,
+			args:         strings.Split("x describe gateway bookinfo-gateway.istio-system", " "),
+			expectedOutput: `Gateway: bookinfo-gateway.istio-system
+Selector: istio=ingressgateway
+Pods:
+   istio-ingressgateway-1.istio-system (Running)
+External address: 1.2.3.4 (Service istio-ingressgateway.istio-system, LoadBalancer)
+Servers:
+   HTTP 80 (http): hosts default/*.example.com
+   HTTPS 443 (https): hosts bookinfo.example.com
+      TLS SIMPLE, credential bookinfo-cert
+         Secret bookinfo-cert.istio-system: found
+   HTTPS 8443 (mtls): hosts ./*
+      TLS MUTUAL, credential missing-cert
+         Secret missing-cert.istio-system: NOT FOUND
+VirtualServices:
+   bookinfo: hosts bookinfo.example.com
+VirtualServices referencing the Gateway that fail to bind:
+   other.foo: no host of [other.example.com] matches a server host visible to namespace foo
+`,
+		},
+		{
+			k8sConfigs:     k8sConfigs,
+			istioConfigs:   istioConfigs,
+			args:           strings.Split("x describe gw not-a-gateway", " "),
+			expectedString: `gateways.networking.istio.io "not-a-gateway" not found`,
+			wantException:  true,
+		},
+		{
+			k8sConfigs:   k8sConfigs,
+			istioConfigs: istioConfigs,
+			args:         strings.Split("x describe vs bookinfo -n default", " "),
+			expectedOutput: `VirtualService: bookinfo
+Hosts:
+   bookinfo.example.com
+Gateways:
+   istio-system/bookinfo-gateway: bound for hosts bookinfo.example.com
+Destinations:
+   productpage.default.svc.cluster.local subset v1
+      Registry: Service productpage
+      DestinationRule: productpage for "productpage"
+      WARNING: subset v1 is not defined in the DestinationRule
+`,
+		},
+		{
+			k8sConfigs:   k8sConfigs,
+			istioConfigs: istioConfigs,
+			args:         strings.Split("x describe vs other -n foo", " "),
+			expectedOutput: `VirtualService: other
+Hosts:
+   other.example.com
+Gateways:
+   istio-system/bookinfo-gateway: NOT BOUND, no host matches a server host visible to namespace foo
+   foo/missing: NOT FOUND
+Destinations: none
+`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecAndK8sConfigTestCaseTestOutput(t, c)
+		})
+	}
+}
+
+func TestPrintGatewayPodsListsServicesOncePerNamespace(t *testing.T) {
+	gwLabels := map[string]string{"istio": "ingressgateway"}
+	client := fake.NewSimpleClientset(
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gw-1", Namespace: "istio-system", Labels: gwLabels}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gw-2", Namespace: "istio-system", Labels: gwLabels}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gw-3", Namespace: "gateways", Labels: gwLabels}},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+			Spec:       v1.ServiceSpec{Selector: gwLabels, Type: v1.ServiceTypeClusterIP, ClusterIP: "10.0.0.1"},
+		},
+	)
+	gw := &clientnetworking.Gateway{Spec: v1alpha3.Gateway{Selector: gwLabels}}
+
+	var out bytes.Buffer
+	pods, err := printGatewayPods(&out, client, gw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 3 {
+		t.Fatalf("got %d pods, want 3", len(pods))
+	}
+	if got := strings.Count(out.String(), "Service istio-ingressgateway.istio-system"); got != 1 {
+		t.Errorf("got the Service printed %d times, want once:\n%s", got, out.String())
+	}
+	lists := map[string]int{}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "services" {
+			lists[action.GetNamespace()]++
+		}
+	}
+	if lists["istio-system"] != 1 || lists["gateways"] != 1 || len(lists) != 2 {
+		t.Errorf("got Service lists %v, want one per namespace", lists)
+	}
+}
+
 func verifyExecAndK8sConfigTestCaseTestOutput(t *testing.T, c execAndK8sConfigTestCase) {
 	t.Helper()
 
 	// Override the Istio config factory
-	configStoreFactory = mockClientFactoryGenerator()
+	configStoreFactory = mockClientFactoryGenerator(func(client istioclient.Interface) {
+		for _, obj := range c.istioConfigs {
+			if err := createIstioConfig(client, obj); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
 
 	// Override the K8s config factory
 	interfaceFactory = mockInterfaceFactoryGenerator(c.k8sConfigs)
@@ -121,6 +311,25 @@ func verifyExecAndK8sConfigTestCaseTestOutput(t *testing.T, c execAndK8sConfigTe
 	}
 }
 
+// createIstioConfig creates the config with the typed client, as the object tracker of the fake
+// client does not tell apart the versions of the networking API.
+func createIstioConfig(client istioclient.Interface, obj runtime.Object) error {
+	var err error
+	switch o := obj.(type) {
+	case *clientnetworking.Gateway:
+		_, err = client.NetworkingV1alpha3().Gateways(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+	case *clientnetworking.VirtualService:
+		_, err = client.NetworkingV1alpha3().VirtualServices(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+	case *clientnetworking.DestinationRule:
+		_, err = client.NetworkingV1alpha3().DestinationRules(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+	case *clientnetworking.ServiceEntry:
+		_, err = client.NetworkingV1alpha3().ServiceEntries(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+	default:
+		err = fmt.Errorf("unsupported config %T", obj)
+	}
+	return err
+}
+
 func mockInterfaceFactoryGenerator(k8sConfigs []runtime.Object) func(kubeconfig string) (kubernetes.Interface, error) {
 	outFactory := func(_ string) (kubernetes.Interface, error) {
 		client := fake.NewSimpleClientset(k8sConfigs...)
@@ -0,0 +1,290 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+)
+
+func virtualServiceDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "virtualservice <virtualservice>",
+		Aliases: []string{"vs"},
+		Short:   "Describe a VirtualService and the configuration it refers to [kube-only]",
+		Long: `Analyzes a VirtualService and reports its hosts, whether it binds to each of its gateways,
+and its destinations along with the DestinationRule applied to them and whether they
+exist in the service registry.`,
+		Example: `  istioctl experimental describe virtualservice bookinfo`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting virtualservice name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vsName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+
+			configClient, err := configStoreFactory()
+			if err != nil {
+				return err
+			}
+			vs, err := configClient.NetworkingV1alpha3().VirtualServices(ns).Get(context.TODO(), vsName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+
+			writer := cmd.OutOrStdout()
+			fmt.Fprintf(writer, "VirtualService: %s\n", kname(vs.ObjectMeta))
+			fmt.Fprintf(writer, "Hosts:\n")
+			for _, h := range vs.Spec.Hosts {
+				if resolved := resolveVirtualServiceHost(h, vs.Namespace); string(resolved) != h {
+					fmt.Fprintf(writer, "   %s (%s)\n", h, resolved)
+				} else {
+					fmt.Fprintf(writer, "   %s\n", h)
+				}
+			}
+			if err := printVirtualServiceGateways(writer, configClient, vs); err != nil {
+				return err
+			}
+			return printVirtualServiceDestinations(writer, client, configClient, vs)
+		},
+	}
+
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
+
+func printVirtualServiceGateways(writer io.Writer, configClient istioclient.Interface, vs *clientnetworking.VirtualService) error {
+	fmt.Fprintf(writer, "Gateways:\n")
+	if len(vs.Spec.Gateways) == 0 {
+		fmt.Fprintf(writer, "   mesh (sidecars only)\n")
+		return nil
+	}
+	for _, ref := range vs.Spec.Gateways {
+		if ref == constants.IstioMeshGateway {
+			fmt.Fprintf(writer, "   mesh (sidecars)\n")
+			continue
+		}
+		gwName := model.ResolveGatewayName(ref, config.Meta{Namespace: vs.Namespace})
+		parts := strings.SplitN(gwName, "/", 2)
+		gw, err := configClient.NetworkingV1alpha3().Gateways(parts[0]).Get(context.TODO(), parts[1], metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				fmt.Fprintf(writer, "   %s: NOT FOUND\n", gwName)
+				continue
+			}
+			return err
+		}
+		if hosts := gatewayBoundHosts(gw, *vs); len(hosts) > 0 {
+			fmt.Fprintf(writer, "   %s: bound for hosts %s\n", gwName, strings.Join(hosts, ", "))
+		} else {
+			fmt.Fprintf(writer, "   %s: NOT BOUND, no host matches a server host visible to namespace %s\n", gwName, vs.Namespace)
+		}
+	}
+	return nil
+}
+
+// vsDestination is a destination of a VirtualService route.
+type vsDestination struct {
+	host   host.Name
+	subset string
+	port   uint32
+}
+
+func (d vsDestination) String() string {
+	out := string(d.host)
+	if d.port != 0 {
+		out += fmt.Sprintf(":%d", d.port)
+	}
+	if d.subset != "" {
+		out += " subset " + d.subset
+	}
+	return out
+}
+
+// virtualServiceDestinations returns the destinations of the routes and mirrors of the VirtualService,
+// with their host resolved to a FQDN.
+func virtualServiceDestinations(vs *clientnetworking.VirtualService) []vsDestination {
+	var out []vsDestination
+	seen := map[vsDestination]bool{}
+	add := func(d *v1alpha3.Destination) {
+		if d == nil {
+			return
+		}
+		dest := vsDestination{
+			host:   resolveVirtualServiceHost(d.Host, vs.Namespace),
+			subset: d.Subset,
+			port:   d.GetPort().GetNumber(),
+		}
+		if !seen[dest] {
+			seen[dest] = true
+			out = append(out, dest)
+		}
+	}
+	for _, r := range vs.Spec.Http {
+		for _, rd := range r.Route {
+			add(rd.Destination)
+		}
+		add(r.Mirror)
+	}
+	for _, r := range vs.Spec.Tcp {
+		for _, rd := range r.Route {
+			add(rd.Destination)
+		}
+	}
+	for _, r := range vs.Spec.Tls {
+		for _, rd := range r.Route {
+			add(rd.Destination)
+		}
+	}
+	return out
+}
+
+func printVirtualServiceDestinations(writer io.Writer, client kubernetes.Interface, configClient istioclient.Interface,
+	vs *clientnetworking.VirtualService) error {
+	destinations := virtualServiceDestinations(vs)
+	if len(destinations) == 0 {
+		fmt.Fprintf(writer, "Destinations: none\n")
+		return nil
+	}
+	drs, err := configClient.NetworkingV1alpha3().DestinationRules(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	ses, err := configClient.NetworkingV1alpha3().ServiceEntries(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(writer, "Destinations:\n")
+	for _, d := range destinations {
+		fmt.Fprintf(writer, "   %s\n", d)
+		registry, err := lookupRegistryHost(client, ses.Items, d.host)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "      Registry: %s\n", registry)
+
+		dr := destinationRuleForHost(drs.Items, d.host, vs.Namespace)
+		if dr == nil {
+			fmt.Fprintf(writer, "      DestinationRule: none\n")
+			if d.subset != "" {
+				fmt.Fprintf(writer, "      WARNING: subset %s is not defined, there is no DestinationRule\n", d.subset)
+			}
+			continue
+		}
+		fmt.Fprintf(writer, "      DestinationRule: %s for %q\n", kname(dr.ObjectMeta), dr.Spec.Host)
+		if d.subset != "" && !hasSubset(dr, d.subset) {
+			fmt.Fprintf(writer, "      WARNING: subset %s is not defined in the DestinationRule\n", d.subset)
+		}
+	}
+	return nil
+}
+
+// lookupRegistryHost describes the Kubernetes Service or ServiceEntry that provides the host, if any.
+func lookupRegistryHost(client kubernetes.Interface, ses []clientnetworking.ServiceEntry, h host.Name) (string, error) {
+	if strings.HasSuffix(string(h), k8sSuffix) {
+		parts := strings.Split(strings.TrimSuffix(string(h), k8sSuffix), ".")
+		if len(parts) == 2 {
+			svc, err := client.CoreV1().Services(parts[1]).Get(context.TODO(), parts[0], metav1.GetOptions{})
+			if err == nil {
+				return fmt.Sprintf("Service %s", kname(svc.ObjectMeta)), nil
+			}
+			if !errors.IsNotFound(err) {
+				return "", err
+			}
+		}
+	}
+	for _, se := range ses {
+		for _, seHost := range se.Spec.Hosts {
+			if host.Name(seHost).Matches(h) {
+				return fmt.Sprintf("ServiceEntry %s", kname(se.ObjectMeta)), nil
+			}
+		}
+	}
+	return "NOT FOUND", nil
+}
+
+// destinationRuleForHost returns the DestinationRule applied to the host for clients in the namespace,
+// preferring those in the client namespace, then the namespace of the service, then the root
+// namespace, and exact host matches over wildcard ones.
+func destinationRuleForHost(drs []clientnetworking.DestinationRule, h host.Name, clientNamespace string) *clientnetworking.DestinationRule {
+	svcNamespace := ""
+	if parts := strings.Split(strings.TrimSuffix(string(h), k8sSuffix), "."); strings.HasSuffix(string(h), k8sSuffix) && len(parts) == 2 {
+		svcNamespace = parts[1]
+	}
+	rank := func(dr clientnetworking.DestinationRule) int {
+		r := 3
+		switch dr.Namespace {
+		case clientNamespace:
+			r = 0
+		case svcNamespace:
+			r = 1
+		case istioNamespace:
+			r = 2
+		}
+		r *= 2
+		if resolveVirtualServiceHost(dr.Spec.Host, dr.Namespace) != h {
+			r++
+		}
+		return r
+	}
+
+	var candidates []clientnetworking.DestinationRule
+	for _, dr := range drs {
+		if resolveVirtualServiceHost(dr.Spec.Host, dr.Namespace).Matches(h) {
+			candidates = append(candidates, dr)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return rank(candidates[i]) < rank(candidates[j])
+	})
+	return &candidates[0]
+}
+
+func hasSubset(dr *clientnetworking.DestinationRule, subset string) bool {
+	for _, s := range dr.Spec.Subsets {
+		if s.Name == subset {
+			return true
+		}
+	}
+	return false
+}
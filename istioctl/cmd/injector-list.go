@@ -23,6 +23,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
 	admit_v1 "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/api/core/v1"
@@ -36,6 +37,7 @@ import (
 	"istio.io/istio/istioctl/pkg/clioptions"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
 )
 
 type revisionCount struct {
@@ -72,7 +74,7 @@ func injectorListCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "list",
 		Short:   "List sidecar injector and sidecar versions",
-		Long:    `List sidecar injector and sidecar versions`,
+		Long:    `List sidecar injector and sidecar versions, along with the injection templates of each revision`,
 		Example: `  istioctl experimental injector list`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			client, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
@@ -110,7 +112,18 @@ func injectorListCommand() *cobra.Command {
 			sort.Slice(hooks, func(i, j int) bool {
 				return hooks[i].Name < hooks[j].Name
 			})
-			return printHooks(cmd.OutOrStdout(), nslist, hooks, injectedImages)
+			if err := printHooks(cmd.OutOrStdout(), nslist, hooks, injectedImages); err != nil {
+				return err
+			}
+			templates, err := getInjectorTemplates(ctx, client)
+			if err != nil {
+				return err
+			}
+			if len(templates) == 0 {
+				return nil
+			}
+			cmd.Println()
+			return printTemplates(cmd.OutOrStdout(), templates)
 		},
 	}
 
@@ -278,6 +291,54 @@ func getInjectedImages(ctx context.Context, client kube.ExtendedClient) (map[str
 	return retval, nil
 }
 
+// getInjectorTemplates() returns a map of revision->injection templates
+func getInjectorTemplates(ctx context.Context, client kube.ExtendedClient) (map[string][]inject.TemplateStatus, error) {
+	retval := map[string][]inject.TemplateStatus{}
+
+	configMaps, err := client.CoreV1().ConfigMaps(istioNamespace).List(ctx, metav1.ListOptions{LabelSelector: label.IstioRev})
+	if err != nil {
+		return retval, err
+	}
+
+	for _, configMap := range configMaps.Items {
+		if !strings.HasPrefix(configMap.Name, defaultInjectConfigMapName) {
+			continue
+		}
+		injectData, ok := configMap.Data[injectConfigMapKey]
+		if !ok {
+			continue
+		}
+		var injectConfig inject.Config
+		if err := yaml.Unmarshal([]byte(injectData), &injectConfig); err != nil {
+			return retval, fmt.Errorf("unable to convert data from configmap %q: %v", configMap.Name, err)
+		}
+		retval[configMap.ObjectMeta.GetLabels()[label.IstioRev]] = injectConfig.TemplateStatuses()
+	}
+
+	return retval, nil
+}
+
+func printTemplates(writer io.Writer, templates map[string][]inject.TemplateStatus) error {
+	revisions := make([]string, 0, len(templates))
+	for revision := range templates {
+		revisions = append(revisions, revision)
+	}
+	sort.Strings(revisions)
+
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "ISTIO-REVISION\tTEMPLATE\tDEFAULT\tSTATUS")
+	for _, revision := range revisions {
+		for _, status := range templates[revision] {
+			valid := "valid"
+			if status.Error != "" {
+				valid = "INVALID: " + status.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", revision, status.Name, status.Default, valid)
+		}
+	}
+	return w.Flush()
+}
+
 // podCountByRevision() returns a map of revision->pods, with "<non-Istio>" as the dummy "revision" for uninjected pods
 func podCountByRevision(pods []v1.Pod, expectedRevision string) map[string]revisionCount {
 	retval := map[string]revisionCount{}
@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/api/label"
+	testKube "istio.io/istio/pkg/test/kube"
+)
+
+func TestInjectorTemplates(t *testing.T) {
+	injector := func(name, revision, config string) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: istioNamespace,
+				Labels:    map[string]string{label.IstioRev: revision},
+			},
+			Data: map[string]string{injectConfigMapKey: config},
+		}
+	}
+	client := testKube.MockClient{
+		Interface: fake.NewSimpleClientset(
+			injector("istio-sidecar-injector", "default", `
+template: "containers: []"
+`),
+			injector("istio-sidecar-injector-canary", "canary", `
+template: "containers: []"
+defaultTemplates: [sidecar]
+templates:
+  grpc-agent: "containers: []"
+  debug: "containers: {{ .Missing"
+`),
+			&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "istio",
+					Namespace: istioNamespace,
+					Labels:    map[string]string{label.IstioRev: "default"},
+				},
+				Data: map[string]string{"mesh": "{}"},
+			},
+		),
+	}
+
+	templates, err := getInjectorTemplates(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := printTemplates(&out, templates); err != nil {
+		t.Fatal(err)
+	}
+	want := `ISTIO-REVISION TEMPLATE   DEFAULT STATUS
+canary         debug      false   INVALID: template: inject:1: unclosed action
+canary         grpc-agent false   valid
+canary         sidecar    true    valid
+default        sidecar    true    valid
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -25,9 +26,11 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
+	"istio.io/api/label"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/kube"
@@ -101,15 +104,15 @@ func getValuesFromConfigMap(kubeconfig string) (string, error) {
 	return valuesData, nil
 }
 
-func getInjectConfigFromConfigMap(kubeconfig string) (string, error) {
+func getInjectorConfigFromConfigMap(kubeconfig string) (*inject.Config, error) {
 	client, err := createInterface(kubeconfig)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	meshConfigMap, err := client.CoreV1().ConfigMaps(istioNamespace).Get(context.TODO(), injectConfigMapName, metav1.GetOptions{})
 	if err != nil {
-		return "", fmt.Errorf("could not find valid configmap %q from namespace  %q: %v - "+
+		return nil, fmt.Errorf("could not find valid configmap %q from namespace  %q: %v - "+
 			"Use --injectConfigFile or re-run kube-inject with `-i <istioSystemNamespace> and ensure istio-sidecar-injector configmap exists",
 			injectConfigMapName, istioNamespace, err)
 	}
@@ -118,16 +121,91 @@ func getInjectConfigFromConfigMap(kubeconfig string) (string, error) {
 	// key
 	injectData, exists := meshConfigMap.Data[injectConfigMapKey]
 	if !exists {
-		return "", fmt.Errorf("missing configuration map key %q in %q",
+		return nil, fmt.Errorf("missing configuration map key %q in %q",
 			injectConfigMapKey, injectConfigMapName)
 	}
 	var injectConfig inject.Config
 	if err := yaml.Unmarshal([]byte(injectData), &injectConfig); err != nil {
-		return "", fmt.Errorf("unable to convert data from configmap %q: %v",
+		return nil, fmt.Errorf("unable to convert data from configmap %q: %v",
 			injectConfigMapName, err)
 	}
-	log.Debugf("using inject template from configmap %q", injectConfigMapName)
-	return injectConfig.Template, nil
+	log.Debugf("using inject config from configmap %q", injectConfigMapName)
+	return &injectConfig, nil
+}
+
+// experimentalInjectCommand is kube-inject, with the option of explaining how the
+// injection webhook would handle each workload instead of injecting it.
+func experimentalInjectCommand() *cobra.Command {
+	injectCmd := injectCommand()
+	injectCmd.Short = "Inject Envoy sidecar into Kubernetes pod resources, or explain injection"
+	injectCmd.Example += `
+  # Explain whether, and how, the injection webhook would inject each workload.
+  istioctl experimental kube-inject --explain -f samples/bookinfo/platform/kube/bookinfo.yaml
+`
+	injectCmd.Flags().BoolVar(&explainInjection, "explain", false,
+		"Instead of injecting, explain whether the injection webhook would inject each workload and why, "+
+			"the templates and values it would render, and the resulting JSON patch. The explanation comes "+
+			"from Istiod, unless --meshConfigFile, --injectConfigFile or --valuesFile is set")
+	return injectCmd
+}
+
+// explainWithIstiod explains the pods of every workload in the input with the explain
+// debug endpoint of a running Istiod of the given revision.
+func explainWithIstiod(kubeClient kube.ExtendedClient, istiodNamespace, revision string, in io.Reader) ([]*inject.Explanation, error) {
+	labelSelector := "app=istiod"
+	if revision != "" {
+		labelSelector += fmt.Sprintf(",%s=%s", label.IstioRev, revision)
+	}
+	istiods, err := kubeClient.GetIstioPods(context.TODO(), istiodNamespace, map[string]string{
+		"labelSelector": labelSelector,
+		"fieldSelector": "status.phase=Running",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(istiods) == 0 {
+		if revision != "" {
+			return nil, fmt.Errorf("no running Istiod of revision %q found in namespace %s", revision, istiodNamespace)
+		}
+		return nil, fmt.Errorf("no running Istiod found in namespace %s", istiodNamespace)
+	}
+	istiod := istiods[0]
+	return inject.ExplainResourceFileWith(in, func(pod *corev1.Pod, _ *metav1.TypeMeta, _ *metav1.ObjectMeta) (*inject.Explanation, error) {
+		body, err := json.Marshal(pod)
+		if err != nil {
+			return nil, err
+		}
+		res, err := kubeClient.CoreV1().RESTClient().Post().
+			Namespace(istiod.Namespace).
+			Resource("pods").
+			SubResource("proxy").
+			Name(fmt.Sprintf("%s:%d", istiod.Name, 15014)).
+			Suffix(inject.ExplainPath).
+			Body(body).
+			DoRaw(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain injection with %s.%s: %v", istiod.Name, istiod.Namespace, err)
+		}
+		explanation := &inject.Explanation{}
+		if err := json.Unmarshal(res, explanation); err != nil {
+			return nil, err
+		}
+		return explanation, nil
+	})
+}
+
+// printInjectionExplanations writes each explanation as a YAML document.
+func printInjectionExplanations(writer io.Writer, explanations []*inject.Explanation) error {
+	for _, explanation := range explanations {
+		out, err := yaml.Marshal(explanation)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(writer, "%s---\n", out); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func validateFlags() error {
@@ -145,7 +223,8 @@ func validateFlags() error {
 }
 
 var (
-	emitTemplate bool
+	emitTemplate     bool
+	explainInjection bool
 
 	inFilename          string
 	outFilename         string
@@ -252,6 +331,19 @@ kube-inject on deployments to get the most up-to-date changes.
 				}()
 			}
 
+			if explainInjection && meshConfigFile == "" && injectConfigFile == "" && valuesFile == "" {
+				// The revision is selected by explainWithIstiod, so the client is not scoped to it
+				client, err := kubeClient(kubeconfig, configContext) // nolint: vetshadow
+				if err != nil {
+					return err
+				}
+				explanations, err := explainWithIstiod(client, istioNamespace, revision, reader)
+				if err != nil {
+					return err
+				}
+				return printInjectionExplanations(writer, explanations)
+			}
+
 			var meshConfig *meshconfig.MeshConfig
 			if meshConfigFile != "" {
 				if meshConfig, err = mesh.ReadMeshConfig(meshConfigFile); err != nil {
@@ -263,18 +355,17 @@ kube-inject on deployments to get the most up-to-date changes.
 				}
 			}
 
-			var sidecarTemplate string
+			var injectConfig *inject.Config
 			if injectConfigFile != "" {
 				injectionConfig, err := ioutil.ReadFile(injectConfigFile) // nolint: vetshadow
 				if err != nil {
 					return err
 				}
-				var injectConfig inject.Config
-				if err := yaml.Unmarshal(injectionConfig, &injectConfig); err != nil {
+				injectConfig = &inject.Config{}
+				if err := yaml.Unmarshal(injectionConfig, injectConfig); err != nil {
 					return multierror.Append(err, fmt.Errorf("loading --injectConfigFile"))
 				}
-				sidecarTemplate = injectConfig.Template
-			} else if sidecarTemplate, err = getInjectConfigFromConfigMap(kubeconfig); err != nil {
+			} else if injectConfig, err = getInjectorConfigFromConfigMap(kubeconfig); err != nil {
 				return err
 			}
 
@@ -291,8 +382,10 @@ kube-inject on deployments to get the most up-to-date changes.
 
 			if emitTemplate {
 				cfg := inject.Config{
-					Policy:   inject.InjectionPolicyEnabled,
-					Template: sidecarTemplate,
+					Policy:           inject.InjectionPolicyEnabled,
+					Template:         injectConfig.Template,
+					Templates:        injectConfig.Templates,
+					DefaultTemplates: injectConfig.DefaultTemplates,
 				}
 				out, err := yaml.Marshal(&cfg)
 				if err != nil {
@@ -302,8 +395,21 @@ kube-inject on deployments to get the most up-to-date changes.
 				return nil
 			}
 
+			if explainInjection {
+				// Like kube-inject, inject unless pods opt out when the config has no policy
+				explainConfig := *injectConfig
+				if explainConfig.Policy == "" {
+					explainConfig.Policy = inject.InjectionPolicyEnabled
+				}
+				explanations, err := inject.ExplainResourceFile(&explainConfig, valuesConfig, revision, meshConfig, reader)
+				if err != nil {
+					return err
+				}
+				return printInjectionExplanations(writer, explanations)
+			}
+
 			var warnings []string
-			retval := inject.IntoResourceFile(sidecarTemplate, valuesConfig, revision, meshConfig,
+			retval := inject.IntoResourceFile(injectConfig, valuesConfig, revision, meshConfig,
 				reader, writer, func(warning string) {
 					warnings = append(warnings, warning)
 				})
@@ -320,7 +426,7 @@ kube-inject on deployments to get the most up-to-date changes.
 			// the default for log messages should be stderr, not stdout
 			_ = c.Root().PersistentFlags().Set("log_target", "stderr")
 
-			return c.Parent().PersistentPreRunE(c, args)
+			return c.Root().PersistentPreRunE(c, args)
 		},
 	}
 
@@ -14,10 +14,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
 	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	testKube "istio.io/istio/pkg/test/kube"
 )
 
 func TestKubeInject(t *testing.T) {
@@ -40,6 +45,14 @@ func TestKubeInject(t *testing.T) {
 				" "),
 			goldenFilename: "testdata/deployment/hello.yaml.injected",
 		},
+		{ // case 3: the pods select the gateway template with the inject.istio.io/templates annotation
+			args: strings.Split(
+				"kube-inject --meshConfigFile testdata/mesh-config.yaml"+
+					" --injectConfigFile testdata/inject-config-templates.yaml -f testdata/deployment/hello-template-gateway.yaml"+
+					" --valuesFile testdata/inject-values.yaml",
+				" "),
+			goldenFilename: "testdata/deployment/hello-template-gateway.yaml.injected",
+		},
 	}
 
 	for i, c := range cases {
@@ -48,3 +61,61 @@ func TestKubeInject(t *testing.T) {
 		})
 	}
 }
+
+func TestKubeInjectExplain(t *testing.T) {
+	cases := []testCase{
+		{
+			args: strings.Split(
+				"experimental kube-inject --explain --meshConfigFile testdata/mesh-config.yaml"+
+					" --injectConfigFile testdata/inject-config.yaml -f testdata/deployment/hello.yaml"+
+					" --valuesFile testdata/inject-values.yaml",
+				" "),
+			goldenFilename: "testdata/deployment/hello.yaml.explained",
+		},
+		{
+			args:           strings.Split("kube-inject --explain -f testdata/deployment/hello.yaml", " "),
+			expectedRegexp: regexp.MustCompile(`unknown flag: --explain`),
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyOutput(t, c)
+		})
+	}
+}
+
+// istiodPodsClient records the parameters used to look up Istiod, and finds no pods.
+type istiodPodsClient struct {
+	testKube.MockClient
+	params map[string]string
+}
+
+func (c *istiodPodsClient) GetIstioPods(_ context.Context, _ string, params map[string]string) ([]v1.Pod, error) {
+	c.params = params
+	return nil, nil
+}
+
+func TestExplainWithIstiodRevision(t *testing.T) {
+	cases := []struct {
+		revision string
+		selector string
+		err      string
+	}{
+		{"", "app=istiod", "no running Istiod found in namespace istio-system"},
+		{"canary", "app=istiod,istio.io/rev=canary", `no running Istiod of revision "canary" found in namespace istio-system`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.revision, func(t *testing.T) {
+			client := &istiodPodsClient{}
+			_, err := explainWithIstiod(client, "istio-system", tc.revision, strings.NewReader(""))
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("got error %v, want %q", err, tc.err)
+			}
+			if got := client.params["labelSelector"]; got != tc.selector {
+				t.Errorf("got label selector %q, want %q", got, tc.selector)
+			}
+		})
+	}
+}
@@ -35,7 +35,13 @@ import (
 
 var (
 	metricsOpts clioptions.ControlPlaneOptions
-	metricsCmd  = &cobra.Command{
+
+	metricsFromProxies bool
+	metricsInterval    time.Duration
+)
+
+func metricsCmd() *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "metrics <workload name>...",
 		Short: "Prints the metrics for the specified workload(s) when running in Kubernetes.",
 		Long: `
@@ -51,12 +57,24 @@ All metrics returned are from server-side reports. This means that latencies
 and error rates are from the perspective of the service itself and not of an
 individual client (or aggregate set of clients). Rates and latencies are
 calculated over a time interval of 1 minute.
+
+With --from-proxies, no Prometheus is needed. The Istio metrics are instead
+scraped twice from the sidecars of each workload, --interval apart, and the
+rates and latencies are calculated over that interval. The pods of a workload
+are those selected by the Deployment of that name or, without a Deployment,
+by the Service of that name; use deployment/<name> or service/<name> to choose
+one of them. Below each workload,
+the metrics of the requests it sent are broken down per destination service;
+these are client-side reports.
 `,
 		Example: `  # Retrieve workload metrics for productpage-v1 workload
   istioctl experimental metrics productpage-v1
 
   # Retrieve workload metrics for various services in the different namespaces
-  istioctl experimental metrics productpage-v1.foo reviews-v1.bar ratings-v1.baz`,
+  istioctl experimental metrics productpage-v1.foo reviews-v1.bar ratings-v1.baz
+
+  # Retrieve workload metrics over 30 seconds from the sidecars, without Prometheus
+  istioctl experimental metrics productpage-v1 --from-proxies --interval 30s`,
 		// nolint: goimports
 		Aliases: []string{"m"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -64,12 +82,20 @@ calculated over a time interval of 1 minute.
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("metrics requires workload name")
 			}
+			if metricsInterval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
 			return nil
 		},
 		RunE:                  run,
 		DisableFlagsInUseLine: true,
 	}
-)
+	cmd.PersistentFlags().BoolVar(&metricsFromProxies, "from-proxies", false,
+		"Calculate the metrics from the stats of the workload sidecars instead of querying Prometheus")
+	cmd.PersistentFlags().DurationVar(&metricsInterval, "interval", 10*time.Second,
+		"Time between the two samples taken from the sidecars, with --from-proxies")
+	return cmd
+}
 
 const (
 	wlabel   = "destination_workload"
@@ -92,6 +118,10 @@ func run(c *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create k8s client: %v", err)
 	}
 
+	if metricsFromProxies {
+		return runFromProxies(c.OutOrStdout(), client, args, metricsInterval)
+	}
+
 	pl, err := client.PodsForSelector(context.TODO(), istioNamespace, "app=prometheus")
 	if err != nil {
 		return fmt.Errorf("not able to locate Prometheus pod: %v", err)
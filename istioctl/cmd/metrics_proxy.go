@@ -0,0 +1,372 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/log"
+)
+
+const (
+	// proxyStatsPath is the Envoy admin path the Istio metrics are scraped from
+	proxyStatsPath = "stats/prometheus"
+	reqDurMillis   = "istio_request_duration_milliseconds"
+)
+
+// requestStats are the request counters and duration histogram of a set of requests at one point in time
+type requestStats struct {
+	total, errors float64
+	// buckets maps the upper bound of each duration bucket, in seconds, to its cumulative count
+	buckets map[float64]float64
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{buckets: map[float64]float64{}}
+}
+
+func (r *requestStats) add(o *requestStats) {
+	r.total += o.total
+	r.errors += o.errors
+	for le, c := range o.buckets {
+		r.buckets[le] += c
+	}
+}
+
+// since returns the requests counted after the before stats were taken. Like Prometheus' increase(),
+// a counter that went down is assumed to have been reset, and its current value is the increase.
+func (r *requestStats) since(before *requestStats) *requestStats {
+	if before == nil || r.total < before.total {
+		return r
+	}
+	d := newRequestStats()
+	d.total = r.total - before.total
+	d.errors = math.Max(r.errors-before.errors, 0)
+	for le, c := range r.buckets {
+		d.buckets[le] = math.Max(c-before.buckets[le], 0)
+	}
+	return d
+}
+
+// quantile estimates the q-quantile of the request duration, interpolating linearly within
+// a bucket, as histogram_quantile() does
+func (r *requestStats) quantile(q float64) time.Duration {
+	bounds := make([]float64, 0, len(r.buckets))
+	for le := range r.buckets {
+		bounds = append(bounds, le)
+	}
+	sort.Float64s(bounds)
+	if len(bounds) == 0 || r.buckets[bounds[len(bounds)-1]] == 0 {
+		return 0
+	}
+	rank := q * r.buckets[bounds[len(bounds)-1]]
+	lower, lowerCount := 0.0, 0.0
+	for _, le := range bounds {
+		count := r.buckets[le]
+		if count >= rank {
+			if math.IsInf(le, 1) {
+				// The quantile is above the largest finite bucket, which is the best estimate available
+				return seconds(lower)
+			}
+			if count == lowerCount {
+				return seconds(le)
+			}
+			return seconds(lower + (le-lower)*(rank-lowerCount)/(count-lowerCount))
+		}
+		lower, lowerCount = le, count
+	}
+	return seconds(lower)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s*1000) * time.Millisecond
+}
+
+// proxySample is the request stats of a pod at one point in time
+type proxySample struct {
+	// inbound are the requests served by the pod, as reported by its sidecar
+	inbound *requestStats
+	// outbound are the requests sent by the pod, by destination service
+	outbound map[string]*requestStats
+}
+
+func newProxySample() *proxySample {
+	return &proxySample{inbound: newRequestStats(), outbound: map[string]*requestStats{}}
+}
+
+func (s *proxySample) stats(m *dto.Metric) *requestStats {
+	labels := map[string]string{}
+	for _, l := range m.Label {
+		labels[l.GetName()] = l.GetValue()
+	}
+	switch labels["reporter"] {
+	case "destination":
+		return s.inbound
+	case "source":
+		dest := labels["destination_service"]
+		if dest == "" || dest == "unknown" {
+			dest = labels["destination_service_name"]
+		}
+		if s.outbound[dest] == nil {
+			s.outbound[dest] = newRequestStats()
+		}
+		return s.outbound[dest]
+	}
+	return nil
+}
+
+// parseProxySample reads the Istio request metrics from the Prometheus stats of an Envoy
+func parseProxySample(data []byte) (*proxySample, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy stats: %v", err)
+	}
+	sample := newProxySample()
+	if f := families[reqTot]; f != nil {
+		for _, m := range f.Metric {
+			stats := sample.stats(m)
+			if stats == nil {
+				continue
+			}
+			v := m.GetCounter().GetValue()
+			if m.Untyped != nil {
+				v = m.GetUntyped().GetValue()
+			}
+			stats.total += v
+			for _, l := range m.Label {
+				if l.GetName() == "response_code" && (strings.HasPrefix(l.GetValue(), "4") || strings.HasPrefix(l.GetValue(), "5")) {
+					stats.errors += v
+				}
+			}
+		}
+	}
+	// Istio reports durations in milliseconds; older proxies report them in seconds
+	f, scale := families[reqDurMillis], 1000.0
+	if f == nil {
+		f, scale = families[reqDur], 1.0
+	}
+	if f != nil {
+		for _, m := range f.Metric {
+			stats := sample.stats(m)
+			if stats == nil || m.Histogram == nil {
+				continue
+			}
+			hasInf := false
+			for _, b := range m.Histogram.Bucket {
+				stats.buckets[b.GetUpperBound()/scale] += float64(b.GetCumulativeCount())
+				hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+			}
+			if !hasInf {
+				stats.buckets[math.Inf(1)] += float64(m.Histogram.GetSampleCount())
+			}
+		}
+	}
+	return sample, nil
+}
+
+// proxyWorkloadMetrics calculates the metrics of a workload, and of the requests it sent per destination
+// service, from samples of each of its pods taken at the start and end of an interval
+func proxyWorkloadMetrics(workload string, before, after map[string]*proxySample, interval time.Duration) (workloadMetrics, []workloadMetrics) {
+	inbound := newRequestStats()
+	outbound := map[string]*requestStats{}
+	for pod, a := range after {
+		b := before[pod]
+		if b == nil {
+			b = newProxySample()
+		}
+		inbound.add(a.inbound.since(b.inbound))
+		for dest, stats := range a.outbound {
+			if outbound[dest] == nil {
+				outbound[dest] = newRequestStats()
+			}
+			outbound[dest].add(stats.since(b.outbound[dest]))
+		}
+	}
+
+	toMetrics := func(name string, r *requestStats) workloadMetrics {
+		return workloadMetrics{
+			workload:   name,
+			totalRPS:   r.total / interval.Seconds(),
+			errorRPS:   r.errors / interval.Seconds(),
+			p50Latency: r.quantile(0.5),
+			p90Latency: r.quantile(0.9),
+			p99Latency: r.quantile(0.99),
+		}
+	}
+	dests := make([]string, 0, len(outbound))
+	for dest := range outbound {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+	perDest := make([]workloadMetrics, 0, len(dests))
+	for _, dest := range dests {
+		perDest = append(perDest, toMetrics("-> "+dest, outbound[dest]))
+	}
+	return toMetrics(workload, inbound), perDest
+}
+
+// workloadPods returns the pods with sidecars of a workload, given as [deployment/|service/]name[.namespace].
+// Without a resource type, a deployment is looked up first, and then a service.
+func workloadPods(client kubernetes.Interface, workload string) ([]v1.Pod, error) {
+	kind, name := "", workload
+	if i := strings.Index(workload, "/"); i >= 0 {
+		kind, name = strings.ToLower(workload[:i]), workload[i+1:]
+	}
+	name, ns := handlers.InferPodInfo(name, handlers.HandleNamespace(namespace, defaultNamespace))
+
+	var selector klabels.Selector
+	var err error
+	switch kind {
+	case "deployment", "deployments", "deploy":
+		selector, err = deploymentSelector(client, name, ns)
+	case "service", "services", "svc":
+		selector, err = serviceSelector(client, name, ns)
+	case "":
+		selector, err = deploymentSelector(client, name, ns)
+		if errors.IsNotFound(err) {
+			selector, err = serviceSelector(client, name, ns)
+		}
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("no deployment or service named %s found in namespace %s", name, ns)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported resource type %q, must be a deployment or service", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pl, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("not able to list pods of %s: %v", workload, err)
+	}
+	var pods []v1.Pod
+	for _, pod := range pl.Items {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name == proxyContainerName {
+				pods = append(pods, pod)
+				break
+			}
+		}
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pods with sidecars found for %s", workload)
+	}
+	return pods, nil
+}
+
+func deploymentSelector(client kubernetes.Interface, name, ns string) (klabels.Selector, error) {
+	deployment, err := client.AppsV1().Deployments(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of deployment %s.%s: %v", name, ns, err)
+	}
+	// An empty selector would select every pod of the namespace
+	if selector.Empty() {
+		return nil, fmt.Errorf("deployment %s.%s has no selector", name, ns)
+	}
+	return selector, nil
+}
+
+func serviceSelector(client kubernetes.Interface, name, ns string) (klabels.Selector, error) {
+	svc, err := client.CoreV1().Services(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %s.%s has no selector", name, ns)
+	}
+	return klabels.SelectorFromSet(svc.Spec.Selector), nil
+}
+
+func scrapeProxies(client kube.ExtendedClient, pods []v1.Pod) (map[string]*proxySample, error) {
+	samples := map[string]*proxySample{}
+	for _, pod := range pods {
+		data, err := client.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", proxyStatsPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scrape stats of %s.%s: %v", pod.Name, pod.Namespace, err)
+		}
+		sample, err := parseProxySample(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stats of %s.%s: %v", pod.Name, pod.Namespace, err)
+		}
+		samples[pod.Namespace+"/"+pod.Name] = sample
+	}
+	return samples, nil
+}
+
+func runFromProxies(writer io.Writer, client kube.ExtendedClient, workloads []string, interval time.Duration) error {
+	pods := make(map[string][]v1.Pod, len(workloads))
+	before := make(map[string]map[string]*proxySample, len(workloads))
+	// The workloads are scraped one after the other, so the interval between the samples of
+	// each workload is timed separately
+	start := make(map[string]time.Time, len(workloads))
+	for _, workload := range workloads {
+		wp, err := workloadPods(client.Kube(), workload)
+		if err != nil {
+			return err
+		}
+		pods[workload] = wp
+	}
+	for _, workload := range workloads {
+		start[workload] = time.Now()
+		samples, err := scrapeProxies(client, pods[workload])
+		if err != nil {
+			return fmt.Errorf("could not build metrics for workload '%s': %v", workload, err)
+		}
+		before[workload] = samples
+	}
+	log.Debugf("waiting %v for the second sample of the proxy stats", interval)
+	time.Sleep(interval)
+
+	printHeader(writer)
+	for _, workload := range workloads {
+		elapsed := time.Since(start[workload])
+		after, err := scrapeProxies(client, pods[workload])
+		if err != nil {
+			return fmt.Errorf("could not build metrics for workload '%s': %v", workload, err)
+		}
+		wm, perDest := proxyWorkloadMetrics(workload, before[workload], after, elapsed)
+		printMetrics(writer, wm)
+		for _, dm := range perDest {
+			printMetrics(writer, dm)
+		}
+	}
+	return nil
+}
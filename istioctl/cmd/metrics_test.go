@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -25,8 +26,10 @@ import (
 
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	prometheus_model "github.com/prometheus/common/model"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"istio.io/istio/pkg/kube"
 	testKube "istio.io/istio/pkg/test/kube"
@@ -144,6 +147,149 @@ func TestPrintMetrics(t *testing.T) {
 	}
 }
 
+const detailsStatsBefore = `# TYPE istio_requests_total counter
+istio_requests_total{response_code="200",reporter="destination",destination_service="details.default.svc.cluster.local"} 100
+istio_requests_total{response_code="503",reporter="destination",destination_service="details.default.svc.cluster.local"} 5
+istio_requests_total{response_code="200",reporter="source",destination_service="ratings.default.svc.cluster.local"} 40
+# TYPE istio_request_duration_milliseconds histogram
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="1"} 10
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="5"} 80
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="10"} 100
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="+Inf"} 105
+istio_request_duration_milliseconds_sum{reporter="destination",destination_service="details.default.svc.cluster.local"} 400
+istio_request_duration_milliseconds_count{reporter="destination",destination_service="details.default.svc.cluster.local"} 105
+`
+
+const detailsStatsAfter = `# TYPE istio_requests_total counter
+istio_requests_total{response_code="200",reporter="destination",destination_service="details.default.svc.cluster.local"} 200
+istio_requests_total{response_code="503",reporter="destination",destination_service="details.default.svc.cluster.local"} 10
+istio_requests_total{response_code="200",reporter="source",destination_service="ratings.default.svc.cluster.local"} 55
+istio_requests_total{response_code="500",reporter="source",destination_service="ratings.default.svc.cluster.local"} 5
+# TYPE istio_request_duration_milliseconds histogram
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="1"} 20
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="5"} 160
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="10"} 200
+istio_request_duration_milliseconds_bucket{reporter="destination",destination_service="details.default.svc.cluster.local",le="+Inf"} 210
+istio_request_duration_milliseconds_sum{reporter="destination",destination_service="details.default.svc.cluster.local"} 800
+istio_request_duration_milliseconds_count{reporter="destination",destination_service="details.default.svc.cluster.local"} 210
+`
+
+func TestProxyWorkloadMetrics(t *testing.T) {
+	sample := func(stats string) *proxySample {
+		t.Helper()
+		s, err := parseProxySample([]byte(stats))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	before := map[string]*proxySample{
+		"default/details-v1-1": sample(detailsStatsBefore),
+		"default/details-v1-2": sample(`istio_requests_total{response_code="200",reporter="destination"} 50` + "\n"),
+	}
+	after := map[string]*proxySample{
+		"default/details-v1-1": sample(detailsStatsAfter),
+		// The proxy restarted, so all of its requests were served during the interval
+		"default/details-v1-2": sample(`istio_requests_total{response_code="200",reporter="destination"} 5` + "\n"),
+	}
+
+	wm, perDest := proxyWorkloadMetrics("details-v1", before, after, 10*time.Second)
+	want := workloadMetrics{
+		workload:   "details-v1",
+		totalRPS:   11,
+		errorRPS:   0.5,
+		p50Latency: 3 * time.Millisecond,
+		p90Latency: 8 * time.Millisecond,
+		p99Latency: 10 * time.Millisecond,
+	}
+	if wm != want {
+		t.Errorf("got workload metrics %+v, want %+v", wm, want)
+	}
+	wantDest := []workloadMetrics{{workload: "-> ratings.default.svc.cluster.local", totalRPS: 2, errorRPS: 0.5}}
+	if !reflect.DeepEqual(perDest, wantDest) {
+		t.Errorf("got destination metrics %+v, want %+v", perDest, wantDest)
+	}
+}
+
+func TestMetricsFromProxies(t *testing.T) {
+	proxyPod := func(name string, labels map[string]string, sidecar bool) *v1.Pod {
+		pod := &v1.Pod{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: labels["app"]}}},
+		}
+		if sidecar {
+			pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "istio-proxy"})
+		}
+		return pod
+	}
+	kubeClientWithRevision = func(_, _, _ string) (kube.ExtendedClient, error) {
+		return &testKube.MockClient{
+			// Pods are selected by the selector of the Deployment, or of the Service without a Deployment.
+			Interface: fake.NewSimpleClientset(
+				&appsv1.Deployment{
+					ObjectMeta: meta_v1.ObjectMeta{Name: "details-v1", Namespace: "default"},
+					Spec: appsv1.DeploymentSpec{Selector: &meta_v1.LabelSelector{
+						MatchLabels: map[string]string{"app": "details", "version": "v1"},
+					}},
+				},
+				&appsv1.Deployment{
+					ObjectMeta: meta_v1.ObjectMeta{Name: "ratings-v1", Namespace: "default"},
+					Spec: appsv1.DeploymentSpec{Selector: &meta_v1.LabelSelector{
+						MatchLabels: map[string]string{"app": "ratings", "version": "v1"},
+					}},
+				},
+				&v1.Service{
+					ObjectMeta: meta_v1.ObjectMeta{Name: "details", Namespace: "default"},
+					Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "details"}},
+				},
+				proxyPod("details-v1-5b7f94f9bc-wp5tb", map[string]string{"app": "details", "version": "v1"}, true),
+				proxyPod("details-v2-6c8d8f6b4-x7k2p", map[string]string{"app": "details", "version": "v2"}, true),
+				proxyPod("ratings-v1-7dc98c7588-4jxqm", map[string]string{"app": "ratings", "version": "v1"}, false),
+			),
+			Results: map[string][]byte{
+				"details-v1-5b7f94f9bc-wp5tb": []byte(detailsStatsAfter),
+				"details-v2-6c8d8f6b4-x7k2p":  []byte(detailsStatsAfter),
+			},
+		}, nil
+	}
+
+	cases := []testCase{
+		{ // case 0
+			args: strings.Split("experimental metrics details-v1 --from-proxies --interval 1ms", " "),
+			expectedRegexp: regexp.MustCompile(`WORKLOAD +TOTAL RPS +ERROR RPS +P50 LATENCY +P90 LATENCY +P99 LATENCY\n` +
+				` +details-v1 +0\.000 +0\.000 +0s +0s +0s\n` +
+				` +-> ratings\.default\.svc\.cluster\.local +0\.000 +0\.000 +0s +0s +0s\n$`),
+		},
+		{ // case 1
+			args:           strings.Split("experimental metrics ratings-v1 --from-proxies --interval 1ms", " "),
+			expectedOutput: "Error: no pods with sidecars found for ratings-v1\n",
+			wantException:  true,
+		},
+		{ // case 2
+			args:           strings.Split("experimental metrics details-v1 --from-proxies --interval 0s", " "),
+			expectedOutput: "Error: --interval must be positive\n",
+			wantException:  true,
+		},
+		{ // case 3
+			args: strings.Split("experimental metrics details --from-proxies --interval 1ms", " "),
+			expectedRegexp: regexp.MustCompile(`WORKLOAD +TOTAL RPS +ERROR RPS +P50 LATENCY +P90 LATENCY +P99 LATENCY\n` +
+				` +details +0\.000 +0\.000 +0s +0s +0s\n` +
+				` +-> ratings\.default\.svc\.cluster\.local +0\.000 +0\.000 +0s +0s +0s\n$`),
+		},
+		{ // case 4
+			args:           strings.Split("experimental metrics reviews-v1 --from-proxies --interval 1ms", " "),
+			expectedOutput: "Error: no deployment or service named reviews-v1 found in namespace default\n",
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyOutput(t, c)
+		})
+	}
+}
+
 func (client mockPromAPI) Alerts(ctx context.Context) (promv1.AlertsResult, error) {
 	return promv1.AlertsResult{}, fmt.Errorf("TODO mockPromAPI doesn't mock Alerts")
 }
@@ -27,7 +27,9 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
 
+	envoyconfigdump "istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/istioctl/pkg/writer/compare"
 	"istio.io/istio/istioctl/pkg/writer/envoy/clusters"
 	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
 	"istio.io/istio/pilot/pkg/model"
@@ -791,13 +793,107 @@ func secretConfigCmd() *cobra.Command {
 	return secretConfigCmd
 }
 
+func diffConfigCmd() *cobra.Command {
+	var files, types []string
+
+	diffConfigCmd := &cobra.Command{
+		Use:   "diff [<pod-name[.namespace]>] [<pod-name[.namespace]>]",
+		Short: "(experimental) Diffs the Envoy configuration of two pods, or of a pod at two points in time",
+		Long: `(experimental) Compares two Envoy config dumps resource by resource and prints the listeners, routes,
+clusters and secrets that were added, removed or modified, with a diff of each modified resource.
+
+The config dumps can be taken from two pods, from two saved config dump files, or from a saved file and a pod.
+Saved files are treated as the baseline, so comparing a file with a pod shows what changed since the file
+was saved. Fields that change without changing behavior, such as versions, update timestamps and nonces,
+are ignored. Certificates are compared by subject, issuer and SANs only.`,
+		Example: `  # Diff the configuration of two pods.
+  istioctl proxy-config diff productpage-v1-bb8d5cbc7-k7qbm reviews-v1-5c5b7b9f8d-2qnxk.bookinfo
+
+  # Save the configuration of a pod, then later see what changed.
+  kubectl exec productpage-v1-bb8d5cbc7-k7qbm -c istio-proxy -- curl -s localhost:15000/config_dump > before.json
+  istioctl proxy-config diff -f before.json productpage-v1-bb8d5cbc7-k7qbm
+
+  # Diff only the clusters of two saved config dumps, as JSON.
+  istioctl proxy-config diff -f before.json -f after.json --type cluster -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args)+len(files) != 2 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("diff requires exactly two pod names or --file parameters, got %d", len(args)+len(files))
+			}
+			for _, t := range types {
+				if !isResourceType(t) {
+					return fmt.Errorf("unknown resource type %q, must be one of listener|route|cluster|secret", t)
+				}
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var names []string
+			var dumps []*envoyconfigdump.Wrapper
+			for _, f := range files {
+				dump, err := getConfigDumpFromFile(f)
+				if err != nil {
+					return err
+				}
+				names = append(names, f)
+				dumps = append(dumps, dump)
+			}
+			for _, arg := range args {
+				podName, podNamespace, err := getPodName(arg)
+				if err != nil {
+					return err
+				}
+				dump, err := getConfigDumpFromPod(podName, podNamespace)
+				if err != nil {
+					return err
+				}
+				names = append(names, fmt.Sprintf("%s.%s", podName, podNamespace))
+				dumps = append(dumps, dump)
+			}
+			resourceTypes := make([]compare.ResourceType, 0, len(types))
+			for _, t := range types {
+				resourceTypes = append(resourceTypes, compare.ResourceType(t))
+			}
+			comparator := compare.NewDumpComparator(c.OutOrStdout(), names[0], dumps[0], names[1], dumps[1])
+			switch outputFormat {
+			case summaryOutput:
+				return comparator.Print(resourceTypes...)
+			case jsonOutput:
+				return comparator.PrintJSON(resourceTypes...)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+		},
+	}
+
+	diffConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|short")
+	diffConfigCmd.PersistentFlags().StringArrayVarP(&files, "file", "f", nil,
+		"Envoy config dump JSON file, may be given twice")
+	diffConfigCmd.PersistentFlags().StringSliceVar(&types, "type", nil,
+		"Resource types to diff, any of listener|route|cluster|secret (default all)")
+	diffConfigCmd.Long += "\n\n" + ExperimentalMsg
+	return diffConfigCmd
+}
+
+func isResourceType(t string) bool {
+	for _, rt := range compare.ResourceTypes {
+		if string(rt) == t {
+			return true
+		}
+	}
+	return false
+}
+
 func proxyConfig() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "proxy-config",
 		Short: "Retrieve information about proxy configuration from Envoy [kube only]",
 		Long:  `A group of commands used to retrieve information about proxy configuration from the Envoy config dump`,
 		Example: `  # Retrieve information about proxy configuration from an Envoy instance.
-  istioctl proxy-config <clusters|listeners|routes|endpoints|bootstrap|log|secret> <pod-name[.namespace]>`,
+  istioctl proxy-config <clusters|listeners|routes|endpoints|bootstrap|log|secret> <pod-name[.namespace]>
+
+  # Diff the proxy configuration of two Envoy instances.
+  istioctl proxy-config diff <pod-name[.namespace]> <pod-name[.namespace]>`,
 		Aliases: []string{"pc"},
 	}
 
@@ -810,6 +906,7 @@ func proxyConfig() *cobra.Command {
 	configCmd.AddCommand(bootstrapConfigCmd())
 	configCmd.AddCommand(endpointConfigCmd())
 	configCmd.AddCommand(secretConfigCmd())
+	configCmd.AddCommand(diffConfigCmd())
 
 	return configCmd
 }
@@ -160,6 +160,42 @@ func TestProxyConfig(t *testing.T) {
 			expectedString:   `config dump has no configuration type`,
 			wantException:    true,
 		},
+		{ // diff requires two config dumps
+			args:           strings.Split("proxy-config diff -f testdata/proxyconfig-diff/before.json", " "),
+			expectedString: "diff requires exactly two pod names or --file parameters, got 1",
+			wantException:  true,
+		},
+		{ // diff with an unknown resource type
+			args: strings.Split("proxy-config diff -f testdata/proxyconfig-diff/before.json "+
+				"-f testdata/proxyconfig-diff/after.json --type endpoint", " "),
+			expectedString: `unknown resource type "endpoint"`,
+			wantException:  true,
+		},
+		{ // diff two saved config dumps, ignoring versions and update times
+			args: strings.Split("proxy-config diff -f testdata/proxyconfig-diff/before.json -f testdata/proxyconfig-diff/after.json", " "),
+			expectedOutput: `--- testdata/proxyconfig-diff/before.json
++++ testdata/proxyconfig-diff/after.json
+Listeners: 1 added, 0 removed, 0 modified
+  + 0.0.0.0_15443
+Routes Match
+Clusters: 0 added, 0 removed, 1 modified
+  ~ outbound|9080||reviews.default.svc.cluster.local
+    --- testdata/proxyconfig-diff/before.json
+    +++ testdata/proxyconfig-diff/after.json
+    @@ -1,4 +1,4 @@
+     {
+    -   "connectTimeout": "1s",
+    +   "connectTimeout": "10s",
+        "name": "outbound|9080||reviews.default.svc.cluster.local"
+     }
+Secrets Match
+`,
+		},
+		{ // diff a config dump with itself
+			args: strings.Split("proxy-config diff -f testdata/proxyconfig-diff/after.json -f testdata/proxyconfig-diff/after.json "+
+				"--type listener,route -o json", " "),
+			expectedOutput: "[]\n",
+		},
 	}
 
 	for i, c := range cases {
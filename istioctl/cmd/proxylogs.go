@@ -0,0 +1,263 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/istioctl/pkg/accesslog"
+	"istio.io/istio/istioctl/pkg/clioptions"
+)
+
+const rawOutput = "raw"
+
+// proxyLogStream opens the log stream of the istio-proxy container of a pod. Tests replace it, as the fake
+// clientset does not return logs.
+var proxyLogStream = func(ctx context.Context, client kubernetes.Interface, pod v1.Pod, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+	return client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+}
+
+func proxyLogsCmd() *cobra.Command {
+	var (
+		opts         clioptions.ControlPlaneOptions
+		filter       accesslog.Filter
+		follow       bool
+		since        time.Duration
+		tail         int64
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "proxy-logs <deployment|service>[.namespace]",
+		Short: "Prints the access logs of the sidecars of a workload",
+		Long: `Prints the Envoy access logs of the sidecars of all pods of a deployment or service. The logs are parsed,
+in either the default Istio text format or the JSON encoding, so that they can be filtered by response code,
+response flags, upstream cluster and duration. Other output of the istio-proxy container is skipped.
+
+Access logs must be enabled, for example with meshConfig.accessLogFile set to /dev/stdout.`,
+		Example: `  # Print the access logs of the sidecars of the productpage-v1 deployment
+  istioctl x proxy-logs deployment/productpage-v1
+
+  # Stream the server errors of the reviews service in the bookinfo namespace
+  istioctl x proxy-logs svc/reviews.bookinfo --follow --response-code 5xx
+
+  # Print the requests which had no healthy upstream or took longer than a second, as JSON
+  istioctl x proxy-logs productpage-v1 --response-flags UH --min-duration 1s -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("proxy-logs requires a deployment or service name")
+			}
+			if outputFormat != summaryOutput && outputFormat != jsonOutput && outputFormat != rawOutput {
+				return fmt.Errorf("unknown output format %q, must be one of short, json or raw", outputFormat)
+			}
+			return filter.Validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			pods, err := workloadPods(client.Kube(), args[0])
+			if err != nil {
+				return err
+			}
+			logOpts := &v1.PodLogOptions{
+				Container: proxyContainerName,
+				Follow:    follow,
+			}
+			if since > 0 {
+				seconds := int64(since.Seconds())
+				logOpts.SinceSeconds = &seconds
+			}
+			if tail >= 0 {
+				logOpts.TailLines = &tail
+			}
+			return streamProxyLogs(context.Background(), c.OutOrStdout(), client.Kube(), pods, logOpts, filter, outputFormat)
+		},
+	}
+	cmd.PersistentFlags().BoolVarP(&follow, "follow", "f", false,
+		"Stream the logs as they are written")
+	cmd.PersistentFlags().DurationVar(&since, "since", 0,
+		"Only print logs newer than this duration, such as 5m")
+	cmd.PersistentFlags().Int64Var(&tail, "tail", -1,
+		"Number of lines of each sidecar's output to read, before filtering. Defaults to all lines")
+	cmd.PersistentFlags().StringSliceVar(&filter.ResponseCodes, "response-code", nil,
+		"Only print requests with these response codes, such as 503, or classes, such as 5xx")
+	cmd.PersistentFlags().StringSliceVar(&filter.ResponseFlags, "response-flags", nil,
+		"Only print requests with any of these Envoy response flags, such as UH or UF")
+	cmd.PersistentFlags().StringVar(&filter.UpstreamCluster, "upstream-cluster", "",
+		"Only print requests whose upstream cluster contains this string")
+	cmd.PersistentFlags().DurationVar(&filter.MinDuration, "min-duration", 0,
+		"Only print requests which took at least this long")
+	cmd.PersistentFlags().DurationVar(&filter.MaxDuration, "max-duration", 0,
+		"Only print requests which took at most this long")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput,
+		"Output format: one of short|json|raw")
+	opts.AttachControlPlaneFlags(cmd)
+	return cmd
+}
+
+type proxyLogLine struct {
+	pod   v1.Pod
+	raw   string
+	entry *accesslog.Entry
+}
+
+// streamProxyLogs prints the access log entries of every pod that match the filter. When following the
+// logs, entries are printed as they arrive; otherwise all entries are read first and printed in time order.
+func streamProxyLogs(ctx context.Context, w io.Writer, client kubernetes.Interface, pods []v1.Pod,
+	logOpts *v1.PodLogOptions, filter accesslog.Filter, outputFormat string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	lines := make(chan proxyLogLine)
+	errs := make(chan error, len(pods))
+	// Stop the readers before returning, for example on a write error, and wait for them to close
+	// their streams
+	defer func() {
+		cancel()
+		for range lines {
+		}
+	}()
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod v1.Pod) {
+			defer wg.Done()
+			errs <- readProxyLogs(ctx, client, pod, logOpts, filter, lines)
+		}(pod)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+		close(errs)
+	}()
+
+	var buffered []proxyLogLine
+	for line := range lines {
+		if logOpts.Follow {
+			if err := printProxyLogLine(w, line, outputFormat); err != nil {
+				return err
+			}
+			continue
+		}
+		buffered = append(buffered, line)
+	}
+	sort.SliceStable(buffered, func(i, j int) bool {
+		return buffered[i].entry.StartTime.Before(buffered[j].entry.StartTime)
+	})
+	for _, line := range buffered {
+		if err := printProxyLogLine(w, line, outputFormat); err != nil {
+			return err
+		}
+	}
+
+	var result error
+	for err := range errs {
+		if err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
+}
+
+func readProxyLogs(ctx context.Context, client kubernetes.Interface, pod v1.Pod, logOpts *v1.PodLogOptions,
+	filter accesslog.Filter, lines chan<- proxyLogLine) error {
+	stream, err := proxyLogStream(ctx, client, pod, logOpts)
+	if err != nil {
+		return fmt.Errorf("unable to read the logs of %s.%s: %v", pod.Name, pod.Namespace, err)
+	}
+	defer stream.Close()
+	// Also close the stream as soon as the context is cancelled, to unblock a pending read
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = stream.Close()
+		case <-done:
+		}
+	}()
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, err := accesslog.Parse(scanner.Text())
+		if err != nil || !filter.Matches(entry) {
+			continue
+		}
+		select {
+		case lines <- proxyLogLine{pod: pod, raw: scanner.Text(), entry: entry}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("unable to read the logs of %s.%s: %v", pod.Name, pod.Namespace, err)
+	}
+	return nil
+}
+
+// proxyLogJSON is the JSON output of an entry. Envoy logs the duration in milliseconds, so that is used
+// instead of the nanoseconds of the embedded time.Duration.
+type proxyLogJSON struct {
+	Pod       string `json:"pod"`
+	Namespace string `json:"namespace"`
+	*accesslog.Entry
+	Duration int64 `json:"duration"`
+}
+
+func printProxyLogLine(w io.Writer, line proxyLogLine, outputFormat string) error {
+	var err error
+	switch outputFormat {
+	case rawOutput:
+		_, err = fmt.Fprintf(w, "%s %s\n", line.pod.Name, line.raw)
+	case jsonOutput:
+		var out []byte
+		out, err = json.Marshal(proxyLogJSON{
+			Pod:       line.pod.Name,
+			Namespace: line.pod.Namespace,
+			Entry:     line.entry,
+			Duration:  line.entry.Duration.Milliseconds(),
+		})
+		if err == nil {
+			_, err = fmt.Fprintln(w, string(out))
+		}
+	default:
+		e := line.entry
+		_, err = fmt.Fprintf(w, "%s [%s] \"%s %s %s\" %d %s %v %s %s\n", line.pod.Name,
+			e.StartTime.UTC().Format("2006-01-02T15:04:05.000Z"), orDash(e.Method), orDash(e.Path), orDash(e.Protocol),
+			e.ResponseCode, orDash(strings.Join(e.ResponseFlags, ",")), e.Duration,
+			orDash(e.UpstreamCluster), orDash(e.UpstreamHost))
+	}
+	return err
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/istioctl/pkg/accesslog"
+	"istio.io/istio/pkg/kube"
+	testKube "istio.io/istio/pkg/test/kube"
+)
+
+var proxyLogsOutput = map[string]string{
+	"productpage-v1-1": `2020-11-20T10:00:00.000000Z	info	Envoy proxy is ready
+[2020-11-20T10:00:00.100Z] "GET /productpage HTTP/1.1" 200 - "-" 0 5183 43 42 "-" "curl/7.64.0" "a" "productpage:9080" ` +
+		`"127.0.0.1:9080" inbound|9080|| 127.0.0.1:41962 10.44.1.7:9080 10.44.0.9:51740 - default
+[2020-11-20T10:00:00.300Z] "GET /reviews/0 HTTP/1.1" 503 UF,URX "-" 0 91 3012 - "-" "Go-http-client/1.1" "b" ` +
+		`"reviews:9080" "10.44.1.8:9080" outbound|9080||reviews.default.svc.cluster.local 10.44.1.7:40100 ` +
+		`10.0.0.14:9080 10.44.1.7:40098 - default
+`,
+	"productpage-v1-2": `{"start_time":"2020-11-20T10:00:00.200Z","method":"GET","path":"/details/0",` +
+		`"protocol":"HTTP/1.1","response_code":"200","response_flags":"-","bytes_received":"0","bytes_sent":"178",` +
+		`"duration":"5","upstream_cluster":"outbound|9080||details.default.svc.cluster.local",` +
+		`"upstream_host":"10.44.1.9:9080"}
+`,
+}
+
+func TestProxyLogs(t *testing.T) {
+	labels := map[string]string{"app": "productpage", "version": "v1"}
+	proxyPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "productpage"},
+				{Name: "istio-proxy"},
+			}},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "productpage-v1", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Selector: &meta_v1.LabelSelector{MatchLabels: labels}},
+		},
+		&v1.Service{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "productpage", Namespace: "default"},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "productpage"}},
+		},
+		proxyPod("productpage-v1-1"),
+		proxyPod("productpage-v1-2"),
+		&v1.Pod{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "productpage-v1-nosidecar", Namespace: "default", Labels: labels},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "productpage"}}},
+		},
+	)
+	kubeClientWithRevision = func(_, _, _ string) (kube.ExtendedClient, error) {
+		return testKube.MockClient{Interface: client}, nil
+	}
+	proxyLogStream = func(_ context.Context, _ kubernetes.Interface, pod v1.Pod, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+		if opts.Container != "istio-proxy" {
+			return nil, fmt.Errorf("unexpected container %q", opts.Container)
+		}
+		return ioutil.NopCloser(strings.NewReader(proxyLogsOutput[pod.Name])), nil
+	}
+
+	cases := []testCase{
+		{ // case 0
+			args: strings.Split("experimental proxy-logs productpage-v1", " "),
+			expectedOutput: `productpage-v1-1 [2020-11-20T10:00:00.100Z] "GET /productpage HTTP/1.1" 200 - 43ms inbound|9080|| 127.0.0.1:9080
+productpage-v1-2 [2020-11-20T10:00:00.200Z] "GET /details/0 HTTP/1.1" 200 - 5ms outbound|9080||details.default.svc.cluster.local 10.44.1.9:9080
+productpage-v1-1 [2020-11-20T10:00:00.300Z] "GET /reviews/0 HTTP/1.1" 503 UF,URX 3.012s outbound|9080||reviews.default.svc.cluster.local 10.44.1.8:9080
+`,
+		},
+		{ // case 1
+			args: strings.Split("experimental proxy-logs svc/productpage.default --response-code 5xx -o raw", " "),
+			expectedOutput: `productpage-v1-1 [2020-11-20T10:00:00.300Z] "GET /reviews/0 HTTP/1.1" 503 UF,URX "-" 0 91 3012 - "-" ` +
+				`"Go-http-client/1.1" "b" "reviews:9080" "10.44.1.8:9080" outbound|9080||reviews.default.svc.cluster.local ` +
+				`10.44.1.7:40100 10.0.0.14:9080 10.44.1.7:40098 - default
+`,
+		},
+		{ // case 2
+			args: strings.Split("experimental proxy-logs deployment/productpage-v1 --upstream-cluster details --max-duration 10ms -o json", " "),
+			expectedOutput: `{"pod":"productpage-v1-2","namespace":"default","start_time":"2020-11-20T10:00:00.2Z","method":"GET",` +
+				`"path":"/details/0","protocol":"HTTP/1.1","response_code":200,"bytes_received":0,"bytes_sent":178,` +
+				`"upstream_host":"10.44.1.9:9080","upstream_cluster":"outbound|9080||details.default.svc.cluster.local","duration":5}
+`,
+		},
+		{ // case 3
+			args:           strings.Split("experimental proxy-logs productpage-v1 --response-flags UH", " "),
+			expectedOutput: "",
+		},
+		{ // case 4
+			args:           strings.Split("experimental proxy-logs ratings", " "),
+			expectedOutput: "Error: no deployment or service named ratings found in namespace default\n",
+			wantException:  true,
+		},
+		{ // case 5
+			args:           strings.Split("experimental proxy-logs statefulset/ratings", " "),
+			expectedOutput: "Error: unsupported resource type \"statefulset\", must be a deployment or service\n",
+			wantException:  true,
+		},
+		{ // case 6
+			args:           strings.Split("experimental proxy-logs productpage-v1 --response-code 6xx", " "),
+			expectedOutput: "Error: invalid response code \"6xx\", must be a code such as 503 or a class such as 5xx\n",
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyOutput(t, c)
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
+
+func TestStreamProxyLogsWriteError(t *testing.T) {
+	pods := []v1.Pod{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "productpage-v1-1", Namespace: "default"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "productpage-v1-2", Namespace: "default"}},
+	}
+	// The streams are followed, so they stay open after the first entry until they are closed
+	writers := map[string]*io.PipeWriter{}
+	streams := map[string]io.ReadCloser{}
+	for _, pod := range pods {
+		r, w := io.Pipe()
+		writers[pod.Name], streams[pod.Name] = w, r
+		go func(w *io.PipeWriter, log string) {
+			_, _ = io.WriteString(w, log)
+		}(w, proxyLogsOutput[pod.Name])
+	}
+	defer func(orig func(context.Context, kubernetes.Interface, v1.Pod, *v1.PodLogOptions) (io.ReadCloser, error)) {
+		proxyLogStream = orig
+	}(proxyLogStream)
+	proxyLogStream = func(_ context.Context, _ kubernetes.Interface, pod v1.Pod, _ *v1.PodLogOptions) (io.ReadCloser, error) {
+		return streams[pod.Name], nil
+	}
+
+	err := streamProxyLogs(context.Background(), failingWriter{}, fake.NewSimpleClientset(), pods,
+		&v1.PodLogOptions{Container: "istio-proxy", Follow: true}, accesslog.Filter{}, summaryOutput)
+	if err == nil {
+		t.Fatalf("expected the write error to be returned")
+	}
+	for name, w := range writers {
+		if _, err := w.Write([]byte("\n")); err != io.ErrClosedPipe {
+			t.Errorf("expected the stream of %s to be closed, got %v", name, err)
+		}
+	}
+}
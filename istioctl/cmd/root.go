@@ -221,13 +221,17 @@ debug and diagnose their Istio mesh.
 	rootCmd.AddCommand(proxyConfig())
 	experimentalCmd.AddCommand(istiodConfig())
 	experimentalCmd.AddCommand(injectorCommand())
+	xKubeInjectCmd := experimentalInjectCommand()
+	hideInheritedFlags(xKubeInjectCmd, "namespace")
+	experimentalCmd.AddCommand(xKubeInjectCmd)
 
 	rootCmd.AddCommand(install.NewVerifyCommand())
 	experimentalCmd.AddCommand(install.NewPrecheckCommand())
 	experimentalCmd.AddCommand(AuthZ())
 	rootCmd.AddCommand(seeExperimentalCmd("authz"))
 	experimentalCmd.AddCommand(uninjectCommand())
-	experimentalCmd.AddCommand(metricsCmd)
+	experimentalCmd.AddCommand(metricsCmd())
+	experimentalCmd.AddCommand(proxyLogsCmd())
 	experimentalCmd.AddCommand(describe())
 	experimentalCmd.AddCommand(addToMeshCmd())
 	experimentalCmd.AddCommand(removeFromMeshCmd())
@@ -235,6 +239,8 @@ debug and diagnose their Istio mesh.
 	deprecate(vmBootstrapCmd)
 	experimentalCmd.AddCommand(vmBootstrapCmd)
 	experimentalCmd.AddCommand(waitCmd())
+	experimentalCmd.AddCommand(caCmd())
+	experimentalCmd.AddCommand(simulateCmd())
 	experimentalCmd.AddCommand(mesh.UninstallCmd(loggingOptions))
 	experimentalCmd.AddCommand(configCmd())
 	postInstallWebhookCmd := Webhook()
@@ -0,0 +1,566 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pilot/pkg/simulation"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+type simulateOptions struct {
+	files          []string
+	configDumpFile string
+	meshConfigFile string
+	proxyLabels    string
+	proxyIP        string
+	gateway        bool
+
+	host       string
+	port       int
+	address    string
+	path       string
+	method     string
+	headers    []string
+	protocol   string
+	tls        string
+	sni        string
+	alpn       string
+	mode       string
+	outputFmt  string
+	domainName string
+}
+
+func simulateCmd() *cobra.Command {
+	opts := &simulateOptions{}
+	cmd := &cobra.Command{
+		Use:   "simulate [<pod-name>[.<namespace>]]",
+		Short: "Simulate how a request is matched by the Envoy configuration of a proxy",
+		Long: `Simulate a request through the Envoy configuration of a proxy, and print the listener, filter chain,
+route and cluster it matches, along with the Istio configuration that produced each of them.
+
+The configuration is either generated offline from Istio and Kubernetes Service YAML files, for a proxy
+described by its namespace and labels, or read from the Envoy config dump of a running pod.`,
+		Example: `  # Where will a request from a sidecar in the default namespace go, given the config in a directory?
+  istioctl x simulate -f samples/bookinfo/networking/ --labels app=productpage --host reviews --port 9080 --path /reviews/0
+
+  # Simulate a request through an ingress gateway, before applying the configuration
+  istioctl x simulate -f gateway.yaml -f virtual-service.yaml --gateway --labels istio=ingressgateway \
+    -n istio-system --host bookinfo.example.com --port 8080 --path /productpage
+
+  # Simulate a request from a running pod
+  istioctl x simulate productpage-v1-123456-abcde.default --host reviews --port 9080 --header end-user=jason
+
+  # Simulate a request through a saved Envoy config dump
+  istioctl x simulate --config-dump envoy-config.json --host reviews --port 9080`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			sources := len(args)
+			if len(opts.files) > 0 {
+				sources++
+			}
+			if opts.configDumpFile != "" {
+				sources++
+			}
+			if sources != 1 || len(args) > 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires exactly one of a pod name, --filename or --config-dump")
+			}
+			if opts.port == 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires --port")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			call, err := opts.call()
+			if err != nil {
+				return err
+			}
+			var sim *simulation.Simulation
+			if len(opts.files) > 0 {
+				cg, proxy, resolve, err := opts.configGenFromFiles()
+				if err != nil {
+					return err
+				}
+				defer cg.stop()
+				if call.Address == "" {
+					call.Address = resolve(opts.host)
+				}
+				sim = simulation.NewSimulationFromResources(cg.Listeners(proxy), cg.Clusters(proxy), cg.Routes(proxy))
+			} else {
+				dump, err := opts.configDump(args)
+				if err != nil {
+					return err
+				}
+				if sim, err = simulationFromConfigDump(dump); err != nil {
+					return err
+				}
+			}
+			trace, err := sim.Trace(call)
+			if err != nil {
+				return fmt.Errorf("failed to simulate the request: %v", err)
+			}
+			switch opts.outputFmt {
+			case summaryOutput:
+				return printSimulationTrace(cmd.OutOrStdout(), trace)
+			case jsonOutput:
+				return printSimulationTraceJSON(cmd.OutOrStdout(), trace)
+			default:
+				return fmt.Errorf("output format %q not supported", opts.outputFmt)
+			}
+		},
+	}
+
+	cmd.PersistentFlags().StringSliceVarP(&opts.files, "filename", "f", nil,
+		"Istio and Kubernetes Service configuration files or directories to generate the proxy configuration from")
+	cmd.PersistentFlags().StringVar(&opts.configDumpFile, "config-dump", "", "Envoy config dump JSON file")
+	cmd.PersistentFlags().StringVar(&opts.meshConfigFile, "meshConfigFile", "",
+		"Mesh configuration filename, used with --filename. Defaults to the default mesh configuration")
+	cmd.PersistentFlags().StringVarP(&opts.proxyLabels, "labels", "l", "",
+		"Labels of the simulated proxy workload, used with --filename, e.g. app=productpage,version=v1")
+	cmd.PersistentFlags().StringVar(&opts.proxyIP, "proxy-ip", "", "IP address of the simulated proxy, used with --filename")
+	cmd.PersistentFlags().BoolVar(&opts.gateway, "gateway", false,
+		"Simulate a gateway rather than a sidecar, used with --filename")
+	cmd.PersistentFlags().StringVar(&opts.domainName, "domain", constants.DefaultKubernetesDomain,
+		"Kubernetes DNS domain suffix, used with --filename")
+
+	cmd.PersistentFlags().StringVar(&opts.host, "host", "", "Host header of the request")
+	cmd.PersistentFlags().IntVar(&opts.port, "port", 0, "Destination port of the request")
+	cmd.PersistentFlags().StringVar(&opts.address, "address", "",
+		"Destination IP address of the request. With --filename, defaults to the address of the service named by --host")
+	cmd.PersistentFlags().StringVar(&opts.path, "path", "/", "Path of the request, optionally with a query string")
+	cmd.PersistentFlags().StringVar(&opts.method, "method", http.MethodGet, "Method of the request")
+	cmd.PersistentFlags().StringArrayVar(&opts.headers, "header", nil, "Header of the request, in the form name=value")
+	cmd.PersistentFlags().StringVar(&opts.protocol, "protocol", string(simulation.HTTP), "Protocol of the request: one of http|http2|tcp")
+	cmd.PersistentFlags().StringVar(&opts.tls, "tls", string(simulation.Plaintext),
+		"TLS mode of the connection: one of plaintext|tls|mtls")
+	cmd.PersistentFlags().StringVar(&opts.sni, "sni", "", "SNI of the connection. Defaults to the host for TLS connections")
+	cmd.PersistentFlags().StringVar(&opts.alpn, "alpn", "", "ALPN of the connection")
+	cmd.PersistentFlags().StringVar(&opts.mode, "mode", "",
+		"How the request reaches the proxy: one of outbound|inbound|gateway. Defaults to gateway with --gateway, outbound otherwise")
+	cmd.PersistentFlags().StringVarP(&opts.outputFmt, "output", "o", summaryOutput, "Output format: one of json|short")
+
+	return cmd
+}
+
+func (o *simulateOptions) call() (simulation.Call, error) {
+	call := simulation.Call{
+		Address:    o.address,
+		Port:       o.port,
+		Path:       o.path,
+		Method:     o.method,
+		Protocol:   simulation.Protocol(o.protocol),
+		TLS:        simulation.TLSMode(o.tls),
+		Alpn:       o.alpn,
+		HostHeader: o.host,
+		Headers:    http.Header{},
+		Sni:        o.sni,
+		CallMode:   simulation.CallMode(o.mode),
+	}
+	switch call.Protocol {
+	case simulation.HTTP, simulation.HTTP2, simulation.TCP:
+	default:
+		return call, fmt.Errorf("unknown protocol %q", o.protocol)
+	}
+	switch call.TLS {
+	case simulation.Plaintext, simulation.TLS, simulation.MTLS:
+	default:
+		return call, fmt.Errorf("unknown TLS mode %q", o.tls)
+	}
+	if call.CallMode == "" {
+		call.CallMode = simulation.CallModeOutbound
+		if o.gateway {
+			call.CallMode = simulation.CallModeGateway
+		}
+	}
+	switch call.CallMode {
+	case simulation.CallModeOutbound, simulation.CallModeInbound, simulation.CallModeGateway:
+	default:
+		return call, fmt.Errorf("unknown mode %q", o.mode)
+	}
+	for _, h := range o.headers {
+		parts := strings.SplitN(h, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return call, fmt.Errorf("invalid header %q, expected name=value", h)
+		}
+		call.Headers.Add(parts[0], parts[1])
+	}
+	return call, nil
+}
+
+// simulationConfigGen generates proxy configuration from configuration files.
+type simulationConfigGen struct {
+	*v1alpha3.ConfigGenTest
+	stop func()
+}
+
+// configGenFromFiles loads configuration files to generate the configuration of the proxy. It also
+// returns the proxy and a function resolving a host name to the address of its service, if any.
+func (o *simulateOptions) configGenFromFiles() (*simulationConfigGen, *model.Proxy, func(string) string, error) {
+	ns := handlers.HandleNamespace(namespace, defaultNamespace)
+	configs, services, err := readSimulationConfigs(o.files, ns, o.domainName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	meshConfig := mesh.DefaultMeshConfig()
+	if o.meshConfigFile != "" {
+		m, err := mesh.ReadMeshConfig(o.meshConfigFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read mesh config: %v", err)
+		}
+		meshConfig = *m
+	}
+	proxyLabels, err := klabels.ConvertSelectorToLabelsMap(o.proxyLabels)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid labels %q: %v", o.proxyLabels, err)
+	}
+	cg, stop, err := v1alpha3.NewConfigGen(v1alpha3.TestOptions{
+		Configs:    configs,
+		Services:   services,
+		MeshConfig: &meshConfig,
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate proxy configuration: %v", err)
+	}
+	proxy := &model.Proxy{
+		Type:            model.SidecarProxy,
+		ConfigNamespace: ns,
+		Metadata: &model.NodeMetadata{
+			Namespace: ns,
+			Labels:    proxyLabels,
+		},
+	}
+	if o.gateway {
+		proxy.Type = model.Router
+	}
+	if o.proxyIP != "" {
+		proxy.IPAddresses = []string{o.proxyIP}
+	}
+	proxy = cg.SetupProxy(proxy)
+	resolve := func(hostname string) string {
+		if hostname == "" {
+			return ""
+		}
+		push := cg.PushContext()
+		svc := push.ServiceForHostname(proxy, host.Name(hostname))
+		if svc == nil {
+			svc = push.ServiceForHostname(proxy, host.Name(extendFQDN(hostname)))
+		}
+		if svc == nil {
+			return ""
+		}
+		if addr := svc.GetServiceAddressForProxy(proxy, push); addr != constants.UnspecifiedIP {
+			return addr
+		}
+		return ""
+	}
+	return &simulationConfigGen{ConfigGenTest: cg, stop: stop}, proxy, resolve, nil
+}
+
+// readSimulationConfigs reads Istio configuration and Kubernetes Services from files or directories.
+func readSimulationConfigs(paths []string, ns, domainSuffix string) ([]config.Config, []*model.Service, error) {
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch filepath.Ext(path) {
+			case ".yaml", ".yml", ".json":
+				if !info.IsDir() {
+					files = append(files, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	var configs []config.Config
+	var services []*model.Service
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfgs, others, err := crd.ParseInputs(string(b))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", f, err)
+		}
+		for _, c := range cfgs {
+			if c.Namespace == "" {
+				c.Namespace = ns
+			}
+			// Short host names are resolved with the domain suffix.
+			c.Domain = domainSuffix
+			configs = append(configs, c)
+		}
+		for _, o := range others {
+			if o.Kind != "Service" {
+				continue
+			}
+			svc := v1.Service{}
+			js, err := json.Marshal(o)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := json.Unmarshal(js, &svc); err != nil {
+				return nil, nil, fmt.Errorf("failed to read Service %s from %s: %v", o.Name, f, err)
+			}
+			if svc.Namespace == "" {
+				svc.Namespace = ns
+			}
+			services = append(services, kube.ConvertService(svc, domainSuffix, ""))
+		}
+	}
+	return configs, services, nil
+}
+
+func (o *simulateOptions) configDump(args []string) ([]byte, error) {
+	if o.configDumpFile != "" {
+		return ioutil.ReadFile(o.configDumpFile)
+	}
+	podName, podNamespace, err := getPodName(args[0])
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	dump, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "config_dump", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on %s.%s sidecar: %v", podName, podNamespace, err)
+	}
+	return dump, nil
+}
+
+// simulationFromConfigDump reads the listeners, routes and clusters of an Envoy config dump.
+func simulationFromConfigDump(dump []byte) (*simulation.Simulation, error) {
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(dump); err != nil {
+		return nil, fmt.Errorf("failed to parse config dump: %v", err)
+	}
+	var listeners []*listener.Listener
+	ld, err := cd.GetListenerConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range ld.StaticListeners {
+		lt := &listener.Listener{}
+		l.Listener.TypeUrl = v3.ListenerType
+		if err := ptypes.UnmarshalAny(l.Listener, lt); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, lt)
+	}
+	for _, l := range ld.DynamicListeners {
+		if l.ActiveState == nil {
+			continue
+		}
+		lt := &listener.Listener{}
+		l.ActiveState.Listener.TypeUrl = v3.ListenerType
+		if err := ptypes.UnmarshalAny(l.ActiveState.Listener, lt); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, lt)
+	}
+
+	var routes []*route.RouteConfiguration
+	rd, err := cd.GetRouteConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rd.StaticRouteConfigs {
+		rt := &route.RouteConfiguration{}
+		r.RouteConfig.TypeUrl = v3.RouteType
+		if err := ptypes.UnmarshalAny(r.RouteConfig, rt); err != nil {
+			return nil, err
+		}
+		routes = append(routes, rt)
+	}
+	for _, r := range rd.DynamicRouteConfigs {
+		rt := &route.RouteConfiguration{}
+		r.RouteConfig.TypeUrl = v3.RouteType
+		if err := ptypes.UnmarshalAny(r.RouteConfig, rt); err != nil {
+			return nil, err
+		}
+		routes = append(routes, rt)
+	}
+
+	var clusters []*cluster.Cluster
+	cld, err := cd.GetClusterConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cld.StaticClusters {
+		ct := &cluster.Cluster{}
+		c.Cluster.TypeUrl = v3.ClusterType
+		if err := ptypes.UnmarshalAny(c.Cluster, ct); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, ct)
+	}
+	for _, c := range cld.DynamicActiveClusters {
+		ct := &cluster.Cluster{}
+		c.Cluster.TypeUrl = v3.ClusterType
+		if err := ptypes.UnmarshalAny(c.Cluster, ct); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, ct)
+	}
+	return simulation.NewSimulationFromResources(listeners, clusters, routes), nil
+}
+
+// simulationStep is a step of a simulated request, and the Istio configuration that produced it.
+type simulationStep struct {
+	Step    string `json:"step"`
+	Matched string `json:"matched"`
+	Config  string `json:"config,omitempty"`
+}
+
+type simulationOutput struct {
+	Steps []simulationStep `json:"steps"`
+	Error string           `json:"error,omitempty"`
+}
+
+func simulationSteps(trace simulation.Trace) simulationOutput {
+	out := simulationOutput{}
+	step := func(step, matched, cfg string) {
+		if matched == "" {
+			matched = "(unnamed)"
+		}
+		out.Steps = append(out.Steps, simulationStep{Step: step, Matched: matched, Config: cfg})
+	}
+	if trace.Listener != nil {
+		step("Listener", trace.Listener.Name, "")
+	}
+	if trace.FilterChain != nil {
+		cfg, _ := getIstioConfig(trace.FilterChain.Metadata)
+		step("Filter chain", trace.FilterChain.Name, cfg)
+	}
+	if trace.RouteConfig != nil {
+		step("Route config", trace.RouteConfig.Name, "")
+	}
+	if trace.VirtualHost != nil {
+		step("Virtual host", trace.VirtualHost.Name, "")
+	}
+	if trace.Route != nil {
+		cfg, _ := getIstioConfig(trace.Route.Metadata)
+		step("Route", trace.Route.Name, cfg)
+		switch action := trace.Route.Action.(type) {
+		case *route.Route_Route:
+			if wc := action.Route.GetWeightedClusters(); wc != nil {
+				for _, c := range wc.Clusters {
+					step("Cluster", fmt.Sprintf("%s (weight %d)", c.Name, c.GetWeight().GetValue()), "")
+				}
+			}
+		case *route.Route_Redirect:
+			step("Redirect", redirectTarget(action.Redirect), "")
+		case *route.Route_DirectResponse:
+			step("Direct response", fmt.Sprintf("status %d", action.DirectResponse.Status), "")
+		}
+	}
+	if trace.ClusterMatched != "" {
+		cfg := ""
+		if trace.Cluster != nil {
+			cfg, _ = getIstioConfig(trace.Cluster.Metadata)
+		}
+		step("Cluster", trace.ClusterMatched, cfg)
+	}
+	if trace.Error != nil {
+		out.Error = trace.Error.Error()
+	}
+	return out
+}
+
+func redirectTarget(r *route.RedirectAction) string {
+	var parts []string
+	if r.HostRedirect != "" {
+		parts = append(parts, "host "+r.HostRedirect)
+	}
+	if p := r.GetPathRedirect(); p != "" {
+		parts = append(parts, "path "+p)
+	}
+	if p := r.GetPrefixRewrite(); p != "" {
+		parts = append(parts, "prefix "+p)
+	}
+	if len(parts) == 0 {
+		return "same location"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printSimulationTrace(writer io.Writer, trace simulation.Trace) error {
+	out := simulationSteps(trace)
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STEP\tMATCHED\tCONFIG")
+	for _, s := range out.Steps {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", s.Step, s.Matched, s.Config)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if out.Error != "" {
+		_, _ = fmt.Fprintf(writer, "Error: %s\n", out.Error)
+	}
+	return nil
+}
+
+func printSimulationTraceJSON(w io.Writer, trace simulation.Trace) error {
+	b, err := json.MarshalIndent(simulationSteps(trace), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
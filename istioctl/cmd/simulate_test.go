@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	cases := []execTestCase{
+		{
+			args:          strings.Split("x simulate --port 9080", " "),
+			wantException: true,
+		},
+		{
+			args:          strings.Split("x simulate -f testdata/simulate/reviews.yaml --host reviews", " "),
+			wantException: true,
+		},
+		{
+			args:          strings.Split("x simulate -f testdata/simulate/reviews.yaml --port 9080 --protocol quic", " "),
+			wantException: true,
+		},
+		{
+			args: strings.Split("x simulate -f testdata/simulate/reviews.yaml -n default --host reviews --port 9080 "+
+				"--header end-user=jason", " "),
+			expectedString: "Route          jason                                                " +
+				"/apis/networking.istio.io/v1alpha3/namespaces/default/virtual-service/reviews",
+		},
+		{
+			args: strings.Split("x simulate -f testdata/simulate/reviews.yaml -n default --host reviews --port 9080", " "),
+			expectedString: "Cluster        outbound|9080|v1|reviews.default.svc.cluster.local   " +
+				"/apis/networking.istio.io/v1alpha3/namespaces/default/destination-rule/reviews",
+		},
+		{
+			args: strings.Split("x simulate -f testdata/simulate --gateway -l istio=ingressgateway -n istio-system "+
+				"--host bookinfo.example.com --port 8080 --path /productpage?q=1", " "),
+			expectedString: "Cluster        outbound|9080||reviews.default.svc.cluster.local",
+		},
+		{
+			args: strings.Split("x simulate -f testdata/simulate --gateway -l istio=ingressgateway -n istio-system "+
+				"--host bookinfo.example.com --port 8080 --path /other -o json", " "),
+			expectedString: `"error": "no route matched"`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accesslog parses the Envoy access logs written by Istio proxies, in either the default text
+// format or the JSON encoding, as configured in pilot/pkg/networking/core/v1alpha3/accesslog.go.
+package accesslog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotAccessLog is returned for lines which are not Envoy access log entries, such as the logs of the
+// Istio agent, which share the istio-proxy container output.
+var ErrNotAccessLog = errors.New("not an access log entry")
+
+// Entry is a single parsed access log entry. Fields which Envoy logged as "-" are left empty.
+type Entry struct {
+	StartTime                      time.Time     `json:"start_time"`
+	Method                         string        `json:"method,omitempty"`
+	Path                           string        `json:"path,omitempty"`
+	Protocol                       string        `json:"protocol,omitempty"`
+	ResponseCode                   int           `json:"response_code"`
+	ResponseFlags                  []string      `json:"response_flags,omitempty"`
+	UpstreamTransportFailureReason string        `json:"upstream_transport_failure_reason,omitempty"`
+	BytesReceived                  int64         `json:"bytes_received"`
+	BytesSent                      int64         `json:"bytes_sent"`
+	Duration                       time.Duration `json:"duration"`
+	UpstreamServiceTime            string        `json:"upstream_service_time,omitempty"`
+	XForwardedFor                  string        `json:"x_forwarded_for,omitempty"`
+	UserAgent                      string        `json:"user_agent,omitempty"`
+	RequestID                      string        `json:"request_id,omitempty"`
+	Authority                      string        `json:"authority,omitempty"`
+	UpstreamHost                   string        `json:"upstream_host,omitempty"`
+	UpstreamCluster                string        `json:"upstream_cluster,omitempty"`
+	UpstreamLocalAddress           string        `json:"upstream_local_address,omitempty"`
+	DownstreamLocalAddress         string        `json:"downstream_local_address,omitempty"`
+	DownstreamRemoteAddress        string        `json:"downstream_remote_address,omitempty"`
+	RequestedServerName            string        `json:"requested_server_name,omitempty"`
+	RouteName                      string        `json:"route_name,omitempty"`
+}
+
+// Parse parses a line of the istio-proxy container output. Lines which are not access log entries return
+// ErrNotAccessLog.
+func Parse(line string) (*Entry, error) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "["):
+		return parseText(line)
+	case strings.HasPrefix(line, "{"):
+		return parseJSON(line)
+	}
+	return nil, ErrNotAccessLog
+}
+
+// The fields of EnvoyTextLogFormat, in order. Quoted fields and the bracketed start time are a single field.
+const (
+	textStartTime = iota
+	textRequest
+	textResponseCode
+	textResponseFlags
+	textUpstreamTransportFailureReason
+	textBytesReceived
+	textBytesSent
+	textDuration
+	textUpstreamServiceTime
+	textXForwardedFor
+	textUserAgent
+	textRequestID
+	textAuthority
+	textUpstreamHost
+	textUpstreamCluster
+	textUpstreamLocalAddress
+	textDownstreamLocalAddress
+	textDownstreamRemoteAddress
+	textRequestedServerName
+	textRouteName
+	// Proxies older than 1.8 do not log the route name.
+	textMinFields = textRouteName
+)
+
+func parseText(line string) (*Entry, error) {
+	fields, err := splitTextFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < textMinFields {
+		return nil, ErrNotAccessLog
+	}
+	field := func(i int) string {
+		if i >= len(fields) || fields[i] == "-" {
+			return ""
+		}
+		return fields[i]
+	}
+	e := &Entry{
+		UpstreamTransportFailureReason: field(textUpstreamTransportFailureReason),
+		UpstreamServiceTime:            field(textUpstreamServiceTime),
+		XForwardedFor:                  field(textXForwardedFor),
+		UserAgent:                      field(textUserAgent),
+		RequestID:                      field(textRequestID),
+		Authority:                      field(textAuthority),
+		UpstreamHost:                   field(textUpstreamHost),
+		UpstreamCluster:                field(textUpstreamCluster),
+		UpstreamLocalAddress:           field(textUpstreamLocalAddress),
+		DownstreamLocalAddress:         field(textDownstreamLocalAddress),
+		DownstreamRemoteAddress:        field(textDownstreamRemoteAddress),
+		RequestedServerName:            field(textRequestedServerName),
+		RouteName:                      field(textRouteName),
+	}
+	if request := strings.Fields(fields[textRequest]); len(request) == 3 {
+		e.Method, e.Path, e.Protocol = dash(request[0]), dash(request[1]), dash(request[2])
+	}
+	if err := e.setCommon(fields[textStartTime], fields[textResponseCode], fields[textResponseFlags],
+		fields[textBytesReceived], fields[textBytesSent], fields[textDuration]); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// splitTextFields splits a text access log line on spaces, keeping quoted and bracketed values together.
+func splitTextFields(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+			continue
+		case '[', '"':
+			end := byte(']')
+			if line[i] == '"' {
+				end = '"'
+			}
+			j := strings.IndexByte(line[i+1:], end)
+			if j < 0 {
+				return nil, ErrNotAccessLog
+			}
+			fields = append(fields, line[i+1:i+1+j])
+			i += j + 2
+		default:
+			j := strings.IndexByte(line[i:], ' ')
+			if j < 0 {
+				j = len(line) - i
+			}
+			fields = append(fields, line[i:i+j])
+			i += j
+		}
+	}
+	return fields, nil
+}
+
+func parseJSON(line string) (*Entry, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return nil, ErrNotAccessLog
+	}
+	if _, f := raw["start_time"]; !f {
+		return nil, ErrNotAccessLog
+	}
+	field := func(key string) string {
+		v, f := raw[key]
+		if !f || v == nil {
+			return ""
+		}
+		s := fmt.Sprint(v)
+		if n, ok := v.(float64); ok {
+			s = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+		return dash(s)
+	}
+	e := &Entry{
+		Method:                         field("method"),
+		Path:                           field("path"),
+		Protocol:                       field("protocol"),
+		UpstreamTransportFailureReason: field("upstream_transport_failure_reason"),
+		UpstreamServiceTime:            field("upstream_service_time"),
+		XForwardedFor:                  field("x_forwarded_for"),
+		UserAgent:                      field("user_agent"),
+		RequestID:                      field("request_id"),
+		Authority:                      field("authority"),
+		UpstreamHost:                   field("upstream_host"),
+		UpstreamCluster:                field("upstream_cluster"),
+		UpstreamLocalAddress:           field("upstream_local_address"),
+		DownstreamLocalAddress:         field("downstream_local_address"),
+		DownstreamRemoteAddress:        field("downstream_remote_address"),
+		RequestedServerName:            field("requested_server_name"),
+		RouteName:                      field("route_name"),
+	}
+	if err := e.setCommon(field("start_time"), field("response_code"), field("response_flags"),
+		field("bytes_received"), field("bytes_sent"), field("duration")); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// setCommon parses the fields which are not plain strings, and which every entry must have.
+func (e *Entry) setCommon(startTime, responseCode, responseFlags, bytesReceived, bytesSent, duration string) error {
+	var err error
+	if e.StartTime, err = time.Parse(time.RFC3339Nano, startTime); err != nil {
+		return ErrNotAccessLog
+	}
+	if e.ResponseCode, err = atoi(responseCode); err != nil {
+		return fmt.Errorf("invalid response code %q: %v", responseCode, err)
+	}
+	if flags := dash(responseFlags); flags != "" {
+		e.ResponseFlags = strings.Split(flags, ",")
+	}
+	if e.BytesReceived, err = atoi64(bytesReceived); err != nil {
+		return fmt.Errorf("invalid bytes received %q: %v", bytesReceived, err)
+	}
+	if e.BytesSent, err = atoi64(bytesSent); err != nil {
+		return fmt.Errorf("invalid bytes sent %q: %v", bytesSent, err)
+	}
+	ms, err := atoi64(duration)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %v", duration, err)
+	}
+	e.Duration = time.Duration(ms) * time.Millisecond
+	return nil
+}
+
+func dash(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func atoi(s string) (int, error) {
+	if s = dash(s); s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func atoi64(s string) (int64, error) {
+	if s = dash(s); s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Filter selects access log entries. Empty fields match every entry.
+type Filter struct {
+	// ResponseCodes are exact codes such as "503", or classes such as "5xx".
+	ResponseCodes []string
+	// ResponseFlags match entries with any of the flags, such as "UH" or "UF".
+	ResponseFlags []string
+	// UpstreamCluster matches entries whose upstream cluster contains it.
+	UpstreamCluster string
+	MinDuration     time.Duration
+	MaxDuration     time.Duration
+}
+
+// Validate checks that the response codes of the filter are well formed.
+func (f Filter) Validate() error {
+	for _, code := range f.ResponseCodes {
+		if len(code) == 3 && code[0] >= '1' && code[0] <= '5' && strings.EqualFold(code[1:], "xx") {
+			continue
+		}
+		if _, err := strconv.Atoi(code); err != nil {
+			return fmt.Errorf("invalid response code %q, must be a code such as 503 or a class such as 5xx", code)
+		}
+	}
+	if f.MaxDuration > 0 && f.MinDuration > f.MaxDuration {
+		return fmt.Errorf("minimum duration %v is greater than maximum duration %v", f.MinDuration, f.MaxDuration)
+	}
+	return nil
+}
+
+// Matches returns true if e is selected by the filter.
+func (f Filter) Matches(e *Entry) bool {
+	if len(f.ResponseCodes) > 0 && !f.matchesCode(e.ResponseCode) {
+		return false
+	}
+	if len(f.ResponseFlags) > 0 && !f.matchesFlags(e.ResponseFlags) {
+		return false
+	}
+	if f.UpstreamCluster != "" && !strings.Contains(e.UpstreamCluster, f.UpstreamCluster) {
+		return false
+	}
+	if e.Duration < f.MinDuration {
+		return false
+	}
+	if f.MaxDuration > 0 && e.Duration > f.MaxDuration {
+		return false
+	}
+	return true
+}
+
+func (f Filter) matchesCode(code int) bool {
+	s := strconv.Itoa(code)
+	for _, want := range f.ResponseCodes {
+		if want == s || (len(want) == 3 && strings.EqualFold(want[1:], "xx") && want[0] == s[0] && len(s) == 3) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) matchesFlags(flags []string) bool {
+	for _, want := range f.ResponseFlags {
+		for _, flag := range flags {
+			if flag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
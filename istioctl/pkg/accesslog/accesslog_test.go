@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accesslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	httpLine = `[2020-11-20T10:00:00.123Z] "GET /productpage HTTP/1.1" 503 UF,URX "-" 0 91 43 - "-" "curl/7.64.0" ` +
+		`"c2b9e6e6-0e56-9a3d-a1b3-4a1f6ac0e8e1" "productpage:9080" "10.44.1.7:9080" ` +
+		`outbound|9080||productpage.default.svc.cluster.local 10.44.0.9:51740 10.0.0.12:9080 10.44.0.9:38862 - default`
+	tcpLine = `[2020-11-20T10:00:01.000Z] "- - -" 0 - "-" 1024 2048 5012 - "-" "-" "-" "-" "10.44.1.8:3306" ` +
+		`outbound|3306||mysql.default.svc.cluster.local 10.44.0.9:40100 10.0.0.13:3306 10.44.0.9:40098 - -`
+	jsonLine = `{"start_time":"2020-11-20T10:00:02.000Z","method":"POST","path":"/api","protocol":"HTTP/2",` +
+		`"response_code":200,"response_flags":"-","bytes_received":"12","bytes_sent":"34","duration":"7",` +
+		`"upstream_service_time":"6","upstream_cluster":"inbound|8080||","upstream_host":"127.0.0.1:8080",` +
+		`"authority":"reviews:8080","route_name":"default"}`
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		want *Entry
+		err  error
+	}{
+		{
+			name: "http",
+			line: httpLine,
+			want: &Entry{
+				StartTime:               time.Date(2020, 11, 20, 10, 0, 0, 123000000, time.UTC),
+				Method:                  "GET",
+				Path:                    "/productpage",
+				Protocol:                "HTTP/1.1",
+				ResponseCode:            503,
+				ResponseFlags:           []string{"UF", "URX"},
+				BytesSent:               91,
+				Duration:                43 * time.Millisecond,
+				UserAgent:               "curl/7.64.0",
+				RequestID:               "c2b9e6e6-0e56-9a3d-a1b3-4a1f6ac0e8e1",
+				Authority:               "productpage:9080",
+				UpstreamHost:            "10.44.1.7:9080",
+				UpstreamCluster:         "outbound|9080||productpage.default.svc.cluster.local",
+				UpstreamLocalAddress:    "10.44.0.9:51740",
+				DownstreamLocalAddress:  "10.0.0.12:9080",
+				DownstreamRemoteAddress: "10.44.0.9:38862",
+				RouteName:               "default",
+			},
+		},
+		{
+			name: "tcp",
+			line: tcpLine,
+			want: &Entry{
+				StartTime:               time.Date(2020, 11, 20, 10, 0, 1, 0, time.UTC),
+				BytesReceived:           1024,
+				BytesSent:               2048,
+				Duration:                5012 * time.Millisecond,
+				UpstreamHost:            "10.44.1.8:3306",
+				UpstreamCluster:         "outbound|3306||mysql.default.svc.cluster.local",
+				UpstreamLocalAddress:    "10.44.0.9:40100",
+				DownstreamLocalAddress:  "10.0.0.13:3306",
+				DownstreamRemoteAddress: "10.44.0.9:40098",
+			},
+		},
+		{
+			name: "json",
+			line: jsonLine,
+			want: &Entry{
+				StartTime:           time.Date(2020, 11, 20, 10, 0, 2, 0, time.UTC),
+				Method:              "POST",
+				Path:                "/api",
+				Protocol:            "HTTP/2",
+				ResponseCode:        200,
+				BytesReceived:       12,
+				BytesSent:           34,
+				Duration:            7 * time.Millisecond,
+				UpstreamServiceTime: "6",
+				Authority:           "reviews:8080",
+				UpstreamHost:        "127.0.0.1:8080",
+				UpstreamCluster:     "inbound|8080||",
+				RouteName:           "default",
+			},
+		},
+		{
+			name: "agent log",
+			line: `2020-11-20T10:00:00.000000Z	info	Envoy proxy is ready`,
+			err:  ErrNotAccessLog,
+		},
+		{
+			name: "envoy log",
+			line: `[Envoy (Epoch 0)] [2020-11-20 10:00:00.000][15][warning][config] gRPC config stream closed`,
+			err:  ErrNotAccessLog,
+		},
+		{
+			name: "unrelated json",
+			line: `{"level":"info","msg":"starting"}`,
+			err:  ErrNotAccessLog,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entry, err := Parse(httpLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"exact code", Filter{ResponseCodes: []string{"404", "503"}}, true},
+		{"code class", Filter{ResponseCodes: []string{"5xx"}}, true},
+		{"other code class", Filter{ResponseCodes: []string{"2xx"}}, false},
+		{"flag", Filter{ResponseFlags: []string{"UH", "URX"}}, true},
+		{"other flag", Filter{ResponseFlags: []string{"NR"}}, false},
+		{"cluster", Filter{UpstreamCluster: "productpage.default"}, true},
+		{"other cluster", Filter{UpstreamCluster: "reviews"}, false},
+		{"min duration", Filter{MinDuration: 40 * time.Millisecond}, true},
+		{"min duration too long", Filter{MinDuration: 50 * time.Millisecond}, false},
+		{"max duration", Filter{MaxDuration: 40 * time.Millisecond}, false},
+		{"all", Filter{ResponseCodes: []string{"5xx"}, ResponseFlags: []string{"UF"}, UpstreamCluster: "outbound",
+			MinDuration: time.Millisecond, MaxDuration: time.Second}, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.filter.Matches(entry); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	for _, f := range []Filter{
+		{ResponseCodes: []string{"fivehundred"}},
+		{ResponseCodes: []string{"6xx"}},
+		{MinDuration: time.Second, MaxDuration: time.Millisecond},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", f)
+		}
+	}
+}
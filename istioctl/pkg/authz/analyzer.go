@@ -23,9 +23,11 @@ import (
 
 	envoy_admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	"github.com/golang/protobuf/ptypes"
 
 	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 )
 
@@ -46,6 +48,39 @@ func NewAnalyzer(envoyConfig *configdump.Wrapper) (*Analyzer, error) {
 
 // Print print sthe analyze results.
 func (a *Analyzer) Print(writer io.Writer) {
+	listeners, err := a.listeners()
+	if err != nil {
+		return
+	}
+	Print(writer, listeners)
+}
+
+// Evaluate evaluates the request against the RBAC filters of the inbound filter chain that
+// handles it.
+func (a *Analyzer) Evaluate(req *Request) (*Decision, error) {
+	listeners, err := a.listeners()
+	if err != nil {
+		return nil, err
+	}
+	fc := selectFilterChain(listeners, req)
+	if fc == nil {
+		return nil, fmt.Errorf("no inbound filter chain found for port %d", req.DestinationPort)
+	}
+	parsed := parse([]*listener.Listener{{FilterChains: []*listener.FilterChain{fc}}})[0].filterChains[0]
+	var filters []*rbacpb.RBAC
+	if parsed.http {
+		for _, f := range parsed.rbacHTTP {
+			filters = append(filters, f.GetRules())
+		}
+	} else {
+		for _, f := range parsed.rbacTCP {
+			filters = append(filters, f.GetRules())
+		}
+	}
+	return Evaluate(filters, req)
+}
+
+func (a *Analyzer) listeners() ([]*listener.Listener, error) {
 	var listeners []*listener.Listener
 	for _, l := range a.listenerDump.DynamicListeners {
 		listenerTyped := &listener.Listener{}
@@ -53,9 +88,68 @@ func (a *Analyzer) Print(writer io.Writer) {
 		l.ActiveState.Listener.TypeUrl = v3.ListenerType
 		err := ptypes.UnmarshalAny(l.ActiveState.Listener, listenerTyped)
 		if err != nil {
-			return
+			return nil, err
 		}
 		listeners = append(listeners, listenerTyped)
 	}
-	Print(writer, listeners)
+	return listeners, nil
+}
+
+// selectFilterChain returns the filter chain of the virtual inbound listener that handles the
+// request, preferring the chains that match on its destination port. Workloads without a virtual
+// inbound listener, like gateways, use the listener bound to the destination port.
+func selectFilterChain(listeners []*listener.Listener, req *Request) *listener.FilterChain {
+	transport := "raw_buffer"
+	if req.SourcePrincipal != "" {
+		transport = "tls"
+	}
+	matches := func(fc *listener.FilterChain, portMatch bool) bool {
+		m := fc.GetFilterChainMatch()
+		if portMatch != (m.GetDestinationPort() != nil) {
+			return false
+		}
+		if portMatch && m.GetDestinationPort().GetValue() != req.DestinationPort {
+			return false
+		}
+		if m.GetTransportProtocol() != "" && m.GetTransportProtocol() != transport {
+			return false
+		}
+		if len(m.GetServerNames()) > 0 && !containsString(m.GetServerNames(), req.SNI) {
+			return false
+		}
+		return true
+	}
+
+	for _, l := range listeners {
+		if l.Name != v1alpha3.VirtualInboundListenerName {
+			continue
+		}
+		for _, portMatch := range []bool{true, false} {
+			for _, fc := range l.FilterChains {
+				if matches(fc, portMatch) {
+					return fc
+				}
+			}
+		}
+	}
+	for _, l := range listeners {
+		if l.GetAddress().GetSocketAddress().GetPortValue() != req.DestinationPort {
+			continue
+		}
+		for _, fc := range l.FilterChains {
+			if matches(fc, false) {
+				return fc
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,342 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+
+	"istio.io/istio/pilot/pkg/simulation/match"
+	"istio.io/pkg/log"
+)
+
+// PolicyResult is the result of evaluating a single rule of an AuthorizationPolicy.
+type PolicyResult struct {
+	Action  rbacpb.RBAC_Action
+	Policy  string
+	Rule    string
+	Matched bool
+}
+
+// Decision is the result of evaluating a request against the RBAC filters of a workload.
+type Decision struct {
+	Allowed bool
+	// Policy and Rule are the AuthorizationPolicy and rule index that decided the request. Both are
+	// empty if no policy decided it.
+	Policy string
+	Rule   string
+	// Reason explains the decision.
+	Reason string
+	// Results lists every rule that was evaluated, in evaluation order.
+	Results []PolicyResult
+}
+
+// evaluator matches the rules of RBAC filters against a request. It keeps the first error
+// returned by a matcher, such as an invalid regex.
+type evaluator struct {
+	req *Request
+	err error
+}
+
+// Evaluate evaluates the request against the given RBAC rules, in the order the filters appear
+// in the filter chain. Filters with only shadow rules, like those used for the CUSTOM action,
+// are not enforced and are skipped. An error is returned if a rule cannot be evaluated.
+func Evaluate(filters []*rbacpb.RBAC, req *Request) (*Decision, error) {
+	e := &evaluator{req: req}
+	d := &Decision{}
+	var allowedBy *PolicyResult
+	for _, rules := range filters {
+		if rules == nil {
+			continue
+		}
+		matched := e.evaluateRules(rules, d)
+		if e.err != nil {
+			return nil, e.err
+		}
+		switch rules.GetAction() {
+		case rbacpb.RBAC_DENY:
+			if matched != nil {
+				d.Policy, d.Rule = matched.Policy, matched.Rule
+				d.Reason = "denied by DENY policy"
+				return d, nil
+			}
+		case rbacpb.RBAC_ALLOW:
+			if matched == nil {
+				d.Reason = "no ALLOW policy matched"
+				return d, nil
+			}
+			allowedBy = matched
+		}
+	}
+
+	d.Allowed = true
+	if allowedBy != nil {
+		d.Policy, d.Rule = allowedBy.Policy, allowedBy.Rule
+		d.Reason = "allowed by ALLOW policy"
+	} else {
+		d.Reason = "no policy denied the request"
+	}
+	return d, nil
+}
+
+// evaluateRules evaluates the policies of a RBAC filter in name order, like Envoy does, and
+// returns the first one that matched.
+func (e *evaluator) evaluateRules(rules *rbacpb.RBAC, d *Decision) *PolicyResult {
+	names := make([]string, 0, len(rules.GetPolicies()))
+	for name := range rules.GetPolicies() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var first *PolicyResult
+	for _, name := range names {
+		p := rules.GetPolicies()[name]
+		result := PolicyResult{Action: rules.GetAction()}
+		result.Policy, result.Rule = extractName(name)
+		if result.Policy == "" {
+			result.Policy = name
+		}
+		result.Matched = e.anyPermission(p.GetPermissions()) && e.anyPrincipal(p.GetPrincipals())
+		d.Results = append(d.Results, result)
+		if result.Matched && first == nil {
+			first = &d.Results[len(d.Results)-1]
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	found := *first
+	return &found
+}
+
+// PrintDecision prints the decision and the rules evaluated to reach it.
+func PrintDecision(writer io.Writer, d *Decision) {
+	buf := strings.Builder{}
+	decision := "DENY"
+	if d.Allowed {
+		decision = "ALLOW"
+	}
+	buf.WriteString(fmt.Sprintf("DECISION: %s (%s)\n", decision, d.Reason))
+	if d.Policy != "" {
+		buf.WriteString(fmt.Sprintf("POLICY: %s\nRULE: %s\n", d.Policy, d.Rule))
+	}
+	if len(d.Results) > 0 {
+		buf.WriteString("\nACTION\tAuthorizationPolicy\tRULE\tMATCHED\n")
+		for _, r := range d.Results {
+			buf.WriteString(fmt.Sprintf("%s\t%s\t%s\t%t\n", r.Action, r.Policy, r.Rule, r.Matched))
+		}
+	}
+
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 3, ' ', 0)
+	if _, err := fmt.Fprint(w, buf.String()); err != nil {
+		log.Errorf("failed to print output: %s", err)
+	}
+	_ = w.Flush()
+}
+
+func (e *evaluator) anyPermission(ps []*rbacpb.Permission) bool {
+	for _, p := range ps {
+		if e.matchPermission(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *evaluator) anyPrincipal(ps []*rbacpb.Principal) bool {
+	for _, p := range ps {
+		if e.matchPrincipal(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *evaluator) matchPermission(p *rbacpb.Permission) bool {
+	switch r := p.GetRule().(type) {
+	case *rbacpb.Permission_Any:
+		return r.Any
+	case *rbacpb.Permission_AndRules:
+		for _, sub := range r.AndRules.GetRules() {
+			if !e.matchPermission(sub) {
+				return false
+			}
+		}
+		return true
+	case *rbacpb.Permission_OrRules:
+		return e.anyPermission(r.OrRules.GetRules())
+	case *rbacpb.Permission_NotRule:
+		return !e.matchPermission(r.NotRule)
+	case *rbacpb.Permission_Header:
+		return e.matchHeader(r.Header)
+	case *rbacpb.Permission_UrlPath:
+		return e.req.Path != "" && e.matchString(r.UrlPath.GetPath(), e.req.urlPath())
+	case *rbacpb.Permission_DestinationIp:
+		return matchCidr(r.DestinationIp, e.req.DestinationIP)
+	case *rbacpb.Permission_DestinationPort:
+		return r.DestinationPort == e.req.DestinationPort
+	case *rbacpb.Permission_RequestedServerName:
+		return e.matchString(r.RequestedServerName, e.req.SNI)
+	case *rbacpb.Permission_Metadata:
+		return e.matchMetadata(r.Metadata)
+	default:
+		return false
+	}
+}
+
+func (e *evaluator) matchPrincipal(p *rbacpb.Principal) bool {
+	switch id := p.GetIdentifier().(type) {
+	case *rbacpb.Principal_Any:
+		return id.Any
+	case *rbacpb.Principal_AndIds:
+		for _, sub := range id.AndIds.GetIds() {
+			if !e.matchPrincipal(sub) {
+				return false
+			}
+		}
+		return true
+	case *rbacpb.Principal_OrIds:
+		return e.anyPrincipal(id.OrIds.GetIds())
+	case *rbacpb.Principal_NotId:
+		return !e.matchPrincipal(id.NotId)
+	case *rbacpb.Principal_Authenticated_:
+		if e.req.SourcePrincipal == "" {
+			return false
+		}
+		if id.Authenticated.GetPrincipalName() == nil {
+			return true
+		}
+		return e.matchString(id.Authenticated.GetPrincipalName(), e.req.peerURI())
+	case *rbacpb.Principal_SourceIp:
+		return matchCidr(id.SourceIp, e.req.SourceIP)
+	case *rbacpb.Principal_DirectRemoteIp:
+		return matchCidr(id.DirectRemoteIp, e.req.SourceIP)
+	case *rbacpb.Principal_RemoteIp:
+		return matchCidr(id.RemoteIp, e.req.remoteIP())
+	case *rbacpb.Principal_Header:
+		return e.matchHeader(id.Header)
+	case *rbacpb.Principal_UrlPath:
+		return e.req.Path != "" && e.matchString(id.UrlPath.GetPath(), e.req.urlPath())
+	case *rbacpb.Principal_Metadata:
+		return e.matchMetadata(id.Metadata)
+	default:
+		return false
+	}
+}
+
+func (e *evaluator) matchHeader(h *route.HeaderMatcher) bool {
+	v, ok := e.req.header(h.GetName())
+	if !ok {
+		// Like Envoy, a missing header only matches an inverted matcher.
+		return h.GetInvertMatch()
+	}
+	var matched bool
+	switch m := h.GetHeaderMatchSpecifier().(type) {
+	case *route.HeaderMatcher_PresentMatch, nil:
+		matched = true
+	case *route.HeaderMatcher_ExactMatch:
+		matched = v == m.ExactMatch
+	case *route.HeaderMatcher_PrefixMatch:
+		matched = strings.HasPrefix(v, m.PrefixMatch)
+	case *route.HeaderMatcher_SuffixMatch:
+		matched = strings.HasSuffix(v, m.SuffixMatch)
+	case *route.HeaderMatcher_ContainsMatch:
+		matched = strings.Contains(v, m.ContainsMatch)
+	case *route.HeaderMatcher_SafeRegexMatch:
+		matched = e.check(match.Regex(m.SafeRegexMatch.GetRegex(), v))
+	default:
+		return false
+	}
+	return matched != h.GetInvertMatch()
+}
+
+func (e *evaluator) matchString(m *matcher.StringMatcher, v string) bool {
+	return e.check(match.String(m, v))
+}
+
+// check returns the result of a matcher. A matcher that cannot be evaluated does not match, and
+// its error is reported by Evaluate.
+func (e *evaluator) check(matched bool, err error) bool {
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return matched
+}
+
+func matchCidr(cidr *core.CidrRange, ip string) bool {
+	addr := net.ParseIP(ip)
+	if cidr == nil || addr == nil {
+		return false
+	}
+	bits := 32
+	if net.ParseIP(cidr.GetAddressPrefix()).To4() == nil {
+		bits = 128
+	}
+	prefixLen := bits
+	if cidr.GetPrefixLen() != nil {
+		prefixLen = int(cidr.GetPrefixLen().GetValue())
+	}
+	_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", cidr.GetAddressPrefix(), prefixLen))
+	if err != nil {
+		return false
+	}
+	return network.Contains(addr)
+}
+
+func (e *evaluator) matchMetadata(m *matcher.MetadataMatcher) bool {
+	var v interface{} = e.req.metadata()[m.GetFilter()]
+	for _, segment := range m.GetPath() {
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = fields[segment.GetKey()]; !ok {
+			return false
+		}
+	}
+	return e.matchValue(m.GetValue(), v)
+}
+
+func (e *evaluator) matchValue(m *matcher.ValueMatcher, v interface{}) bool {
+	switch p := m.GetMatchPattern().(type) {
+	case *matcher.ValueMatcher_PresentMatch:
+		return p.PresentMatch
+	case *matcher.ValueMatcher_StringMatch:
+		s, ok := v.(string)
+		return ok && e.matchString(p.StringMatch, s)
+	case *matcher.ValueMatcher_ListMatch:
+		list, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if e.matchValue(p.ListMatch.GetOneOf(), item) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
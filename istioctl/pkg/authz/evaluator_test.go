@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/security/authz/matcher"
+	sm "istio.io/istio/pilot/pkg/security/model"
+)
+
+func policy(permission *rbacpb.Permission, principal *rbacpb.Principal) *rbacpb.Policy {
+	return &rbacpb.Policy{
+		Permissions: []*rbacpb.Permission{permission},
+		Principals:  []*rbacpb.Principal{principal},
+	}
+}
+
+var (
+	anyPermissions = &rbacpb.Permission{Rule: &rbacpb.Permission_Any{Any: true}}
+	anyPrincipals  = &rbacpb.Principal{Identifier: &rbacpb.Principal_Any{Any: true}}
+)
+
+func TestEvaluate(t *testing.T) {
+	deny := &rbacpb.RBAC{
+		Action: rbacpb.RBAC_DENY,
+		Policies: map[string]*rbacpb.Policy{
+			"ns[foo]-policy[deny-ip]-rule[0]": policy(anyPermissions, &rbacpb.Principal{
+				Identifier: &rbacpb.Principal_DirectRemoteIp{DirectRemoteIp: &core.CidrRange{
+					AddressPrefix: "10.0.0.0",
+					PrefixLen:     &wrappers.UInt32Value{Value: 8},
+				}},
+			}),
+		},
+	}
+	allow := &rbacpb.RBAC{
+		Action: rbacpb.RBAC_ALLOW,
+		Policies: map[string]*rbacpb.Policy{
+			"ns[foo]-policy[allow-header]-rule[0]": policy(anyPermissions, &rbacpb.Principal{
+				Identifier: &rbacpb.Principal_Header{Header: matcher.HeaderMatcher("x-user", "admin-*")},
+			}),
+			"ns[foo]-policy[allow-principal]-rule[1]": policy(
+				&rbacpb.Permission{Rule: &rbacpb.Permission_UrlPath{UrlPath: matcher.PathMatcher("/public")}},
+				&rbacpb.Principal{Identifier: &rbacpb.Principal_Metadata{
+					Metadata: matcher.MetadataStringMatcher(sm.AuthnFilterName, "source.principal",
+						matcher.StringMatcherRegex(".*/ns/bar/.*")),
+				}}),
+		},
+	}
+
+	cases := []struct {
+		name    string
+		filters []*rbacpb.RBAC
+		req     *Request
+		allowed bool
+		policy  string
+		rule    string
+	}{
+		{
+			name:    "no filters",
+			req:     &Request{},
+			allowed: true,
+		},
+		{
+			name:    "denied by ip",
+			filters: []*rbacpb.RBAC{deny, allow},
+			req:     &Request{SourceIP: "10.1.2.3", Headers: map[string]string{"X-User": "admin-alice"}},
+			policy:  "deny-ip.foo",
+			rule:    "0",
+		},
+		{
+			name:    "allowed by header",
+			filters: []*rbacpb.RBAC{deny, allow},
+			req:     &Request{SourceIP: "192.168.0.1", Headers: map[string]string{"X-User": "admin-alice"}},
+			allowed: true,
+			policy:  "allow-header.foo",
+			rule:    "0",
+		},
+		{
+			name:    "allowed by principal and path ignoring query",
+			filters: []*rbacpb.RBAC{deny, allow},
+			req:     &Request{SourcePrincipal: "cluster.local/ns/bar/sa/default", Path: "/public?a=b"},
+			allowed: true,
+			policy:  "allow-principal.foo",
+			rule:    "1",
+		},
+		{
+			name:    "no allow policy matched",
+			filters: []*rbacpb.RBAC{deny, allow},
+			req:     &Request{SourcePrincipal: "cluster.local/ns/baz/sa/default", Path: "/public"},
+		},
+		{
+			name:    "shadow only filter is skipped",
+			filters: []*rbacpb.RBAC{nil},
+			req:     &Request{},
+			allowed: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := Evaluate(tc.filters, tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Allowed != tc.allowed || d.Policy != tc.policy || d.Rule != tc.rule {
+				t.Errorf("got allowed %v by %q rule %q (%s), want allowed %v by %q rule %q",
+					d.Allowed, d.Policy, d.Rule, d.Reason, tc.allowed, tc.policy, tc.rule)
+			}
+		})
+	}
+}
+
+func TestMatchHeader(t *testing.T) {
+	req := &Request{Host: "httpbin.foo", Method: "GET", Headers: map[string]string{"X-Env": "prod"}}
+	cases := []struct {
+		name    string
+		header  *route.HeaderMatcher
+		matched bool
+	}{
+		{"authority", matcher.HeaderMatcher(":authority", "httpbin.*"), true},
+		{"method", matcher.HeaderMatcher(":method", "POST"), false},
+		{"case insensitive name", matcher.HeaderMatcher("x-env", "prod"), true},
+		{"present", matcher.HeaderMatcher("x-env", "*"), true},
+		{"missing", matcher.HeaderMatcher("x-missing", "*"), false},
+		{"inverted missing", &route.HeaderMatcher{Name: "x-missing", InvertMatch: true,
+			HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "v"}}, true},
+		{"inverted", &route.HeaderMatcher{Name: "x-env", InvertMatch: true,
+			HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "prod"}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &evaluator{req: req}
+			if got := e.matchHeader(tc.header); got != tc.matched || e.err != nil {
+				t.Errorf("got %v (error %v), want %v", got, e.err, tc.matched)
+			}
+		})
+	}
+}
+
+func TestEvaluateInvalidRegex(t *testing.T) {
+	filters := []*rbacpb.RBAC{{
+		Action: rbacpb.RBAC_ALLOW,
+		Policies: map[string]*rbacpb.Policy{
+			"ns[foo]-policy[allow-regex]-rule[0]": policy(anyPermissions, &rbacpb.Principal{
+				Identifier: &rbacpb.Principal_Header{Header: &route.HeaderMatcher{
+					Name:                 "x-user",
+					HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: matcher.StringMatcherRegex("(").GetSafeRegex()},
+				}},
+			}),
+		},
+	}}
+	if _, err := Evaluate(filters, &Request{Headers: map[string]string{"x-user": "admin"}}); err == nil {
+		t.Fatalf("expected an error for an invalid regex")
+	}
+}
+
+func TestSelectFilterChain(t *testing.T) {
+	chain := func(name string, port uint32, transport string) *listener.FilterChain {
+		fc := &listener.FilterChain{Name: name, FilterChainMatch: &listener.FilterChainMatch{TransportProtocol: transport}}
+		if port != 0 {
+			fc.FilterChainMatch.DestinationPort = &wrappers.UInt32Value{Value: port}
+		}
+		return fc
+	}
+	listeners := []*listener.Listener{
+		{
+			Name: v1alpha3.VirtualInboundListenerName,
+			FilterChains: []*listener.FilterChain{
+				chain("passthrough-tls", 0, "tls"),
+				chain("passthrough", 0, "raw_buffer"),
+				chain("80-tls", 80, "tls"),
+				chain("80", 80, "raw_buffer"),
+			},
+		},
+	}
+	cases := []struct {
+		req  *Request
+		want string
+	}{
+		{&Request{DestinationPort: 80}, "80"},
+		{&Request{DestinationPort: 80, SourcePrincipal: "cluster.local/ns/foo/sa/sleep"}, "80-tls"},
+		{&Request{DestinationPort: 8080}, "passthrough"},
+		{&Request{DestinationPort: 8080, SourcePrincipal: "cluster.local/ns/foo/sa/sleep"}, "passthrough-tls"},
+	}
+	for _, tc := range cases {
+		if got := selectFilterChain(listeners, tc.req); got.GetName() != tc.want {
+			t.Errorf("selectFilterChain(%+v) = %q, want %q", tc.req, got.GetName(), tc.want)
+		}
+	}
+}
@@ -43,6 +43,8 @@ var (
 )
 
 type filterChain struct {
+	// http is true if the filter chain has an HTTP connection manager.
+	http     bool
 	rbacHTTP []*rbac_http_filter.RBAC
 	rbacTCP  []*rbac_tcp_filter.RBAC
 }
@@ -89,6 +91,7 @@ func parse(listeners []*listener.Listener) []*parsedListener {
 			for _, filter := range fc.Filters {
 				switch filter.Name {
 				case wellknown.HTTPConnectionManager, "envoy.http_connection_manager":
+					parsedFC.http = true
 					if cm := getHTTPConnectionManager(filter); cm != nil {
 						for _, httpFilter := range cm.GetHttpFilters() {
 							switch httpFilter.GetName() {
@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbac_http_filter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	rbac_tcp_filter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	"github.com/golang/protobuf/ptypes"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// Workload identifies the workload the AuthorizationPolicies are applied to.
+type Workload struct {
+	Namespace string
+	Labels    map[string]string
+	// TCP selects the RBAC filters built for TCP filter chains instead of HTTP ones.
+	TCP bool
+}
+
+// EvaluatePolicies builds the RBAC filters of the workload from the given AuthorizationPolicies,
+// the same way Istiod does, and evaluates the request against them. Other configs are ignored, and
+// so are CUSTOM policies as they are delegated to an external authorizer.
+func EvaluatePolicies(configs []config.Config, mc *meshconfig.MeshConfig, w Workload, req *Request) (*Decision, error) {
+	filters, err := buildRBAC(configs, mc, w)
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(filters, req)
+}
+
+func buildRBAC(configs []config.Config, mc *meshconfig.MeshConfig, w Workload) ([]*rbacpb.RBAC, error) {
+	store := model.MakeIstioStore(memory.Make(collections.Pilot))
+	for _, c := range configs {
+		if c.GroupVersionKind != collections.IstioSecurityV1Beta1Authorizationpolicies.Resource().GroupVersionKind() {
+			continue
+		}
+		if c.Namespace == "" {
+			c.Namespace = w.Namespace
+		}
+		if _, err := store.Create(c); err != nil {
+			return nil, fmt.Errorf("failed to add AuthorizationPolicy %s.%s: %v", c.Name, c.Namespace, err)
+		}
+	}
+	env := &model.Environment{IstioConfigStore: store, Watcher: mesh.NewFixedWatcher(mc)}
+	policies, err := model.GetAuthorizationPolicies(env)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &plugin.InputParams{
+		Node: &model.Proxy{
+			ConfigNamespace: w.Namespace,
+			Metadata:        &model.NodeMetadata{Labels: w.Labels},
+		},
+		Push: &model.PushContext{AuthzPolicies: policies, Mesh: mc},
+	}
+	tdBundle := trustdomain.NewBundle(mc.GetTrustDomain(), mc.GetTrustDomainAliases())
+	b := builder.New(tdBundle, in, builder.Option{IsIstioVersionGE15: true})
+	if b == nil {
+		return nil, nil
+	}
+
+	var filters []*rbacpb.RBAC
+	if w.TCP {
+		for _, f := range b.BuildTCP() {
+			rbac := &rbac_tcp_filter.RBAC{}
+			if err := ptypes.UnmarshalAny(f.GetTypedConfig(), rbac); err != nil {
+				return nil, err
+			}
+			filters = append(filters, rbac.GetRules())
+		}
+		return filters, nil
+	}
+	for _, f := range b.BuildHTTP() {
+		rbac := &rbac_http_filter.RBAC{}
+		if err := ptypes.UnmarshalAny(f.GetTypedConfig(), rbac); err != nil {
+			return nil, err
+		}
+		filters = append(filters, rbac.GetRules())
+	}
+	return filters, nil
+}
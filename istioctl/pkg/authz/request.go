@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"strings"
+
+	sm "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// Request is a hypothetical request to a workload, evaluated against the authorization policies
+// applied to it.
+type Request struct {
+	// SourcePrincipal is the identity of the peer, e.g. "cluster.local/ns/default/sa/sleep". It is
+	// empty for plain text requests.
+	SourcePrincipal string
+	// SourceIP is the IP address of the peer.
+	SourceIP string
+	// RemoteIP is the original client IP, e.g. from X-Forwarded-For. Defaults to SourceIP.
+	RemoteIP string
+	// DestinationIP and DestinationPort are the address the request is sent to on the workload.
+	DestinationIP   string
+	DestinationPort uint32
+	// SNI is the server name of the TLS connection.
+	SNI string
+
+	// Host, Method, Path and Headers describe an HTTP request. They are ignored for TCP.
+	Host    string
+	Method  string
+	Path    string
+	Headers map[string]string
+
+	// RequestPrincipal is the principal of the request JWT, e.g. "issuer/subject". Defaults to
+	// the "iss" and "sub" claims.
+	RequestPrincipal string
+	// Claims are the claims of the request JWT.
+	Claims map[string][]string
+}
+
+// header returns the value of the named request header, including the pseudo headers Envoy
+// matches the host, method and path on.
+func (r *Request) header(name string) (string, bool) {
+	switch name = strings.ToLower(name); name {
+	case ":authority", "host":
+		return r.Host, r.Host != ""
+	case ":method":
+		return r.Method, r.Method != ""
+	case ":path":
+		return r.Path, r.Path != ""
+	}
+	for k, v := range r.Headers {
+		if strings.ToLower(k) == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// urlPath returns the path of the request without the query string.
+func (r *Request) urlPath() string {
+	if i := strings.IndexAny(r.Path, "?#"); i >= 0 {
+		return r.Path[:i]
+	}
+	return r.Path
+}
+
+func (r *Request) remoteIP() string {
+	if r.RemoteIP != "" {
+		return r.RemoteIP
+	}
+	return r.SourceIP
+}
+
+// peerURI returns the URI SAN of the peer certificate.
+func (r *Request) peerURI() string {
+	if r.SourcePrincipal == "" {
+		return ""
+	}
+	return spiffe.URIPrefix + r.SourcePrincipal
+}
+
+// metadata returns the dynamic metadata the Istio authentication filter sets for the request,
+// keyed by filter name.
+func (r *Request) metadata() map[string]map[string]interface{} {
+	authn := map[string]interface{}{}
+	if r.SourcePrincipal != "" {
+		authn["source.principal"] = r.SourcePrincipal
+		authn["source.user"] = r.SourcePrincipal
+	}
+	principal := r.RequestPrincipal
+	if principal == "" && len(r.Claims["iss"]) > 0 && len(r.Claims["sub"]) > 0 {
+		principal = r.Claims["iss"][0] + "/" + r.Claims["sub"][0]
+	}
+	if principal != "" {
+		authn["request.auth.principal"] = principal
+	}
+	if aud := r.Claims["aud"]; len(aud) > 0 {
+		authn["request.auth.audiences"] = aud[0]
+	}
+	if azp := r.Claims["azp"]; len(azp) > 0 {
+		authn["request.auth.presenter"] = azp[0]
+	}
+	if len(r.Claims) > 0 {
+		claims := map[string]interface{}{}
+		for k, vs := range r.Claims {
+			list := make([]interface{}, 0, len(vs))
+			for _, v := range vs {
+				list = append(list, v)
+			}
+			claims[k] = list
+		}
+		authn["request.auth.claims"] = claims
+	}
+	return map[string]map[string]interface{}{sm.AuthnFilterName: authn}
+}
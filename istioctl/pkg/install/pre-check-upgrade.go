@@ -0,0 +1,391 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
+	iopversion "istio.io/istio/operator/pkg/version"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/validation"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/version"
+)
+
+const (
+	injectorConfigMapPrefix = "istio-sidecar-injector"
+	proxyContainerName      = "istio-proxy"
+	upgradeAnalysisTimeout  = 30 * time.Second
+	componentLabel          = "operator.istio.io/component"
+)
+
+// installerFieldManagers are the field managers that write the injector ConfigMap when a revision is
+// installed or upgraded. Changes to the ConfigMap made by any other manager are customizations.
+var installerFieldManagers = map[string]bool{
+	"istioctl":       true,
+	"operator":       true,
+	"istio-operator": true,
+	"helm":           true,
+	"Helm":           true,
+}
+
+// upgradeTarget is the control plane revision an upgrade pre-check is run against
+type upgradeTarget struct {
+	revision string
+	// version is nil when the version of the target is not known
+	version *iopversion.Version
+}
+
+func (t upgradeTarget) String() string {
+	rev := t.revision
+	if rev == "" {
+		rev = "default"
+	}
+	if t.version == nil {
+		return fmt.Sprintf("revision %q", rev)
+	}
+	return fmt.Sprintf("revision %q (%s)", rev, t.version)
+}
+
+// newUpgradeTarget builds the upgrade target from the revision and the image tag it will run.
+// An empty tag means the version of this istioctl.
+func newUpgradeTarget(revision string, tag string) upgradeTarget {
+	target := upgradeTarget{revision: revision}
+	if tag == "" {
+		tag = version.Info.Version
+	}
+	if v, err := iopversion.TagToVersionString(tag); err == nil {
+		target.version, _ = iopversion.NewVersionFromString(v)
+	}
+	return target
+}
+
+// upgradeFinding is existing configuration, or an existing proxy, that may behave differently
+// once the target revision is installed
+type upgradeFinding struct {
+	resource    string
+	message     string
+	remediation string
+}
+
+// upgradeCheckInputs are the sources of the state of the running mesh
+type upgradeCheckInputs struct {
+	kube  kubernetes.Interface
+	istio istioclient.Interface
+	// addConfigSource adds the Istio configuration of the mesh to an analyzer
+	addConfigSource func(sa *local.SourceAnalyzer) error
+}
+
+func createUpgradeCheckInputs(restClientGetter genericclioptions.RESTClientGetter) (upgradeCheckInputs, error) {
+	restConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return upgradeCheckInputs{}, err
+	}
+	client, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig))
+	if err != nil {
+		return upgradeCheckInputs{}, err
+	}
+	return upgradeCheckInputs{
+		kube:  client.Kube(),
+		istio: client.Istio(),
+		addConfigSource: func(sa *local.SourceAnalyzer) error {
+			sa.AddRunningKubeSource(cfgKube.NewInterfaces(restConfig))
+			return nil
+		},
+	}, nil
+}
+
+// Tell the user which of the existing configuration and proxies may change behavior when the target
+// revision is installed, and what to do about it.
+func upgradePreCheck(in upgradeCheckInputs, target upgradeTarget, istioNamespace string, writer io.Writer) error {
+	fmt.Fprintf(writer, "\n")
+	fmt.Fprintf(writer, "Checking the mesh for configuration that may change behavior with %s...\n", target)
+	if target.version == nil {
+		fmt.Fprintf(writer, "The version of the target is unknown; checks that depend on it are skipped. "+
+			"Use -f with an IstioOperator that sets spec.tag to provide it.\n")
+	}
+	checks := []struct {
+		title string
+		run   func() ([]upgradeFinding, error)
+	}{
+		{"Deprecated-fields", func() ([]upgradeFinding, error) {
+			return checkDeprecatedFields(in, istioNamespace, target)
+		}},
+		{"EnvoyFilters", func() ([]upgradeFinding, error) {
+			return checkEnvoyFilters(in.istio, target)
+		}},
+		{"Injection-templates", func() ([]upgradeFinding, error) {
+			return checkInjectionTemplates(in.kube, istioNamespace, target)
+		}},
+		{"Proxy-versions", func() ([]upgradeFinding, error) {
+			return checkProxyVersions(in.kube, target)
+		}},
+	}
+
+	var errs error
+	found := 0
+	for i, check := range checks {
+		fmt.Fprintf(writer, "\n")
+		fmt.Fprintf(writer, "#%d. %s\n", i+1, check.title)
+		fmt.Fprintf(writer, "-----------------------\n")
+		findings, err := check.run()
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to check %s: %v", strings.ToLower(check.title), err))
+			fmt.Fprintf(writer, "Failed to check: %v.\n", err)
+			continue
+		}
+		if len(findings) == 0 {
+			fmt.Fprintf(writer, "No issues found.\n")
+			continue
+		}
+		found += len(findings)
+		for _, f := range findings {
+			fmt.Fprintf(writer, "%s: %s\n", f.resource, f.message)
+			fmt.Fprintf(writer, "    Remediation: %s\n", f.remediation)
+		}
+	}
+	fmt.Fprintf(writer, "\n")
+	fmt.Fprintf(writer, "-----------------------\n")
+	if found > 0 {
+		errs = multierror.Append(errs, fmt.Errorf("found %d issue(s) that may change behavior with %s", found, target))
+	} else if errs == nil {
+		fmt.Fprintf(writer, "Upgrade Pre-Check passed! No issues found for %s.\n", target)
+	}
+	fmt.Fprintf(writer, "\n")
+	return errs
+}
+
+// checkDeprecatedFields reports the deprecated fields and types in use, which newer revisions may ignore or reject
+func checkDeprecatedFields(in upgradeCheckInputs, istioNamespace string, target upgradeTarget) ([]upgradeFinding, error) {
+	sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("upgrade", &deprecation.FieldAnalyzer{}),
+		resource.Namespace(""), resource.Namespace(istioNamespace), nil, false, upgradeAnalysisTimeout)
+	if err := in.addConfigSource(sa); err != nil {
+		return nil, err
+	}
+	cancel := make(chan struct{})
+	result, err := sa.Analyze(cancel)
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]upgradeFinding, 0, len(result.Messages))
+	for _, m := range result.Messages.SortedDedupedCopy() {
+		res := "<unknown>"
+		if m.Resource != nil {
+			res = m.Resource.Origin.FriendlyName()
+		}
+		findings = append(findings, upgradeFinding{
+			resource: res,
+			message:  fmt.Sprintf(m.Type.Template(), m.Parameters...),
+			remediation: fmt.Sprintf("Stop using the deprecated field or type before upgrading; %s may ignore or reject it.",
+				target),
+		})
+	}
+	return findings, nil
+}
+
+// checkEnvoyFilters reports EnvoyFilters that stop applying to the target, or that patch by
+// filter names and types that changed
+func checkEnvoyFilters(ic istioclient.Interface, target upgradeTarget) ([]upgradeFinding, error) {
+	efs, err := ic.NetworkingV1alpha3().EnvoyFilters(meta_v1.NamespaceAll).List(context.TODO(), meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]upgradeFinding, 0)
+	for _, ef := range efs.Items {
+		// EnvoyFilters installed with a revision, such as the telemetry filters, are installed again for the target
+		if _, f := ef.Labels[componentLabel]; f {
+			continue
+		}
+		res := fmt.Sprintf("EnvoyFilter %s.%s", ef.Name, ef.Namespace)
+		if target.version != nil {
+			for i, cp := range ef.Spec.ConfigPatches {
+				pv := cp.GetMatch().GetProxy().GetProxyVersion()
+				if pv == "" {
+					continue
+				}
+				re, err := regexp.Compile(pv)
+				if err != nil {
+					continue
+				}
+				// Matched against the ISTIO_VERSION of the proxy, as in model.proxyMatch
+				if !re.MatchString(target.version.String()) {
+					findings = append(findings, upgradeFinding{
+						resource: res,
+						message: fmt.Sprintf("config patch %d matches proxy version %q, which does not match %s, "+
+							"so it will not be applied to proxies of the target", i, pv, target.version),
+						remediation: fmt.Sprintf("Widen the proxyVersion match, or add an EnvoyFilter for proxies "+
+							"of version %s.", target.version.MinorVersion),
+					})
+				}
+			}
+		}
+		warnings, _ := validation.ValidateEnvoyFilter(config.Config{
+			Meta: config.Meta{Name: ef.Name, Namespace: ef.Namespace},
+			Spec: &ef.Spec,
+		})
+		for _, w := range unwrapWarnings(warnings) {
+			findings = append(findings, upgradeFinding{
+				resource: res,
+				message:  w.Error(),
+				remediation: "Update the match and patch to the canonical filter names and Envoy v3 types; " +
+					"deprecated names and types may not be supported by the target.",
+			})
+		}
+	}
+	return findings, nil
+}
+
+func unwrapWarnings(w validation.Warning) []error {
+	if w == nil {
+		return nil
+	}
+	if merr, ok := w.(*multierror.Error); ok {
+		return merr.Errors
+	}
+	return []error{w}
+}
+
+// checkInjectionTemplates reports injection templates that were changed after their revision was installed,
+// as the target revision is installed with its own, unmodified template
+func checkInjectionTemplates(kc kubernetes.Interface, istioNamespace string, target upgradeTarget) ([]upgradeFinding, error) {
+	cms, err := kc.CoreV1().ConfigMaps(istioNamespace).List(context.TODO(), meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]upgradeFinding, 0)
+	for _, cm := range cms.Items {
+		if !strings.HasPrefix(cm.Name, injectorConfigMapPrefix) {
+			continue
+		}
+		for _, mf := range cm.ManagedFields {
+			if installerFieldManagers[mf.Manager] || mf.Time == nil || !mf.Time.After(cm.CreationTimestamp.Time) {
+				continue
+			}
+			if mf.FieldsV1 == nil || !strings.Contains(string(mf.FieldsV1.Raw), `"f:config"`) {
+				continue
+			}
+			rev := cm.Labels["istio.io/rev"]
+			if rev == "" {
+				rev = "default"
+			}
+			findings = append(findings, upgradeFinding{
+				resource: fmt.Sprintf("ConfigMap %s.%s", cm.Name, cm.Namespace),
+				message: fmt.Sprintf("the injection template of revision %q was modified by %q at %s, after it was installed",
+					rev, mf.Manager, mf.Time.UTC().Format(time.RFC3339)),
+				remediation: fmt.Sprintf("Port the customizations to the injection template of %s after installing it, "+
+					"or workloads injected by it will not have them.", target),
+			})
+			break
+		}
+	}
+	return findings, nil
+}
+
+// checkProxyVersions reports proxies that will be connected to the target while outside of the supported
+// version skew: proxies may be at most one minor version older than the control plane, and never newer
+func checkProxyVersions(kc kubernetes.Interface, target upgradeTarget) ([]upgradeFinding, error) {
+	if target.version == nil {
+		return nil, nil
+	}
+	pods, err := kc.CoreV1().Pods(meta_v1.NamespaceAll).List(context.TODO(), meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	unsupported := map[string][]string{}
+	for _, pod := range pods.Items {
+		v := proxyVersion(pod)
+		if v == nil || v.Major != target.version.Major {
+			continue
+		}
+		if v.Minor > target.version.Minor || v.Minor+1 < target.version.Minor {
+			key := v.MinorVersion.String()
+			unsupported[key] = append(unsupported[key], fmt.Sprintf("%s.%s", pod.Name, pod.Namespace))
+		}
+	}
+	versions := make([]string, 0, len(unsupported))
+	for v := range unsupported {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	findings := make([]upgradeFinding, 0, len(versions))
+	for _, v := range versions {
+		pods := unsupported[v]
+		sort.Strings(pods)
+		res := strings.Join(pods, ", ")
+		if len(pods) > 3 {
+			res = fmt.Sprintf("%s and %d more", strings.Join(pods[:3], ", "), len(pods)-3)
+		}
+		findings = append(findings, upgradeFinding{
+			resource: "Pods " + res,
+			message:  fmt.Sprintf("%s version %s, outside the supported skew of %s", proxiesRun(len(pods)), v, target.version),
+			remediation: fmt.Sprintf("Restart the workloads so they are injected by a control plane at most one minor "+
+				"version older than %s before moving them to the target.", target.version.MinorVersion),
+		})
+	}
+	return findings, nil
+}
+
+func proxiesRun(n int) string {
+	if n == 1 {
+		return "1 proxy runs"
+	}
+	return fmt.Sprintf("%d proxies run", n)
+}
+
+// proxyVersion returns the version of the sidecar or gateway proxy of a pod, from its image tag
+func proxyVersion(pod v1.Pod) *iopversion.Version {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != proxyContainerName {
+			continue
+		}
+		image := c.Image
+		if at := strings.Index(image, "@"); at >= 0 {
+			image = image[:at]
+		}
+		colon := strings.LastIndex(image, ":")
+		if colon < 0 || strings.Contains(image[colon:], "/") {
+			return nil
+		}
+		vs, err := iopversion.TagToVersionString(image[colon+1:])
+		if err != nil {
+			return nil
+		}
+		v, err := iopversion.NewVersionFromString(vs)
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gogotypes "github.com/gogo/protobuf/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/pkg/kube"
+)
+
+const deprecatedVirtualService = `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews
+  http:
+  - fault:
+      delay:
+        percent: 50
+        fixedDelay: 5s
+    route:
+    - destination:
+        host: reviews
+`
+
+func proxyPod(name, tag string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.PodSpec{Containers: []v1.Container{
+			{Name: "app", Image: "docker.io/bookinfo/reviews:1.16.2"},
+			{Name: "istio-proxy", Image: "docker.io/istio/proxyv2:" + tag},
+		}},
+	}
+}
+
+func injectorConfigMap(name, rev, manager string, modified time.Duration) *v1.ConfigMap {
+	created := metav1.NewTime(time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC))
+	updated := metav1.NewTime(created.Add(modified))
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "istio-system",
+			Labels:            map[string]string{"istio.io/rev": rev},
+			CreationTimestamp: created,
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:   "istioctl",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					Time:      &created,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{".":{},"f:config":{},"f:values":{}}}`)},
+				},
+				{
+					Manager:   manager,
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					Time:      &updated,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:config":{}}}`)},
+				},
+			},
+		},
+	}
+}
+
+func proxyVersionFilter(name, proxyVersion string, labels map[string]string) *clientnetworking.EnvoyFilter {
+	return &clientnetworking.EnvoyFilter{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels},
+		Spec: networking.EnvoyFilter{
+			ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{{
+				ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+				Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+					Proxy: &networking.EnvoyFilter_ProxyMatch{ProxyVersion: proxyVersion},
+					ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+						Listener: &networking.EnvoyFilter_ListenerMatch{
+							FilterChain: &networking.EnvoyFilter_ListenerMatch_FilterChainMatch{
+								Filter: &networking.EnvoyFilter_ListenerMatch_FilterMatch{
+									Name:      "envoy.http_connection_manager",
+									SubFilter: &networking.EnvoyFilter_ListenerMatch_SubFilterMatch{Name: "envoy.filters.http.router"},
+								},
+							},
+						},
+					},
+				},
+				Patch: &networking.EnvoyFilter_Patch{
+					Operation: networking.EnvoyFilter_Patch_INSERT_BEFORE,
+					Value: &gogotypes.Struct{Fields: map[string]*gogotypes.Value{
+						"name": {Kind: &gogotypes.Value_StringValue{StringValue: "envoy.filters.http.lua"}},
+					}},
+				},
+			}},
+		},
+	}
+}
+
+func TestUpgradePreCheck(t *testing.T) {
+	cases := []struct {
+		description string
+		config      string
+		objects     []runtime.Object
+		filters     []*clientnetworking.EnvoyFilter
+		tag         string
+		wantErr     bool
+		want        []string
+		notWant     []string
+	}{
+		{
+			description: "nothing to report",
+			objects: []runtime.Object{
+				proxyPod("reviews-v1", "1.7.3"),
+				proxyPod("reviews-v2", "1.8.0"),
+				injectorConfigMap("istio-sidecar-injector", "default", "istioctl", time.Hour),
+			},
+			filters: []*clientnetworking.EnvoyFilter{
+				// Installed with the revision, so replaced by the filters of the target
+				proxyVersionFilter("stats-filter-1.7", `^1\.7.*`, map[string]string{componentLabel: "Pilot"}),
+			},
+			tag:  "1.8.0",
+			want: []string{`revision "canary" (1.8.0)`, "Upgrade Pre-Check passed!"},
+		},
+		{
+			description: "issues in every check",
+			config:      deprecatedVirtualService,
+			objects: []runtime.Object{
+				proxyPod("reviews-v1", "1.6.8"),
+				proxyPod("reviews-v2", "1.7.3"),
+				proxyPod("reviews-v3", "1.9.0"),
+				proxyPod("reviews-v4", "latest"),
+				injectorConfigMap("istio-sidecar-injector", "default", "kubectl-edit", time.Hour),
+			},
+			filters: []*clientnetworking.EnvoyFilter{proxyVersionFilter("lua", `^1\.7.*`, nil)},
+			tag:     "1.8.0",
+			wantErr: true,
+			want: []string{
+				"HTTPRoute.fault.delay.percent is deprecated; use HTTPRoute.fault.delay.percentage",
+				`EnvoyFilter lua.istio-system: config patch 0 matches proxy version "^1\\.7.*", which does not match 1.8.0`,
+				`using deprecated filter name "envoy.http_connection_manager"`,
+				`ConfigMap istio-sidecar-injector.istio-system: the injection template of revision "default" was modified by "kubectl-edit"`,
+				"Pods reviews-v1.default: 1 proxy runs version 1.6",
+				"Pods reviews-v3.default: 1 proxy runs version 1.9",
+				"Remediation:",
+			},
+			notWant: []string{"reviews-v2", "reviews-v4", "Upgrade Pre-Check passed!"},
+		},
+		{
+			description: "version dependent checks skipped without a target version",
+			objects:     []runtime.Object{proxyPod("reviews-v1", "1.6.8")},
+			filters:     []*clientnetworking.EnvoyFilter{proxyVersionFilter("lua", `^1\.7.*`, nil)},
+			tag:         "master",
+			wantErr:     true,
+			want:        []string{"The version of the target is unknown", `using deprecated filter name`},
+			notWant:     []string{"does not match", "run version"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			client := kube.NewFakeClient(c.objects...)
+			for _, ef := range c.filters {
+				if _, err := client.Istio().NetworkingV1alpha3().EnvoyFilters(ef.Namespace).Create(
+					context.TODO(), ef, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			in := upgradeCheckInputs{
+				kube:  client.Kube(),
+				istio: client.Istio(),
+				addConfigSource: func(sa *local.SourceAnalyzer) error {
+					return sa.AddReaderKubeSource([]local.ReaderSource{{Name: "config", Reader: strings.NewReader(c.config)}})
+				},
+			}
+			var out bytes.Buffer
+			err := upgradePreCheck(in, newUpgradeTarget("canary", c.tag), "istio-system", &out)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error %v, output:\n%s", err, c.wantErr, out.String())
+			}
+			for _, w := range c.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("expected output to contain %q, got:\n%s", w, out.String())
+				}
+			}
+			for _, w := range c.notWant {
+				if strings.Contains(out.String(), w) {
+					t.Errorf("expected output not to contain %q, got:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradePreCheckCommand(t *testing.T) {
+	client := kube.NewFakeClient()
+	upgradeInputsFactory = func(genericclioptions.RESTClientGetter) (upgradeCheckInputs, error) {
+		return upgradeCheckInputs{
+			kube:  client.Kube(),
+			istio: client.Istio(),
+			addConfigSource: func(sa *local.SourceAnalyzer) error {
+				return sa.AddReaderKubeSource([]local.ReaderSource{{Name: "config", Reader: strings.NewReader("")}})
+			},
+		}, nil
+	}
+	defer func() { upgradeInputsFactory = createUpgradeCheckInputs }()
+
+	var out bytes.Buffer
+	precheckCmd := NewPrecheckCommand()
+	precheckCmd.SetArgs([]string{"--upgrade", "--revision", "canary"})
+	precheckCmd.SetOut(&out)
+	precheckCmd.SetErr(&out)
+	if err := precheckCmd.Execute(); err != nil {
+		t.Fatalf("Unwanted exception for 'istioctl x precheck --upgrade': %v", err)
+	}
+	if !strings.Contains(out.String(), `with revision "canary"`) {
+		t.Errorf("expected the target revision in the output, got:\n%s", out.String())
+	}
+}
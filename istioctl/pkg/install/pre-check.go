@@ -45,7 +45,8 @@ import (
 )
 
 var (
-	clientFactory = createKubeClient
+	clientFactory        = createKubeClient
+	upgradeInputsFactory = createUpgradeCheckInputs
 )
 
 type istioInstall struct {
@@ -302,12 +303,18 @@ func NewPrecheckCommand() *cobra.Command {
 		}
 		istioNamespace string
 		opts           clioptions.ControlPlaneOptions
+		upgrade        bool
 	)
 	precheckCmd := &cobra.Command{
 		Use:   "precheck [-f <deployment or istio operator file>]",
 		Short: "Checks Istio cluster compatibility",
 		Long: `
   precheck inspects a Kubernetes cluster for Istio install requirements.
+
+  With --upgrade, precheck instead reports the existing configuration and proxies that may change
+  behavior once the target revision is installed: deprecated fields in use, EnvoyFilters that will not
+  apply to the target or that use renamed filters and types, customized injection templates, and
+  proxies outside the supported version skew. Each issue comes with a remediation.
 `,
 		Example: `  # Verify that Istio can be installed
   istioctl experimental precheck
@@ -316,9 +323,35 @@ func NewPrecheckCommand() *cobra.Command {
   istioctl x precheck --set profile=demo
 
   # Verify the deployment matches the Istio Operator deployment definition
-  istioctl x precheck -f iop.yaml`,
+  istioctl x precheck -f iop.yaml
+
+  # Check which existing configuration and proxies may change behavior with a canary revision
+  istioctl x precheck --upgrade --revision canary
+
+  # Check the same for the revision and version in an Istio Operator deployment definition
+  istioctl x precheck --upgrade -f iop.yaml`,
 		Args: cobra.ExactArgs(0),
 		RunE: func(c *cobra.Command, args []string) error {
+			if upgrade {
+				targetRevision := opts.Revision
+				tag := ""
+				if len(fileNameFlags.ToOptions().Filenames) > 0 {
+					iop, err := getIOPFromFile(fileNameFlags.ToOptions().Filenames[0])
+					if err != nil {
+						return err
+					}
+					targetRevision = iop.Spec.Revision
+					if iop.Spec.Tag != nil {
+						tag = fmt.Sprint(iop.Spec.Tag)
+					}
+				}
+				in, err := upgradeInputsFactory(kubeConfigFlags)
+				if err != nil {
+					return err
+				}
+				return upgradePreCheck(in, newUpgradeTarget(targetRevision, tag), istioNamespace, c.OutOrStdout())
+			}
+
 			targetNamespace := istioNamespace
 			targetRevision := opts.Revision
 			specific := c.Flags().Changed("istioNamespace") // is user asking about a specific Istio System ns or revision
@@ -383,6 +416,8 @@ func NewPrecheckCommand() *cobra.Command {
 	flags := precheckCmd.PersistentFlags()
 	flags.StringVarP(&istioNamespace, "istioNamespace", "i", controller.IstioNamespace,
 		"Istio system namespace")
+	flags.BoolVar(&upgrade, "upgrade", false,
+		"Check existing configuration and proxies for changes in behavior with the target revision, instead of install requirements")
 	kubeConfigFlags.AddFlags(flags)
 	fileNameFlags.AddFlags(flags)
 	opts.AttachControlPlaneFlags(precheckCmd)
@@ -64,6 +64,17 @@ func (w *Wrapper) MarshalJSON() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// MarshalIndent marshals a message taken from a config dump, such as a listener or cluster,
+// to indented JSON. Embedded Any types unknown to istioctl are tolerated.
+func MarshalIndent(m proto.Message, indent string) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	err := (&jsonpb.Marshaler{Indent: indent, AnyResolver: &envoyResolver}).Marshal(buffer, m)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
 // UnmarshalJSON is a custom unmarshaller to handle protobuf pain
 func (w *Wrapper) UnmarshalJSON(b []byte) error {
 	cd := &adminapi.ConfigDump{}
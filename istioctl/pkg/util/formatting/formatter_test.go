@@ -15,11 +15,14 @@
 package formatting
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"testing"
 
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/url"
 )
 
@@ -152,3 +155,91 @@ func TestFormatter_PrintEmpty(t *testing.T) {
 	yamlOutput, _ := Print(msgs, YAMLFormat, false)
 	g.Expect(yamlOutput).To(Equal("[]\n"))
 }
+
+func TestFormatter_PrintSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		diag.MockResource("SoapBubble"),
+		"the bubble is too big",
+	)
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+
+	msgs := diag.Messages{firstMsg, secondMsg}
+	output, err := Print(msgs, SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Runs).To(HaveLen(1))
+	rules := log.Runs[0].Tool.Driver.Rules
+	g.Expect(rules).To(HaveLen(2))
+	g.Expect(rules[0].ID).To(Equal("B1"))
+	g.Expect(rules[0].HelpURI).To(Equal(url.ConfigAnalysis + "/b1/"))
+	g.Expect(rules[1].DefaultConfiguration.Level).To(Equal("note"))
+
+	results := log.Runs[0].Results
+	g.Expect(results).To(HaveLen(2))
+	g.Expect(results[0].RuleID).To(Equal("B1"))
+	g.Expect(results[0].Level).To(Equal("error"))
+	g.Expect(results[0].Message.Text).To(Equal("Explosion accident: the bubble is too big"))
+	g.Expect(results[0].Locations[0].LogicalLocations[0].FullyQualifiedName).To(Equal("SoapBubble"))
+	g.Expect(results[1].RuleIndex).To(Equal(1))
+}
+
+func TestFormatter_PrintSARIFDescribesKnownCodes(t *testing.T) {
+	g := NewWithT(t)
+
+	m := msg.NewPodMissingProxy(diag.MockResource("pod"))
+	output, err := Print(diag.Messages{m}, SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	rule := log.Runs[0].Tool.Driver.Rules[0]
+	g.Expect(rule.Name).To(Equal("PodMissingProxy"))
+	g.Expect(rule.ShortDescription).NotTo(BeNil())
+}
+
+func TestFormatter_PrintJUnit(t *testing.T) {
+	g := NewWithT(t)
+
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		diag.MockResource("SoapBubble"),
+		"the bubble is too big",
+	)
+	firstMsg.Analyzer = "bubble.Analyzer"
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+
+	msgs := diag.Messages{firstMsg, secondMsg}
+	output, err := Print(msgs, JUnitFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var suites junitTestSuites
+	g.Expect(xml.Unmarshal([]byte(output), &suites)).To(Succeed())
+	g.Expect(suites.Tests).To(Equal(2))
+	g.Expect(suites.Failures).To(Equal(1))
+	g.Expect(suites.Suites).To(HaveLen(2))
+
+	g.Expect(suites.Suites[0].Name).To(Equal("bubble.Analyzer"))
+	tc := suites.Suites[0].TestCases[0]
+	g.Expect(tc.Name).To(Equal("B1 SoapBubble"))
+	g.Expect(tc.Failure).NotTo(BeNil())
+	g.Expect(tc.Failure.Type).To(Equal("Error"))
+	g.Expect(tc.Failure.Message).To(Equal("Explosion accident: the bubble is too big"))
+
+	g.Expect(suites.Suites[1].Name).To(Equal("istioctl analyze"))
+	g.Expect(suites.Suites[1].TestCases[0].Failure).To(BeNil())
+	g.Expect(suites.Suites[1].TestCases[0].SystemOut).To(ContainSubstring("Collapse danger"))
+}
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+// unknownAnalyzer groups the messages that were not reported by a known analyzer.
+const unknownAnalyzer = "istioctl analyze"
+
+// The subset of the JUnit XML format used to report analysis messages, with a test suite per
+// analyzer and a test case per message.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// printJUnit reports errors and warnings as failed test cases, and other messages as passed ones.
+func printJUnit(ms diag.Messages) (string, error) {
+	suites := map[string]*junitTestSuite{}
+	for _, m := range ms {
+		analyzer := m.Analyzer
+		if analyzer == "" {
+			analyzer = unknownAnalyzer
+		}
+		suite, ok := suites[analyzer]
+		if !ok {
+			suite = &junitTestSuite{Name: analyzer}
+			suites[analyzer] = suite
+		}
+		name := m.Type.Code()
+		if m.Resource != nil {
+			name += " " + m.Resource.Origin.FriendlyName()
+		}
+		tc := junitTestCase{
+			Name:      name,
+			ClassName: analyzer,
+		}
+		text := render(m, false)
+		if m.Type.Level() == diag.Error || m.Type.Level() == diag.Warning {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf(m.Type.Template(), m.Parameters...),
+				Type:    m.Type.Level().String(),
+				Text:    text,
+			}
+			suite.Failures++
+		} else {
+			tc.SystemOut = text
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	out := junitTestSuites{Name: unknownAnalyzer, Suites: []junitTestSuite{}}
+	var names []string
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := suites[name]
+		out.Tests += s.Tests
+		out.Failures += s.Failures
+		out.Suites = append(out.Suites, *s)
+	}
+	b, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b), nil
+}
@@ -0,0 +1,416 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// ResourceType is a kind of Envoy resource that can be diffed between config dumps
+type ResourceType string
+
+const (
+	ListenerResource ResourceType = "listener"
+	RouteResource    ResourceType = "route"
+	ClusterResource  ResourceType = "cluster"
+	SecretResource   ResourceType = "secret"
+)
+
+// ResourceTypes lists the resource types in the order they are diffed
+var ResourceTypes = []ResourceType{ListenerResource, RouteResource, ClusterResource, SecretResource}
+
+var resourceTitles = map[ResourceType]string{
+	ListenerResource: "Listeners",
+	RouteResource:    "Routes",
+	ClusterResource:  "Clusters",
+	SecretResource:   "Secrets",
+}
+
+// Change describes how a resource differs between two config dumps
+type Change string
+
+const (
+	Added    Change = "added"
+	Removed  Change = "removed"
+	Modified Change = "modified"
+)
+
+// ResourceDiff is the difference of a single named resource between two config dumps
+type ResourceDiff struct {
+	Type   ResourceType `json:"type"`
+	Name   string       `json:"name"`
+	Change Change       `json:"change"`
+	Diff   string       `json:"diff,omitempty"`
+}
+
+// DumpComparator diffs the config dumps of two Envoy instances, or of one instance at two points in time
+type DumpComparator struct {
+	from, to         *configdump.Wrapper
+	fromName, toName string
+	w                io.Writer
+	context          int
+}
+
+// NewDumpComparator is a dump comparator constructor. The from dump is treated as the baseline.
+func NewDumpComparator(w io.Writer, fromName string, from *configdump.Wrapper, toName string, to *configdump.Wrapper) *DumpComparator {
+	return &DumpComparator{
+		from:     from,
+		to:       to,
+		fromName: fromName,
+		toName:   toName,
+		w:        w,
+		context:  7,
+	}
+}
+
+// Diff returns the per-resource differences of the given types, ordered by type and resource name.
+// Fields that change without a change in behavior, such as versions and update timestamps, are ignored.
+func (c *DumpComparator) Diff(types ...ResourceType) ([]ResourceDiff, error) {
+	if len(types) == 0 {
+		types = ResourceTypes
+	}
+	diffs := make([]ResourceDiff, 0)
+	for _, t := range types {
+		from, err := normalizedResources(c.from, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %ss from %s: %v", t, c.fromName, err)
+		}
+		to, err := normalizedResources(c.to, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %ss from %s: %v", t, c.toName, err)
+		}
+		names := make([]string, 0, len(from)+len(to))
+		for name := range from {
+			names = append(names, name)
+		}
+		for name := range to {
+			if _, f := from[name]; !f {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			a, inFrom := from[name]
+			b, inTo := to[name]
+			switch {
+			case !inFrom:
+				diffs = append(diffs, ResourceDiff{Type: t, Name: name, Change: Added})
+			case !inTo:
+				diffs = append(diffs, ResourceDiff{Type: t, Name: name, Change: Removed})
+			case a != b:
+				text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+					FromFile: c.fromName,
+					A:        difflib.SplitLines(a),
+					ToFile:   c.toName,
+					B:        difflib.SplitLines(b),
+					Context:  c.context,
+				})
+				if err != nil {
+					return nil, err
+				}
+				diffs = append(diffs, ResourceDiff{Type: t, Name: name, Change: Modified, Diff: text})
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// Print writes a per-resource diff of the given types to the passed writer
+func (c *DumpComparator) Print(types ...ResourceType) error {
+	if len(types) == 0 {
+		types = ResourceTypes
+	}
+	diffs, err := c.Diff(types...)
+	if err != nil {
+		return err
+	}
+	byType := map[ResourceType][]ResourceDiff{}
+	for _, d := range diffs {
+		byType[d.Type] = append(byType[d.Type], d)
+	}
+	fmt.Fprintf(c.w, "--- %s\n+++ %s\n", c.fromName, c.toName)
+	for _, t := range types {
+		title := resourceTitles[t]
+		if len(byType[t]) == 0 {
+			fmt.Fprintf(c.w, "%s Match\n", title)
+			continue
+		}
+		counts := map[Change]int{}
+		for _, d := range byType[t] {
+			counts[d.Change]++
+		}
+		fmt.Fprintf(c.w, "%s: %d added, %d removed, %d modified\n", title, counts[Added], counts[Removed], counts[Modified])
+		for _, d := range byType[t] {
+			switch d.Change {
+			case Added:
+				fmt.Fprintf(c.w, "  + %s\n", d.Name)
+			case Removed:
+				fmt.Fprintf(c.w, "  - %s\n", d.Name)
+			case Modified:
+				fmt.Fprintf(c.w, "  ~ %s\n", d.Name)
+				// SplitLines terminates the last line, so trim the trailing newline of the diff first
+				for _, line := range difflib.SplitLines(strings.TrimSuffix(d.Diff, "\n")) {
+					fmt.Fprintf(c.w, "    %s", line)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PrintJSON writes the per-resource diff of the given types to the passed writer as JSON
+func (c *DumpComparator) PrintJSON(types ...ResourceType) error {
+	diffs, err := c.Diff(types...)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(diffs, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.w, string(out))
+	return nil
+}
+
+// normalizedResources returns the resources of a type in the dump, keyed by name, rendered as JSON with
+// volatile fields removed. Only the resources themselves are rendered, so the version_info and
+// last_updated fields of the enclosing dump entries never take part in the comparison.
+func normalizedResources(w *configdump.Wrapper, t ResourceType) (map[string]string, error) {
+	resources, err := namedResources(w, t)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(resources))
+	for name, r := range resources {
+		b, err := configdump.MarshalIndent(r, "")
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		v = stripVolatile(v)
+		// encoding/json sorts map keys, which keeps the rendering stable between dumps
+		b, err = json.MarshalIndent(v, "", "   ")
+		if err != nil {
+			return nil, err
+		}
+		out[name] = string(b)
+	}
+	return out, nil
+}
+
+func namedResources(w *configdump.Wrapper, t ResourceType) (map[string]proto.Message, error) {
+	out := map[string]proto.Message{}
+	switch t {
+	case ListenerResource:
+		dump, err := w.GetListenerConfigDump()
+		if err != nil {
+			return nil, err
+		}
+		anys := make([]*any.Any, 0, len(dump.StaticListeners)+len(dump.DynamicListeners))
+		for _, l := range dump.StaticListeners {
+			anys = append(anys, l.Listener)
+		}
+		for _, l := range dump.DynamicListeners {
+			// Compare what the listener is serving; fall back to a listener that is still warming
+			if l.ActiveState != nil {
+				anys = append(anys, l.ActiveState.Listener)
+			} else if l.WarmingState != nil {
+				anys = append(anys, l.WarmingState.Listener)
+			}
+		}
+		for _, a := range anys {
+			l := &listener.Listener{}
+			if err := unmarshalResource(a, v3.ListenerType, l); err != nil {
+				return nil, err
+			}
+			out[l.Name] = l
+		}
+	case RouteResource:
+		dump, err := w.GetRouteConfigDump()
+		if err != nil {
+			return nil, err
+		}
+		anys := make([]*any.Any, 0, len(dump.StaticRouteConfigs)+len(dump.DynamicRouteConfigs))
+		for _, r := range dump.StaticRouteConfigs {
+			anys = append(anys, r.RouteConfig)
+		}
+		for _, r := range dump.DynamicRouteConfigs {
+			anys = append(anys, r.RouteConfig)
+		}
+		for _, a := range anys {
+			r := &route.RouteConfiguration{}
+			if err := unmarshalResource(a, v3.RouteType, r); err != nil {
+				return nil, err
+			}
+			// Virtual hosts are selected by domain rather than position, so their order is not significant
+			sort.Slice(r.VirtualHosts, func(i, j int) bool {
+				return r.VirtualHosts[i].Name < r.VirtualHosts[j].Name
+			})
+			out[r.Name] = r
+		}
+	case ClusterResource:
+		dump, err := w.GetClusterConfigDump()
+		if err != nil {
+			return nil, err
+		}
+		anys := make([]*any.Any, 0, len(dump.StaticClusters)+len(dump.DynamicActiveClusters))
+		for _, c := range dump.StaticClusters {
+			anys = append(anys, c.Cluster)
+		}
+		for _, c := range dump.DynamicActiveClusters {
+			anys = append(anys, c.Cluster)
+		}
+		for _, a := range anys {
+			c := &cluster.Cluster{}
+			if err := unmarshalResource(a, v3.ClusterType, c); err != nil {
+				return nil, err
+			}
+			out[c.Name] = c
+		}
+	case SecretResource:
+		dump, err := w.GetSecretConfigDump()
+		if err != nil {
+			return nil, err
+		}
+		add := func(name string, a *any.Any) error {
+			s := &tls.Secret{}
+			if a != nil {
+				if err := unmarshalResource(a, v3.SecretType, s); err != nil {
+					return err
+				}
+			}
+			if s.Name == "" {
+				s.Name = name
+			}
+			out[s.Name] = s
+			return nil
+		}
+		for _, s := range dump.StaticSecrets {
+			if err := add(s.Name, s.Secret); err != nil {
+				return nil, err
+			}
+		}
+		for _, s := range dump.DynamicActiveSecrets {
+			if err := add(s.Name, s.Secret); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown resource type %q", t)
+	}
+	return out, nil
+}
+
+// unmarshalResource unmarshals a resource, accepting both the v2 and v3 type URLs in the config dump
+func unmarshalResource(a *any.Any, typeURL string, m proto.Message) error {
+	return ptypes.UnmarshalAny(&any.Any{TypeUrl: typeURL, Value: a.Value}, m)
+}
+
+// volatileFields are fields that change between pushes, or between proxies, without a change in behavior
+var volatileFields = map[string]bool{
+	"versionInfo":       true,
+	"lastUpdated":       true,
+	"lastUpdateAttempt": true,
+	"nonce":             true,
+	"responseNonce":     true,
+}
+
+// stripVolatile removes volatile fields from a JSON value. Inline PEM certificates are replaced by their
+// subject, issuer and SANs, as their serial numbers and validity change on every rotation.
+func stripVolatile(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if volatileFields[k] {
+				delete(t, k)
+				continue
+			}
+			if k == "inlineBytes" || k == "inlineString" {
+				if certs := summarizeCertificates(child); certs != nil {
+					delete(t, k)
+					t["certificates"] = certs
+					continue
+				}
+			}
+			t[k] = stripVolatile(child)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = stripVolatile(child)
+		}
+	}
+	return v
+}
+
+func summarizeCertificates(v interface{}) []interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	data := []byte(s)
+	if decoded, err := base64.StdEncoding.DecodeString(s); err == nil {
+		data = decoded
+	}
+	var certs []interface{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		sans := make([]interface{}, 0)
+		for _, u := range cert.URIs {
+			sans = append(sans, u.String())
+		}
+		for _, d := range cert.DNSNames {
+			sans = append(sans, d)
+		}
+		certs = append(certs, map[string]interface{}{
+			"subject": cert.Subject.String(),
+			"issuer":  cert.Issuer.String(),
+			"sans":    sans,
+		})
+	}
+	return certs
+}
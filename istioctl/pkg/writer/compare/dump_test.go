@@ -0,0 +1,241 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+type dumpContents struct {
+	version   string
+	updated   int64
+	listeners []*listener.Listener
+	routes    []*route.RouteConfiguration
+	clusters  []*cluster.Cluster
+	secrets   []*tls.Secret
+}
+
+func mustAny(t *testing.T, m proto.Message) *any.Any {
+	t.Helper()
+	a, err := ptypes.MarshalAny(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func buildDump(t *testing.T, c dumpContents) *configdump.Wrapper {
+	t.Helper()
+	ts := &timestamp.Timestamp{Seconds: c.updated}
+	ld := &adminapi.ListenersConfigDump{VersionInfo: c.version}
+	for _, l := range c.listeners {
+		ld.DynamicListeners = append(ld.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name: l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{
+				VersionInfo: c.version, Listener: mustAny(t, l), LastUpdated: ts,
+			},
+		})
+	}
+	rd := &adminapi.RoutesConfigDump{}
+	for _, r := range c.routes {
+		rd.DynamicRouteConfigs = append(rd.DynamicRouteConfigs, &adminapi.RoutesConfigDump_DynamicRouteConfig{
+			VersionInfo: c.version, RouteConfig: mustAny(t, r), LastUpdated: ts,
+		})
+	}
+	cd := &adminapi.ClustersConfigDump{VersionInfo: c.version}
+	for _, cl := range c.clusters {
+		cd.DynamicActiveClusters = append(cd.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			VersionInfo: c.version, Cluster: mustAny(t, cl), LastUpdated: ts,
+		})
+	}
+	sd := &adminapi.SecretsConfigDump{}
+	for _, s := range c.secrets {
+		sd.DynamicActiveSecrets = append(sd.DynamicActiveSecrets, &adminapi.SecretsConfigDump_DynamicSecret{
+			Name: s.Name, VersionInfo: c.version, Secret: mustAny(t, s), LastUpdated: ts,
+		})
+	}
+	return &configdump.Wrapper{ConfigDump: &adminapi.ConfigDump{
+		Configs: []*any.Any{mustAny(t, ld), mustAny(t, rd), mustAny(t, cd), mustAny(t, sd)},
+	}}
+}
+
+func newCertSecret(t *testing.T, host string) *tls.Secret {
+	t.Helper()
+	cert, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         host,
+		NotBefore:    time.Now(),
+		TTL:          time.Hour,
+		Org:          "cluster.local",
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Secret{
+		Name: "default",
+		Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{
+			CertificateChain: &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: cert}},
+			PrivateKey:       &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "[redacted]"}},
+		}},
+	}
+}
+
+func TestDumpComparator(t *testing.T) {
+	outbound := &cluster.Cluster{Name: "outbound|9080||reviews.default.svc.cluster.local", ConnectTimeout: ptypes.DurationProto(time.Second)}
+	inbound := &cluster.Cluster{Name: "inbound|9080||"}
+	productpage := &route.RouteConfiguration{Name: "9080", VirtualHosts: []*route.VirtualHost{
+		{Name: "reviews.default.svc.cluster.local:9080", Domains: []string{"reviews"}},
+		{Name: "allow_any", Domains: []string{"*"}},
+	}}
+	// Same virtual hosts in another order
+	reordered := &route.RouteConfiguration{Name: "9080", VirtualHosts: []*route.VirtualHost{
+		productpage.VirtualHosts[1], productpage.VirtualHosts[0],
+	}}
+	secret := newCertSecret(t, "spiffe://cluster.local/ns/default/sa/productpage")
+	// Reissued certificate for the same identity, with a new serial and validity
+	rotated := newCertSecret(t, "spiffe://cluster.local/ns/default/sa/productpage")
+
+	base := buildDump(t, dumpContents{
+		version:   "2020-10-01T00:00:00Z/1",
+		updated:   100,
+		listeners: []*listener.Listener{{Name: "virtualInbound"}, {Name: "0.0.0.0_9080"}},
+		routes:    []*route.RouteConfiguration{productpage},
+		clusters:  []*cluster.Cluster{outbound, inbound},
+		secrets:   []*tls.Secret{secret},
+	})
+
+	cases := []struct {
+		name     string
+		to       dumpContents
+		types    []ResourceType
+		want     []ResourceDiff
+		contains []string
+	}{
+		{
+			name: "only volatile fields differ",
+			to: dumpContents{
+				version:   "2020-10-02T00:00:00Z/7",
+				updated:   200,
+				listeners: []*listener.Listener{{Name: "0.0.0.0_9080"}, {Name: "virtualInbound"}},
+				routes:    []*route.RouteConfiguration{reordered},
+				clusters:  []*cluster.Cluster{inbound, outbound},
+				secrets:   []*tls.Secret{rotated},
+			},
+			want: []ResourceDiff{},
+		},
+		{
+			name: "resources added, removed and modified",
+			to: dumpContents{
+				version:   "2020-10-02T00:00:00Z/7",
+				updated:   200,
+				listeners: []*listener.Listener{{Name: "virtualInbound"}, {Name: "0.0.0.0_15443"}},
+				routes:    []*route.RouteConfiguration{productpage},
+				clusters: []*cluster.Cluster{
+					{Name: outbound.Name, ConnectTimeout: ptypes.DurationProto(10 * time.Second)},
+					inbound,
+				},
+				secrets: []*tls.Secret{newCertSecret(t, "spiffe://cluster.local/ns/default/sa/reviews")},
+			},
+			want: []ResourceDiff{
+				{Type: ListenerResource, Name: "0.0.0.0_15443", Change: Added},
+				{Type: ListenerResource, Name: "0.0.0.0_9080", Change: Removed},
+				{Type: ClusterResource, Name: outbound.Name, Change: Modified},
+				{Type: SecretResource, Name: "default", Change: Modified},
+			},
+			contains: []string{`-   "connectTimeout": "1s"`, `+   "connectTimeout": "10s"`, "sa/reviews"},
+		},
+		{
+			name: "restricted to types",
+			to: dumpContents{
+				listeners: []*listener.Listener{{Name: "virtualInbound"}},
+				clusters:  []*cluster.Cluster{inbound, outbound},
+			},
+			types: []ResourceType{ClusterResource},
+			want:  []ResourceDiff{},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewDumpComparator(&bytes.Buffer{}, "before", base, "after", buildDump(t, tt.to))
+			got, err := c.Diff(tt.types...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d diffs, want %d: %+v", len(got), len(tt.want), got)
+			}
+			diffs := ""
+			for i, d := range got {
+				w := tt.want[i]
+				if d.Type != w.Type || d.Name != w.Name || d.Change != w.Change {
+					t.Errorf("diff %d: got %s %s %s, want %s %s %s", i, d.Change, d.Type, d.Name, w.Change, w.Type, w.Name)
+				}
+				if (d.Change == Modified) != (d.Diff != "") {
+					t.Errorf("diff %d: unexpected diff text %q", i, d.Diff)
+				}
+				diffs += d.Diff
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(diffs, s) {
+					t.Errorf("expected diff to contain %q, got:\n%s", s, diffs)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpComparatorPrint(t *testing.T) {
+	from := buildDump(t, dumpContents{
+		listeners: []*listener.Listener{{Name: "virtualInbound"}},
+		clusters:  []*cluster.Cluster{{Name: "inbound|9080||"}},
+	})
+	to := buildDump(t, dumpContents{
+		listeners: []*listener.Listener{{Name: "virtualInbound"}, {Name: "0.0.0.0_9080"}},
+		clusters:  []*cluster.Cluster{{Name: "inbound|9080||"}},
+	})
+	out := &bytes.Buffer{}
+	if err := NewDumpComparator(out, "a", from, "b", to).Print(); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- a
++++ b
+Listeners: 1 added, 0 removed, 0 modified
+  + 0.0.0.0_9080
+Routes Match
+Clusters Match
+Secrets Match
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -138,7 +138,37 @@ var (
 	fileMountedCertsEnv = env.RegisterBoolVar("FILE_MOUNTED_CERTS", false, "").Get()
 	useTokenForCSREnv   = env.RegisterBoolVar("USE_TOKEN_FOR_CSR", false, "CSR requires a token").Get()
 	credFetcherTypeEnv  = env.RegisterStringVar("CREDENTIAL_FETCHER_TYPE", "",
-		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
+		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine, "+
+			"HTTPMetadata and TokenFile").Get()
+	credFetcherAudienceEnv = env.RegisterStringVar("CREDENTIAL_FETCHER_AUDIENCE", "",
+		"The audience of the token requested by the credential fetcher. Defaults to the trust domain").Get()
+	credFetcherURLEnv = env.RegisterStringVar("CREDENTIAL_FETCHER_METADATA_URL", "",
+		"The URL of the identity token on the metadata server, for the HTTPMetadata credential fetcher").Get()
+	credFetcherHeadersEnv = env.RegisterStringVar("CREDENTIAL_FETCHER_METADATA_HEADERS", "",
+		"Comma separated list of key=value headers sent to the metadata server, for the HTTPMetadata credential fetcher").Get()
+	credFetcherSessionURLEnv = env.RegisterStringVar("CREDENTIAL_FETCHER_SESSION_TOKEN_URL", "",
+		"If set, the HTTPMetadata credential fetcher obtains a session token from this URL with PUT, "+
+			"and sends it in the CREDENTIAL_FETCHER_SESSION_TOKEN_HEADER header").Get()
+	credFetcherSessionHeaderEnv = env.RegisterStringVar("CREDENTIAL_FETCHER_SESSION_TOKEN_HEADER", "",
+		"The header carrying the session token, for the HTTPMetadata credential fetcher").Get()
+	credFetcherTokenFileEnv = env.RegisterStringVar("CREDENTIAL_FETCHER_TOKEN_FILE", "",
+		"The token file, rotated by another agent, read by the TokenFile credential fetcher").Get()
+	vaultAddrEnv = env.RegisterStringVar("VAULT_ADDR", "",
+		"The address of the Vault server, for the "+istio_agent.VaultCAProvider+" CA provider").Get()
+	vaultAuthMethodEnv = env.RegisterStringVar("VAULT_AUTH_METHOD", "kubernetes",
+		"The Vault auth method: kubernetes, jwt or approle").Get()
+	vaultAuthPathEnv = env.RegisterStringVar("VAULT_AUTH_PATH", "",
+		"The path of the Vault login endpoint. Defaults to the default mount of the auth method").Get()
+	vaultRoleEnv = env.RegisterStringVar("VAULT_ROLE", "",
+		"The Vault role, for the kubernetes and jwt auth methods").Get()
+	vaultAppRoleIDEnv = env.RegisterStringVar("VAULT_APPROLE_ROLE_ID", "",
+		"The Vault AppRole role ID, for the approle auth method").Get()
+	vaultAppRoleSecretIDPathEnv = env.RegisterStringVar("VAULT_APPROLE_SECRET_ID_PATH", "",
+		"The file holding the Vault AppRole secret ID, for the approle auth method").Get()
+	vaultSignCsrPathEnv = env.RegisterStringVar("VAULT_SIGN_CSR_PATH", "",
+		"The Vault path used to sign CSRs. It may reference {{.Namespace}} and {{.ServiceAccount}}").Get()
+	vaultTLSRootCertEnv = env.RegisterStringVar("VAULT_TLS_ROOT_CERT", "",
+		"The PEM encoded root certificate of the Vault server, in addition to the system roots").Get()
 	credIdentityProvider = env.RegisterStringVar("CREDENTIAL_IDENTITY_PROVIDER", "GoogleComputeEngine",
 		"The identity provider for credential. Currently default supported identity provider is GoogleComputeEngine").Get()
 	proxyXDSViaAgent = env.RegisterBoolVar("PROXY_XDS_VIA_AGENT", true,
@@ -263,6 +293,14 @@ var (
 			secOpts.EnableWorkloadSDS = true
 			secOpts.EnableGatewaySDS = enableGatewaySDSEnv
 			secOpts.CAProviderName = caProviderEnv
+			secOpts.VaultAddress = vaultAddrEnv
+			secOpts.VaultAuthMethod = vaultAuthMethodEnv
+			secOpts.VaultAuthPath = vaultAuthPathEnv
+			secOpts.VaultRole = vaultRoleEnv
+			secOpts.VaultAppRoleID = vaultAppRoleIDEnv
+			secOpts.VaultAppRoleSecretIDPath = vaultAppRoleSecretIDPathEnv
+			secOpts.VaultSignCsrPath = vaultSignCsrPathEnv
+			secOpts.VaultTLSRootCert = vaultTLSRootCertEnv
 
 			secOpts.TrustDomain = trustDomainEnv
 			secOpts.Pkcs8Keys = pkcs8KeysEnv
@@ -275,11 +313,23 @@ var (
 			// Disable the secret eviction for istio agent.
 			secOpts.EvictionDuration = 0
 
-			// TODO (liminw): CredFetcher is a general interface. In 1.7, we limit the use on GCE only because
-			// GCE is the only supported plugin at the moment.
-			if credFetcherTypeEnv == security.GCE {
+			if credFetcherTypeEnv != "" {
+				if credFetcherTypeEnv == security.Mock {
+					return fmt.Errorf("credential fetcher type %s is only supported in tests", security.Mock)
+				}
 				secOpts.CredIdentityProvider = credIdentityProvider
-				credFetcher, err := credentialfetcher.NewCredFetcher(credFetcherTypeEnv, secOpts.TrustDomain, jwtPath, secOpts.CredIdentityProvider)
+				credFetcher, err := credentialfetcher.NewCredFetcherWithOptions(credentialfetcher.Options{
+					Type:               credFetcherTypeEnv,
+					TrustDomain:        secOpts.TrustDomain,
+					JWTPath:            jwtPath,
+					IdentityProvider:   secOpts.CredIdentityProvider,
+					Audience:           credFetcherAudienceEnv,
+					MetadataURL:        credFetcherURLEnv,
+					MetadataHeaders:    parseCredFetcherHeaders(credFetcherHeadersEnv),
+					SessionTokenURL:    credFetcherSessionURLEnv,
+					SessionTokenHeader: credFetcherSessionHeaderEnv,
+					TokenFile:          credFetcherTokenFileEnv,
+				})
 				if err != nil {
 					return fmt.Errorf("failed to create credential fetcher: %v", err)
 				}
@@ -400,6 +450,19 @@ func extractXDSHeadersFromEnv(config *istio_agent.AgentConfig) {
 	}
 }
 
+// parseCredFetcherHeaders parses a comma separated list of key=value headers.
+func parseCredFetcherHeaders(in string) map[string]string {
+	headers := map[string]string{}
+	for _, kv := range strings.Split(in, ",") {
+		parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		headers[parts[0]] = parts[1]
+	}
+	return headers
+}
+
 func initStatusServer(ctx context.Context, proxyIPv6 bool, proxyConfig meshconfig.ProxyConfig) error {
 	localHostAddr := localHostIPv4
 	if proxyIPv6 {
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/server/components"
 	"istio.io/istio/galley/pkg/server/settings"
 	configaggregate "istio.io/istio/pilot/pkg/config/aggregate"
@@ -215,6 +216,15 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 	return nil
 }
 
+// analysisLevel returns the level of analysis messages with the given name.
+func analysisLevel(envVar, name string) (diag.Level, error) {
+	level, ok := diag.GetUppercaseStringToLevelMap()[strings.ToUpper(name)]
+	if !ok {
+		return diag.Level{}, fmt.Errorf("invalid %s %q, must be one of %v", envVar, name, diag.GetAllLevelStrings())
+	}
+	return level, nil
+}
+
 // initInprocessAnalysisController spins up an instance of Galley which serves no purpose other than
 // running Analyzers for status updates.  The Status Updater will eventually need to allow input from istiod
 // to support config distribution status as well.
@@ -225,6 +235,19 @@ func (s *Server) initInprocessAnalysisController(args *PilotArgs) error {
 	processingArgs.WatchedNamespaces = args.RegistryOptions.KubeOptions.WatchedNamespaces
 	processingArgs.MeshConfigFile = args.MeshConfigFile
 	processingArgs.EnableConfigAnalysis = true
+	processingArgs.AnalysisRulesDir = features.AnalysisRulesDir
+	processingArgs.EnableAnalysisEvents = features.EnableAnalysisEvents
+	processingArgs.AnalysisEventQPS = features.AnalysisEventQPS
+	processingArgs.AnalysisEventBurst = features.AnalysisEventBurst
+	processingArgs.EnableAnalysisMetrics = features.EnableAnalysisMetrics
+
+	var err error
+	if processingArgs.AnalysisEventThreshold, err = analysisLevel("PILOT_ANALYSIS_EVENT_LEVEL", features.AnalysisEventLevel); err != nil {
+		return err
+	}
+	if processingArgs.AnalysisMetricThreshold, err = analysisLevel("PILOT_ANALYSIS_METRIC_LEVEL", features.AnalysisMetricLevel); err != nil {
+		return err
+	}
 
 	processing := components.NewProcessing(processingArgs)
 
@@ -247,6 +270,7 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 	s.statusReporter = &status.Reporter{
 		UpdateInterval: time.Millisecond * 500, // TODO: use args here?
 		PodName:        args.PodName,
+		History:        s.environment.GetConfigHistory(),
 	}
 	s.statusReporter.Init(s.environment.GetLedger())
 	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
@@ -17,16 +17,19 @@ package bootstrap
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -35,11 +38,14 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/cmd"
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
 	caserver "istio.io/istio/security/pkg/server/ca"
+	"istio.io/istio/security/pkg/server/ca/audit"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/istio/security/pkg/server/ca/authorize"
 	"istio.io/pkg/env"
 	"istio.io/pkg/log"
 )
@@ -52,6 +58,7 @@ type caOptions struct {
 	TrustDomain    string
 	Namespace      string
 	Authenticators []authenticate.Authenticator
+	Authorizers    []authorize.Authorizer
 }
 
 // Based on istio_ca main - removing creation of Secrets with private keys in all namespaces and install complexity.
@@ -138,6 +145,38 @@ var (
 	//TODO: Likely to be removed and added to mesh config
 	k8sSigner = env.RegisterStringVar("K8S_SIGNER", "",
 		"Kubernates CA Signer type. Valid from Kubernates 1.18").Get()
+
+	caAuthorizationConfigMap = env.RegisterStringVar("CA_AUTHORIZATION_CONFIGMAP", "",
+		"Name of a ConfigMap in the istiod namespace holding a CA authorization policy under the "+
+			"'"+authorize.PolicyConfigMapKey+"' key. If set, CSRs are checked against the policy after authentication.")
+
+	caAuthenticatorConfig = env.RegisterStringVar("CA_AUTHENTICATOR_CONFIG", "",
+		"Path to a YAML file configuring additional CA authenticators: external SPIFFE trust domains "+
+			"whose X.509 SVIDs are accepted, and OIDC issuers whose tokens are accepted, each with rules "+
+			"mapping the external identity to a mesh identity.")
+
+	caAuditRecentRecords = env.RegisterIntVar("CA_AUDIT_RECENT_RECORDS", 1000,
+		"Number of recently issued certificates kept in memory and served on "+audit.DebugPath)
+
+	caAuditQueueSize = env.RegisterIntVar("CA_AUDIT_QUEUE_SIZE", 1000,
+		"Number of issued certificate records waiting to be written to the CA audit log file and sink. "+
+			"Records that arrive while the queue is full are dropped.")
+
+	caAuditLogFile = env.RegisterStringVar("CA_AUDIT_LOG_FILE", "",
+		"If set, a record of every certificate issued by the CA is appended to this file as JSON lines.")
+
+	caAuditLogMaxSizeMB = env.RegisterIntVar("CA_AUDIT_LOG_MAX_SIZE_MB", 100,
+		"Size in megabytes at which the CA audit log file is rotated.")
+
+	caAuditLogMaxBackups = env.RegisterIntVar("CA_AUDIT_LOG_MAX_BACKUPS", 10,
+		"Number of rotated CA audit log files to keep. Zero keeps all of them.")
+
+	caAuditSinkAddress = env.RegisterStringVar("CA_AUDIT_SINK_ADDRESS", "",
+		"If set, a record of every certificate issued by the CA is sent to the gRPC service at this address, "+
+			"using the "+audit.GRPCSinkMethod+" method.")
+
+	caAuditSinkInsecure = env.RegisterBoolVar("CA_AUDIT_SINK_INSECURE", false,
+		"If true, connect to CA_AUDIT_SINK_ADDRESS without TLS.")
 )
 
 // EnableCA returns whether CA functionality is enabled in istiod.
@@ -200,6 +239,8 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 	if startErr != nil {
 		log.Fatalf("failed to create istio ca server: %v", startErr)
 	}
+	caServer.Authorizers = opts.Authorizers
+	caServer.Auditor = s.caAuditor
 
 	// TODO: if not set, parse Istiod's own token (if present) and get the issuer. The same issuer is used
 	// for all tokens - no need to configure twice. The token may also include cluster info to auto-configure
@@ -222,6 +263,112 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 	log.Info("Istiod CA has started")
 }
 
+// caAuthenticatorChain holds the authenticators configured by CA_AUTHENTICATOR_CONFIG.
+type caAuthenticatorChain struct {
+	// x509SVID authenticators are evaluated before the built-in authenticators.
+	x509SVID []authenticate.Authenticator
+	// oidc authenticators are evaluated after the built-in authenticators.
+	oidc []authenticate.Authenticator
+	// roots of the external trust domains, which must be trusted by the secure gRPC server.
+	roots map[string][]*x509.Certificate
+}
+
+// initCAAuthenticatorChain builds the additional CA authenticators, if configured.
+func (s *Server) initCAAuthenticatorChain() error {
+	path := caAuthenticatorConfig.Get()
+	if path == "" || !s.EnableCA() {
+		return nil
+	}
+	cfg, err := authenticate.LoadChainConfig(path)
+	if err != nil {
+		return fmt.Errorf("failed to load CA authenticator config: %v", err)
+	}
+	trustDomain := spiffe.GetTrustDomain()
+	chain := &caAuthenticatorChain{}
+	if chain.x509SVID, chain.roots, err = cfg.BuildX509SVIDAuthenticators(trustDomain); err != nil {
+		return err
+	}
+	if chain.oidc, err = cfg.BuildOIDCAuthenticators(trustDomain); err != nil {
+		return err
+	}
+	log.Infof("Loaded CA authenticator config from %s: %d external trust domains, %d OIDC issuers",
+		path, len(chain.x509SVID), len(chain.oidc))
+	s.caAuthenticators = chain
+	return nil
+}
+
+// externalTrustDomains returns the trust domains of the configured X.509 SVID authenticators.
+func (c *caAuthenticatorChain) externalTrustDomains() []string {
+	if c == nil {
+		return nil
+	}
+	var trustDomains []string
+	for td := range c.roots {
+		trustDomains = append(trustDomains, td)
+	}
+	sort.Strings(trustDomains)
+	return trustDomains
+}
+
+// buildCAAuthenticators returns the built-in authenticators wrapped by the configured ones.
+func (s *Server) buildCAAuthenticators(builtin []authenticate.Authenticator) []authenticate.Authenticator {
+	if s.caAuthenticators == nil {
+		return builtin
+	}
+	authenticators := append([]authenticate.Authenticator{}, s.caAuthenticators.x509SVID...)
+	authenticators = append(authenticators, builtin...)
+	return append(authenticators, s.caAuthenticators.oidc...)
+}
+
+// initCAAuthorizers sets up the authorizers used by the CA server, based on the environment.
+func (s *Server) initCAAuthorizers(opts *caOptions, stop <-chan struct{}) {
+	name := caAuthorizationConfigMap.Get()
+	if name == "" {
+		return
+	}
+	if s.kubeClient == nil {
+		log.Warnf("CA authorization ConfigMap %s configured without Kubernetes access, ignoring", name)
+		return
+	}
+	log.Infof("Using CA authorization policy from ConfigMap %s/%s", opts.Namespace, name)
+	opts.Authorizers = append(opts.Authorizers,
+		authorize.NewConfigMapAuthorizer(s.kubeClient, opts.Namespace, name, authorize.PolicyConfigMapKey, stop))
+}
+
+// initCAAuditor sets up the recording of issued certificates, based on the environment.
+func (s *Server) initCAAuditor() error {
+	if !s.EnableCA() {
+		return nil
+	}
+	var sinks []audit.Sink
+	if path := caAuditLogFile.Get(); path != "" {
+		log.Infof("Recording issued certificates to %s", path)
+		sinks = append(sinks, audit.NewFileSink(audit.FileOptions{
+			Path:       path,
+			MaxSizeMB:  caAuditLogMaxSizeMB.Get(),
+			MaxBackups: caAuditLogMaxBackups.Get(),
+		}))
+	}
+	if addr := caAuditSinkAddress.Get(); addr != "" {
+		opt := grpc.WithTransportCredentials(credentials.NewTLS(nil))
+		if caAuditSinkInsecure.Get() {
+			opt = grpc.WithInsecure()
+		}
+		conn, err := grpc.Dial(addr, opt)
+		if err != nil {
+			return fmt.Errorf("failed to dial CA audit sink %s: %v", addr, err)
+		}
+		log.Infof("Sending issued certificate records to %s", addr)
+		sinks = append(sinks, audit.NewGRPCSink(conn, 5*time.Second))
+	}
+	s.caAuditor = audit.NewAuditor(caAuditRecentRecords.Get(), caAuditQueueSize.Get(), sinks...)
+	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+		<-stop
+		return s.caAuditor.Close()
+	})
+	return nil
+}
+
 // detectAuthEnv will use the JWT token that is mounted in istiod to set the default audience
 // and trust domain for Istiod, if not explicitly defined.
 // K8S will use the same kind of tokens for the pods, and the value in istiod's own token is
@@ -65,6 +65,7 @@ import (
 	"istio.io/istio/security/pkg/k8s/chiron"
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
+	"istio.io/istio/security/pkg/server/ca/audit"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 	"istio.io/pkg/ctrlz"
 	"istio.io/pkg/filewatcher"
@@ -158,6 +159,9 @@ type Server struct {
 	certController *chiron.WebhookController
 	CA             *ca.IstioCA
 	RA             ra.RegistrationAuthority
+	caAuditor      *audit.Auditor
+	// caAuthenticators are the CA authenticators configured in addition to the built-in ones.
+	caAuthenticators *caAuthenticatorChain
 	// path to the caBundle that signs the DNS certs. This should be agnostic to provider.
 	caBundlePath string
 	certMu       sync.Mutex
@@ -187,6 +191,7 @@ func NewServer(args *PilotArgs) (*Server, error) {
 		DomainSuffix: args.RegistryOptions.KubeOptions.DomainSuffix,
 	}
 	e.SetLedger(buildLedger(args.RegistryOptions))
+	e.SetConfigHistory(buildConfigHistory(args.RegistryOptions))
 	ac := aggregate.NewController(aggregate.Options{
 		MeshHolder: e,
 	})
@@ -258,6 +263,12 @@ func NewServer(args *PilotArgs) (*Server, error) {
 	if err := s.maybeCreateCA(caOpts); err != nil {
 		return nil, err
 	}
+	if err := s.initCAAuditor(); err != nil {
+		return nil, err
+	}
+	if err := s.initCAAuthenticatorChain(); err != nil {
+		return nil, err
+	}
 
 	// Create Istiod certs and setup watches.
 	if err := s.initIstiodCerts(args, string(istiodHost)); err != nil {
@@ -309,12 +320,13 @@ func NewServer(args *PilotArgs) (*Server, error) {
 	// authenticators are activated sequentially and the first successful attempt
 	// is used as the authentication result.
 	// The JWT authenticator requires the multicluster registry to be initialized, so we build this later
+	// Certificates of external trust domains are only accepted by the CA, once mapped to mesh identities.
 	authenticators := []authenticate.Authenticator{
-		&authenticate.ClientCertAuthenticator{},
+		&authenticate.ClientCertAuthenticator{ExternalTrustDomains: s.caAuthenticators.externalTrustDomains()},
 		authenticate.NewKubeJWTAuthenticator(s.kubeClient, s.clusterID, s.multicluster.GetRemoteKubeClient, spiffe.GetTrustDomain(), features.JwtPolicy.Get()),
 	}
 
-	caOpts.Authenticators = authenticators
+	caOpts.Authenticators = s.buildCAAuthenticators(authenticators)
 	if features.XDSAuth {
 		s.XDSServer.Authenticators = authenticators
 	}
@@ -534,8 +546,8 @@ func (s *Server) initIstiodAdminServer(args *PilotArgs, wh *inject.Webhook) erro
 		log.Info("initializing Istiod admin server")
 	}
 
-	whc := func() string {
-		return wh.Config.Template
+	whc := func() *inject.Config {
+		return wh.Config
 	}
 
 	// Debug Server.
@@ -546,6 +558,20 @@ func (s *Server) initIstiodAdminServer(args *PilotArgs, wh *inject.Webhook) erro
 	if !shouldMultiplex {
 		s.XDSServer.AddDebugHandlers(s.httpMux, args.ServerOptions.EnableProfiling, whc)
 	}
+	if s.caAuditor != nil && features.EnableDebugOnHTTP {
+		s.monitoringMux.Handle(audit.DebugPath, s.caAuditor)
+		if !shouldMultiplex {
+			s.httpMux.Handle(audit.DebugPath, s.caAuditor)
+		}
+	}
+	if wh != nil && features.EnableDebugOnHTTP {
+		s.monitoringMux.HandleFunc(inject.ExplainPath, wh.ServeExplain)
+		s.monitoringMux.HandleFunc(inject.ExplainPath+"/", wh.ServeExplain)
+		if !shouldMultiplex {
+			s.httpMux.HandleFunc(inject.ExplainPath, wh.ServeExplain)
+			s.httpMux.HandleFunc(inject.ExplainPath+"/", wh.ServeExplain)
+		}
+	}
 
 	// Monitoring Server.
 	if err := s.initMonitor(args.ServerOptions.MonitoringAddr); err != nil {
@@ -1003,6 +1029,11 @@ func (s *Server) setPeerCertVerifier(tlsOptions TLSOptions) error {
 		s.peerCertVerifier.AddMappings(certMap)
 	}
 
+	if s.caAuthenticators != nil {
+		// Client certificates from external trust domains are authenticated by the CA.
+		s.peerCertVerifier.AddMappings(s.caAuthenticators.roots)
+	}
+
 	return nil
 }
 
@@ -1117,6 +1148,7 @@ func (s *Server) startCA(caOpts *caOptions) {
 		if s.secureGrpcServer == nil {
 			grpcServer = s.grpcServer
 		}
+		s.initCAAuthorizers(caOpts, stop)
 		// Start the RA server if configured, else start the CA server
 		if s.RA != nil {
 			log.Infof("Starting RA")
@@ -31,7 +31,24 @@ import (
 	"istio.io/pkg/filewatcher"
 )
 
+// chdirTemp runs the test in a temporary working directory, so that the files Istiod keeps under ./var are
+// not written to the source tree.
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
 func TestNewServerWithExternalCertificates(t *testing.T) {
+	chdirTemp(t)
 	configDir, err := ioutil.TempDir("", "test_istiod_config")
 	if err != nil {
 		t.Fatal(err)
@@ -196,6 +213,7 @@ func TestNewServer(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
+			chdirTemp(t)
 			configDir, err := ioutil.TempDir("", "TestNewServer")
 			if err != nil {
 				t.Fatal(err)
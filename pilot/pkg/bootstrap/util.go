@@ -38,3 +38,10 @@ func buildLedger(ca RegistryOptions) ledger.Ledger {
 	}
 	return result
 }
+
+func buildConfigHistory(ca RegistryOptions) *model.ConfigHistory {
+	if !ca.DistributionTrackingEnabled {
+		return nil
+	}
+	return model.NewConfigHistory(ca.DistributionCacheRetention)
+}
@@ -240,6 +240,51 @@ var (
 			"Istio Resources",
 	).Get()
 
+	AnalysisRulesDir = env.RegisterStringVar(
+		"PILOT_ANALYSIS_RULES_DIR",
+		"",
+		"If set, pilot will also run the analyzers defined by the rule files in this directory when "+
+			"PILOT_ENABLE_ANALYSIS is enabled. See istioctl analyze --rules-dir for the format of the rules.",
+	).Get()
+
+	EnableAnalysisEvents = env.RegisterBoolVar(
+		"PILOT_ENABLE_ANALYSIS_EVENTS",
+		false,
+		"If enabled, pilot will also publish analysis messages as Kubernetes events on the resources they are "+
+			"reported for. PILOT_ENABLE_ANALYSIS must be enabled as well.",
+	).Get()
+
+	AnalysisEventLevel = env.RegisterStringVar(
+		"PILOT_ANALYSIS_EVENT_LEVEL",
+		"Warning",
+		"The minimum level (Info, Warning or Error) of the analysis messages that are published as events.",
+	).Get()
+
+	AnalysisEventQPS = env.RegisterFloatVar(
+		"PILOT_ANALYSIS_EVENT_QPS",
+		5,
+		"If analysis events are enabled, controls the QPS with which events will be published.",
+	).Get()
+
+	AnalysisEventBurst = env.RegisterIntVar(
+		"PILOT_ANALYSIS_EVENT_BURST",
+		50,
+		"If analysis events are enabled, controls the Burst rate with which events will be published.",
+	).Get()
+
+	EnableAnalysisMetrics = env.RegisterBoolVar(
+		"PILOT_ENABLE_ANALYSIS_METRICS",
+		true,
+		"If enabled, pilot will also publish the number of analysis messages by code, namespace and level "+
+			"as the galley_analysis_messages metric. PILOT_ENABLE_ANALYSIS must be enabled as well.",
+	).Get()
+
+	AnalysisMetricLevel = env.RegisterStringVar(
+		"PILOT_ANALYSIS_METRIC_LEVEL",
+		"Info",
+		"The minimum level (Info, Warning or Error) of the analysis messages that are counted by the metrics.",
+	).Get()
+
 	EnableStatus = env.RegisterBoolVar(
 		"PILOT_ENABLE_STATUS",
 		false,
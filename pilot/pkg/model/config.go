@@ -221,9 +221,9 @@ func ResolveShortnameToFQDN(hostname string, meta config.Meta) host.Name {
 	return host.Name(out)
 }
 
-// resolveGatewayName uses metadata information to resolve a reference
+// ResolveGatewayName uses metadata information to resolve a reference
 // to shortname of the gateway to FQDN
-func resolveGatewayName(gwname string, meta config.Meta) string {
+func ResolveGatewayName(gwname string, meta config.Meta) string {
 	out := gwname
 
 	// New way of binding to a gateway in remote namespace
@@ -0,0 +1,221 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/ledger"
+)
+
+// ConfigChangeOp describes how a config changed in a ConfigHistoryEntry.
+type ConfigChangeOp string
+
+const (
+	ConfigAdded   ConfigChangeOp = "added"
+	ConfigUpdated ConfigChangeOp = "updated"
+	ConfigDeleted ConfigChangeOp = "deleted"
+)
+
+// DefaultConfigHistoryEntries bounds the number of entries kept by a ConfigHistory, regardless of retention.
+const DefaultConfigHistoryEntries = 1000
+
+// ConfigHistoryEntry is a single config change, and the ledger version it produced.
+type ConfigHistoryEntry struct {
+	Version         string         `json:"version"`
+	Time            time.Time      `json:"time"`
+	Op              ConfigChangeOp `json:"op"`
+	Kind            string         `json:"kind"`
+	Name            string         `json:"name"`
+	Namespace       string         `json:"namespace"`
+	ResourceVersion string         `json:"resourceVersion,omitempty"`
+
+	// previous is the config before the change, or nil if it was added.
+	previous *config.Config
+}
+
+// ConfigHistory records every config written to the config ledger, so that recent ledger versions can be
+// listed with the change that produced them, and the full config at a retained version can be rebuilt.
+// The ledger itself only allows lookups by key, which is not enough to answer either question.
+type ConfigHistory struct {
+	mu         sync.RWMutex
+	retention  time.Duration
+	maxEntries int
+	// entries are ordered from oldest to newest.
+	entries []ConfigHistoryEntry
+	current map[string]config.Config
+	now     func() time.Time
+}
+
+// NewConfigHistory creates a ConfigHistory which keeps entries for the given retention, in line with the
+// ledger's own history retention.
+func NewConfigHistory(retention time.Duration) *ConfigHistory {
+	return &ConfigHistory{
+		retention:  retention,
+		maxEntries: DefaultConfigHistoryEntries,
+		current:    map[string]config.Config{},
+		now:        time.Now,
+	}
+}
+
+func historyKey(cfg config.Config) string {
+	return config.Key(cfg.GroupVersionKind.Kind, cfg.Name, cfg.Namespace)
+}
+
+// Put writes cfg to the ledger and records it as the change that produced the new ledger version.
+func (h *ConfigHistory) Put(l ledger.Ledger, cfg config.Config) error {
+	key := historyKey(cfg)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := l.Put(key, cfg.ResourceVersion); err != nil {
+		return err
+	}
+	updated := cfg.DeepCopy()
+	entry := h.newEntry(l, ConfigAdded, updated)
+	if prev, f := h.current[key]; f {
+		entry.Op = ConfigUpdated
+		entry.previous = &prev
+	}
+	h.current[key] = updated
+	h.record(entry)
+	return nil
+}
+
+// Delete removes cfg from the ledger and records the removal as the change that produced the new ledger version.
+func (h *ConfigHistory) Delete(l ledger.Ledger, cfg config.Config) error {
+	key := historyKey(cfg)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := l.Delete(key); err != nil {
+		return err
+	}
+	entry := h.newEntry(l, ConfigDeleted, cfg)
+	if prev, f := h.current[key]; f {
+		entry.previous = &prev
+		entry.ResourceVersion = prev.ResourceVersion
+	}
+	delete(h.current, key)
+	h.record(entry)
+	return nil
+}
+
+func (h *ConfigHistory) newEntry(l ledger.Ledger, op ConfigChangeOp, cfg config.Config) ConfigHistoryEntry {
+	return ConfigHistoryEntry{
+		Version:         l.RootHash(),
+		Time:            h.now(),
+		Op:              op,
+		Kind:            cfg.GroupVersionKind.Kind,
+		Name:            cfg.Name,
+		Namespace:       cfg.Namespace,
+		ResourceVersion: cfg.ResourceVersion,
+	}
+}
+
+// record appends entry and drops the entries that are no longer retained. Must be called with the lock held.
+func (h *ConfigHistory) record(entry ConfigHistoryEntry) {
+	h.entries = append(h.entries, entry)
+	drop := 0
+	if len(h.entries) > h.maxEntries {
+		drop = len(h.entries) - h.maxEntries
+	}
+	if h.retention > 0 {
+		cutoff := entry.Time.Add(-h.retention)
+		for drop < len(h.entries)-1 && h.entries[drop].Time.Before(cutoff) {
+			drop++
+		}
+	}
+	if drop > 0 {
+		h.entries = append([]ConfigHistoryEntry(nil), h.entries[drop:]...)
+	}
+}
+
+// Entries returns the retained history, newest first.
+func (h *ConfigHistory) Entries() []ConfigHistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]ConfigHistoryEntry, 0, len(h.entries))
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		out = append(out, h.entries[i])
+	}
+	return out
+}
+
+// Retained reports whether the config at version can still be rebuilt.
+func (h *ConfigHistory) Retained(version string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.indexOf(version) >= 0
+}
+
+// Before reports whether version a was produced before version b. Both versions must be retained.
+func (h *ConfigHistory) Before(a, b string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.indexOf(a) < h.indexOf(b)
+}
+
+// indexOf returns the index of the newest entry that produced version, or -1. The ledger is a hash of its
+// content, so the same version may be produced more than once. A change may also leave the version as it
+// was, as the ledger does not reflect deletions in its root hash; the newest state is used in both cases.
+func (h *ConfigHistory) indexOf(version string) int {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// ConfigsAt returns all configs as they were at the given ledger version, sorted by kind, namespace and name.
+func (h *ConfigHistory) ConfigsAt(version string) ([]config.Config, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	idx := h.indexOf(version)
+	if idx < 0 {
+		return nil, fmt.Errorf("config version %q is not in the retained history", version)
+	}
+	state := make(map[string]config.Config, len(h.current))
+	for k, v := range h.current {
+		state[k] = v
+	}
+	// Undo every change made after the requested version, newest first.
+	for i := len(h.entries) - 1; i > idx; i-- {
+		e := h.entries[i]
+		key := config.Key(e.Kind, e.Name, e.Namespace)
+		if e.previous != nil {
+			state[key] = *e.previous
+		} else {
+			delete(state, key)
+		}
+	}
+	out := make([]config.Config, 0, len(state))
+	for _, v := range state {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].GroupVersionKind.Kind != out[j].GroupVersionKind.Kind {
+			return out[i].GroupVersionKind.Kind < out[j].GroupVersionKind.Kind
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
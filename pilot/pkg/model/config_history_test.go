@@ -0,0 +1,161 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/pkg/ledger"
+)
+
+func historyTestConfig(name, resourceVersion string, hosts ...string) config.Config {
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.VirtualService,
+			Name:             name,
+			Namespace:        "default",
+			ResourceVersion:  resourceVersion,
+		},
+		Spec: &networking.VirtualService{Hosts: hosts},
+	}
+}
+
+func configSummary(cfgs []config.Config) map[string]string {
+	out := map[string]string{}
+	for _, c := range cfgs {
+		out[c.Name] = c.ResourceVersion
+	}
+	return out
+}
+
+func TestConfigHistory(t *testing.T) {
+	l := ledger.Make(time.Minute)
+	h := NewConfigHistory(time.Minute)
+
+	if err := h.Put(l, historyTestConfig("a", "1", "a.com")); err != nil {
+		t.Fatal(err)
+	}
+	v1 := l.RootHash()
+	if err := h.Put(l, historyTestConfig("b", "2", "b.com")); err != nil {
+		t.Fatal(err)
+	}
+	v2 := l.RootHash()
+	if err := h.Put(l, historyTestConfig("a", "3", "a.org")); err != nil {
+		t.Fatal(err)
+	}
+	v3 := l.RootHash()
+	if err := h.Delete(l, historyTestConfig("b", "4")); err != nil {
+		t.Fatal(err)
+	}
+	v4 := l.RootHash()
+
+	entries := h.Entries()
+	wantOps := []struct {
+		version string
+		op      ConfigChangeOp
+		name    string
+		rv      string
+	}{
+		{v4, ConfigDeleted, "b", "2"},
+		{v3, ConfigUpdated, "a", "3"},
+		{v2, ConfigAdded, "b", "2"},
+		{v1, ConfigAdded, "a", "1"},
+	}
+	if len(entries) != len(wantOps) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantOps))
+	}
+	for i, want := range wantOps {
+		got := entries[i]
+		if got.Version != want.version || got.Op != want.op || got.Name != want.name || got.ResourceVersion != want.rv {
+			t.Errorf("entry %d: got %+v, want %+v", i, got, want)
+		}
+	}
+
+	cases := []struct {
+		version string
+		want    map[string]string
+	}{
+		{v1, map[string]string{"a": "1"}},
+		{v2, map[string]string{"a": "1", "b": "2"}},
+		{v4, map[string]string{"a": "3"}},
+	}
+	for _, tt := range cases {
+		cfgs, err := h.ConfigsAt(tt.version)
+		if err != nil {
+			t.Fatalf("version %s: %v", tt.version, err)
+		}
+		got := configSummary(cfgs)
+		if len(got) != len(tt.want) {
+			t.Errorf("version %s: got %v, want %v", tt.version, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("version %s: got %v, want %v", tt.version, got, tt.want)
+			}
+		}
+	}
+	cfgs, _ := h.ConfigsAt(v2)
+	for _, c := range cfgs {
+		if c.Name == "a" && c.Spec.(*networking.VirtualService).Hosts[0] != "a.com" {
+			t.Errorf("config a at %s has hosts %v, want a.com", v2, c.Spec.(*networking.VirtualService).Hosts)
+		}
+	}
+
+	if !h.Before(v1, v3) || h.Before(v3, v1) {
+		t.Errorf("expected %s to be before %s", v1, v3)
+	}
+	if _, err := h.ConfigsAt("unknown"); err == nil {
+		t.Errorf("expected error for unknown version")
+	}
+}
+
+func TestConfigHistoryRetention(t *testing.T) {
+	l := ledger.Make(time.Minute)
+	h := NewConfigHistory(time.Minute)
+	h.maxEntries = 3
+	now := time.Unix(0, 0)
+	h.now = func() time.Time { return now }
+
+	var versions []string
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := h.Put(l, historyTestConfig(name, "1")); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, l.RootHash())
+	}
+	if h.Retained(versions[0]) || !h.Retained(versions[1]) {
+		t.Errorf("expected only the last 3 versions to be retained, got %d entries", len(h.Entries()))
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := h.Put(l, historyTestConfig("e", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if entries := h.Entries(); len(entries) != 1 || entries[0].Name != "e" {
+		t.Errorf("expected expired entries to be dropped, got %+v", entries)
+	}
+	cfgs, err := h.ConfigsAt(l.RootHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfgs) != 5 {
+		t.Errorf("expected 5 configs at latest version, got %d", len(cfgs))
+	}
+}
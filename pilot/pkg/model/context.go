@@ -76,6 +76,8 @@ type Environment struct {
 	DomainSuffix string
 
 	ledger ledger.Ledger
+
+	configHistory *ConfigHistory
 }
 
 func (e *Environment) GetDomainSuffix() string {
@@ -148,6 +150,15 @@ func (e *Environment) SetLedger(l ledger.Ledger) {
 	e.ledger = l
 }
 
+// GetConfigHistory returns the history of ledger versions, or nil if distribution tracking is disabled.
+func (e *Environment) GetConfigHistory() *ConfigHistory {
+	return e.configHistory
+}
+
+func (e *Environment) SetConfigHistory(h *ConfigHistory) {
+	e.configHistory = h
+}
+
 // Request is an alias for array of marshaled resources.
 type Resources = []*any.Any
 
@@ -1264,7 +1264,7 @@ func getGatewayNames(vs *networking.VirtualService, meta config.Meta) []string {
 		if g == constants.IstioMeshGateway {
 			res = append(res, constants.IstioMeshGateway)
 		} else {
-			name := resolveGatewayName(g, meta)
+			name := ResolveGatewayName(g, meta)
 			res = append(res, name)
 		}
 	}
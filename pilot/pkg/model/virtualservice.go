@@ -35,7 +35,7 @@ func resolveVirtualServiceShortnames(rule *networking.VirtualService, meta confi
 	// resolve gateways to bind to
 	for i, g := range rule.Gateways {
 		if g != constants.IstioMeshGateway {
-			rule.Gateways[i] = resolveGatewayName(g, meta)
+			rule.Gateways[i] = ResolveGatewayName(g, meta)
 		}
 	}
 	// resolve host in http route.destination, route.mirror
@@ -43,7 +43,7 @@ func resolveVirtualServiceShortnames(rule *networking.VirtualService, meta confi
 		for _, m := range d.Match {
 			for i, g := range m.Gateways {
 				if g != constants.IstioMeshGateway {
-					m.Gateways[i] = resolveGatewayName(g, meta)
+					m.Gateways[i] = ResolveGatewayName(g, meta)
 				}
 			}
 		}
@@ -61,7 +61,7 @@ func resolveVirtualServiceShortnames(rule *networking.VirtualService, meta confi
 		for _, m := range d.Match {
 			for i, g := range m.Gateways {
 				if g != constants.IstioMeshGateway {
-					m.Gateways[i] = resolveGatewayName(g, meta)
+					m.Gateways[i] = ResolveGatewayName(g, meta)
 				}
 			}
 		}
@@ -76,7 +76,7 @@ func resolveVirtualServiceShortnames(rule *networking.VirtualService, meta confi
 		for _, m := range tls.Match {
 			for i, g := range m.Gateways {
 				if g != constants.IstioMeshGateway {
-					m.Gateways[i] = resolveGatewayName(g, meta)
+					m.Gateways[i] = ResolveGatewayName(g, meta)
 				}
 			}
 		}
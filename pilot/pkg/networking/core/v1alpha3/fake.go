@@ -17,6 +17,7 @@ package v1alpha3
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"sync"
 	"text/template"
 	"time"
@@ -99,7 +100,36 @@ func NewConfigGenTest(t test.Failer, opts TestOptions) *ConfigGenTest {
 		close(stop)
 	})
 
-	configs := getConfigs(t, opts)
+	configs, err := getConfigs(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := newConfigGen(opts, configs, stop)
+	fake.t = t
+	if !opts.SkipRun {
+		fake.Run()
+	}
+	return fake
+}
+
+// NewConfigGen creates a ConfigGenTest outside of tests, to generate the configuration of proxies
+// from the configuration and services in opts, for example in istioctl. Failures are returned rather
+// than failing a test. The returned function stops the ConfigGenTest once it is not used anymore.
+func NewConfigGen(opts TestOptions) (*ConfigGenTest, func(), error) {
+	configs, err := getConfigs(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := make(chan struct{})
+	cg := newConfigGen(opts, configs, stop)
+	if err := cg.run(); err != nil {
+		close(stop)
+		return nil, nil, err
+	}
+	return cg, func() { close(stop) }, nil
+}
+
+func newConfigGen(opts TestOptions, configs []config.Config, stop chan struct{}) *ConfigGenTest {
 	configStore := memory.MakeSkipValidation(collections.Pilot, true)
 
 	cc := memory.NewSyncController(configStore)
@@ -146,8 +176,7 @@ func NewConfigGenTest(t test.Failer, opts TestOptions) *ConfigGenTest {
 		opts.Plugins = registry.NewPlugins([]string{plugin.AuthzCustom, plugin.Authn, plugin.Authz})
 	}
 
-	fake := &ConfigGenTest{
-		t:                    t,
+	return &ConfigGenTest{
 		store:                configController,
 		env:                  env,
 		initialConfigs:       configs,
@@ -158,34 +187,39 @@ func NewConfigGenTest(t test.Failer, opts TestOptions) *ConfigGenTest {
 		ServiceEntryRegistry: se,
 		pushContextLock:      opts.PushContextLock,
 	}
-	if !opts.SkipRun {
-		fake.Run()
-	}
-	return fake
 }
 
 func (f *ConfigGenTest) Run() {
+	if err := f.run(); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *ConfigGenTest) run() error {
 	go f.store.Run(f.stop)
 	// Setup configuration. This should be done after registries are added so they can process events.
 	for _, cfg := range f.initialConfigs {
 		if _, err := f.store.Create(cfg); err != nil {
-			f.t.Fatalf("failed to create config %v: %v", cfg.Name, err)
+			return fmt.Errorf("failed to create config %v: %v", cfg.Name, err)
 		}
 	}
 
 	// TODO allow passing event handlers for controller
 
-	retry.UntilSuccessOrFail(f.t, func() error {
+	if err := retry.UntilSuccess(func() error {
 		if !f.Registry.HasSynced() {
 			return errors.New("not synced")
 		}
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
 
 	f.ServiceEntryRegistry.ResyncEDS()
 	if err := f.PushContext().InitContext(f.env, nil, nil); err != nil {
-		f.t.Fatalf("Failed to initialize push context: %v", err)
+		return fmt.Errorf("failed to initialize push context: %v", err)
 	}
+	return nil
 }
 
 // SetupProxy initializes a proxy for the current environment. This should generally be used when creating
@@ -261,7 +295,7 @@ func (f *ConfigGenTest) Store() model.ConfigStoreCache {
 
 var _ model.XDSUpdater = &FakeXdsUpdater{}
 
-func getConfigs(t test.Failer, opts TestOptions) []config.Config {
+func getConfigs(opts TestOptions) ([]config.Config, error) {
 	for _, p := range opts.ConfigPointers {
 		if p != nil {
 			opts.Configs = append(opts.Configs, *p)
@@ -272,7 +306,7 @@ func getConfigs(t test.Failer, opts TestOptions) []config.Config {
 		tmpl := template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(opts.ConfigString))
 		var buf bytes.Buffer
 		if err := tmpl.Execute(&buf, opts.ConfigTemplateInput); err != nil {
-			t.Fatalf("failed to execute template: %v", err)
+			return nil, fmt.Errorf("failed to execute template: %v", err)
 		}
 		configStr = buf.String()
 	}
@@ -281,7 +315,7 @@ func getConfigs(t test.Failer, opts TestOptions) []config.Config {
 		t0 := time.Now()
 		configs, _, err := crd.ParseInputs(configStr)
 		if err != nil {
-			t.Fatalf("failed to read config: %v", err)
+			return nil, fmt.Errorf("failed to read config: %v", err)
 		}
 		// setup default namespace if not defined
 		for _, c := range configs {
@@ -294,7 +328,7 @@ func getConfigs(t test.Failer, opts TestOptions) []config.Config {
 			cfgs = append(cfgs, c)
 		}
 	}
-	return cfgs
+	return cfgs, nil
 }
 
 type FakeXdsUpdater struct{}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package match evaluates Envoy string and regex matchers the way Envoy does. It is shared by the
+// tools that simulate how a proxy handles a request.
+package match
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+)
+
+// regexes caches the compiled form of every regex seen, keyed by the regex as written in the
+// configuration.
+var regexes sync.Map
+
+// Regex matches s against a RE2 safe regex. Like Envoy, the regex must match the whole string rather
+// than a substring of it.
+func Regex(regex, s string) (bool, error) {
+	if r, ok := regexes.Load(regex); ok {
+		return r.(*regexp.Regexp).MatchString(s), nil
+	}
+	r, err := regexp.Compile("^(?:" + regex + ")$")
+	if err != nil {
+		return false, fmt.Errorf("invalid regex %q: %v", regex, err)
+	}
+	regexes.Store(regex, r)
+	return r.MatchString(s), nil
+}
+
+// String matches s against a StringMatcher. A nil matcher matches nothing.
+func String(m *matcher.StringMatcher, s string) (bool, error) {
+	if m == nil {
+		return false, nil
+	}
+	lower := func(v string) string {
+		if m.GetIgnoreCase() {
+			return strings.ToLower(v)
+		}
+		return v
+	}
+	s = lower(s)
+	switch mt := m.GetMatchPattern().(type) {
+	case *matcher.StringMatcher_Exact:
+		return s == lower(mt.Exact), nil
+	case *matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(s, lower(mt.Prefix)), nil
+	case *matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(s, lower(mt.Suffix)), nil
+	case *matcher.StringMatcher_Contains:
+		return strings.Contains(s, lower(mt.Contains)), nil
+	case *matcher.StringMatcher_SafeRegex:
+		return Regex(mt.SafeRegex.GetRegex(), s)
+	default:
+		return false, fmt.Errorf("unknown string match type %T", mt)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"testing"
+
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+)
+
+func TestString(t *testing.T) {
+	regex := func(r string) *matcher.StringMatcher {
+		return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_SafeRegex{
+			SafeRegex: &matcher.RegexMatcher{Regex: r},
+		}}
+	}
+	cases := []struct {
+		name    string
+		m       *matcher.StringMatcher
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{name: "nil", m: nil, value: "foo", want: false},
+		{name: "exact", m: &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: "foo"}}, value: "foo", want: true},
+		{
+			name:  "exact ignore case",
+			m:     &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: "FOO"}, IgnoreCase: true},
+			value: "Foo",
+			want:  true,
+		},
+		{name: "prefix", m: &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "/api"}}, value: "/api/v1", want: true},
+		{name: "suffix", m: &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Suffix{Suffix: ".com"}}, value: "foo.org", want: false},
+		{name: "contains", m: &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Contains{Contains: "oo"}}, value: "foo", want: true},
+		// Like Envoy, the regex must match the whole value.
+		{name: "regex", m: regex("/foo|/bar"), value: "/bar", want: true},
+		{name: "regex substring", m: regex("/foo|/bar"), value: "/barbaz", want: false},
+		{name: "invalid regex", m: regex("("), value: "(", wantErr: true},
+		{name: "no pattern", m: &matcher.StringMatcher{}, value: "foo", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := String(tt.m, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -19,7 +19,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"regexp"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -27,12 +27,17 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/yl2chen/cidranger"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/simulation/match"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pilot/pkg/xds"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
@@ -96,7 +101,9 @@ var (
 type Call struct {
 	Address string
 	Port    int
-	Path    string
+	// Path of the request, optionally including a query string.
+	Path   string
+	Method string
 
 	// Protocol describes the protocol type. TLS encapsulation is separate
 	Protocol Protocol
@@ -129,6 +136,9 @@ func (c Call) FillDefaults() Call {
 	if c.Path == "" {
 		c.Path = "/"
 	}
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
 	if c.TLS == "" {
 		c.TLS = Plaintext
 	}
@@ -189,13 +199,36 @@ func (r Result) Matches(t *testing.T, want Result) {
 }
 
 type Simulation struct {
-	t         *testing.T
+	t         test.Failer
 	Listeners []*listener.Listener
 	Clusters  []*cluster.Cluster
 	Routes    []*route.RouteConfiguration
 }
 
-func NewSimulationFromConfigGen(t *testing.T, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
+// Trace holds the configuration matched at each step of a simulated call.
+type Trace struct {
+	Result
+	Listener    *listener.Listener
+	FilterChain *listener.FilterChain
+	RouteConfig *route.RouteConfiguration
+	VirtualHost *route.VirtualHost
+	Route       *route.Route
+	Cluster     *cluster.Cluster
+}
+
+// NewSimulationFromResources creates a Simulation over already generated configuration, for
+// example read from an Envoy config dump. Invalid configuration is reported by Trace; Run and
+// RunExpectations are only available to simulations created for a test.
+func NewSimulationFromResources(listeners []*listener.Listener, clusters []*cluster.Cluster,
+	routes []*route.RouteConfiguration) *Simulation {
+	return &Simulation{
+		Listeners: listeners,
+		Clusters:  clusters,
+		Routes:    routes,
+	}
+}
+
+func NewSimulationFromConfigGen(t test.Failer, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
 	sim := &Simulation{
 		t:         t,
 		Listeners: s.Listeners(proxy),
@@ -217,24 +250,42 @@ func (sim *Simulation) withT(t *testing.T) *Simulation {
 }
 
 func (sim *Simulation) RunExpectations(es []Expect) {
+	t, ok := sim.t.(*testing.T)
+	if !ok {
+		sim.t.Fatalf("RunExpectations requires a *testing.T")
+	}
 	for _, e := range es {
-		sim.t.Run(e.Name, func(t *testing.T) {
+		t.Run(e.Name, func(t *testing.T) {
 			sim.withT(t).Run(e.Call).Matches(t, e.Result)
 		})
 	}
 }
 
 func (sim *Simulation) Run(input Call) (result Result) {
-	result = Result{t: sim.t}
+	trace, err := sim.Trace(input)
+	if err != nil {
+		sim.t.Fatal(err)
+	}
+	return trace.Result
+}
+
+// Trace simulates the call, and returns the configuration matched at each step. Calls that do
+// not reach a cluster are reported in the Result; the error is only set for configuration that
+// cannot be evaluated, such as an invalid regex.
+func (sim *Simulation) Trace(input Call) (Trace, error) {
+	var trace Trace
+	result := &trace.Result
+	result.t = sim.t
 	input = input.FillDefaults()
 
 	// First we will match a listener
 	l := matchListener(sim.Listeners, input)
 	if l == nil {
 		result.Error = ErrNoListener
-		return
+		return trace, nil
 	}
 	result.ListenerMatched = l.Name
+	trace.Listener = l
 
 	// Apply listener filters. This will likely need the TLS inspector in the future as well
 	if _, f := xdstest.ExtractListenerFilters(l)[xdsfilters.HTTPInspector.Name]; f {
@@ -244,30 +295,46 @@ func (sim *Simulation) Run(input Call) (result Result) {
 	}
 	_, hasTLSInspector := xdstest.ExtractListenerFilters(l)[xdsfilters.TLSInspector.Name]
 	fc, err := sim.matchFilterChain(l.FilterChains, l.DefaultFilterChain, input, hasTLSInspector)
-	if err != nil {
+	if errors.Is(err, ErrNoFilterChain) || errors.Is(err, ErrMultipleFilterChain) {
 		result.Error = err
-		return
+		return trace, nil
+	} else if err != nil {
+		return trace, err
 	}
 	result.FilterChainMatched = fc.Name
+	trace.FilterChain = fc
 	if fc.TransportSocket != nil && input.TLS == Plaintext {
 		result.Error = ErrTLSError
-		return
+		return trace, nil
 	}
 
-	if hcm := xdstest.ExtractHTTPConnectionManager(sim.t, fc); hcm != nil {
+	httpManager, err := httpConnectionManager(fc)
+	if err != nil {
+		return trace, err
+	}
+	tcp, err := tcpProxy(fc)
+	if err != nil {
+		return trace, err
+	}
+	if httpManager != nil {
 		if input.TLS != Plaintext && fc.TransportSocket == nil {
 			result.Error = ErrProtocolError
-			return
+			return trace, nil
 		}
 
 		// Fetch inline route
-		rc := hcm.GetRouteConfig()
+		rc := httpManager.GetRouteConfig()
 		if rc == nil {
 			// If not set, fallback to RDS
-			routeName := hcm.GetRds().RouteConfigName
+			routeName := httpManager.GetRds().RouteConfigName
 			result.RouteConfigMatched = routeName
 			rc = xdstest.ExtractRouteConfigurations(sim.Routes)[routeName]
 		}
+		if rc == nil {
+			result.Error = ErrNoVirtualHost
+			return trace, nil
+		}
+		trace.RouteConfig = rc
 		hostHeader := ""
 		if len(input.Headers["Host"]) > 0 {
 			hostHeader = input.Headers["Host"][0]
@@ -275,55 +342,185 @@ func (sim *Simulation) Run(input Call) (result Result) {
 		vh := sim.matchVirtualHost(rc, hostHeader)
 		if vh == nil {
 			result.Error = ErrNoVirtualHost
-			return
+			return trace, nil
 		}
 		result.VirtualHostMatched = vh.Name
-		r := sim.matchRoute(vh, input)
-
+		trace.VirtualHost = vh
+		r, err := sim.matchRoute(vh, input)
+		if err != nil {
+			return trace, err
+		}
 		if r == nil {
 			result.Error = ErrNoRoute
-			return
+			return trace, nil
 		}
 		result.RouteMatched = r.Name
+		trace.Route = r
 		switch t := r.GetAction().(type) {
 		case *route.Route_Route:
 			result.ClusterMatched = t.Route.GetCluster()
 		}
-	} else if tcp := xdstest.ExtractTCPProxy(sim.t, fc); tcp != nil {
+	} else if tcp != nil {
 		result.ClusterMatched = tcp.GetCluster()
 	}
-	return
+	if result.ClusterMatched != "" {
+		trace.Cluster = xdstest.ExtractCluster(result.ClusterMatched, sim.Clusters)
+	}
+	return trace, nil
+}
+
+// httpConnectionManager returns the HTTP connection manager of the filter chain, if it has one.
+func httpConnectionManager(fc *listener.FilterChain) (*hcm.HttpConnectionManager, error) {
+	for _, f := range fc.Filters {
+		if f.Name == wellknown.HTTPConnectionManager {
+			h := &hcm.HttpConnectionManager{}
+			if f.GetTypedConfig() != nil {
+				if err := ptypes.UnmarshalAny(f.GetTypedConfig(), h); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal hcm: %v", err)
+				}
+			}
+			return h, nil
+		}
+	}
+	return nil, nil
 }
 
-func (sim *Simulation) matchRoute(vh *route.VirtualHost, input Call) *route.Route {
+// tcpProxy returns the TCP proxy of the filter chain, if it has one.
+func tcpProxy(fc *listener.FilterChain) (*tcpproxy.TcpProxy, error) {
+	for _, f := range fc.Filters {
+		if f.Name == wellknown.TCPProxy {
+			tcp := &tcpproxy.TcpProxy{}
+			if f.GetTypedConfig() != nil {
+				if err := ptypes.UnmarshalAny(f.GetTypedConfig(), tcp); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal tcp proxy: %v", err)
+				}
+			}
+			return tcp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (sim *Simulation) matchRoute(vh *route.VirtualHost, input Call) (*route.Route, error) {
+	path, query := input.Path, url.Values{}
+	if i := strings.Index(path, "?"); i >= 0 {
+		query, _ = url.ParseQuery(path[i+1:])
+		path = path[:i]
+	}
 	for _, r := range vh.Routes {
 		// check path
 		switch pt := r.Match.GetPathSpecifier().(type) {
 		case *route.RouteMatch_Prefix:
-			if !strings.HasPrefix(input.Path, pt.Prefix) {
+			if !strings.HasPrefix(path, pt.Prefix) {
 				continue
 			}
 		case *route.RouteMatch_Path:
-			if input.Path != pt.Path {
+			if path != pt.Path {
 				continue
 			}
 		case *route.RouteMatch_SafeRegex:
-			r, err := regexp.Compile(pt.SafeRegex.GetRegex())
+			matched, err := match.Regex(pt.SafeRegex.GetRegex(), path)
 			if err != nil {
-				sim.t.Fatalf("invalid regex %v: %v", r, err)
+				return nil, err
 			}
-			if !r.MatchString(input.Path) {
+			if !matched {
 				continue
 			}
 		default:
-			sim.t.Fatalf("unknown route path type")
+			return nil, fmt.Errorf("unknown route path type %T", pt)
 		}
 
-		// TODO this only handles path - we need to add headers, query params, etc to be complete.
+		matched, err := sim.matchHeaders(r.Match.GetHeaders(), input)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		matched, err = sim.matchQueryParameters(r.Match.GetQueryParameters(), query)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
 
-		return r
+		return r, nil
 	}
-	return nil
+	return nil, nil
+}
+
+// headerValue returns the value of a header, including the pseudo headers derived from the call.
+func headerValue(input Call, name string) (string, bool) {
+	switch name {
+	case ":authority":
+		name = "Host"
+	case ":path":
+		return input.Path, true
+	case ":method":
+		return input.Method, true
+	}
+	v, f := input.Headers[http.CanonicalHeaderKey(name)]
+	if !f || len(v) == 0 {
+		return "", false
+	}
+	return v[0], true
+}
+
+func (sim *Simulation) matchHeaders(headers []*route.HeaderMatcher, input Call) (bool, error) {
+	for _, h := range headers {
+		value, present := headerValue(input, h.GetName())
+		var matched bool
+		switch hm := h.GetHeaderMatchSpecifier().(type) {
+		case *route.HeaderMatcher_ExactMatch:
+			matched = present && value == hm.ExactMatch
+		case *route.HeaderMatcher_PrefixMatch:
+			matched = present && strings.HasPrefix(value, hm.PrefixMatch)
+		case *route.HeaderMatcher_SuffixMatch:
+			matched = present && strings.HasSuffix(value, hm.SuffixMatch)
+		case *route.HeaderMatcher_ContainsMatch:
+			matched = present && strings.Contains(value, hm.ContainsMatch)
+		case *route.HeaderMatcher_SafeRegexMatch:
+			if present {
+				var err error
+				if matched, err = match.Regex(hm.SafeRegexMatch.GetRegex(), value); err != nil {
+					return false, err
+				}
+			}
+		case *route.HeaderMatcher_PresentMatch:
+			matched = present == hm.PresentMatch
+		default:
+			return false, fmt.Errorf("unknown header match type %T", hm)
+		}
+		if matched == h.GetInvertMatch() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (sim *Simulation) matchQueryParameters(params []*route.QueryParameterMatcher, query url.Values) (bool, error) {
+	for _, p := range params {
+		_, present := query[p.GetName()]
+		value := query.Get(p.GetName())
+		switch pm := p.GetQueryParameterMatchSpecifier().(type) {
+		case *route.QueryParameterMatcher_PresentMatch:
+			if present != pm.PresentMatch {
+				return false, nil
+			}
+		case *route.QueryParameterMatcher_StringMatch:
+			if !present {
+				return false, nil
+			}
+			matched, err := match.String(pm.StringMatch, value)
+			if err != nil || !matched {
+				return false, err
+			}
+		default:
+			return false, fmt.Errorf("unknown query parameter match type %T", pm)
+		}
+	}
+	return true, nil
 }
 
 func (sim *Simulation) matchVirtualHost(rc *route.RouteConfiguration, host string) *route.VirtualHost {
@@ -387,6 +584,7 @@ func (sim *Simulation) matchVirtualHost(rc *route.RouteConfiguration, host strin
 // matches one criteria but not another.
 func (sim *Simulation) matchFilterChain(chains []*listener.FilterChain, defaultChain *listener.FilterChain,
 	input Call, hasTLSInspector bool) (*listener.FilterChain, error) {
+	var rangeErr error
 	chains = filter(chains, func(fc *listener.FilterChainMatch) bool {
 		return fc.GetDestinationPort() == nil
 	}, func(fc *listener.FilterChainMatch) bool {
@@ -399,18 +597,23 @@ func (sim *Simulation) matchFilterChain(chains []*listener.FilterChain, defaultC
 		for _, a := range fc.GetPrefixRanges() {
 			_, cidr, err := net.ParseCIDR(fmt.Sprintf("%s/%d", a.AddressPrefix, a.GetPrefixLen().GetValue()))
 			if err != nil {
-				sim.t.Fatal(err)
+				rangeErr = err
+				return false
 			}
 			if err := ranger.Insert(cidranger.NewBasicRangerEntry(*cidr)); err != nil {
-				sim.t.Fatal(err)
+				rangeErr = err
+				return false
 			}
 		}
 		f, err := ranger.Contains(net.ParseIP(input.Address))
 		if err != nil {
-			sim.t.Fatal(err)
+			rangeErr = err
 		}
 		return f
 	})
+	if rangeErr != nil {
+		return nil, rangeErr
+	}
 	chains = filter(chains, func(fc *listener.FilterChainMatch) bool {
 		return fc.GetServerNames() == nil
 	}, func(fc *listener.FilterChainMatch) bool {
@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulation
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+)
+
+func TestMatchRouteRegex(t *testing.T) {
+	regexRoute := func(name, regex string) *route.Route {
+		return &route.Route{
+			Name: name,
+			Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_SafeRegex{
+				SafeRegex: &matcher.RegexMatcher{Regex: regex},
+			}},
+		}
+	}
+	headerRoute := func(name, header, regex string) *route.Route {
+		return &route.Route{
+			Name: name,
+			Match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				Headers: []*route.HeaderMatcher{{
+					Name: header,
+					HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: &matcher.RegexMatcher{Regex: regex},
+					},
+				}},
+			},
+		}
+	}
+	vh := &route.VirtualHost{Routes: []*route.Route{
+		headerRoute("header", "x-version", "v[0-9]"),
+		regexRoute("foo", "/foo"),
+		regexRoute("bar", "/bar/.*|/baz"),
+	}}
+
+	cases := []struct {
+		name string
+		call Call
+		want string
+	}{
+		// The regex must match the whole path, as in Envoy, not only a substring of it.
+		{name: "exact path", call: Call{Path: "/foo"}, want: "foo"},
+		{name: "path with suffix", call: Call{Path: "/foo/bar"}, want: ""},
+		{name: "path with prefix", call: Call{Path: "/x/foo"}, want: ""},
+		{name: "alternation", call: Call{Path: "/baz"}, want: "bar"},
+		{name: "alternation substring", call: Call{Path: "/bazz"}, want: ""},
+		{name: "wildcard", call: Call{Path: "/bar/a/b"}, want: "bar"},
+		{name: "query string", call: Call{Path: "/foo?q=1"}, want: "foo"},
+		{name: "header", call: Call{Path: "/foo", Headers: map[string][]string{"X-Version": {"v1"}}}, want: "header"},
+		{name: "header substring", call: Call{Path: "/foo", Headers: map[string][]string{"X-Version": {"v10"}}}, want: "foo"},
+	}
+	sim := NewSimulationFromResources(nil, nil, nil)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			r, err := sim.matchRoute(vh, tt.call.FillDefaults())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r != nil {
+				got = r.Name
+			}
+			if got != tt.want {
+				t.Fatalf("got route %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
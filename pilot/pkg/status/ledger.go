@@ -15,20 +15,33 @@
 package status
 
 import (
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	"istio.io/pkg/ledger"
 )
 
-func tryLedgerPut(configLedger ledger.Ledger, obj config.Config) {
+func tryLedgerPut(configLedger ledger.Ledger, history *model.ConfigHistory, obj config.Config) {
 	key := config.Key(obj.GroupVersionKind.Kind, obj.Name, obj.Namespace)
-	if _, err := configLedger.Put(key, obj.ResourceVersion); err != nil {
+	var err error
+	if history != nil {
+		err = history.Put(configLedger, obj)
+	} else {
+		_, err = configLedger.Put(key, obj.ResourceVersion)
+	}
+	if err != nil {
 		scope.Errorf("Failed to update %s in ledger, status will be out of date.", key)
 	}
 }
 
-func tryLedgerDelete(configLedger ledger.Ledger, obj config.Config) {
+func tryLedgerDelete(configLedger ledger.Ledger, history *model.ConfigHistory, obj config.Config) {
 	key := config.Key(obj.GroupVersionKind.Kind, obj.Name, obj.Namespace)
-	if err := configLedger.Delete(key); err != nil {
+	var err error
+	if history != nil {
+		err = history.Delete(configLedger, obj)
+	} else {
+		err = configLedger.Delete(key)
+	}
+	if err != nil {
 		scope.Errorf("Failed to delete %s in ledger, status will be out of date.", key)
 	}
 }
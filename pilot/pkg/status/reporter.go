@@ -29,6 +29,7 @@ import (
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/utils/clock"
 
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	"istio.io/istio/pkg/config"
 	"istio.io/pkg/ledger"
@@ -56,13 +57,15 @@ type Reporter struct {
 	status map[string]string
 	// map from nonce to connection ids for which it is current
 	// using map[string]struct to approximate a hashset
-	reverseStatus          map[string]map[string]struct{}
-	dirty                  bool
-	inProgressResources    map[string]*inProgressEntry
-	client                 v1.ConfigMapInterface
-	cm                     *corev1.ConfigMap
-	UpdateInterval         time.Duration
-	PodName                string
+	reverseStatus       map[string]map[string]struct{}
+	dirty               bool
+	inProgressResources map[string]*inProgressEntry
+	client              v1.ConfigMapInterface
+	cm                  *corev1.ConfigMap
+	UpdateInterval      time.Duration
+	PodName             string
+	// History, if set, records every ledger write so that past config versions can be inspected.
+	History                *model.ConfigHistory
 	clock                  clock.Clock
 	ledger                 ledger.Ledger
 	distributionEventQueue chan distributionEvent
@@ -204,7 +207,7 @@ func (r *Reporter) removeCompletedResource(completedResources []Resource) {
 // This function must be called every time a resource change is detected by pilot.  This allows us to lookup
 // only the resources we expect to be in flight, not the ones that have already distributed
 func (r *Reporter) AddInProgressResource(res config.Config) {
-	tryLedgerPut(r.ledger, res)
+	tryLedgerPut(r.ledger, r.History, res)
 	myRes := ResourceFromModelConfig(res)
 	if myRes == nil {
 		scope.Errorf("Unable to locate schema for %v, will not update status.", res)
@@ -219,7 +222,7 @@ func (r *Reporter) AddInProgressResource(res config.Config) {
 }
 
 func (r *Reporter) DeleteInProgressResource(res config.Config) {
-	tryLedgerDelete(r.ledger, res)
+	tryLedgerDelete(r.ledger, r.History, res)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.inProgressResources, res.Key())
@@ -41,6 +41,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/kube/inject"
 	"istio.io/pkg/log"
 )
 
@@ -123,7 +124,7 @@ type SyncedVersions struct {
 }
 
 // InitDebug initializes the debug handlers and adds a debug in-memory registry.
-func (s *DiscoveryServer) InitDebug(mux *http.ServeMux, sctl *aggregate.Controller, enableProfiling bool, fetchWebhook func() string) {
+func (s *DiscoveryServer) InitDebug(mux *http.ServeMux, sctl *aggregate.Controller, enableProfiling bool, fetchWebhook func() *inject.Config) {
 	// For debugging and load testing v2 we add an memory registry.
 	s.MemRegistry = memory.NewServiceDiscovery(nil)
 	s.MemRegistry.EDSUpdater = s
@@ -138,7 +139,7 @@ func (s *DiscoveryServer) InitDebug(mux *http.ServeMux, sctl *aggregate.Controll
 	s.AddDebugHandlers(mux, enableProfiling, fetchWebhook)
 }
 
-func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling bool, webhook func() string) {
+func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling bool, webhook func() *inject.Config) {
 	// Debug handlers on HTTP ports are added for backward compatibility.
 	// They will be exposed on XDS-over-TLS in future releases.
 	if !features.EnableDebugOnHTTP {
@@ -166,6 +167,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling b
 
 	s.addDebugHandler(mux, "/debug/syncz", "Synchronization status of all Envoys connected to this Pilot instance", s.Syncz)
 	s.addDebugHandler(mux, "/debug/config_distribution", "Version status of all Envoys connected to this Pilot instance", s.distributedVersions)
+	s.addDebugHandler(mux, "/debug/config_history", "Recent config versions, or the config at a version or proxyID", s.configHistory)
 
 	s.addDebugHandler(mux, "/debug/registryz", "Debug support for registry", s.registryz)
 	s.addDebugHandler(mux, "/debug/endpointz", "Debug support for endpoints", s.endpointz)
@@ -179,7 +181,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling b
 	s.addDebugHandler(mux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
 	s.addDebugHandler(mux, "/debug/push_status", "Last PushContext Details", s.PushStatusHandler)
 
-	s.addDebugHandler(mux, "/debug/inject", "Active inject template", s.InjectTemplateHandler(webhook))
+	s.addDebugHandler(mux, "/debug/inject", "Active inject templates, or a single template by name", s.InjectTemplateHandler(webhook))
 	s.addDebugHandler(mux, "/debug/mesh", "Active mesh config", s.MeshHandler)
 }
 
@@ -378,6 +380,88 @@ func (s *DiscoveryServer) getResourceVersion(nonce, key string, cache map[string
 	return result
 }
 
+// ConfigVersion is the full config at a version of the config ledger, as returned by /debug/config_history.
+type ConfigVersion struct {
+	Version string             `json:"version"`
+	ProxyID string             `json:"proxyID,omitempty"`
+	Configs []kubernetesConfig `json:"configs"`
+}
+
+// configHistory lists the retained config versions with the change that produced each one. If a version
+// or proxyID is given, the full config at that version, or at the version the proxy is running, is returned instead.
+func (s *DiscoveryServer) configHistory(w http.ResponseWriter, req *http.Request) {
+	history := s.Env.GetConfigHistory()
+	if !features.EnableDistributionTracking || history == nil {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprint(w, "Pilot Version tracking is disabled.  Please set the "+
+			"PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING environment variable to true to enable.")
+		return
+	}
+	var out interface{}
+	version := req.URL.Query().Get("version")
+	proxyID := req.URL.Query().Get("proxyID")
+	if proxyID != "" {
+		con := s.getProxyConnection(proxyID)
+		if con == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Proxy not connected to this Pilot instance"))
+			return
+		}
+		if version = s.proxyConfigVersion(con, history); version == "" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(w, "the config version of proxy %s is not in the retained history", con.proxy.ID)
+			return
+		}
+		proxyID = con.proxy.ID
+	}
+	if version == "" {
+		out = history.Entries()
+	} else {
+		cfgs, err := history.ConfigsAt(version)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		cv := ConfigVersion{Version: version, ProxyID: proxyID, Configs: make([]kubernetesConfig, 0, len(cfgs))}
+		for _, c := range cfgs {
+			cv.Configs = append(cv.Configs, kubernetesConfig{c})
+		}
+		out = cv
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal config history: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// proxyConfigVersion returns the oldest retained config version among the last CDS, LDS and RDS nonces of
+// the connection, as that is the version all of the proxy's config is at least as recent as.
+func (s *DiscoveryServer) proxyConfigVersion(con *Connection, history *model.ConfigHistory) string {
+	if s.StatusReporter == nil {
+		return ""
+	}
+	version := ""
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType, v3.RouteType} {
+		nonce := s.StatusReporter.QueryLastNonce(con.ConID, typeURL)
+		if len(nonce) < VersionLen {
+			continue
+		}
+		v := nonce[:VersionLen]
+		if !history.Retained(v) {
+			continue
+		}
+		if version == "" || history.Before(v, version) {
+			version = v
+		}
+	}
+	return version
+}
+
 type kubernetesConfig struct {
 	config.Config
 }
@@ -609,19 +693,39 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 	return configDump, nil
 }
 
-// InjectTemplateHandler dumps the injection template
+// InjectTemplateHandler dumps the injection templates along with their validation status,
+// or the raw text of the template selected with ?template=.
 // Replaces dumping the template at startup.
-func (s *DiscoveryServer) InjectTemplateHandler(webhook func() string) func(http.ResponseWriter, *http.Request) {
+func (s *DiscoveryServer) InjectTemplateHandler(webhook func() *inject.Config) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
-		// TODO: we should split the inject template into smaller modules (separate one for dump core, etc),
-		// and allow pods to select which patches will be selected. When this happen, this should return
-		// all inject templates or take a param to select one.
 		if webhook == nil {
 			w.WriteHeader(404)
 			return
 		}
+		config := webhook()
+		if config == nil {
+			w.WriteHeader(404)
+			return
+		}
 
-		_, _ = w.Write([]byte(webhook()))
+		if name := req.URL.Query().Get("template"); name != "" {
+			tmpl, f := config.RawTemplates()[name]
+			if !f {
+				w.WriteHeader(404)
+				_, _ = fmt.Fprintf(w, "unknown injection template %q\n", name)
+				return
+			}
+			_, _ = w.Write([]byte(tmpl))
+			return
+		}
+
+		b, err := json.MarshalIndent(config.TemplateStatuses(), "", "  ")
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = w.Write(b)
 	}
 }
 
@@ -20,13 +20,19 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/pkg/ledger"
 )
 
 func TestSyncz(t *testing.T) {
@@ -213,3 +219,118 @@ func TestDebugHandlers(t *testing.T) {
 		t.Errorf("Error in generatating debug endpoint list")
 	}
 }
+
+func TestConfigHistory(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	l := ledger.Make(time.Minute)
+	history := model.NewConfigHistory(time.Minute)
+	s.Discovery.Env.SetLedger(l)
+	s.Discovery.Env.SetConfigHistory(history)
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, false, func() *inject.Config { return nil })
+
+	se := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.ServiceEntry,
+			Name:             "se",
+			Namespace:        "default",
+			ResourceVersion:  "1",
+		},
+		Spec: &networking.ServiceEntry{Hosts: []string{"example.com"}},
+	}
+	if err := history.Put(l, se); err != nil {
+		t.Fatal(err)
+	}
+	v1 := l.RootHash()
+	se.ResourceVersion = "2"
+	if err := history.Put(l, se); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/debug/config_history")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	var entries []model.ConfigHistoryEntry
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Op != model.ConfigUpdated || entries[1].Op != model.ConfigAdded {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	rr = get("/debug/config_history?version=" + v1)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	var version struct {
+		Version string `json:"version"`
+		Configs []struct {
+			Metadata struct {
+				Name            string `json:"name"`
+				ResourceVersion string `json:"resourceVersion"`
+			} `json:"metadata"`
+		} `json:"configs"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &version); err != nil {
+		t.Fatal(err)
+	}
+	if version.Version != v1 || len(version.Configs) != 1 || version.Configs[0].Metadata.ResourceVersion != "1" {
+		t.Errorf("unexpected config at version %s: %s", v1, rr.Body.String())
+	}
+
+	if rr = get("/debug/config_history?version=unknown"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected not found for unknown version, got %d", rr.Code)
+	}
+	if rr = get("/debug/config_history?proxyID=missing"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected not found for unknown proxy, got %d", rr.Code)
+	}
+}
+
+func TestInjectTemplateHandler(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	handler := s.Discovery.InjectTemplateHandler(func() *inject.Config {
+		return &inject.Config{
+			Template: "containers: []",
+			Templates: map[string]string{
+				"debug": "containers: {{ .Missing",
+			},
+		}
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := get("/debug/inject")
+	var statuses []inject.TemplateStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Name != "debug" || statuses[0].Error == "" ||
+		statuses[1].Name != inject.SidecarTemplateName || !statuses[1].Default || statuses[1].Error != "" {
+		t.Errorf("unexpected template statuses: %+v", statuses)
+	}
+
+	if rr = get("/debug/inject?template=sidecar"); rr.Body.String() != "containers: []" {
+		t.Errorf("unexpected sidecar template: %q", rr.Body.String())
+	}
+	if rr = get("/debug/inject?template=gateway"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected not found for unknown template, got %d", rr.Code)
+	}
+}
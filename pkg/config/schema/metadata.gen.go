@@ -169,6 +169,10 @@ collections:
     kind: "Secret"
     group: ""
 
+  - name: "k8s/core/v1/serviceaccounts"
+    kind: "ServiceAccount"
+    group: ""
+
   - name: "k8s/core/v1/services"
     kind: "Service"
     group: ""
@@ -281,14 +285,18 @@ snapshots:
       - "istio/networking/v1alpha3/destinationrules"
       - "istio/networking/v1alpha3/gateways"
       - "istio/networking/v1alpha3/serviceentries"
+      - "istio/networking/v1alpha3/workloadentries"
+      - "istio/networking/v1alpha3/workloadgroups"
       - "istio/networking/v1alpha3/sidecars"
       - "istio/networking/v1alpha3/virtualservices"
       - "istio/security/v1beta1/authorizationpolicies"
+      - "istio/security/v1beta1/peerauthentications"
       - "k8s/apiextensions.k8s.io/v1beta1/customresourcedefinitions"
       - "k8s/apps/v1/deployments"
       - "k8s/core/v1/namespaces"
       - "k8s/core/v1/pods"
       - "k8s/core/v1/secrets"
+      - "k8s/core/v1/serviceaccounts"
       - "k8s/core/v1/services"
       - "k8s/core/v1/configmaps"
 
@@ -341,6 +349,12 @@ resources:
     proto: "k8s.io.api.core.v1.Secret"
     protoPackage: "k8s.io/api/core/v1"
 
+  - kind: "ServiceAccount"
+    plural: "serviceaccounts"
+    version: "v1"
+    proto: "k8s.io.api.core.v1.ServiceAccount"
+    protoPackage: "k8s.io/api/core/v1"
+
   - kind: "Service"
     plural: "services"
     version: "v1"
@@ -566,6 +580,7 @@ transforms:
       "k8s/core/v1/namespaces": "k8s/core/v1/namespaces"
       "k8s/core/v1/pods": "k8s/core/v1/pods"
       "k8s/core/v1/secrets": "k8s/core/v1/secrets"
+      "k8s/core/v1/serviceaccounts": "k8s/core/v1/serviceaccounts"
       "k8s/core/v1/services": "k8s/core/v1/services"
       "k8s/core/v1/configmaps": "k8s/core/v1/configmaps"
       "istio/mesh/v1alpha1/MeshConfig": "istio/mesh/v1alpha1/MeshConfig"
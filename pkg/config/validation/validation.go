@@ -1123,27 +1123,27 @@ func validateTLS(settings *networking.ClientTLSSettings) (errs error) {
 	return
 }
 
-func validateSubset(subset *networking.Subset) error {
-	return appendErrors(validateSubsetName(subset.Name),
+func validateSubset(subset *networking.Subset) Validation {
+	return appendValidation(WrapError(validateSubsetName(subset.Name)),
 		labels.Instance(subset.Labels).Validate(),
 		validateTrafficPolicy(subset.TrafficPolicy))
 }
 
-func validatePortTrafficPolicies(pls []*networking.TrafficPolicy_PortTrafficPolicy) (errs error) {
+func validatePortTrafficPolicies(pls []*networking.TrafficPolicy_PortTrafficPolicy) (errs Validation) {
 	for _, t := range pls {
 		if t == nil {
-			errs = appendErrors(errs, fmt.Errorf("traffic policy may not be null"))
+			errs = appendValidation(errs, fmt.Errorf("traffic policy may not be null"))
 			continue
 		}
 		if t.Port == nil {
-			errs = appendErrors(errs, fmt.Errorf("portTrafficPolicy must have valid port"))
+			errs = appendValidation(errs, fmt.Errorf("portTrafficPolicy must have valid port"))
 		}
 		if t.OutlierDetection == nil && t.ConnectionPool == nil &&
 			t.LoadBalancer == nil && t.Tls == nil {
-			errs = appendErrors(errs, fmt.Errorf("port traffic policy must have at least one field"))
+			errs = appendValidation(errs, fmt.Errorf("port traffic policy must have at least one field"))
 		} else {
 
-			errs = appendErrors(errs, validateOutlierDetection(t.OutlierDetection),
+			errs = appendValidation(errs, validateOutlierDetection(t.OutlierDetection),
 				validateConnectionPool(t.ConnectionPool),
 				validateLoadBalancer(t.LoadBalancer),
 				validateTLS(t.Tls))
@@ -1783,6 +1783,11 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 			} else if appliesToMesh && virtualHost == "*" {
 				errs = appendValidation(errs, fmt.Errorf("wildcard host * is not allowed for virtual services bound to the mesh gateway"))
 				allHostsValid = false
+			} else if len(virtualService.Gateways) == 0 && host.Name(virtualHost).IsWildCarded() {
+				// Without gateways the routes apply to the traffic of all the sidecars to any host matching the
+				// wildcard, which is rarely intended.
+				errs = appendValidation(errs, WrapWarning(fmt.Errorf("wildcard host %s applies to the traffic of all "+
+					"the sidecars in the mesh because no gateways are specified", virtualHost)))
 			}
 		}
 
@@ -2192,13 +2192,13 @@ func TestValidateVirtualService(t *testing.T) {
 					Destination: &networking.Destination{Host: "foo.baz"},
 				}},
 			}},
-		}, valid: false},
+		}, valid: false, warning: true},
 		{name: "with no destination", in: &networking.VirtualService{
 			Hosts: []string{"*.foo.bar", "*.bar"},
 			Http: []*networking.HTTPRoute{{
 				Route: []*networking.HTTPRouteDestination{{}},
 			}},
-		}, valid: false},
+		}, valid: false, warning: true},
 		{name: "destination with out hosts", in: &networking.VirtualService{
 			Hosts: []string{"*.foo.bar", "*.bar"},
 			Http: []*networking.HTTPRoute{{
@@ -2206,7 +2206,7 @@ func TestValidateVirtualService(t *testing.T) {
 					Destination: &networking.Destination{},
 				}},
 			}},
-		}, valid: false},
+		}, valid: false, warning: true},
 		{name: "delegate with no hosts", in: &networking.VirtualService{
 			Hosts: nil,
 			Http: []*networking.HTTPRoute{{
@@ -2317,6 +2317,32 @@ func TestValidateVirtualService(t *testing.T) {
 				}},
 			}},
 		}, valid: true, warning: true},
+		{name: "wildcard host without gateways", in: &networking.VirtualService{
+			Hosts: []string{"*.foo.bar"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{
+					Destination: &networking.Destination{Host: "foo.baz"},
+				}},
+			}},
+		}, valid: true, warning: true},
+		{name: "wildcard host with gateways", in: &networking.VirtualService{
+			Hosts:    []string{"*.foo.bar"},
+			Gateways: []string{"ns1/gateway"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{
+					Destination: &networking.Destination{Host: "foo.baz"},
+				}},
+			}},
+		}, valid: true, warning: false},
+		{name: "wildcard host with mesh gateway", in: &networking.VirtualService{
+			Hosts:    []string{"*.foo.bar"},
+			Gateways: []string{"mesh"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{
+					Destination: &networking.Destination{Host: "foo.baz"},
+				}},
+			}},
+		}, valid: true, warning: false},
 	}
 
 	for _, tc := range testCases {
@@ -2526,6 +2552,51 @@ func TestValidateDestinationRule(t *testing.T) {
 	}
 }
 
+func TestValidateDestinationRuleWarnings(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      *networking.DestinationRule
+		warning string
+	}{
+		{name: "tls mode simple", in: &networking.DestinationRule{
+			Host: "reviews",
+			TrafficPolicy: &networking.TrafficPolicy{
+				Tls: &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE},
+			},
+		}, warning: ""},
+		{name: "tls mode disable", in: &networking.DestinationRule{
+			Host: "reviews",
+			TrafficPolicy: &networking.TrafficPolicy{
+				Tls: &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_DISABLE},
+			},
+		}, warning: ""},
+		{name: "deprecated consecutive errors in subset port traffic policy", in: &networking.DestinationRule{
+			Host: "reviews",
+			Subsets: []*networking.Subset{{
+				Name: "v1",
+				TrafficPolicy: &networking.TrafficPolicy{
+					PortLevelSettings: []*networking.TrafficPolicy_PortTrafficPolicy{{
+						Port:             &networking.PortSelector{Number: 8080},
+						OutlierDetection: &networking.OutlierDetection{ConsecutiveErrors: 5},
+					}},
+				},
+			}},
+		}, warning: "outlier detection consecutive errors is deprecated"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			warn, err := ValidateDestinationRule(config.Config{
+				Meta: config.Meta{
+					Name:      someName,
+					Namespace: someNamespace,
+				},
+				Spec: c.in,
+			})
+			checkValidationMessage(t, warn, err, c.warning, "")
+		})
+	}
+}
+
 func TestValidateTrafficPolicy(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -34,6 +34,7 @@ import (
 	"istio.io/istio/security/pkg/nodeagent/cache"
 	citadel "istio.io/istio/security/pkg/nodeagent/caclient/providers/citadel"
 	gca "istio.io/istio/security/pkg/nodeagent/caclient/providers/google"
+	vault "istio.io/istio/security/pkg/nodeagent/caclient/providers/vault"
 	"istio.io/istio/security/pkg/nodeagent/plugin"
 	"istio.io/istio/security/pkg/nodeagent/sds"
 	"istio.io/istio/security/pkg/nodeagent/secretfetcher"
@@ -80,6 +81,9 @@ const (
 	MetadataClientRootCert  = "ISTIO_META_TLS_CLIENT_ROOT_CERT"
 )
 
+// VaultCAProvider is the CA_PROVIDER selecting Vault as the CA, configured by the Vault options.
+const VaultCAProvider = "VaultCA"
+
 // Agent contains the configuration of the agent, based on the injected
 // environment:
 // - SDS hostPath if node-agent was used
@@ -398,6 +402,8 @@ func (sa *Agent) newWorkloadSecretCache() (workloadSecretCache *cache.SecretCach
 		// used.
 		caClient, err = gca.NewGoogleCAClient(sa.secOpts.CAEndpoint, true)
 		pluginNames = []string{plugin.GoogleTokenExchange}
+	} else if sa.secOpts.CAProviderName == VaultCAProvider {
+		caClient, err = newVaultClient(sa.secOpts)
 	} else {
 		var rootCert []byte
 		// Special case: if Istiod runs on a secure network, on the default port, don't use TLS
@@ -442,6 +448,22 @@ func (sa *Agent) newWorkloadSecretCache() (workloadSecretCache *cache.SecretCach
 	return
 }
 
+// newVaultClient creates a client using Vault as the CA, authenticating with the workload token or
+// an AppRole, as configured by the Vault options.
+func newVaultClient(opts *security.Options) (security.Client, error) {
+	return vault.NewVaultClientWithOptions(vault.Options{
+		EnableTLS:           strings.HasPrefix(opts.VaultAddress, "https://"),
+		TLSRootCert:         []byte(opts.VaultTLSRootCert),
+		Addr:                opts.VaultAddress,
+		AuthMethod:          vault.AuthMethod(opts.VaultAuthMethod),
+		LoginPath:           opts.VaultAuthPath,
+		LoginRole:           opts.VaultRole,
+		AppRoleRoleID:       opts.VaultAppRoleID,
+		AppRoleSecretIDPath: opts.VaultAppRoleSecretIDPath,
+		SignCsrPath:         opts.VaultSignCsrPath,
+	})
+}
+
 // TODO: use existing 'sidecar/router' config to enable loading Secrets
 func (sa *Agent) newSecretCache(namespace string) (gatewaySecretCache *cache.SecretCache) {
 	gSecretFetcher := &secretfetcher.SecretFetcher{}
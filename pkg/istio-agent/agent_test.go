@@ -81,3 +81,55 @@ func TestNewAgent(t *testing.T) {
 		}
 	}
 }
+
+func TestNewVaultClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *security.Options
+		wantErr bool
+	}{
+		{
+			name: "kubernetes",
+			opts: &security.Options{
+				VaultAddress:     "https://vault:8200",
+				VaultRole:        "istio",
+				VaultSignCsrPath: "pki/sign/{{.Namespace}}",
+			},
+		},
+		{
+			name: "approle",
+			opts: &security.Options{
+				VaultAddress:             "http://vault:8200",
+				VaultAuthMethod:          "approle",
+				VaultAppRoleID:           "role-id",
+				VaultAppRoleSecretIDPath: "/etc/vault/secret-id",
+				VaultSignCsrPath:         "pki/sign/istio",
+			},
+		},
+		{
+			name: "approle without role ID",
+			opts: &security.Options{
+				VaultAddress:             "http://vault:8200",
+				VaultAuthMethod:          "approle",
+				VaultAppRoleSecretIDPath: "/etc/vault/secret-id",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported auth method",
+			opts: &security.Options{
+				VaultAddress:    "http://vault:8200",
+				VaultAuthMethod: "ldap",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newVaultClient(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -50,7 +50,9 @@ import (
 	"istio.io/istio/pkg/istio-agent/health"
 	"istio.io/istio/pkg/istio-agent/metrics"
 	"istio.io/istio/pkg/mcp/status"
+	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/uds"
+	"istio.io/istio/security/pkg/util"
 	"istio.io/pkg/log"
 )
 
@@ -394,6 +396,33 @@ func (ts *fileTokenSource) Token() (*oauth2.Token, error) {
 	}, nil
 }
 
+// credFetcherTokenSource fetches the token through the platform credential fetcher, so that
+// the token does not need to be written to JWTPath before connecting to Istiod.
+// It is wrapped in an oauth2.ReuseTokenSource, which only fetches a new token once the
+// previous one expires.
+type credFetcherTokenSource struct {
+	credFetcher security.CredFetcher
+}
+
+var _ = oauth2.TokenSource(&credFetcherTokenSource{})
+
+func (ts *credFetcherTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := ts.credFetcher.GetPlatformCredential()
+	if err != nil {
+		proxyLog.Errorf("failed to fetch token through %s credential fetcher: %v", ts.credFetcher.GetType(), err)
+		return nil, fmt.Errorf("failed to fetch token through %s credential fetcher: %v", ts.credFetcher.GetType(), err)
+	}
+	// Tokens without a known expiration are fetched again for every request.
+	expiry := time.Now()
+	if exp, err := util.GetExp(tok); err == nil && !exp.IsZero() {
+		expiry = exp
+	}
+	return &oauth2.Token{
+		AccessToken: tok,
+		Expiry:      expiry,
+	}, nil
+}
+
 func (p *XdsProxy) initDownstreamServer() error {
 	l, err := uds.NewListener(xdsUdsPath)
 	if err != nil {
@@ -456,7 +485,11 @@ func (p *XdsProxy) buildUpstreamClientDialOpts(sa *Agent) ([]grpc.DialOption, er
 	// as the intention behind provisioned certs on k8s pods is only for data plane comm.
 	if sa.proxyConfig.ControlPlaneAuthPolicy != meshconfig.AuthenticationPolicy_NONE {
 		if sa.secOpts.ProvCert == "" || !sa.secOpts.FileMountedCerts {
-			dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: &fileTokenSource{sa.secOpts.JWTPath}}))
+			var ts oauth2.TokenSource = &fileTokenSource{sa.secOpts.JWTPath}
+			if sa.secOpts.CredFetcher != nil {
+				ts = oauth2.ReuseTokenSource(nil, &credFetcherTokenSource{sa.secOpts.CredFetcher})
+			}
+			dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: ts}))
 		}
 	}
 	return dialOptions, nil
@@ -16,12 +16,16 @@ package istioagent
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"net"
 	"path"
 	"testing"
+	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/test/bufconn"
@@ -212,3 +216,59 @@ func setupDownstreamConnection(t *testing.T) *grpc.ClientConn {
 	})
 	return conn
 }
+
+type countingCredFetcher struct {
+	token string
+	calls int
+}
+
+func (f *countingCredFetcher) GetPlatformCredential() (string, error) {
+	f.calls++
+	return f.token, nil
+}
+
+func (f *countingCredFetcher) GetType() string {
+	return "Counting"
+}
+
+func (f *countingCredFetcher) GetIdentityProvider() string {
+	return ""
+}
+
+func TestCredFetcherTokenSource(t *testing.T) {
+	claims := fmt.Sprintf(`{"exp":%d}`, time.Now().Add(time.Hour).Unix())
+	cases := []struct {
+		name  string
+		token string
+		calls int
+	}{
+		{
+			name:  "token with expiration is reused",
+			token: "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature",
+			calls: 1,
+		},
+		{
+			name:  "token without expiration is fetched again",
+			token: "opaque-token",
+			calls: 3,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetcher := &countingCredFetcher{token: tc.token}
+			ts := oauth2.ReuseTokenSource(nil, &credFetcherTokenSource{fetcher})
+			for i := 0; i < 3; i++ {
+				tok, err := ts.Token()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tok.AccessToken != tc.token {
+					t.Fatalf("got token %q, want %q", tok.AccessToken, tc.token)
+				}
+			}
+			if fetcher.calls != tc.calls {
+				t.Errorf("got %d credential fetches, want %d", fetcher.calls, tc.calls)
+			}
+		})
+	}
+}
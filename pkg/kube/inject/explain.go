@@ -0,0 +1,290 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	yamlDecoder "k8s.io/apimachinery/pkg/util/yaml"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	"istio.io/pkg/log"
+)
+
+// Explanation describes how the injection webhook handles a pod, without injecting it.
+type Explanation struct {
+	// Object identifies the workload the pod belongs to, when explaining resource files.
+	Object string `json:"object,omitempty"`
+
+	// Inject is whether the pod would be injected.
+	Inject bool `json:"inject"`
+
+	// Reason is the rule that decided whether the pod is injected.
+	Reason string `json:"reason"`
+
+	// Templates are the injection templates applied to the pod, in order.
+	Templates []string `json:"templates,omitempty"`
+
+	// Values are the inputs the templates were rendered over.
+	Values *ExplainedValues `json:"values,omitempty"`
+
+	// Rendered is the output of each template, by name.
+	Rendered map[string]string `json:"rendered,omitempty"`
+
+	// Patch is the JSON patch the webhook would respond with.
+	Patch json.RawMessage `json:"patch,omitempty"`
+
+	// Error is why injection would fail, if it would.
+	Error string `json:"error,omitempty"`
+}
+
+// ExplainedValues are the inputs the injection templates were rendered over.
+type ExplainedValues struct {
+	// ProxyConfig is the proxy config after applying the proxy.istio.io/config annotation.
+	ProxyConfig map[string]interface{} `json:"proxyConfig,omitempty"`
+
+	// ProxyEnvs are the environment overrides from the webhook URL and the values config.
+	ProxyEnvs map[string]string `json:"proxyEnvs,omitempty"`
+
+	// Values is the injector values config.
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// explain runs the injection decision and templates for a pod, the same way the webhook does.
+func explain(config *Config, req InjectionParameters) *Explanation {
+	ex := &Explanation{}
+	ex.Inject, ex.Reason = injectionDecision(ignoredNamespaces, config, &req.pod.Spec, req.pod.ObjectMeta)
+	if !ex.Inject {
+		return ex
+	}
+
+	rendered, err := renderTemplates(req)
+	if err != nil {
+		ex.Error = err.Error()
+		return ex
+	}
+	ex.Templates = rendered.names
+	ex.Rendered = make(map[string]string, len(rendered.names))
+	for i, name := range rendered.names {
+		ex.Rendered[name] = string(rendered.output[i])
+	}
+	ex.Values = &ExplainedValues{
+		ProxyEnvs: req.proxyEnvs,
+		Values:    rendered.data.Values,
+	}
+	if rendered.data.ProxyConfig != nil {
+		if ex.Values.ProxyConfig, err = gogoprotomarshal.ToJSONMap(rendered.data.ProxyConfig); err != nil {
+			ex.Error = fmt.Sprintf("failed to marshal proxy config: %v", err)
+			return ex
+		}
+	}
+
+	patch, err := injectPod(req)
+	if err != nil {
+		ex.Error = err.Error()
+		return ex
+	}
+	ex.Patch = patch
+	return ex
+}
+
+// ExplainPath is the debug path explaining how the webhook would inject the pod in the request body.
+const ExplainPath = "/debug/inject/explain"
+
+// maxExplainBodySize limits the size of the pods explained by ServeExplain.
+const maxExplainBodySize = 1 << 20
+
+// PodExplainer explains how a pod of a workload would be injected.
+type PodExplainer func(pod *corev1.Pod, typeMeta *metav1.TypeMeta, deployMeta *metav1.ObjectMeta) (*Explanation, error)
+
+// ExplainObject explains how the injection webhook would handle the pods of a workload.
+// Lists are explained item by item, and resources without pods are skipped.
+// nolint: lll
+func ExplainObject(config *Config, valuesConfig string, revision string, meshconfig *meshconfig.MeshConfig, in runtime.Object) ([]*Explanation, error) {
+	return ExplainObjectWith(in, func(pod *corev1.Pod, typeMeta *metav1.TypeMeta, deployMeta *metav1.ObjectMeta) (*Explanation, error) {
+		return explain(config, InjectionParameters{
+			pod:                 pod,
+			deployMeta:          deployMeta,
+			typeMeta:            typeMeta,
+			templates:           config.RawTemplates(),
+			defaultTemplates:    config.GetDefaultTemplates(),
+			version:             templatesVersionHash(config.RawTemplates()),
+			meshConfig:          meshconfig,
+			valuesConfig:        valuesConfig,
+			revision:            revision,
+			proxyEnvs:           map[string]string{},
+			injectedAnnotations: config.InjectedAnnotations,
+		}), nil
+	})
+}
+
+// ExplainObjectWith explains the pods of a workload with explainPod, for example by sending
+// them to the explain endpoint of Istiod.
+func ExplainObjectWith(in runtime.Object, explainPod PodExplainer) ([]*Explanation, error) {
+	if list, ok := in.(*corev1.List); ok {
+		var out []*Explanation
+		for _, item := range list.Items {
+			obj, err := FromRawToObject(item.Raw)
+			if runtime.IsNotRegisteredError(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			explanations, err := ExplainObjectWith(obj, explainPod)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, explanations...)
+		}
+		return out, nil
+	}
+
+	obj := in.DeepCopyObject()
+	typeMeta, deploymentMetadata, metadata, podSpec, err := podTemplate(obj)
+	if err != nil {
+		return nil, err
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: *metadata,
+		Spec:       *podSpec,
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = deploymentMetadata.Namespace
+	}
+	ex, err := explainPod(pod, typeMeta, deploymentMetadata)
+	if err != nil {
+		return nil, err
+	}
+	ex.Object = fmt.Sprintf("%s %s", typeMeta.Kind, deploymentMetadata.Name)
+	if deploymentMetadata.Namespace != "" {
+		ex.Object += "." + deploymentMetadata.Namespace
+	}
+	return []*Explanation{ex}, nil
+}
+
+// ExplainResourceFile explains how the injection webhook would handle the pods of every
+// workload in a kubernetes YAML file.
+// nolint: lll
+func ExplainResourceFile(config *Config, valuesConfig string, revision string, meshconfig *meshconfig.MeshConfig, in io.Reader) ([]*Explanation, error) {
+	return explainResourceFile(in, func(obj runtime.Object) ([]*Explanation, error) {
+		return ExplainObject(config, valuesConfig, revision, meshconfig, obj)
+	})
+}
+
+// ExplainResourceFileWith explains the pods of every workload in a kubernetes YAML file with explainPod.
+func ExplainResourceFileWith(in io.Reader, explainPod PodExplainer) ([]*Explanation, error) {
+	return explainResourceFile(in, func(obj runtime.Object) ([]*Explanation, error) {
+		return ExplainObjectWith(obj, explainPod)
+	})
+}
+
+func explainResourceFile(in io.Reader, explainObject func(runtime.Object) ([]*Explanation, error)) ([]*Explanation, error) {
+	var out []*Explanation
+	reader := yamlDecoder.NewYAMLReader(bufio.NewReaderSize(in, 4096))
+	for {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		obj, err := FromRawToObject(raw)
+		if runtime.IsNotRegisteredError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		explanations, err := explainObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, explanations...)
+	}
+	return out, nil
+}
+
+// ServeExplain explains how the webhook would inject the pod in the request body, given as
+// JSON or YAML. Like /inject, the path may carry environment overrides, e.g.
+// /debug/inject/explain/cluster/cluster1. It is served with the debug endpoints of Istiod,
+// rather than on the webhook server.
+func (wh *Webhook) ServeExplain(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxExplainBodySize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not read body: %v", err), http.StatusRequestEntityTooLarge)
+			return
+		}
+		body = data
+	}
+	if len(body) == 0 {
+		http.Error(w, "no body found", http.StatusBadRequest)
+		return
+	}
+
+	var pod corev1.Pod
+	if err := yaml.Unmarshal(body, &pod); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode pod: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	path := ""
+	if r.URL != nil {
+		path = "/inject" + strings.TrimPrefix(r.URL.Path, ExplainPath)
+	}
+
+	wh.mu.RLock()
+	config := wh.Config
+	params := InjectionParameters{
+		pod:                 &pod,
+		templates:           config.RawTemplates(),
+		defaultTemplates:    config.GetDefaultTemplates(),
+		version:             wh.sidecarTemplateVersion,
+		meshConfig:          wh.meshConfig,
+		valuesConfig:        wh.valuesConfig,
+		revision:            wh.revision,
+		injectedAnnotations: config.InjectedAnnotations,
+		proxyEnvs:           parseInjectEnvs(path),
+	}
+	wh.mu.RUnlock()
+	params.deployMeta, params.typeMeta = kube.GetDeployMetaFromPod(&pod)
+
+	resp, err := json.MarshalIndent(explain(config, params), "", "  ")
+	if err != nil {
+		log.Errorf("Could not encode explanation: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode explanation: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("Could not write explanation: %v", err)
+	}
+}
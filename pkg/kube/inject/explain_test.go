@@ -0,0 +1,226 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestInjectionDecision(t *testing.T) {
+	config := &Config{
+		Policy: InjectionPolicyEnabled,
+		NeverInjectSelector: []metav1.LabelSelector{
+			{MatchLabels: map[string]string{"app": "never"}},
+		},
+	}
+	cases := []struct {
+		name        string
+		namespace   string
+		labels      map[string]string
+		annotations map[string]string
+		hostNetwork bool
+		want        bool
+		wantReason  string
+	}{
+		{
+			name:       "policy",
+			namespace:  "default",
+			want:       true,
+			wantReason: "injection policy is enabled",
+		},
+		{
+			name:        "annotation",
+			namespace:   "default",
+			annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+			wantReason:  `annotation sidecar.istio.io/inject is "false"`,
+		},
+		{
+			name:       "selector",
+			namespace:  "default",
+			labels:     map[string]string{"app": "never"},
+			wantReason: `pod labels match neverInjectSelector "app=never"`,
+		},
+		{
+			name:       "ignored namespace",
+			namespace:  metav1.NamespaceSystem,
+			wantReason: "namespace kube-system is never injected",
+		},
+		{
+			name:        "host network",
+			namespace:   "default",
+			hostNetwork: true,
+			wantReason:  "host networking is enabled",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			metadata := metav1.ObjectMeta{Name: "pod", Namespace: tc.namespace, Labels: tc.labels, Annotations: tc.annotations}
+			got, reason := injectionDecision(ignoredNamespaces, config, &corev1.PodSpec{HostNetwork: tc.hostNetwork}, metadata)
+			if got != tc.want || reason != tc.wantReason {
+				t.Errorf("got %v %q, want %v %q", got, reason, tc.want, tc.wantReason)
+			}
+		})
+	}
+}
+
+const explainInput = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: hello
+  template:
+    metadata:
+      labels:
+        app: hello
+      annotations:
+        inject.istio.io/templates: sidecar,debug
+    spec:
+      containers:
+      - name: hello
+        image: hello:latest
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: hello
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: disabled
+  annotations:
+    sidecar.istio.io/inject: "false"
+spec:
+  containers:
+  - name: hello
+    image: hello:latest
+`
+
+func TestExplainResourceFile(t *testing.T) {
+	config := &Config{
+		Policy:   InjectionPolicyEnabled,
+		Template: "containers:\n- name: istio-proxy\n  image: {{ .Values.global.hub }}/proxyv2\n",
+		Templates: map[string]string{
+			"debug": "containers:\n- name: istio-proxy\n  args: [--proxyLogLevel=debug]\n",
+		},
+	}
+	m := mesh.DefaultMeshConfig()
+	got, err := ExplainResourceFile(config, `{"global": {"hub": "example.com"}}`, "", &m, strings.NewReader(explainInput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected an explanation for each workload, got %d", len(got))
+	}
+
+	hello := got[0]
+	if hello.Object != "Deployment hello.default" || !hello.Inject || hello.Error != "" {
+		t.Fatalf("unexpected explanation: %+v", hello)
+	}
+	if strings.Join(hello.Templates, ",") != "sidecar,debug" {
+		t.Errorf("unexpected templates: %v", hello.Templates)
+	}
+	if !strings.Contains(hello.Rendered["sidecar"], "image: example.com/proxyv2") {
+		t.Errorf("unexpected rendered sidecar template: %q", hello.Rendered["sidecar"])
+	}
+	if hello.Values == nil || hello.Values.ProxyConfig["discoveryAddress"] == nil {
+		t.Errorf("expected the proxy config in the values: %+v", hello.Values)
+	}
+	var patch []map[string]interface{}
+	if err := json.Unmarshal(hello.Patch, &patch); err != nil {
+		t.Fatalf("invalid patch: %v", err)
+	}
+	if !strings.Contains(string(hello.Patch), "--proxyLogLevel=debug") {
+		t.Errorf("expected the debug template in the patch: %s", hello.Patch)
+	}
+
+	disabled := got[1]
+	if disabled.Object != "Pod disabled" || disabled.Inject || disabled.Patch != nil ||
+		disabled.Reason != `annotation sidecar.istio.io/inject is "false"` {
+		t.Errorf("unexpected explanation: %+v", disabled)
+	}
+}
+
+func TestServeExplain(t *testing.T) {
+	wh := createTestWebhookFromFile("testdata/webhook/TestWebhookInject_templates_template.yaml", t)
+	explainPod := func(path, pod string) *Explanation {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(pod))
+		rec := httptest.NewRecorder()
+		wh.ServeExplain(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		ex := &Explanation{}
+		if err := json.Unmarshal(rec.Body.Bytes(), ex); err != nil {
+			t.Fatal(err)
+		}
+		return ex
+	}
+
+	ex := explainPod(ExplainPath+"/cluster/cluster1", `
+metadata:
+  name: hello
+  namespace: default
+spec:
+  containers:
+  - name: hello
+`)
+	if !ex.Inject || strings.Join(ex.Templates, ",") != "sidecar" || len(ex.Patch) == 0 {
+		t.Errorf("unexpected explanation: %+v", ex)
+	}
+	if ex.Values.ProxyEnvs["ISTIO_META_CLUSTER_ID"] != "cluster1" {
+		t.Errorf("expected the cluster from the path, got %v", ex.Values.ProxyEnvs)
+	}
+
+	ex = explainPod(ExplainPath, `
+metadata:
+  name: hello
+  namespace: default
+  annotations:
+    inject.istio.io/templates: invalid
+spec:
+  containers:
+  - name: hello
+`)
+	if !ex.Inject || ex.Error == "" || ex.Patch != nil {
+		t.Errorf("expected the invalid template to fail injection: %+v", ex)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, ExplainPath, strings.NewReader(strings.Repeat("a", maxExplainBodySize+1)))
+	rec := httptest.NewRecorder()
+	wh.ServeExplain(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected an oversized body to be rejected, got status %d", rec.Code)
+	}
+}
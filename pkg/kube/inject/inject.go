@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -82,6 +83,14 @@ const (
 
 	// EnableCoreDumpName is the name of the init container that allows core dumps
 	EnableCoreDumpName = "enable-core-dump"
+
+	// SidecarTemplateName is the name under which Config.Template is available
+	// to pods selecting injection templates.
+	SidecarTemplateName = "sidecar"
+
+	// InjectTemplatesAnnotation is a comma separated list of the injection templates
+	// to apply to a pod, in order. Pods without it get Config.DefaultTemplates.
+	InjectTemplatesAnnotation = "inject.istio.io/templates"
 )
 
 // SidecarTemplateData is the data object to which the templated
@@ -106,6 +115,15 @@ type Config struct {
 	// expansion over the `SidecarTemplateData`.
 	Template string `json:"template"`
 
+	// Templates is a set of named templates, in the same form as Template.
+	// Template is available as the "sidecar" template unless Templates
+	// defines one with that name.
+	Templates map[string]string `json:"templates,omitempty"`
+
+	// DefaultTemplates are the templates applied to pods that do not select
+	// any with the inject.istio.io/templates annotation. Defaults to "sidecar".
+	DefaultTemplates []string `json:"defaultTemplates,omitempty"`
+
 	// NeverInjectSelector: Refuses the injection on pods whose labels match this selector.
 	// It's an array of label selectors, that will be OR'ed, meaning we will iterate
 	// over it and stop at the first match
@@ -122,7 +140,117 @@ type Config struct {
 	InjectedAnnotations map[string]string `json:"injectedAnnotations"`
 }
 
+// RawTemplates returns all of the named templates, including Template as the
+// "sidecar" template.
+func (c *Config) RawTemplates() map[string]string {
+	templates := make(map[string]string, len(c.Templates)+1)
+	if c.Template != "" {
+		templates[SidecarTemplateName] = c.Template
+	}
+	for name, tmpl := range c.Templates {
+		templates[name] = tmpl
+	}
+	return templates
+}
+
+// GetDefaultTemplates returns the templates applied to pods that do not select any.
+func (c *Config) GetDefaultTemplates() []string {
+	if len(c.DefaultTemplates) > 0 {
+		return c.DefaultTemplates
+	}
+	return []string{SidecarTemplateName}
+}
+
+// ValidateTemplates checks each template independently, so one broken template
+// does not prevent pods from using the others. The errors are keyed by template name.
+func (c *Config) ValidateTemplates() map[string]error {
+	errs := map[string]error{}
+	templates := c.RawTemplates()
+	for name, tmpl := range templates {
+		if err := validateTemplate(tmpl); err != nil {
+			errs[name] = err
+		}
+	}
+	for _, name := range c.GetDefaultTemplates() {
+		if _, f := templates[name]; !f {
+			errs[name] = fmt.Errorf("default template %q is not defined", name)
+		}
+	}
+	return errs
+}
+
+// TemplateStatus describes a named injection template.
+type TemplateStatus struct {
+	Name     string `json:"name"`
+	Default  bool   `json:"default"`
+	Error    string `json:"error,omitempty"`
+	Template string `json:"template"`
+}
+
+// TemplateStatuses returns the status of every template, sorted by name.
+func (c *Config) TemplateStatuses() []TemplateStatus {
+	errs := c.ValidateTemplates()
+	defaults := map[string]bool{}
+	for _, name := range c.GetDefaultTemplates() {
+		defaults[name] = true
+	}
+	templates := c.RawTemplates()
+	for name := range errs {
+		if _, f := templates[name]; !f {
+			templates[name] = ""
+		}
+	}
+	out := make([]TemplateStatus, 0, len(templates))
+	for name, tmpl := range templates {
+		status := TemplateStatus{Name: name, Default: defaults[name], Template: tmpl}
+		if err := errs[name]; err != nil {
+			status.Error = err.Error()
+		}
+		out = append(out, status)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func validateTemplate(tmplStr string) error {
+	funcMap := CreateInjectionFuncmap()
+	funcMap["render"] = func(string) string { return "" }
+	_, err := template.New("inject").Funcs(sprig.TxtFuncMap()).Funcs(funcMap).Parse(tmplStr)
+	return err
+}
+
+// selectTemplates returns the names of the templates to apply to a pod, in order.
+func selectTemplates(templates map[string]string, defaults []string, annos map[string]string) ([]string, error) {
+	selected, f := annos[InjectTemplatesAnnotation]
+	if !f {
+		return defaults, nil
+	}
+	var names []string
+	for _, name := range strings.Split(selected, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, f := templates[name]; !f {
+			return nil, fmt.Errorf("unknown injection template %q", name)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("annotation %s selects no templates", InjectTemplatesAnnotation)
+	}
+	return names, nil
+}
+
 func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, metadata metav1.ObjectMeta) bool { // nolint: lll
+	required, _ := injectionDecision(ignored, config, podSpec, metadata)
+	return required
+}
+
+// injectionDecision returns whether the pod requires injection, along with the rule that decided it.
+func injectionDecision(ignored []string, config *Config, podSpec *corev1.PodSpec, metadata metav1.ObjectMeta) (bool, string) { // nolint: lll
 	// Skip injection when host networking is enabled. The problem is
 	// that the iptables changes are assumed to be within the pod when,
 	// in fact, they are changing the routing at the host level. This
@@ -130,13 +258,13 @@ func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, m
 	// affect the network provider within the cluster causing
 	// additional pod failures.
 	if podSpec.HostNetwork {
-		return false
+		return false, "host networking is enabled"
 	}
 
 	// skip special kubernetes system namespaces
 	for _, namespace := range ignored {
 		if metadata.Namespace == namespace {
-			return false
+			return false, fmt.Sprintf("namespace %s is never injected", namespace)
 		}
 	}
 
@@ -147,12 +275,16 @@ func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, m
 
 	var useDefault bool
 	var inject bool
-	switch strings.ToLower(annos[annotation.SidecarInject.Name]) {
+	var reason string
+	switch value := annos[annotation.SidecarInject.Name]; strings.ToLower(value) {
 	// http://yaml.org/type/bool.html
 	case "y", "yes", "true", "on":
 		inject = true
+		reason = fmt.Sprintf("annotation %s is %q", annotation.SidecarInject.Name, value)
 	case "":
 		useDefault = true
+	default:
+		reason = fmt.Sprintf("annotation %s is %q", annotation.SidecarInject.Name, value)
 	}
 
 	// If an annotation is not explicitly given, check the LabelSelectors, starting with NeverInject
@@ -166,6 +298,7 @@ func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, m
 					metadata.Namespace, potentialPodName(metadata))
 				inject = false
 				useDefault = false
+				reason = fmt.Sprintf("pod labels match neverInjectSelector %q", selector.String())
 				break
 			}
 		}
@@ -182,6 +315,7 @@ func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, m
 					metadata.Namespace, potentialPodName(metadata))
 				inject = true
 				useDefault = false
+				reason = fmt.Sprintf("pod labels match alwaysInjectSelector %q", selector.String())
 				break
 			}
 		}
@@ -193,15 +327,18 @@ func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, m
 		log.Errorf("Illegal value for autoInject:%s, must be one of [%s,%s]. Auto injection disabled!",
 			config.Policy, InjectionPolicyDisabled, InjectionPolicyEnabled)
 		required = false
+		reason = fmt.Sprintf("injection policy %q is invalid", config.Policy)
 	case InjectionPolicyDisabled:
 		if useDefault {
 			required = false
+			reason = fmt.Sprintf("injection policy is %s", config.Policy)
 		} else {
 			required = inject
 		}
 	case InjectionPolicyEnabled:
 		if useDefault {
 			required = true
+			reason = fmt.Sprintf("injection policy is %s", config.Policy)
 		} else {
 			required = inject
 		}
@@ -218,42 +355,75 @@ func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, m
 			annotationStr += fmt.Sprintf("%s:%s ", name, value)
 		}
 
-		log.Debugf("Sidecar injection policy for %v/%v: namespacePolicy:%v useDefault:%v inject:%v required:%v %s",
+		log.Debugf("Sidecar injection policy for %v/%v: namespacePolicy:%v useDefault:%v inject:%v required:%v reason:%q %s",
 			metadata.Namespace,
 			potentialPodName(metadata),
 			config.Policy,
 			useDefault,
 			inject,
 			required,
+			reason,
 			annotationStr)
 	}
 
-	return required
+	return required, reason
 }
 
-// RunTemplate renders the sidecar template
-// Returns the raw string template, as well as the parse pod form
-func RunTemplate(params InjectionParameters) ([]byte, *corev1.PodSpec, error) {
+// RunTemplate renders the injection templates selected for the pod and merges them,
+// in order, into its spec. Returns the merged pod spec, as well as everything the
+// templates injected.
+func RunTemplate(params InjectionParameters) (*corev1.PodSpec, *corev1.PodSpec, error) {
+	rendered, err := renderTemplates(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Overlay each template, in order, onto the original pod spec
+	mergedPodSpec := params.pod.Spec
+	injectedPodSpec := &corev1.PodSpec{}
+	for i, name := range rendered.names {
+		podSpec := corev1.PodSpec{}
+		if err := yaml.Unmarshal(rendered.output[i], &podSpec); err != nil {
+			return nil, nil, fmt.Errorf("failed parsing generated injected YAML for template %q (check Istio sidecar injector configuration): %v",
+				name, err)
+		}
+		if mergedPodSpec, err = mergeInjectedConfig(mergedPodSpec, rendered.output[i]); err != nil {
+			return nil, nil, fmt.Errorf("failed to merge template %q: %v", name, err)
+		}
+		addInjectedSpec(injectedPodSpec, podSpec)
+	}
+	return &mergedPodSpec, injectedPodSpec, nil
+}
+
+// renderedTemplates is the output of the templates selected for a pod, along with
+// the data they were rendered over.
+type renderedTemplates struct {
+	data   SidecarTemplateData
+	names  []string
+	output [][]byte
+}
+
+func renderTemplates(params InjectionParameters) (*renderedTemplates, error) {
 	metadata := &params.pod.ObjectMeta
 	meshConfig := params.meshConfig
 
 	if err := validateAnnotations(metadata.GetAnnotations()); err != nil {
 		log.Errorf("Injection failed due to invalid annotations: %v", err)
-		return nil, nil, err
+		return nil, err
 	}
 
 	if pca, f := metadata.GetAnnotations()[annotation.ProxyConfig.Name]; f {
 		var merr error
 		meshConfig, merr = mesh.ApplyProxyConfig(pca, *meshConfig)
 		if merr != nil {
-			return nil, nil, merr
+			return nil, merr
 		}
 	}
 
 	valuesStruct := &opconfig.Values{}
 	if err := gogoprotomarshal.ApplyYAML(params.valuesConfig, valuesStruct); err != nil {
 		log.Infof("Failed to parse values config: %v [%v]\n", err, params.valuesConfig)
-		return nil, nil, multierror.Prefix(err, "could not parse configuration values:")
+		return nil, multierror.Prefix(err, "could not parse configuration values:")
 	}
 
 	cluster := valuesStruct.GetGlobal().GetMultiCluster().GetClusterName()
@@ -282,7 +452,7 @@ func RunTemplate(params InjectionParameters) ([]byte, *corev1.PodSpec, error) {
 	values := map[string]interface{}{}
 	if err := yaml.Unmarshal([]byte(params.valuesConfig), &values); err != nil {
 		log.Infof("Failed to parse values config: %v [%v]\n", err, params.valuesConfig)
-		return nil, nil, multierror.Prefix(err, "could not parse configuration values:")
+		return nil, multierror.Prefix(err, "could not parse configuration values:")
 	}
 
 	strippedPod := stripPod(params)
@@ -308,16 +478,70 @@ func RunTemplate(params InjectionParameters) ([]byte, *corev1.PodSpec, error) {
 		return bbuf.String()
 	}
 
-	bbuf, err := parseTemplate(params.template, funcMap, data)
+	names, err := selectTemplates(params.templates, params.defaultTemplates, metadata.GetAnnotations())
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	podSpec := corev1.PodSpec{}
-	if err := yaml.Unmarshal(bbuf.Bytes(), &podSpec); err != nil {
-		return nil, nil, fmt.Errorf("failed parsing generated injected YAML (check Istio sidecar injector configuration): %v", err)
+	out := &renderedTemplates{data: data, names: names}
+	for _, name := range names {
+		tmpl, f := params.templates[name]
+		if !f {
+			return nil, fmt.Errorf("unknown injection template %q", name)
+		}
+		bbuf, err := parseTemplate(tmpl, funcMap, data)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %v", name, err)
+		}
+		out.output = append(out.output, bbuf.Bytes())
+	}
+	return out, nil
+}
+
+// addInjectedSpec records the containers, volumes and pull secrets injected by a template.
+// Later templates may patch resources injected by earlier ones, so names are only recorded once.
+func addInjectedSpec(injected *corev1.PodSpec, spec corev1.PodSpec) {
+	seen := map[string]bool{}
+	for _, c := range injected.InitContainers {
+		seen["init/"+c.Name] = true
+	}
+	for _, c := range injected.Containers {
+		seen["container/"+c.Name] = true
+	}
+	for _, v := range injected.Volumes {
+		seen["volume/"+v.Name] = true
+	}
+	for _, s := range injected.ImagePullSecrets {
+		seen["secret/"+s.Name] = true
+	}
+	add := func(key string) bool {
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		return true
+	}
+
+	for _, c := range spec.InitContainers {
+		if add("init/" + c.Name) {
+			injected.InitContainers = append(injected.InitContainers, c)
+		}
+	}
+	for _, c := range spec.Containers {
+		if add("container/" + c.Name) {
+			injected.Containers = append(injected.Containers, c)
+		}
+	}
+	for _, v := range spec.Volumes {
+		if add("volume/" + v.Name) {
+			injected.Volumes = append(injected.Volumes, v)
+		}
+	}
+	for _, s := range spec.ImagePullSecrets {
+		if add("secret/" + s.Name) {
+			injected.ImagePullSecrets = append(injected.ImagePullSecrets, s)
+		}
 	}
-	return bbuf.Bytes(), &podSpec, nil
 }
 
 func stripPod(req InjectionParameters) *corev1.Pod {
@@ -388,7 +612,7 @@ func parseTemplate(tmplStr string, funcMap map[string]interface{}, data SidecarT
 // IntoResourceFile injects the istio proxy into the specified
 // kubernetes YAML file.
 // nolint: lll
-func IntoResourceFile(sidecarTemplate string, valuesConfig string, revision string, meshconfig *meshconfig.MeshConfig, in io.Reader, out io.Writer, warningHandler func(string)) error {
+func IntoResourceFile(injectConfig *Config, valuesConfig string, revision string, meshconfig *meshconfig.MeshConfig, in io.Reader, out io.Writer, warningHandler func(string)) error {
 	reader := yamlDecoder.NewYAMLReader(bufio.NewReaderSize(in, 4096))
 	for {
 		raw, err := reader.Read()
@@ -406,7 +630,7 @@ func IntoResourceFile(sidecarTemplate string, valuesConfig string, revision stri
 
 		var updated []byte
 		if err == nil {
-			outObject, err := IntoObject(sidecarTemplate, valuesConfig, revision, meshconfig, obj, warningHandler) // nolint: vetshadow
+			outObject, err := IntoObject(injectConfig, valuesConfig, revision, meshconfig, obj, warningHandler) // nolint: vetshadow
 			if err != nil {
 				return err
 			}
@@ -448,14 +672,9 @@ func FromRawToObject(raw []byte) (runtime.Object, error) {
 
 // IntoObject convert the incoming resources into Injected resources
 // nolint: lll
-func IntoObject(sidecarTemplate string, valuesConfig string, revision string, meshconfig *meshconfig.MeshConfig, in runtime.Object, warningHandler func(string)) (interface{}, error) {
+func IntoObject(injectConfig *Config, valuesConfig string, revision string, meshconfig *meshconfig.MeshConfig, in runtime.Object, warningHandler func(string)) (interface{}, error) {
 	out := in.DeepCopyObject()
 
-	var deploymentMetadata *metav1.ObjectMeta
-	var metadata *metav1.ObjectMeta
-	var podSpec *corev1.PodSpec
-	var typeMeta *metav1.TypeMeta
-
 	// Handle Lists
 	if list, ok := out.(*corev1.List); ok {
 		result := list
@@ -469,7 +688,7 @@ func IntoObject(sidecarTemplate string, valuesConfig string, revision string, me
 				return nil, err
 			}
 
-			r, err := IntoObject(sidecarTemplate, valuesConfig, revision, meshconfig, obj, warningHandler) // nolint: vetshadow
+			r, err := IntoObject(injectConfig, valuesConfig, revision, meshconfig, obj, warningHandler) // nolint: vetshadow
 			if err != nil {
 				return nil, err
 			}
@@ -481,46 +700,9 @@ func IntoObject(sidecarTemplate string, valuesConfig string, revision string, me
 		return result, nil
 	}
 
-	// CronJobs have JobTemplates in them, instead of Templates, so we
-	// special case them.
-	switch v := out.(type) {
-	case *v2alpha1.CronJob:
-		job := v
-		typeMeta = &job.TypeMeta
-		metadata = &job.Spec.JobTemplate.ObjectMeta
-		deploymentMetadata = &job.ObjectMeta
-		podSpec = &job.Spec.JobTemplate.Spec.Template.Spec
-	case *corev1.Pod:
-		pod := v
-		typeMeta = &pod.TypeMeta
-		metadata = &pod.ObjectMeta
-		deploymentMetadata = &pod.ObjectMeta
-		podSpec = &pod.Spec
-	case *appsv1.Deployment: // Added to be explicit about the most expected case
-		deploy := v
-		typeMeta = &deploy.TypeMeta
-		deploymentMetadata = &deploy.ObjectMeta
-		metadata = &deploy.Spec.Template.ObjectMeta
-		podSpec = &deploy.Spec.Template.Spec
-	default:
-		// `in` is a pointer to an Object. Dereference it.
-		outValue := reflect.ValueOf(out).Elem()
-
-		typeMeta = outValue.FieldByName("TypeMeta").Addr().Interface().(*metav1.TypeMeta)
-
-		deploymentMetadata = outValue.FieldByName("ObjectMeta").Addr().Interface().(*metav1.ObjectMeta)
-
-		templateValue := outValue.FieldByName("Spec").FieldByName("Template")
-		// `Template` is defined as a pointer in some older API
-		// definitions, e.g. ReplicationController
-		if templateValue.Kind() == reflect.Ptr {
-			if templateValue.IsNil() {
-				return out, fmt.Errorf("spec.template is required value")
-			}
-			templateValue = templateValue.Elem()
-		}
-		metadata = templateValue.FieldByName("ObjectMeta").Addr().Interface().(*metav1.ObjectMeta)
-		podSpec = templateValue.FieldByName("Spec").Addr().Interface().(*corev1.PodSpec)
+	typeMeta, deploymentMetadata, metadata, podSpec, err := podTemplate(out)
+	if err != nil {
+		return out, err
 	}
 
 	name := metadata.Name
@@ -562,8 +744,9 @@ func IntoObject(sidecarTemplate string, valuesConfig string, revision string, me
 		pod:                 pod,
 		deployMeta:          deploymentMetadata,
 		typeMeta:            typeMeta,
-		template:            sidecarTemplate,
-		version:             sidecarTemplateVersionHash(sidecarTemplate),
+		templates:           injectConfig.RawTemplates(),
+		defaultTemplates:    injectConfig.GetDefaultTemplates(),
+		version:             templatesVersionHash(injectConfig.RawTemplates()),
 		meshConfig:          meshconfig,
 		valuesConfig:        valuesConfig,
 		revision:            revision,
@@ -588,6 +771,54 @@ func IntoObject(sidecarTemplate string, valuesConfig string, revision string, me
 	return out, nil
 }
 
+// podTemplate returns the type and metadata of a workload, along with the metadata and
+// spec of the pods it creates. The returned pointers refer into the workload.
+func podTemplate(out runtime.Object) (typeMeta *metav1.TypeMeta, deploymentMetadata *metav1.ObjectMeta,
+	metadata *metav1.ObjectMeta, podSpec *corev1.PodSpec, err error) {
+	// CronJobs have JobTemplates in them, instead of Templates, so we
+	// special case them.
+	switch v := out.(type) {
+	case *v2alpha1.CronJob:
+		job := v
+		typeMeta = &job.TypeMeta
+		metadata = &job.Spec.JobTemplate.ObjectMeta
+		deploymentMetadata = &job.ObjectMeta
+		podSpec = &job.Spec.JobTemplate.Spec.Template.Spec
+	case *corev1.Pod:
+		pod := v
+		typeMeta = &pod.TypeMeta
+		metadata = &pod.ObjectMeta
+		deploymentMetadata = &pod.ObjectMeta
+		podSpec = &pod.Spec
+	case *appsv1.Deployment: // Added to be explicit about the most expected case
+		deploy := v
+		typeMeta = &deploy.TypeMeta
+		deploymentMetadata = &deploy.ObjectMeta
+		metadata = &deploy.Spec.Template.ObjectMeta
+		podSpec = &deploy.Spec.Template.Spec
+	default:
+		// `in` is a pointer to an Object. Dereference it.
+		outValue := reflect.ValueOf(out).Elem()
+
+		typeMeta = outValue.FieldByName("TypeMeta").Addr().Interface().(*metav1.TypeMeta)
+
+		deploymentMetadata = outValue.FieldByName("ObjectMeta").Addr().Interface().(*metav1.ObjectMeta)
+
+		templateValue := outValue.FieldByName("Spec").FieldByName("Template")
+		// `Template` is defined as a pointer in some older API
+		// definitions, e.g. ReplicationController
+		if templateValue.Kind() == reflect.Ptr {
+			if templateValue.IsNil() {
+				return nil, nil, nil, nil, fmt.Errorf("spec.template is required value")
+			}
+			templateValue = templateValue.Elem()
+		}
+		metadata = templateValue.FieldByName("ObjectMeta").Addr().Interface().(*metav1.ObjectMeta)
+		podSpec = templateValue.FieldByName("Spec").Addr().Interface().(*corev1.PodSpec)
+	}
+	return typeMeta, deploymentMetadata, metadata, podSpec, nil
+}
+
 func applyJSONPatchToPod(input *corev1.Pod, patch []byte) ([]byte, error) {
 	objJS, err := runtime.Encode(jsonSerializer, input)
 	if err != nil {
@@ -624,6 +855,27 @@ func sidecarTemplateVersionHash(in string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// templatesVersionHash hashes all of the named templates. A lone sidecar template hashes
+// as it did before templates were named, so existing pods keep a matching version.
+func templatesVersionHash(templates map[string]string) string {
+	if tmpl, f := templates[SidecarTemplateName]; f && len(templates) == 1 {
+		return sidecarTemplateVersionHash(tmpl)
+	}
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(templates[name]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 func potentialPodName(metadata metav1.ObjectMeta) string {
 	if metadata.Name != "" {
 		return metadata.Name
@@ -50,10 +50,25 @@ func TestInjection(t *testing.T) {
 		setFlags      []string
 		inFilePath    string
 		mesh          func(m *meshapi.MeshConfig)
+		templates     map[string]string
 		skipWebhook   bool
 		expectedError string
 	}
 	cases := []testCase{
+		{
+			// The gateway template is selected instead of the default sidecar template.
+			in:   "hello-template-gateway.yaml",
+			want: "hello-template-gateway.yaml.injected",
+			templates: map[string]string{
+				"gateway": `containers:
+- name: istio-proxy
+  image: example.com/proxy:latest
+  args:
+  - proxy
+  - router
+`,
+			},
+		},
 		// verify cni
 		{
 			in:   "hello.yaml",
@@ -300,6 +315,11 @@ func TestInjection(t *testing.T) {
 			if c.mesh != nil {
 				c.mesh(mc)
 			}
+			if c.templates != nil {
+				cfg := *sidecarTemplate
+				cfg.Templates = c.templates
+				sidecarTemplate = &cfg
+			}
 
 			inputFilePath := "testdata/inject/" + c.in
 			wantFilePath := "testdata/inject/" + c.want
@@ -314,7 +334,7 @@ func TestInjection(t *testing.T) {
 			// First we test kube-inject. This will run exactly what kube-inject does, and write output to the golden files
 			t.Run("kube-inject", func(t *testing.T) {
 				var got bytes.Buffer
-				if err = IntoResourceFile(sidecarTemplate.Template, valuesConfig, "", mc, in, &got, nullWarningHandler); err != nil {
+				if err = IntoResourceFile(sidecarTemplate, valuesConfig, "", mc, in, &got, nullWarningHandler); err != nil {
 					if c.expectedError != "" {
 						if !strings.Contains(strings.ToLower(err.Error()), c.expectedError) {
 							t.Fatalf("expected error %q got %q", c.expectedError, err)
@@ -667,3 +687,90 @@ func TestAppendMultusNetwork(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectTemplates(t *testing.T) {
+	config := &Config{
+		Template: "containers: []",
+		Templates: map[string]string{
+			"grpc-agent": "containers: []",
+			"debug":      "containers: []",
+		},
+	}
+	cases := []struct {
+		name       string
+		annotation *string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name: "default",
+			want: []string{SidecarTemplateName},
+		},
+		{
+			name:       "ordered",
+			annotation: stringPtr("sidecar, debug"),
+			want:       []string{"sidecar", "debug"},
+		},
+		{
+			name:       "single",
+			annotation: stringPtr("grpc-agent"),
+			want:       []string{"grpc-agent"},
+		},
+		{
+			name:       "unknown",
+			annotation: stringPtr("sidecar,gateway"),
+			wantErr:    true,
+		},
+		{
+			name:       "empty",
+			annotation: stringPtr(" , "),
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			annos := map[string]string{}
+			if tc.annotation != nil {
+				annos[InjectTemplatesAnnotation] = *tc.annotation
+			}
+			got, err := selectTemplates(config.RawTemplates(), config.GetDefaultTemplates(), annos)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("unexpected templates (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestTemplateStatuses(t *testing.T) {
+	config := &Config{
+		Template: "containers: []",
+		Templates: map[string]string{
+			"debug":   "containers: []",
+			"invalid": "containers: {{ .Missing",
+		},
+		DefaultTemplates: []string{"sidecar", "gateway"},
+	}
+	got := config.TemplateStatuses()
+	var summary []string
+	for _, status := range got {
+		summary = append(summary, fmt.Sprintf("%s default=%v valid=%v", status.Name, status.Default, status.Error == ""))
+	}
+	want := []string{
+		"debug default=false valid=true",
+		"gateway default=true valid=false",
+		"invalid default=false valid=false",
+		"sidecar default=true valid=true",
+	}
+	if diff := cmp.Diff(want, summary); diff != "" {
+		t.Fatalf("unexpected template statuses (-want +got): %s", diff)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -70,6 +70,7 @@ var (
 		annotation.SidecarTrafficKubevirtInterfaces.Name:          alwaysValidFunc,
 		annotation.PrometheusMergeMetrics.Name:                    validateBool,
 		annotation.ProxyConfig.Name:                               validateProxyConfig,
+		InjectTemplatesAnnotation:                                 alwaysValidFunc,
 		"k8s.v1.cni.cncf.io/networks":                             alwaysValidFunc,
 	}
 )
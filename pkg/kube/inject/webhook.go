@@ -119,6 +119,10 @@ func unmarshalConfig(data []byte) (*Config, error) {
 	log.Debugf("AlwaysInjectSelector: %v", c.AlwaysInjectSelector)
 	log.Debugf("NeverInjectSelector: %v", c.NeverInjectSelector)
 	log.Debugf("Template: |\n  %v", strings.Replace(c.Template, "\n", "\n  ", -1))
+	log.Debugf("DefaultTemplates: %v", c.DefaultTemplates)
+	for name, tmpl := range c.Templates {
+		log.Debugf("Template %s: |\n  %v", name, strings.Replace(tmpl, "\n", "\n  ", -1))
+	}
 	return &c, nil
 }
 
@@ -225,7 +229,10 @@ func (wh *Webhook) Run(stop <-chan struct{}) {
 }
 
 func (wh *Webhook) updateConfig(sidecarConfig *Config, valuesConfig string) {
-	version := sidecarTemplateVersionHash(sidecarConfig.Template)
+	version := templatesVersionHash(sidecarConfig.RawTemplates())
+	for name, err := range sidecarConfig.ValidateTemplates() {
+		log.Warnf("Invalid injection template %q, pods selecting it will fail injection: %v", name, err)
+	}
 	wh.mu.Lock()
 	wh.Config = sidecarConfig
 	wh.valuesConfig = valuesConfig
@@ -338,7 +345,8 @@ type InjectionParameters struct {
 	pod                 *corev1.Pod
 	deployMeta          *metav1.ObjectMeta
 	typeMeta            *metav1.TypeMeta
-	template            string
+	templates           map[string]string
+	defaultTemplates    []string
 	version             string
 	meshConfig          *meshconfig.MeshConfig
 	valuesConfig        string
@@ -386,19 +394,13 @@ func injectPod(req InjectionParameters) ([]byte, error) {
 		return nil, err
 	}
 
-	// Run the injection template, giving us a partial pod spec
-	spec, injectedSpec, err := RunTemplate(req)
+	// Run the injection templates, merging them into the original pod spec
+	mergedPodSpec, injectedSpec, err := RunTemplate(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run injection template: %v", err)
 	}
-
-	// Merge the original pod spec with the injected overlay
-	mergedPodSpec, err := mergeInjectedConfig(req, spec)
-	if err != nil {
-		return nil, fmt.Errorf("failed to merge pod spec: %v", err)
-	}
 	pod := req.pod.DeepCopy()
-	pod.Spec = mergedPodSpec
+	pod.Spec = *mergedPodSpec
 
 	// Apply some additional transformations to the pod
 	if err := postProcessPod(pod, *injectedSpec, req); err != nil {
@@ -589,8 +591,8 @@ func applyPrometheusMerge(pod *corev1.Pod, mesh *meshconfig.MeshConfig) error {
 	return nil
 }
 
-func mergeInjectedConfig(req InjectionParameters, injected []byte) (corev1.PodSpec, error) {
-	current, err := json.Marshal(req.pod.Spec)
+func mergeInjectedConfig(spec corev1.PodSpec, injected []byte) (corev1.PodSpec, error) {
+	current, err := json.Marshal(spec)
 	if err != nil {
 		return corev1.PodSpec{}, err
 	}
@@ -644,7 +646,8 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 		pod:                 &pod,
 		deployMeta:          deploy,
 		typeMeta:            typeMeta,
-		template:            wh.Config.Template,
+		templates:           wh.Config.RawTemplates(),
+		defaultTemplates:    wh.Config.GetDefaultTemplates(),
 		version:             wh.sidecarTemplateVersion,
 		meshConfig:          wh.meshConfig,
 		valuesConfig:        wh.valuesConfig,
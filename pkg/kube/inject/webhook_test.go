@@ -594,6 +594,11 @@ func TestWebhookInject(t *testing.T) {
 			wantFile:     "TestWebhookInject_cron_job.patch",
 			templateFile: "TestWebhookInject_cron_job_template.yaml",
 		},
+		{
+			inputFile:    "TestWebhookInject_templates.yaml",
+			wantFile:     "TestWebhookInject_templates.patch",
+			templateFile: "TestWebhookInject_templates_template.yaml",
+		},
 	}
 
 	for i, c := range cases {
@@ -39,8 +39,10 @@ const (
 	DefaultRootCertFilePath = "./etc/certs/root-cert.pem"
 
 	// Credential fetcher type
-	GCE  = "GoogleComputeEngine"
-	Mock = "Mock" // testing only
+	GCE          = "GoogleComputeEngine"
+	HTTPMetadata = "HTTPMetadata"
+	TokenFile    = "TokenFile"
+	Mock         = "Mock" // testing only
 )
 
 // TODO: For 1.8, make sure MeshConfig is updated with those settings,
@@ -91,13 +93,23 @@ type Options struct {
 	// The Vault CA address.
 	VaultAddress string
 
+	// The Vault auth method: kubernetes, jwt or approle.
+	VaultAuthMethod string
+
 	// The Vault auth path.
 	VaultAuthPath string
 
 	// The Vault role.
 	VaultRole string
 
-	// The Vault sign CSR path.
+	// The Vault AppRole role ID, used by the approle auth method.
+	VaultAppRoleID string
+
+	// The file holding the Vault AppRole secret ID, used by the approle auth method.
+	VaultAppRoleSecretIDPath string
+
+	// The Vault sign CSR path. It may be a template referencing the namespace and
+	// service account of the workload, e.g. "pki/sign/{{.Namespace}}-{{.ServiceAccount}}".
 	VaultSignCsrPath string
 
 	// The Vault TLS root certificate.
@@ -268,7 +280,8 @@ type CredFetcher interface {
 	// GetPlatformCredential fetches workload credential provided by the platform.
 	GetPlatformCredential() (string, error)
 
-	// GetType returns credential fetcher type. Currently the supported type is "GoogleComputeEngine".
+	// GetType returns credential fetcher type. Currently the supported types are "GoogleComputeEngine",
+	// "HTTPMetadata" and "TokenFile".
 	GetType() string
 
 	// The name of the IdentityProvider that can authenticate the workload credential.
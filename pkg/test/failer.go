@@ -14,7 +14,11 @@
 
 package test
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
 
 var _ Failer = &testing.T{}
 
@@ -29,3 +33,73 @@ type Failer interface {
 	Helper()
 	Cleanup(func())
 }
+
+// errFailed is returned by Wrap when the function failed without a message.
+var errFailed = errors.New("failed")
+
+// Wrap calls fn with a Failer that records failures rather than failing a test, and returns the
+// first failure reported, if any. This allows functions that take a Failer, such as test fakes, to
+// be used outside of tests. As with testing.T, Fatal and FailNow stop fn, and functions registered
+// with Cleanup run once fn returns.
+func Wrap(fn func(t Failer)) (err error) {
+	w := &errorWrapper{}
+	defer w.cleanup()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(wrapperAbort); !ok {
+				panic(r)
+			}
+		}
+		err = w.err
+	}()
+	fn(w)
+	return nil
+}
+
+// errorWrapper is the Failer used by Wrap.
+type errorWrapper struct {
+	err      error
+	cleanups []func()
+}
+
+// wrapperAbort is used to unwind fn when FailNow or Fatal is called.
+type wrapperAbort struct{}
+
+var _ Failer = &errorWrapper{}
+
+func (w *errorWrapper) Fail() {
+	if w.err == nil {
+		w.err = errFailed
+	}
+}
+
+func (w *errorWrapper) FailNow() {
+	w.Fail()
+	panic(wrapperAbort{})
+}
+
+func (w *errorWrapper) Fatal(args ...interface{}) {
+	if w.err == nil || w.err == errFailed {
+		w.err = errors.New(fmt.Sprint(args...))
+	}
+	panic(wrapperAbort{})
+}
+
+func (w *errorWrapper) Fatalf(format string, args ...interface{}) {
+	if w.err == nil || w.err == errFailed {
+		w.err = fmt.Errorf(format, args...)
+	}
+	panic(wrapperAbort{})
+}
+
+func (w *errorWrapper) Helper() {}
+
+func (w *errorWrapper) Cleanup(fn func()) {
+	w.cleanups = append(w.cleanups, fn)
+}
+
+func (w *errorWrapper) cleanup() {
+	for i := len(w.cleanups) - 1; i >= 0; i-- {
+		w.cleanups[i]()
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -228,6 +229,72 @@ func TestAdmitPilot(t *testing.T) {
 	}
 }
 
+func TestAdmitPilotWarnings(t *testing.T) {
+	wh, cancel := createTestWebhook(t)
+	defer cancel()
+	wh.schemas = collections.Pilot
+
+	makeDestinationRule := func(trafficPolicy map[string]interface{}) []byte {
+		t.Helper()
+		r := collections.IstioNetworkingV1Alpha3Destinationrules.Resource()
+		var un unstructured.Unstructured
+		un.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   r.Group(),
+			Version: r.Version(),
+			Kind:    r.Kind(),
+		})
+		un.SetName("reviews")
+		un.SetNamespace("default")
+		un.Object["spec"] = map[string]interface{}{
+			"host":          "reviews",
+			"trafficPolicy": trafficPolicy,
+		}
+		raw, err := json.Marshal(&un)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		return raw
+	}
+
+	cases := []struct {
+		name          string
+		trafficPolicy map[string]interface{}
+		warnings      []string
+	}{
+		{
+			name: "no warnings",
+			trafficPolicy: map[string]interface{}{
+				"tls": map[string]interface{}{"mode": "DISABLE"},
+			},
+		},
+		{
+			name: "deprecated consecutive errors",
+			trafficPolicy: map[string]interface{}{
+				"outlierDetection": map[string]interface{}{"consecutiveErrors": 5},
+			},
+			warnings: []string{"outlier detection consecutive errors is deprecated, " +
+				"use consecutiveGatewayErrors or consecutive5xxErrors instead"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := wh.admitPilot(&kube.AdmissionRequest{
+				Kind:      kubeApisMeta.GroupVersionKind{Kind: "DestinationRule"},
+				Namespace: "default",
+				Object:    runtime.RawExtension{Raw: makeDestinationRule(c.trafficPolicy)},
+				Operation: kube.Create,
+			})
+			if !got.Allowed {
+				t.Fatalf("got not allowed: %v", got.Result)
+			}
+			if !reflect.DeepEqual(got.Warnings, c.warnings) {
+				t.Fatalf("got warnings %v want %v", got.Warnings, c.warnings)
+			}
+		})
+	}
+}
+
 func makeTestReview(t *testing.T, valid bool, apiVersion string) []byte {
 	t.Helper()
 	review := kubeApiAdmission.AdmissionReview{
@@ -22,13 +22,72 @@ import (
 	"istio.io/istio/security/pkg/credentialfetcher/plugin"
 )
 
+// Options configures the credential fetcher plugins.
+type Options struct {
+	// Type of the credential fetcher.
+	Type string
+	// TrustDomain is used as the token audience if Audience is unset.
+	TrustDomain string
+	// JWTPath is the location to save the fetched token.
+	JWTPath string
+	// IdentityProvider is the name of the identity provider that can authenticate the token.
+	IdentityProvider string
+
+	// Audience of the token requested from the HTTP metadata server.
+	Audience string
+	// MetadataURL is the URL of the identity token on the HTTP metadata server.
+	MetadataURL string
+	// MetadataHeaders are added to the requests sent to the HTTP metadata server.
+	MetadataHeaders map[string]string
+	// SessionTokenURL and SessionTokenHeader configure IMDSv2-like session tokens.
+	SessionTokenURL    string
+	SessionTokenHeader string
+
+	// TokenFile is the token file read by the token file plugin.
+	TokenFile string
+}
+
 func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string) (security.CredFetcher, error) {
-	switch credtype {
+	return NewCredFetcherWithOptions(Options{
+		Type:             credtype,
+		TrustDomain:      trustdomain,
+		JWTPath:          jwtPath,
+		IdentityProvider: identityProvider,
+	})
+}
+
+// NewCredFetcherWithOptions creates the credential fetcher plugin of the given type.
+func NewCredFetcherWithOptions(opts Options) (security.CredFetcher, error) {
+	audience := opts.Audience
+	if audience == "" {
+		audience = opts.TrustDomain
+	}
+	switch opts.Type {
 	case security.GCE:
-		return plugin.CreateGCEPlugin(trustdomain, jwtPath, identityProvider), nil
+		return plugin.CreateGCEPlugin(audience, opts.JWTPath, opts.IdentityProvider), nil
+	case security.HTTPMetadata:
+		p, err := plugin.CreateHTTPMetadataPlugin(plugin.HTTPMetadataOptions{
+			URL:                opts.MetadataURL,
+			Headers:            opts.MetadataHeaders,
+			Audience:           audience,
+			SessionTokenURL:    opts.SessionTokenURL,
+			SessionTokenHeader: opts.SessionTokenHeader,
+			JWTPath:            opts.JWTPath,
+			IdentityProvider:   opts.IdentityProvider,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case security.TokenFile:
+		p, err := plugin.CreateTokenFilePlugin(opts.TokenFile, opts.JWTPath, opts.IdentityProvider)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
 	case security.Mock: // for test only
 		return plugin.CreateMockPlugin("test_token"), nil
 	default:
-		return nil, fmt.Errorf("invalid credential fetcher type %s", credtype)
+		return nil, fmt.Errorf("invalid credential fetcher type %s", opts.Type)
 	}
 }
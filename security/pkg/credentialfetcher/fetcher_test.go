@@ -48,6 +48,24 @@ func TestNewCredFetcher(t *testing.T) {
 			expectedToken:    "test_token",
 			expectedIdp:      "fakeIDP",
 		},
+		"http metadata without URL": {
+			fetcherType:      security.HTTPMetadata,
+			trustdomain:      "",
+			jwtPath:          "",
+			identityProvider: "",
+			expectedErr:      "metadata URL is unset",
+			expectedToken:    "",
+			expectedIdp:      "",
+		},
+		"token file without file": {
+			fetcherType:      security.TokenFile,
+			trustdomain:      "",
+			jwtPath:          "",
+			identityProvider: "",
+			expectedErr:      "token file is unset",
+			expectedToken:    "",
+			expectedIdp:      "",
+		},
 		"invalid test": {
 			fetcherType:      "foo",
 			trustdomain:      "",
@@ -88,3 +106,18 @@ func TestNewCredFetcher(t *testing.T) {
 		}
 	}
 }
+
+func TestNewCredFetcherWithOptions(t *testing.T) {
+	cf, err := NewCredFetcherWithOptions(Options{
+		Type:             security.HTTPMetadata,
+		TrustDomain:      "cluster.local",
+		IdentityProvider: "ExampleCloud",
+		MetadataURL:      "http://169.254.169.254/latest/identity-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.GetType() != security.HTTPMetadata || cf.GetIdentityProvider() != "ExampleCloud" {
+		t.Errorf("unexpected type %s or identity provider %s", cf.GetType(), cf.GetIdentityProvider())
+	}
+}
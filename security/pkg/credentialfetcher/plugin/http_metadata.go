@@ -0,0 +1,172 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is the generic HTTP metadata plugin of credentialfetcher.
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+var (
+	httpcredLog = log.RegisterScope("httpcred", "HTTP metadata credential fetcher for istio agent", 0)
+)
+
+const (
+	defaultAudienceParam       = "audience"
+	defaultHTTPMetadataTimeout = 5 * time.Second
+)
+
+// HTTPMetadataOptions configures the HTTP metadata plugin.
+type HTTPMetadataOptions struct {
+	// URL of the instance identity token, e.g. http://169.254.169.254/latest/identity-token.
+	URL string
+
+	// Headers added to every request sent to the metadata server.
+	Headers map[string]string
+
+	// Audience of the requested token. It is sent as the AudienceParam query parameter, if set.
+	Audience string
+
+	// AudienceParam is the name of the query parameter holding the audience. Defaults to "audience".
+	AudienceParam string
+
+	// SessionTokenURL, if set, is called with PUT before fetching the identity token, as done by
+	// IMDSv2-like metadata servers. The returned session token is sent in the SessionTokenHeader.
+	SessionTokenURL string
+
+	// SessionTokenHeader is the header carrying the session token.
+	SessionTokenHeader string
+
+	// The location to save the identity token.
+	JWTPath string
+
+	// The name of the identity provider that can authenticate the token.
+	IdentityProvider string
+
+	// Timeout of each request. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// The plugin object.
+type HTTPMetadataPlugin struct {
+	opts   HTTPMetadataOptions
+	client *http.Client
+}
+
+// CreateHTTPMetadataPlugin creates a credential fetcher plugin fetching the instance identity token
+// from a generic HTTP metadata server. Return the pointer to the created plugin.
+func CreateHTTPMetadataPlugin(opts HTTPMetadataOptions) (*HTTPMetadataPlugin, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("metadata URL is unset")
+	}
+	if opts.SessionTokenURL != "" && opts.SessionTokenHeader == "" {
+		return nil, fmt.Errorf("session token header is required with session token URL")
+	}
+	if opts.AudienceParam == "" {
+		opts.AudienceParam = defaultAudienceParam
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultHTTPMetadataTimeout
+	}
+	return &HTTPMetadataPlugin{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}, nil
+}
+
+// GetPlatformCredential fetches the instance identity token from the metadata server, and writes
+// it to jwtPath if set. The local copy of the token is used by both Envoy STS client and istio
+// agent to fetch certificate and access token.
+func (p *HTTPMetadataPlugin) GetPlatformCredential() (string, error) {
+	headers := map[string]string{}
+	for k, v := range p.opts.Headers {
+		headers[k] = v
+	}
+	if p.opts.SessionTokenURL != "" {
+		session, err := p.do(http.MethodPut, p.opts.SessionTokenURL, headers)
+		if err != nil {
+			httpcredLog.Errorf("Failed to get session token from metadata server: %v", err)
+			return "", err
+		}
+		headers[p.opts.SessionTokenHeader] = session
+	}
+	u, err := url.Parse(p.opts.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid metadata URL %q: %v", p.opts.URL, err)
+	}
+	if p.opts.Audience != "" {
+		q := u.Query()
+		q.Set(p.opts.AudienceParam, p.opts.Audience)
+		u.RawQuery = q.Encode()
+	}
+	token, err := p.do(http.MethodGet, u.String(), headers)
+	if err != nil {
+		httpcredLog.Errorf("Failed to get identity token from metadata server: %v", err)
+		return "", err
+	}
+	httpcredLog.Debugf("Got identity token: %d", len(token))
+	if p.opts.JWTPath != "" {
+		if err := ioutil.WriteFile(p.opts.JWTPath, []byte(token), 0640); err != nil {
+			httpcredLog.Errorf("Encountered error when writing identity token: %v", err)
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+func (p *HTTPMetadataPlugin) do(method, u string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s returned status %d", method, u, resp.StatusCode)
+	}
+	token := strings.TrimSpace(string(body))
+	if token == "" {
+		return "", fmt.Errorf("%s %s returned an empty token", method, u)
+	}
+	return token, nil
+}
+
+// GetType returns credential fetcher type.
+func (p *HTTPMetadataPlugin) GetType() string {
+	return security.HTTPMetadata
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *HTTPMetadataPlugin) GetIdentityProvider() string {
+	return p.opts.IdentityProvider
+}
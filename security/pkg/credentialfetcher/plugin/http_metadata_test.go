@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"istio.io/istio/pkg/security"
+)
+
+const (
+	testSessionHeader = "X-Metadata-Token"
+	testSessionToken  = "session-token"
+)
+
+// metadataServer is a stand-in for an IMDSv2-like metadata server.
+type metadataServer struct {
+	*httptest.Server
+	requireSession bool
+	requests       int32
+}
+
+func newMetadataServer(t *testing.T, requireSession bool) *metadataServer {
+	s := &metadataServer{requireSession: requireSession}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte(testSessionToken))
+	})
+	mux.HandleFunc("/latest/identity-token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		if r.Header.Get("Metadata-Flavor") != "test" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if s.requireSession && r.Header.Get(testSessionHeader) != testSessionToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("token-for-" + r.URL.Query().Get("aud") + "\n"))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHTTPMetadataPlugin(t *testing.T) {
+	server := newMetadataServer(t, true)
+	jwtPath := filepath.Join(t.TempDir(), "istio-token")
+	p, err := CreateHTTPMetadataPlugin(HTTPMetadataOptions{
+		URL:                server.URL + "/latest/identity-token",
+		Headers:            map[string]string{"Metadata-Flavor": "test"},
+		Audience:           "example.com",
+		AudienceParam:      "aud",
+		SessionTokenURL:    server.URL + "/latest/api/token",
+		SessionTokenHeader: testSessionHeader,
+		JWTPath:            jwtPath,
+		IdentityProvider:   "ExampleCloud",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := p.GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token-for-example.com" {
+		t.Errorf("got token %q", token)
+	}
+	saved, err := ioutil.ReadFile(jwtPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != token {
+		t.Errorf("saved token %q, want %q", saved, token)
+	}
+	if p.GetType() != security.HTTPMetadata || p.GetIdentityProvider() != "ExampleCloud" {
+		t.Errorf("unexpected type %s or identity provider %s", p.GetType(), p.GetIdentityProvider())
+	}
+}
+
+func TestHTTPMetadataPluginErrors(t *testing.T) {
+	server := newMetadataServer(t, true)
+	cases := []struct {
+		name      string
+		opts      HTTPMetadataOptions
+		createErr string
+		fetchErr  string
+	}{
+		{
+			name:      "no URL",
+			opts:      HTTPMetadataOptions{},
+			createErr: "metadata URL is unset",
+		},
+		{
+			name:      "session URL without header",
+			opts:      HTTPMetadataOptions{URL: server.URL, SessionTokenURL: server.URL},
+			createErr: "session token header is required",
+		},
+		{
+			name: "missing session token",
+			opts: HTTPMetadataOptions{
+				URL:     server.URL + "/latest/identity-token",
+				Headers: map[string]string{"Metadata-Flavor": "test"},
+			},
+			fetchErr: "returned status 401",
+		},
+		{
+			name: "missing header",
+			opts: HTTPMetadataOptions{
+				URL:                server.URL + "/latest/identity-token",
+				SessionTokenURL:    server.URL + "/latest/api/token",
+				SessionTokenHeader: testSessionHeader,
+			},
+			fetchErr: "returned status 403",
+		},
+		{
+			name: "session endpoint not found",
+			opts: HTTPMetadataOptions{
+				URL:                server.URL + "/latest/identity-token",
+				SessionTokenURL:    server.URL + "/missing",
+				SessionTokenHeader: testSessionHeader,
+			},
+			fetchErr: "returned status 404",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := CreateHTTPMetadataPlugin(tc.opts)
+			if tc.createErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.createErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.createErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := p.GetPlatformCredential(); err == nil || !strings.Contains(err.Error(), tc.fetchErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.fetchErr, err)
+			}
+		})
+	}
+}
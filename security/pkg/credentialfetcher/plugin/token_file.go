@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is the token file plugin of credentialfetcher.
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/filewatcher"
+	"istio.io/pkg/log"
+)
+
+var (
+	filecredLog = log.RegisterScope("filecred", "Token file credential fetcher for istio agent", 0)
+)
+
+// The plugin object.
+type TokenFilePlugin struct {
+	// The token file, rotated by another agent.
+	sourcePath string
+
+	// The location to save the identity token. Unset if it is the source file.
+	jwtPath string
+
+	// identity provider
+	identityProvider string
+
+	watcher filewatcher.FileWatcher
+	stop    chan struct{}
+
+	mu    sync.Mutex
+	token string
+}
+
+// CreateTokenFilePlugin creates a credential fetcher plugin reading the identity token from a file
+// that is rotated by another agent. The file is watched, and the token is re-read when it changes.
+// Return the pointer to the created plugin.
+func CreateTokenFilePlugin(sourcePath, jwtPath, identityProvider string) (*TokenFilePlugin, error) {
+	if sourcePath == "" {
+		return nil, fmt.Errorf("token file is unset")
+	}
+	if jwtPath == sourcePath {
+		jwtPath = ""
+	}
+	p := &TokenFilePlugin{
+		sourcePath:       sourcePath,
+		jwtPath:          jwtPath,
+		identityProvider: identityProvider,
+		watcher:          filewatcher.NewWatcher(),
+		stop:             make(chan struct{}),
+	}
+	if err := p.watcher.Add(sourcePath); err != nil {
+		_ = p.watcher.Close()
+		return nil, fmt.Errorf("failed to watch token file %s: %v", sourcePath, err)
+	}
+	go p.watch()
+	return p, nil
+}
+
+func (p *TokenFilePlugin) watch() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case _, ok := <-p.watcher.Events(p.sourcePath):
+			if !ok {
+				return
+			}
+			filecredLog.Debugf("Token file %s changed", p.sourcePath)
+			p.mu.Lock()
+			p.token = ""
+			p.mu.Unlock()
+		case err, ok := <-p.watcher.Errors(p.sourcePath):
+			if !ok {
+				return
+			}
+			filecredLog.Warnf("Error watching token file %s: %v", p.sourcePath, err)
+		}
+	}
+}
+
+// GetPlatformCredential returns the token read from the source file, and writes it to jwtPath if
+// set. The file is only read again after it changes.
+func (p *TokenFilePlugin) GetPlatformCredential() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" {
+		return p.token, nil
+	}
+	b, err := ioutil.ReadFile(p.sourcePath)
+	if err != nil {
+		filecredLog.Errorf("Failed to read token file %s: %v", p.sourcePath, err)
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", p.sourcePath)
+	}
+	if p.jwtPath != "" {
+		if err := ioutil.WriteFile(p.jwtPath, []byte(token), 0640); err != nil {
+			filecredLog.Errorf("Encountered error when writing identity token: %v", err)
+			return "", err
+		}
+	}
+	p.token = token
+	return token, nil
+}
+
+// Stop stops watching the token file.
+func (p *TokenFilePlugin) Stop() {
+	close(p.stop)
+	_ = p.watcher.Close()
+}
+
+// GetType returns credential fetcher type.
+func (p *TokenFilePlugin) GetType() string {
+	return security.TokenFile
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *TokenFilePlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
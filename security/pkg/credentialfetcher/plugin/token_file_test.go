@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestTokenFilePlugin(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "token")
+	jwtPath := filepath.Join(dir, "istio-token")
+	if err := ioutil.WriteFile(source, []byte("token-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := CreateTokenFilePlugin(source, jwtPath, "OnPrem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	expectToken := func(want string) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			token, err := p.GetPlatformCredential()
+			if err != nil {
+				return err
+			}
+			if token != want {
+				return fmt.Errorf("got token %q, want %q", token, want)
+			}
+			saved, err := ioutil.ReadFile(jwtPath)
+			if err != nil {
+				return err
+			}
+			if string(saved) != want {
+				return fmt.Errorf("saved token %q, want %q", saved, want)
+			}
+			return nil
+		}, retry.Timeout(5*time.Second))
+	}
+	expectToken("token-1")
+
+	// The token is rotated by another agent.
+	if err := ioutil.WriteFile(source, []byte("token-2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expectToken("token-2")
+
+	if p.GetType() != security.TokenFile || p.GetIdentityProvider() != "OnPrem" {
+		t.Errorf("unexpected type %s or identity provider %s", p.GetType(), p.GetIdentityProvider())
+	}
+}
+
+func TestTokenFilePluginErrors(t *testing.T) {
+	if _, err := CreateTokenFilePlugin("", "", ""); err == nil {
+		t.Error("expected error for unset token file")
+	}
+	if _, err := CreateTokenFilePlugin(filepath.Join(t.TempDir(), "missing", "token"), "", ""); err == nil {
+		t.Error("expected error for token file in missing directory")
+	}
+	source := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(source, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := CreateTokenFilePlugin(source, source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	if _, err := p.GetPlatformCredential(); err == nil {
+		t.Error("expected error for empty token file")
+	}
+}
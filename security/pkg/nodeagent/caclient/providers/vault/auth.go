@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package caclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// AuthMethod is the Vault auth method used to obtain a Vault token.
+type AuthMethod string
+
+const (
+	// AuthMethodKubernetes logs in with the workload's Kubernetes service account token.
+	AuthMethodKubernetes AuthMethod = "kubernetes"
+	// AuthMethodJWT logs in with the workload's token through the generic JWT/OIDC auth method.
+	// This is used by workloads, such as VMs, whose token is not issued by a Kubernetes API server.
+	AuthMethodJWT AuthMethod = "jwt"
+	// AuthMethodAppRole logs in with a static AppRole role ID and secret ID.
+	AuthMethodAppRole AuthMethod = "approle"
+)
+
+// defaultLoginPath returns the login path of an auth method mounted at its default location.
+func (m AuthMethod) defaultLoginPath() string {
+	return "auth/" + string(m) + "/login"
+}
+
+// vaultAuthenticator logs in to Vault.
+type vaultAuthenticator interface {
+	// login returns the auth data of a new Vault token.
+	login(client *api.Client, loginPath, saToken string) (*api.SecretAuth, error)
+	// cacheKey returns the key under which the token obtained with saToken is cached.
+	cacheKey(saToken string) string
+}
+
+func newVaultAuthenticator(opts *Options) (vaultAuthenticator, error) {
+	switch opts.AuthMethod {
+	case "", AuthMethodKubernetes, AuthMethodJWT:
+		return &jwtAuthenticator{role: opts.LoginRole}, nil
+	case AuthMethodAppRole:
+		if opts.AppRoleRoleID == "" {
+			return nil, fmt.Errorf("the AppRole auth method requires a role ID")
+		}
+		if opts.AppRoleSecretIDPath == "" {
+			return nil, fmt.Errorf("the AppRole auth method requires a secret ID file")
+		}
+		return &appRoleAuthenticator{roleID: opts.AppRoleRoleID, secretIDPath: opts.AppRoleSecretIDPath}, nil
+	default:
+		return nil, fmt.Errorf("unsupported Vault auth method %q", opts.AuthMethod)
+	}
+}
+
+// jwtAuthenticator implements both the Kubernetes and the JWT auth methods, which share
+// the same login API.
+type jwtAuthenticator struct {
+	role string
+}
+
+func (a *jwtAuthenticator) login(client *api.Client, loginPath, saToken string) (*api.SecretAuth, error) {
+	return loginVault(client, loginPath, map[string]interface{}{
+		"jwt":  saToken,
+		"role": a.role,
+	})
+}
+
+func (a *jwtAuthenticator) cacheKey(saToken string) string {
+	h := sha256.Sum256([]byte(saToken))
+	return hex.EncodeToString(h[:])
+}
+
+// appRoleAuthenticator logs in with an AppRole. The secret ID is read from a file on every
+// login so that it can be rotated by another agent.
+type appRoleAuthenticator struct {
+	roleID       string
+	secretIDPath string
+}
+
+func (a *appRoleAuthenticator) login(client *api.Client, loginPath, _ string) (*api.SecretAuth, error) {
+	secretID, err := ioutil.ReadFile(a.secretIDPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AppRole secret ID: %v", err)
+	}
+	return loginVault(client, loginPath, map[string]interface{}{
+		"role_id":   a.roleID,
+		"secret_id": strings.TrimSpace(string(secretID)),
+	})
+}
+
+func (a *appRoleAuthenticator) cacheKey(_ string) string {
+	// All workloads served by this client share the AppRole token.
+	return string(AuthMethodAppRole)
+}
+
+// loginVault writes the login request to loginPath and returns the auth data of the response.
+func loginVault(client *api.Client, loginPath string, data map[string]interface{}) (*api.SecretAuth, error) {
+	resp, err := client.Logical().Write(loginPath, data)
+	if err != nil {
+		vaultClientLog.Errorf("failed to login Vault: %v", err)
+		return nil, err
+	}
+	if resp == nil {
+		vaultClientLog.Errorf("login response is nil")
+		return nil, fmt.Errorf("login response is nil")
+	}
+	if resp.Auth == nil {
+		vaultClientLog.Errorf("login response auth field is nil")
+		return nil, fmt.Errorf("login response auth field is nil")
+	}
+	return resp.Auth, nil
+}
@@ -21,10 +21,14 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"text/template"
 
 	"github.com/hashicorp/vault/api"
 
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/log"
 )
 
@@ -32,14 +36,37 @@ var (
 	vaultClientLog = log.RegisterScope("vault", "Vault client debugging", 0)
 )
 
+// Options configures the Vault CA client.
+type Options struct {
+	// EnableTLS enables TLS to Vault, verified with the system roots and TLSRootCert.
+	EnableTLS   bool
+	TLSRootCert []byte
+	// Addr is the address of the Vault server (e.g., "https://127.0.0.1:8200").
+	Addr string
+
+	// AuthMethod is the auth method used to log in to Vault. Defaults to AuthMethodKubernetes.
+	AuthMethod AuthMethod
+	// LoginPath is the path of the login endpoint. Defaults to the default mount of AuthMethod.
+	LoginPath string
+	// LoginRole is the role used by the Kubernetes and JWT auth methods.
+	LoginRole string
+	// AppRoleRoleID is the role ID used by the AppRole auth method.
+	AppRoleRoleID string
+	// AppRoleSecretIDPath is the file holding the secret ID used by the AppRole auth method.
+	AppRoleSecretIDPath string
+
+	// SignCsrPath is the path used to sign CSRs. It may be a text/template referencing the
+	// TrustDomain, Namespace and ServiceAccount of the identity in the CSR, for example
+	// "pki/sign/{{.Namespace}}-{{.ServiceAccount}}", to use a different PKI role per identity.
+	SignCsrPath string
+}
+
 type vaultClient struct {
-	enableTLS   bool
-	tlsRootCert []byte
+	opts Options
 
-	vaultAddr        string
-	vaultLoginRole   string
-	vaultLoginPath   string
-	vaultSignCsrPath string
+	signCsrPath *template.Template
+	auth        vaultAuthenticator
+	tokens      *tokenCache
 
 	client *api.Client
 }
@@ -47,27 +74,52 @@ type vaultClient struct {
 // NewVaultClient create a CA client for the Vault provider 1.
 func NewVaultClient(tls bool, tlsRootCert []byte,
 	vaultAddr, vaultLoginRole, vaultLoginPath, vaultSignCsrPath string) (security.Client, error) {
+	return NewVaultClientWithOptions(Options{
+		EnableTLS:   tls,
+		TLSRootCert: tlsRootCert,
+		Addr:        vaultAddr,
+		AuthMethod:  AuthMethodKubernetes,
+		LoginRole:   vaultLoginRole,
+		LoginPath:   vaultLoginPath,
+		SignCsrPath: vaultSignCsrPath,
+	})
+}
+
+// NewVaultClientWithOptions creates a CA client for the Vault provider, configured by opts.
+func NewVaultClientWithOptions(opts Options) (security.Client, error) {
+	if opts.AuthMethod == "" {
+		opts.AuthMethod = AuthMethodKubernetes
+	}
+	if opts.LoginPath == "" {
+		opts.LoginPath = opts.AuthMethod.defaultLoginPath()
+	}
+	auth, err := newVaultAuthenticator(&opts)
+	if err != nil {
+		return nil, err
+	}
+	signCsrPath, err := template.New("signCsrPath").Option("missingkey=error").Parse(opts.SignCsrPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Vault sign CSR path %q: %v", opts.SignCsrPath, err)
+	}
 	c := &vaultClient{
-		enableTLS:        tls,
-		tlsRootCert:      tlsRootCert,
-		vaultAddr:        vaultAddr,
-		vaultLoginRole:   vaultLoginRole,
-		vaultLoginPath:   vaultLoginPath,
-		vaultSignCsrPath: vaultSignCsrPath,
+		opts:        opts,
+		signCsrPath: signCsrPath,
+		auth:        auth,
+		tokens:      newTokenCache(),
 	}
 
 	var client *api.Client
-	var err error
-	if tls {
-		client, err = createVaultTLSClient(vaultAddr, tlsRootCert)
+	if opts.EnableTLS {
+		client, err = createVaultTLSClient(opts.Addr, opts.TLSRootCert)
 	} else {
-		client, err = createVaultClient(vaultAddr)
+		client, err = createVaultClient(opts.Addr)
 	}
 	if err != nil {
 		return nil, err
 	}
 	c.client = client
-	vaultClientLog.Infof("created Vault client for Vault address: %s, TLS: %v", vaultAddr, tls)
+	vaultClientLog.Infof("created Vault client for Vault address: %s, TLS: %v, auth method: %s",
+		opts.Addr, opts.EnableTLS, opts.AuthMethod)
 
 	return c, nil
 }
@@ -75,13 +127,25 @@ func NewVaultClient(tls bool, tlsRootCert []byte,
 // CSR Sign calls Vault to sign a CSR.
 func (c *vaultClient) CSRSign(ctx context.Context, reqID string, csrPEM []byte, saToken string,
 	certValidTTLInSec int64) ([]string /*PEM-encoded certificate chain*/, error) {
-	token, err := loginVaultK8sAuthMethod(c.client, c.vaultLoginPath, c.vaultLoginRole, saToken)
+	signPath, err := c.signPathForCSR(csrPEM)
 	if err != nil {
-		return nil, fmt.Errorf("failed to login Vault at %s: %v", c.vaultAddr, err)
+		return nil, fmt.Errorf("failed to sign CSR: %v", err)
 	}
-	c.client.SetToken(token)
-	certChain, err := signCsrByVault(c.client, c.vaultSignCsrPath, certValidTTLInSec, csrPEM)
+	key := c.auth.cacheKey(saToken)
+	token, err := c.tokens.get(key, func() (*api.SecretAuth, error) {
+		return c.auth.login(c.client, c.opts.LoginPath, saToken)
+	}, func(token string) (*api.SecretAuth, error) {
+		return renewVaultToken(c.client, token)
+	})
 	if err != nil {
+		return nil, fmt.Errorf("failed to login Vault at %s: %v", c.opts.Addr, err)
+	}
+	certChain, err := signCsrByVault(ctx, c.client, token, signPath, certValidTTLInSec, csrPEM)
+	if err != nil {
+		if isPermissionDenied(err) {
+			// The token may have been revoked, log in again on the next request.
+			c.tokens.invalidate(key)
+		}
 		return nil, fmt.Errorf("failed to sign CSR: %v", err)
 	}
 
@@ -93,6 +157,63 @@ func (c *vaultClient) CSRSign(ctx context.Context, reqID string, csrPEM []byte,
 	return certChain, nil
 }
 
+// signPathIdentity holds the values available to the sign CSR path template.
+type signPathIdentity struct {
+	TrustDomain    string
+	Namespace      string
+	ServiceAccount string
+}
+
+// signPathForCSR renders the sign CSR path for the SPIFFE identity in the CSR. The CSR is
+// only parsed if the path is a template.
+func (c *vaultClient) signPathForCSR(csrPEM []byte) (string, error) {
+	if !strings.Contains(c.opts.SignCsrPath, "{{") {
+		return c.opts.SignCsrPath, nil
+	}
+	csr, err := util.ParsePemEncodedCSR(csrPEM)
+	if err != nil {
+		return "", err
+	}
+	if len(csr.URIs) == 0 {
+		return "", fmt.Errorf("the CSR has no URI SAN to select the sign path")
+	}
+	id, err := spiffe.ParseIdentity(csr.URIs[0].String())
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := c.signCsrPath.Execute(&b, signPathIdentity{
+		TrustDomain:    id.TrustDomain,
+		Namespace:      id.Namespace,
+		ServiceAccount: id.ServiceAccount,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render the sign path: %v", err)
+	}
+	return b.String(), nil
+}
+
+// renewVaultToken extends the lease of a token and returns its new auth data.
+func renewVaultToken(client *api.Client, token string) (*api.SecretAuth, error) {
+	resp, err := client.Auth().Token().RenewTokenAsSelf(token, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Auth == nil {
+		return nil, fmt.Errorf("renew response auth field is nil")
+	}
+	return resp.Auth, nil
+}
+
+// permissionDeniedError is returned when Vault rejects the token of a request.
+type permissionDeniedError struct {
+	error
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(*permissionDeniedError)
+	return ok
+}
+
 // createVaultClient creates a client to a Vault server
 // vaultAddr: the address of the Vault server (e.g., "http://127.0.0.1:8200").
 func createVaultClient(vaultAddr string) (*api.Client, error) {
@@ -147,49 +268,38 @@ func createVaultTLSClient(vaultAddr string, tlsRootCert []byte) (*api.Client, er
 	return client, nil
 }
 
-// loginVaultK8sAuthMethod logs into the Vault k8s auth method with the service account and
-// returns the auth client token.
-// loginPath: the path of the login
-// role: the login role
-// jwt: the service account used for login
-func loginVaultK8sAuthMethod(client *api.Client, loginPath, role, sa string) (string, error) {
-	resp, err := client.Logical().Write(
-		loginPath,
-		map[string]interface{}{
-			"jwt":  sa,
-			"role": role,
-		})
-
-	if err != nil {
-		vaultClientLog.Errorf("failed to login Vault: %v", err)
-		return "", err
-	}
-	if resp == nil {
-		vaultClientLog.Errorf("login response is nil")
-		return "", fmt.Errorf("login response is nil")
-	}
-	if resp.Auth == nil {
-		vaultClientLog.Errorf("login response auth field is nil")
-		return "", fmt.Errorf("login response auth field is nil")
-	}
-	return resp.Auth.ClientToken, nil
-}
-
 // signCsrByVault signs the CSR and return the signed certificate and the CA certificate chain
 // Return the signed certificate chain when succeed.
 // client: the Vault client
+// token: the Vault token authorizing the request
 // csrSigningPath: the path for signing a CSR
 // csr: the CSR to be signed, in pem format
-func signCsrByVault(client *api.Client, csrSigningPath string, certTTLInSec int64, csr []byte) ([]string, error) {
+func signCsrByVault(ctx context.Context, client *api.Client, token, csrSigningPath string,
+	certTTLInSec int64, csr []byte) ([]string, error) {
 	m := map[string]interface{}{
 		"format":               "pem",
 		"csr":                  string(csr),
 		"ttl":                  strconv.FormatInt(certTTLInSec, 10) + "s",
 		"exclude_cn_from_sans": true,
 	}
-	res, err := client.Logical().Write(csrSigningPath, m)
+	req := client.NewRequest("PUT", "/v1/"+csrSigningPath)
+	req.ClientToken = token
+	if err := req.SetJSONBody(m); err != nil {
+		return nil, err
+	}
+	var res *api.Secret
+	resp, err := client.RawRequestWithContext(ctx, req)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err == nil {
+		res, err = api.ParseSecret(resp.Body)
+	}
 	if err != nil {
 		vaultClientLog.Errorf("failed to post to %v: %v", csrSigningPath, err)
+		if respErr, ok := err.(*api.ResponseError); ok && respErr.StatusCode == http.StatusForbidden {
+			return nil, &permissionDeniedError{fmt.Errorf("failed to post to %v: %v", csrSigningPath, err)}
+		}
 		return nil, fmt.Errorf("failed to post to %v: %v", csrSigningPath, err)
 	}
 	if res == nil {
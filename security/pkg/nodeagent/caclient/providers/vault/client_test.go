@@ -21,9 +21,15 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
 )
 
 // vaultAuthHeaderName is the name of the header containing the token.
@@ -267,3 +273,212 @@ func newMockVaultServer(t *testing.T, tls bool, loginRole, token, loginResp, sig
 
 	return vaultServer
 }
+
+// vaultStandIn is a minimal Vault server supporting the Kubernetes, JWT and AppRole login
+// endpoints, token renewal and PKI signing on any path under "pki/sign/".
+type vaultStandIn struct {
+	t         *testing.T
+	server    *httptest.Server
+	mu        sync.Mutex
+	logins    int
+	renewals  int
+	signPaths []string
+	lease     int
+	revoked   bool
+}
+
+func newVaultStandIn(t *testing.T, lease int) *vaultStandIn {
+	v := &vaultStandIn{t: t, lease: lease}
+	v.server = httptest.NewServer(http.HandlerFunc(v.handle))
+	t.Cleanup(v.server.Close)
+	return v
+}
+
+func (v *vaultStandIn) handle(resp http.ResponseWriter, req *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	body := map[string]interface{}{}
+	_ = json.NewDecoder(req.Body).Decode(&body)
+	writeAuth := func(token string) {
+		resp.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(resp).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": token, "lease_duration": v.lease, "renewable": true},
+		})
+	}
+	switch {
+	case req.URL.Path == "/v1/auth/kubernetes/login" || req.URL.Path == "/v1/auth/jwt/login":
+		if body["jwt"] != "fake-client-token" || body["role"] != "istio" {
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		v.logins++
+		v.revoked = false
+		writeAuth("fake-vault-token")
+	case req.URL.Path == "/v1/auth/approle/login":
+		if body["role_id"] != "fake-role-id" || body["secret_id"] != "fake-secret-id" {
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		v.logins++
+		v.revoked = false
+		writeAuth("fake-vault-token")
+	case req.URL.Path == "/v1/auth/token/renew-self":
+		if req.Header.Get(vaultAuthHeaderName) != "fake-vault-token" {
+			resp.WriteHeader(http.StatusForbidden)
+			return
+		}
+		v.renewals++
+		writeAuth("fake-vault-token")
+	case strings.HasPrefix(req.URL.Path, "/v1/pki/sign/"):
+		if v.revoked || req.Header.Get(vaultAuthHeaderName) != "fake-vault-token" {
+			resp.WriteHeader(http.StatusForbidden)
+			return
+		}
+		v.signPaths = append(v.signPaths, strings.TrimPrefix(req.URL.Path, "/v1/"))
+		resp.Header().Set("Content-Type", "application/json")
+		_, _ = resp.Write([]byte(vaultSignResp))
+	default:
+		resp.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (v *vaultStandIn) counts() (int, int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.logins, v.renewals
+}
+
+func TestVaultTokenCaching(t *testing.T) {
+	v := newVaultStandIn(t, 3600)
+	cli, err := NewVaultClientWithOptions(Options{
+		Addr:        v.server.URL,
+		AuthMethod:  AuthMethodJWT,
+		LoginRole:   "istio",
+		SignCsrPath: "pki/sign/istio",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cli.CSRSign(context.Background(), "", []byte{01}, "fake-client-token", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins, _ := v.counts(); logins != 1 {
+		t.Fatalf("expected a single login, got %d", logins)
+	}
+
+	// A token rejected by Vault is dropped from the cache.
+	v.mu.Lock()
+	v.revoked = true
+	v.mu.Unlock()
+	if _, err := cli.CSRSign(context.Background(), "", []byte{01}, "fake-client-token", 1); err == nil {
+		t.Fatal("expected a failure with a revoked token")
+	}
+	if _, err := cli.CSRSign(context.Background(), "", []byte{01}, "fake-client-token", 1); err != nil {
+		t.Fatal(err)
+	}
+	if logins, _ := v.counts(); logins != 2 {
+		t.Fatalf("expected a second login after the token was revoked, got %d", logins)
+	}
+}
+
+func TestVaultTokenRenewal(t *testing.T) {
+	v := newVaultStandIn(t, 30)
+	cli, err := NewVaultClientWithOptions(Options{
+		Addr:        v.server.URL,
+		LoginRole:   "istio",
+		SignCsrPath: "pki/sign/istio",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	vc := cli.(*vaultClient)
+	vc.tokens.now = func() time.Time { return now }
+	sign := func() {
+		t.Helper()
+		if _, err := cli.CSRSign(context.Background(), "", []byte{01}, "fake-client-token", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sign()
+	now = now.Add(10 * time.Second)
+	sign()
+	if logins, renewals := v.counts(); logins != 1 || renewals != 0 {
+		t.Fatalf("expected 1 login and no renewal, got %d logins and %d renewals", logins, renewals)
+	}
+	now = now.Add(15 * time.Second)
+	sign()
+	if logins, renewals := v.counts(); logins != 1 || renewals != 1 {
+		t.Fatalf("expected 1 login and 1 renewal, got %d logins and %d renewals", logins, renewals)
+	}
+	now = now.Add(time.Hour)
+	sign()
+	if logins, _ := v.counts(); logins != 2 {
+		t.Fatalf("expected a new login after the token expired, got %d", logins)
+	}
+}
+
+func TestVaultAppRole(t *testing.T) {
+	v := newVaultStandIn(t, 3600)
+	secretIDPath := filepath.Join(t.TempDir(), "secret-id")
+	if err := ioutil.WriteFile(secretIDPath, []byte("fake-secret-id\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVaultClientWithOptions(Options{Addr: v.server.URL, AuthMethod: AuthMethodAppRole}); err == nil {
+		t.Fatal("expected AppRole without a role ID to be rejected")
+	}
+	cli, err := NewVaultClientWithOptions(Options{
+		Addr:                v.server.URL,
+		AuthMethod:          AuthMethodAppRole,
+		AppRoleRoleID:       "fake-role-id",
+		AppRoleSecretIDPath: secretIDPath,
+		SignCsrPath:         "pki/sign/istio",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The AppRole token is shared by all workloads, whatever their own token is.
+	for _, saToken := range []string{"token-a", "token-b"} {
+		resp, err := cli.CSRSign(context.Background(), "", []byte{01}, saToken, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resp, fakeCert) {
+			t.Fatalf("got %v, expected %v", resp, fakeCert)
+		}
+	}
+	if logins, _ := v.counts(); logins != 1 {
+		t.Fatalf("expected a single AppRole login, got %d", logins)
+	}
+}
+
+func TestVaultSignPathTemplate(t *testing.T) {
+	v := newVaultStandIn(t, 3600)
+	cli, err := NewVaultClientWithOptions(Options{
+		Addr:        v.server.URL,
+		LoginRole:   "istio",
+		SignCsrPath: "pki/sign/{{.Namespace}}-{{.ServiceAccount}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, _, err := util.GenCSR(util.CertOptions{Host: "spiffe://cluster.local/ns/foo/sa/bar", RSAKeySize: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.CSRSign(context.Background(), "", csr, "fake-client-token", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.CSRSign(context.Background(), "", []byte("invalid"), "fake-client-token", 1); err == nil {
+		t.Fatal("expected an invalid CSR to fail with a templated sign path")
+	}
+	if len(v.signPaths) != 1 || v.signPaths[0] != "pki/sign/foo-bar" {
+		t.Fatalf("unexpected sign paths %v", v.signPaths)
+	}
+
+	if _, err := NewVaultClientWithOptions(Options{Addr: v.server.URL, SignCsrPath: "pki/sign/{{.Namespace"}); err == nil {
+		t.Fatal("expected an invalid template to be rejected")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package caclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// renewFraction is the fraction of a token's lease after which the token is renewed.
+	renewFraction = 2.0 / 3
+
+	// noLeaseTTL is how long tokens without a lease, such as periodic tokens, are cached. They are treated
+	// as if they had a lease of this duration, so that a token that Vault never rejects is not kept forever.
+	noLeaseTTL = time.Hour
+)
+
+type cachedToken struct {
+	token     string
+	renewable bool
+	// renewAt is when the token should be renewed, or replaced if it is not renewable.
+	renewAt time.Time
+	// expireAt is when the lease of the token ends.
+	expireAt time.Time
+}
+
+func (t *cachedToken) expired(now time.Time) bool {
+	return !now.Before(t.expireAt)
+}
+
+func (t *cachedToken) needsRenewal(now time.Time) bool {
+	return !now.Before(t.renewAt)
+}
+
+// tokenCache caches Vault tokens so that Vault is not logged in to on every CSR. Tokens are
+// renewed once renewFraction of their lease has passed, and replaced by a new login if they
+// cannot be renewed. Logins and renewals are round trips to Vault, so they are made without
+// holding the lock, and concurrent requests for the same key share a single round trip.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*cachedToken
+	now    func() time.Time
+	group  singleflight.Group
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{
+		tokens: map[string]*cachedToken{},
+		now:    time.Now,
+	}
+}
+
+func (c *tokenCache) newCachedToken(auth *api.SecretAuth) *cachedToken {
+	lease := time.Duration(auth.LeaseDuration) * time.Second
+	if lease <= 0 {
+		lease = noLeaseTTL
+	}
+	now := c.now()
+	return &cachedToken{
+		token:     auth.ClientToken,
+		renewable: auth.Renewable,
+		renewAt:   now.Add(time.Duration(float64(lease) * renewFraction)),
+		expireAt:  now.Add(lease),
+	}
+}
+
+// get returns a usable token for key. login is called if there is no cached token or if the
+// cached token is about to expire and cannot be renewed. renew is called to extend the lease
+// of a renewable token.
+func (c *tokenCache) get(key string, login func() (*api.SecretAuth, error),
+	renew func(token string) (*api.SecretAuth, error)) (string, error) {
+	if token, f := c.cached(key); f {
+		return token, nil
+	}
+
+	token, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// The token may have been replaced while waiting for another request for the key.
+		if token, f := c.cached(key); f {
+			return token, nil
+		}
+
+		c.mu.Lock()
+		t := c.tokens[key]
+		c.mu.Unlock()
+		if t != nil && t.renewable {
+			auth, err := renew(t.token)
+			if err == nil && auth != nil {
+				if auth.ClientToken == "" {
+					auth.ClientToken = t.token
+				}
+				vaultClientLog.Debugf("renewed Vault token, lease %ds", auth.LeaseDuration)
+				c.store(key, auth)
+				return auth.ClientToken, nil
+			}
+			vaultClientLog.Warnf("failed to renew Vault token, logging in again: %v", err)
+		}
+
+		auth, err := login()
+		if err != nil {
+			return "", err
+		}
+		c.store(key, auth)
+		return auth.ClientToken, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// cached returns the cached token for key, unless it needs to be renewed.
+func (c *tokenCache) cached(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.pruneLocked(now)
+	if t, f := c.tokens[key]; f && !t.needsRenewal(now) {
+		return t.token, true
+	}
+	return "", false
+}
+
+func (c *tokenCache) store(key string, auth *api.SecretAuth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = c.newCachedToken(auth)
+}
+
+// invalidate drops the cached token for key, for example after Vault rejected it.
+func (c *tokenCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, key)
+}
+
+func (c *tokenCache) pruneLocked(now time.Time) {
+	for k, t := range c.tokens {
+		if t.expired(now) {
+			delete(c.tokens, k)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package caclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func noRenew(string) (*api.SecretAuth, error) {
+	return nil, nil
+}
+
+func TestTokenCacheSlowLogin(t *testing.T) {
+	c := newTokenCache()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	logins := 0
+	slowLogin := func() (*api.SecretAuth, error) {
+		mu.Lock()
+		logins++
+		mu.Unlock()
+		<-release
+		return &api.SecretAuth{ClientToken: "slow", LeaseDuration: 3600}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := c.get("slow", slowLogin, noRenew); err != nil || token != "slow" {
+				t.Errorf("unexpected token %q, error %v", token, err)
+			}
+		}()
+	}
+
+	// A login for another key is not blocked by the slow login.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		token, err := c.get("fast", func() (*api.SecretAuth, error) {
+			return &api.SecretAuth{ClientToken: "fast", LeaseDuration: 3600}, nil
+		}, noRenew)
+		if err != nil || token != "fast" {
+			t.Errorf("unexpected token %q, error %v", token, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("login for another key blocked by a slow login")
+	}
+
+	close(release)
+	wg.Wait()
+	if logins != 1 {
+		t.Fatalf("expected concurrent requests for a key to share a login, got %d logins", logins)
+	}
+}
+
+func TestTokenCacheNoLease(t *testing.T) {
+	c := newTokenCache()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	logins := 0
+	login := func() (*api.SecretAuth, error) {
+		logins++
+		return &api.SecretAuth{ClientToken: "token"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.get("key", login, noRenew); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 1 {
+		t.Fatalf("expected a single login, got %d", logins)
+	}
+
+	// Tokens without a lease are replaced after noLeaseTTL.
+	now = now.Add(noLeaseTTL)
+	if _, err := c.get("key", login, noRenew); err != nil {
+		t.Fatal(err)
+	}
+	if logins != 2 {
+		t.Fatalf("expected a new login after %v, got %d logins", noLeaseTTL, logins)
+	}
+}
@@ -30,11 +30,13 @@ type FakeCA struct {
 	SignErr       *caerror.Error
 	KeyCertBundle util.KeyCertBundle
 	ReceivedIDs   []string
+	ReceivedForCA bool
 }
 
 // Sign returns the SignErr if SignErr is not nil, otherwise, it returns SignedCert.
 func (ca *FakeCA) Sign(csr []byte, identities []string, lifetime time.Duration, forCA bool) ([]byte, error) {
 	ca.ReceivedIDs = identities
+	ca.ReceivedForCA = forCA
 	if ca.SignErr != nil {
 		return nil, ca.SignErr
 	}
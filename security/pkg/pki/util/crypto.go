@@ -19,6 +19,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/pem"
 	"fmt"
 	"reflect"
@@ -30,6 +31,8 @@ const (
 	blockTypePKCS8PrivateKey = "PRIVATE KEY"     // PKCS#8 plain private key
 )
 
+var oidBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+
 // ParsePemEncodedCertificate constructs a `x509.Certificate` object using the
 // given a PEM-encoded certificate.
 func ParsePemEncodedCertificate(certBytes []byte) (*x509.Certificate, error) {
@@ -60,6 +63,25 @@ func ParsePemEncodedCSR(csrBytes []byte) (*x509.CertificateRequest, error) {
 	return csr, nil
 }
 
+// IsCACertificateRequest returns whether the certificate signing request asks for a CA certificate,
+// that is whether it has a basic constraints extension with the CA flag set.
+func IsCACertificateRequest(csr *x509.CertificateRequest) (bool, error) {
+	for _, ext := range csr.Extensions {
+		if !ext.Id.Equal(oidBasicConstraints) {
+			continue
+		}
+		var constraints struct {
+			IsCA       bool `asn1:"optional"`
+			MaxPathLen int  `asn1:"optional,default:-1"`
+		}
+		if rest, err := asn1.Unmarshal(ext.Value, &constraints); err != nil || len(rest) != 0 {
+			return false, fmt.Errorf("failed to parse the basic constraints of the certificate signing request")
+		}
+		return constraints.IsCA, nil
+	}
+	return false, nil
+}
+
 // ParsePemEncodedKey takes a PEM-encoded key and parsed the bytes into a `crypto.PrivateKey`.
 func ParsePemEncodedKey(keyBytes []byte) (crypto.PrivateKey, error) {
 	kb, _ := pem.Decode(keyBytes)
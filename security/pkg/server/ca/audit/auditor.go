@@ -0,0 +1,214 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/pkg/log"
+)
+
+// DebugPath is the istiod debug endpoint serving recently issued certificates.
+const DebugPath = "/debug/ca/issued"
+
+var auditLog = log.RegisterScope("caaudit", "CA certificate audit log", 0)
+
+// Auditor records issued certificates to a set of sinks and keeps the most recent records in
+// memory so they can be queried through the debug endpoint.
+//
+// Records are written to the sinks in the background, so that a slow sink does not delay the
+// issuance of certificates. Records that arrive while the queue of records to write is full are
+// dropped, and counted by the citadel_server_audit_records_dropped_count metric.
+type Auditor struct {
+	sinks []Sink
+	queue chan *Record
+	done  chan struct{}
+
+	mu      sync.RWMutex
+	recent  []*Record
+	next    int
+	wrapped bool
+	closed  bool
+}
+
+// NewAuditor creates an Auditor that keeps up to maxRecent records in memory and forwards all
+// records to the given sinks. Up to queueSize records wait to be written to the sinks.
+func NewAuditor(maxRecent, queueSize int, sinks ...Sink) *Auditor {
+	if maxRecent <= 0 {
+		maxRecent = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	a := &Auditor{
+		sinks:  sinks,
+		recent: make([]*Record, maxRecent),
+		done:   make(chan struct{}),
+	}
+	if len(sinks) == 0 {
+		close(a.done)
+		return a
+	}
+	a.queue = make(chan *Record, queueSize)
+	go a.run()
+	return a
+}
+
+// Record stores r. It does not wait for r to be written to the sinks.
+func (a *Auditor) Record(r *Record) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recent[a.next] = r
+	a.next++
+	if a.next == len(a.recent) {
+		a.next = 0
+		a.wrapped = true
+	}
+
+	if a.queue == nil || a.closed {
+		return
+	}
+	select {
+	case a.queue <- r:
+	default:
+		droppedRecords.Increment()
+		auditLog.Warnf("dropped certificate audit record %s: too many records waiting to be written", r.SerialNumber)
+	}
+}
+
+// run writes the queued records to the sinks. Failures to write to a sink are logged but do not
+// fail the request, since the certificate has already been issued.
+func (a *Auditor) run() {
+	defer close(a.done)
+	for r := range a.queue {
+		for _, s := range a.sinks {
+			if err := s.Write(r); err != nil {
+				auditLog.Errorf("failed to write certificate audit record %s: %v", r.SerialNumber, err)
+			}
+		}
+	}
+}
+
+// Close writes the queued records, and closes all sinks.
+func (a *Auditor) Close() error {
+	a.mu.Lock()
+	if !a.closed && a.queue != nil {
+		close(a.queue)
+	}
+	a.closed = true
+	a.mu.Unlock()
+	<-a.done
+
+	var errs error
+	for _, s := range a.sinks {
+		if err := s.Close(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Query selects records held in memory.
+type Query struct {
+	// Identity matches records whose SANs or caller identities contain the given substring.
+	Identity string
+	// Serial matches records with the given serial number.
+	Serial string
+	// Since matches records issued at or after the given time.
+	Since time.Time
+	// Limit is the maximum number of records returned, newest first. Zero means no limit.
+	Limit int
+}
+
+func (q Query) matches(r *Record) bool {
+	if q.Serial != "" && !strings.EqualFold(q.Serial, r.SerialNumber) {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if q.Identity != "" {
+		for _, id := range append(append([]string{}, r.SANs...), r.CallerIdentities...) {
+			if strings.Contains(id, q.Identity) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Recent returns the in-memory records matching q, newest first.
+func (a *Auditor) Recent(q Query) []*Record {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	n := a.next
+	if a.wrapped {
+		n = len(a.recent)
+	}
+	res := make([]*Record, 0)
+	for i := 0; i < n; i++ {
+		idx := (a.next - 1 - i + len(a.recent)) % len(a.recent)
+		r := a.recent[idx]
+		if !q.matches(r) {
+			continue
+		}
+		res = append(res, r)
+		if q.Limit > 0 && len(res) == q.Limit {
+			break
+		}
+	}
+	return res
+}
+
+// ServeHTTP serves the records matching the "identity", "serial", "since" (RFC3339) and "limit"
+// query parameters as JSON.
+func (a *Auditor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	q := Query{
+		Identity: params.Get("identity"),
+		Serial:   params.Get("serial"),
+	}
+	if since := params.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.Since = t
+	}
+	if limit := params.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			http.Error(w, "invalid limit: "+limit, http.StatusBadRequest)
+			return
+		}
+		q.Limit = l
+	}
+	b, err := json.MarshalIndent(a.Recent(q), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestNewRecord(t *testing.T) {
+	certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "spiffe://cluster.local/ns/foo/sa/bar",
+		TTL:          time.Hour,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRecord(certPEM, []string{"spiffe://cluster.local/ns/foo/sa/bar"}, "IDToken", "10.0.0.1:1234", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SerialNumber == "" {
+		t.Errorf("expected serial number")
+	}
+	if len(r.SANs) != 1 || r.SANs[0] != "spiffe://cluster.local/ns/foo/sa/bar" {
+		t.Errorf("unexpected SANs %v", r.SANs)
+	}
+	if r.TTL != "1h0m0s" || r.PeerAddress != "10.0.0.1:1234" || r.AuthSource != "IDToken" {
+		t.Errorf("unexpected record %+v", r)
+	}
+
+	r, err = NewRecord([]byte("not a cert"), []string{"id"}, "", "", 0)
+	if err == nil {
+		t.Fatal("expected error for invalid certificate")
+	}
+	if r == nil || r.CallerIdentities[0] != "id" {
+		t.Errorf("expected partial record, got %+v", r)
+	}
+}
+
+func TestAuditorRecent(t *testing.T) {
+	a := NewAuditor(3, 1)
+	base := time.Now()
+	for i, id := range []string{"a", "b", "c", "d"} {
+		a.Record(&Record{Time: base.Add(time.Duration(i) * time.Minute), SerialNumber: id, SANs: []string{"spiffe://" + id}})
+	}
+	serials := func(rs []*Record) []string {
+		var res []string
+		for _, r := range rs {
+			res = append(res, r.SerialNumber)
+		}
+		return res
+	}
+	cases := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"all", Query{}, []string{"d", "c", "b"}},
+		{"limit", Query{Limit: 2}, []string{"d", "c"}},
+		{"serial", Query{Serial: "C"}, []string{"c"}},
+		{"identity", Query{Identity: "spiffe://b"}, []string{"b"}},
+		{"since", Query{Since: base.Add(150 * time.Second)}, []string{"d"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := serials(a.Recent(tc.q))
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	a := NewAuditor(10, 1)
+	a.Record(&Record{Time: time.Now(), SerialNumber: "01"})
+	a.Record(&Record{Time: time.Now(), SerialNumber: "02"})
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", DebugPath+"?serial=02", nil))
+	var got []*Record
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].SerialNumber != "02" {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", DebugPath+"?since=yesterday", nil))
+	if w.Code != 400 {
+		t.Fatalf("expected bad request, got %d", w.Code)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a := NewAuditor(1, 10, NewFileSink(FileOptions{Path: path, MaxSizeMB: 1}))
+	a.Record(&Record{SerialNumber: "01"})
+	a.Record(&Record{SerialNumber: "02"})
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var serials []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			t.Fatal(err)
+		}
+		serials = append(serials, r.SerialNumber)
+	}
+	if len(serials) != 2 || serials[0] != "01" || serials[1] != "02" {
+		t.Fatalf("unexpected records %v", serials)
+	}
+}
+
+// blockingSink records the serial numbers written, and blocks writes until unblocked.
+type blockingSink struct {
+	written chan string
+	unblock chan struct{}
+}
+
+func (s *blockingSink) Write(r *Record) error {
+	s.written <- r.SerialNumber
+	<-s.unblock
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	close(s.written)
+	return nil
+}
+
+func droppedCount(t *testing.T) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData("citadel_server_audit_records_dropped_count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 {
+		return 0
+	}
+	return rows[0].Data.(*view.SumData).Value
+}
+
+func TestAuditorSlowSink(t *testing.T) {
+	sink := &blockingSink{written: make(chan string, 10), unblock: make(chan struct{})}
+	a := NewAuditor(10, 1, sink)
+	dropped := droppedCount(t)
+
+	// The first record is being written, and blocks the sink.
+	a.Record(&Record{SerialNumber: "01"})
+	if got := <-sink.written; got != "01" {
+		t.Fatalf("got record %s, want 01", got)
+	}
+	// The second record is queued, and the third does not fit in the queue.
+	a.Record(&Record{SerialNumber: "02"})
+	a.Record(&Record{SerialNumber: "03"})
+	if got := droppedCount(t) - dropped; got != 1 {
+		t.Fatalf("got %v dropped records, want 1", got)
+	}
+	// Dropped records are still kept in memory.
+	if got := len(a.Recent(Query{})); got != 3 {
+		t.Fatalf("got %d recent records, want 3", got)
+	}
+
+	close(sink.unblock)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var written []string
+	for serial := range sink.written {
+		written = append(written, serial)
+	}
+	if len(written) != 1 || written[0] != "02" {
+		t.Fatalf("got records %v written after 01, want [02]", written)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var droppedRecords = monitoring.NewSum(
+	"citadel_server_audit_records_dropped_count",
+	"The number of certificate audit records dropped because the sinks could not keep up.",
+)
+
+func init() {
+	monitoring.MustRegister(droppedRecords)
+}
@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+// Record describes a single certificate issued by the CA.
+type Record struct {
+	// Time is when the certificate was issued.
+	Time time.Time `json:"time"`
+	// SerialNumber is the hex encoded serial number of the certificate.
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// SANs are the URI and DNS subject alternative names of the certificate.
+	SANs []string `json:"sans,omitempty"`
+	// CallerIdentities are the identities of the authenticated caller.
+	CallerIdentities []string `json:"callerIdentities,omitempty"`
+	// AuthSource is the authenticator that authenticated the caller.
+	AuthSource string `json:"authSource,omitempty"`
+	// PeerAddress is the source address of the request.
+	PeerAddress string `json:"peerAddress,omitempty"`
+	// TTL is the validity duration requested by the caller, or zero for the CA default.
+	TTL string `json:"ttl,omitempty"`
+	// NotBefore and NotAfter bound the validity of the certificate.
+	NotBefore time.Time `json:"notBefore,omitempty"`
+	NotAfter  time.Time `json:"notAfter,omitempty"`
+	// IssuerKeyID is the hex encoded authority key ID of the certificate, which identifies
+	// the CA key that signed it.
+	IssuerKeyID string `json:"issuerKeyID,omitempty"`
+	// ForCA is true if the certificate is an intermediate CA certificate.
+	ForCA bool `json:"forCA,omitempty"`
+}
+
+// NewRecord builds a Record from a PEM encoded certificate. Fields that cannot be extracted
+// from the certificate are left empty and an error is returned along with the partial record.
+func NewRecord(certPEM []byte, callerIDs []string, authSource, peerAddress string, ttl time.Duration) (*Record, error) {
+	r := &Record{
+		Time:             time.Now(),
+		CallerIdentities: callerIDs,
+		AuthSource:       authSource,
+		PeerAddress:      peerAddress,
+	}
+	if ttl > 0 {
+		r.TTL = ttl.String()
+	}
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		return r, fmt.Errorf("failed to parse issued certificate: %v", err)
+	}
+	r.fillFromCert(cert)
+	return r, nil
+}
+
+func (r *Record) fillFromCert(cert *x509.Certificate) {
+	if cert.SerialNumber != nil {
+		r.SerialNumber = hex.EncodeToString(cert.SerialNumber.Bytes())
+	}
+	for _, u := range cert.URIs {
+		r.SANs = append(r.SANs, u.String())
+	}
+	r.SANs = append(r.SANs, cert.DNSNames...)
+	r.NotBefore = cert.NotBefore
+	r.NotAfter = cert.NotAfter
+	r.IssuerKeyID = hex.EncodeToString(cert.AuthorityKeyId)
+	r.ForCA = cert.IsCA
+}
@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sink receives audit records. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(r *Record) error
+	Close() error
+}
+
+// FileSink appends records to a file as JSON lines, rotating the file when it grows too large.
+type FileSink struct {
+	mu     sync.Mutex
+	logger *lumberjack.Logger
+}
+
+var _ Sink = &FileSink{}
+
+// FileOptions configures a FileSink.
+type FileOptions struct {
+	// Path of the audit file.
+	Path string
+	// MaxSizeMB is the size at which the file is rotated.
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackups int
+	// MaxAgeDays is the number of days to keep rotated files. Zero keeps them forever.
+	MaxAgeDays int
+}
+
+// NewFileSink creates a FileSink. The file is created on the first write.
+func NewFileSink(opts FileOptions) *FileSink {
+	return &FileSink{
+		logger: &lumberjack.Logger{
+			Filename:   opts.Path,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+		},
+	}
+}
+
+func (f *FileSink) Write(r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.logger.Write(b)
+	return err
+}
+
+func (f *FileSink) Close() error {
+	return f.logger.Close()
+}
+
+// GRPCSinkMethod is the full name of the unary method invoked on the remote sink for every
+// record. The request is a google.protobuf.Struct holding the JSON form of the Record and
+// the response is google.protobuf.Empty.
+const GRPCSinkMethod = "/istio.security.audit.v1alpha1.CertificateAuditSink/Record"
+
+// GRPCSink forwards records to a remote gRPC service.
+type GRPCSink struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+var _ Sink = &GRPCSink{}
+
+// NewGRPCSink creates a GRPCSink that sends records over the given connection. The sink takes
+// ownership of the connection.
+func NewGRPCSink(conn *grpc.ClientConn, timeout time.Duration) *GRPCSink {
+	return &GRPCSink{conn: conn, timeout: timeout}
+}
+
+func (g *GRPCSink) Write(r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	msg := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, msg); err != nil {
+		return fmt.Errorf("failed to convert audit record: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+	return g.conn.Invoke(ctx, GRPCSinkMethod, msg, &emptypb.Empty{})
+}
+
+func (g *GRPCSink) Close() error {
+	return g.conn.Close()
+}
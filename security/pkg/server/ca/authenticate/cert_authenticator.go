@@ -16,11 +16,13 @@ package authenticate
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
 
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/pki/util"
 )
 
@@ -40,8 +42,24 @@ const (
 	AuthSourceIDToken
 )
 
+func (a AuthSource) String() string {
+	switch a {
+	case AuthSourceClientCertificate:
+		return "ClientCertificate"
+	case AuthSourceIDToken:
+		return "IDToken"
+	default:
+		return "Unknown"
+	}
+}
+
 // ClientCertAuthenticator extracts identities from client certificate.
-type ClientCertAuthenticator struct{}
+type ClientCertAuthenticator struct {
+	// ExternalTrustDomains are trust domains whose certificates are trusted by the TLS server, but whose
+	// SPIFFE IDs are not mesh identities. Their certificates are rejected, so that they can only be
+	// authenticated by an X509SVIDAuthenticator mapping them to mesh identities.
+	ExternalTrustDomains []string
+}
 
 var _ Authenticator = &ClientCertAuthenticator{}
 
@@ -73,6 +91,17 @@ func (cca *ClientCertAuthenticator) Authenticate(ctx context.Context) (*Caller,
 	if err != nil {
 		return nil, err
 	}
+	for _, id := range ids {
+		if !strings.HasPrefix(id, spiffe.URIPrefix) {
+			continue
+		}
+		td := strings.SplitN(id[spiffe.URIPrefixLen:], "/", 2)[0]
+		for _, external := range cca.ExternalTrustDomains {
+			if td == external {
+				return nil, fmt.Errorf("client certificate of external trust domain %s is not a mesh identity", td)
+			}
+		}
+	}
 
 	return &Caller{
 		AuthSource: AuthSourceClientCertificate,
@@ -46,6 +46,17 @@ func TestAuthenticate_clientCertAuthenticator(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	meshID := "spiffe://cluster.local/ns/foo/sa/bar"
+	meshSANExt, err := util.BuildSANExtension([]util.Identity{{Type: util.TypeURI, Value: []byte(meshID)}})
+	if err != nil {
+		t.Error(err)
+	}
+	externalSANExt, err := util.BuildSANExtension([]util.Identity{
+		{Type: util.TypeURI, Value: []byte("spiffe://vm.example.com/host/vm-1")},
+	})
+	if err != nil {
+		t.Error(err)
+	}
 
 	testCases := map[string]struct {
 		certChain          [][]*x509.Certificate
@@ -89,9 +100,29 @@ func TestAuthenticate_clientCertAuthenticator(t *testing.T) {
 			},
 			caller: &Caller{Identities: []string{callerID}},
 		},
+		"With mesh SPIFFE certificate": {
+			certChain: [][]*x509.Certificate{
+				{
+					{
+						Extensions: []pkix.Extension{*meshSANExt},
+					},
+				},
+			},
+			caller: &Caller{Identities: []string{meshID}},
+		},
+		"With external SPIFFE certificate": {
+			certChain: [][]*x509.Certificate{
+				{
+					{
+						Extensions: []pkix.Extension{*externalSANExt},
+					},
+				},
+			},
+			authenticateErrMsg: "client certificate of external trust domain vm.example.com is not a mesh identity",
+		},
 	}
 
-	auth := &ClientCertAuthenticator{}
+	auth := &ClientCertAuthenticator{ExternalTrustDomains: []string{"vm.example.com"}}
 
 	for id, tc := range testCases {
 		ctx := context.Background()
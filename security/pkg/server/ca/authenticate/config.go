@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/spiffe"
+)
+
+// ChainConfig configures the authenticators added to the CA server in addition to the built-in
+// client certificate and Kubernetes JWT authenticators.
+//
+// Example:
+//
+//   x509Svid:
+//   - trustDomain: vm.example.com
+//     bundleFile: /etc/istio/vm-bundle/roots.pem
+//     rules:
+//     - pattern: spiffe://vm.example.com/ns/([^/]+)/sa/([^/]+)
+//       namespace: $1
+//       serviceAccount: $2
+//   oidc:
+//   - issuer: https://accounts.google.com
+//     audiences: [istio-ca]
+//     rules:
+//     - claim: email
+//       pattern: (.+)@my-project.iam.gserviceaccount.com
+//       namespace: vm
+//       serviceAccount: $1
+type ChainConfig struct {
+	// X509SVID lists external SPIFFE trust domains whose certificates are accepted.
+	X509SVID []X509SVIDConfig `json:"x509Svid,omitempty"`
+	// OIDC lists OIDC issuers whose tokens are accepted.
+	OIDC []OIDCIssuerConfig `json:"oidc,omitempty"`
+}
+
+// ParseChainConfig parses a ChainConfig from YAML or JSON.
+func ParseChainConfig(in []byte) (*ChainConfig, error) {
+	c := &ChainConfig{}
+	if err := yaml.UnmarshalStrict(in, c); err != nil {
+		return nil, fmt.Errorf("failed to parse authenticator config: %v", err)
+	}
+	return c, nil
+}
+
+// LoadChainConfig reads a ChainConfig from a file.
+func LoadChainConfig(path string) (*ChainConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseChainConfig(b)
+}
+
+// BuildX509SVIDAuthenticators creates the authenticators for external SPIFFE trust domains. They
+// must be placed before the ClientCertAuthenticator, which would accept the external identity
+// as-is. The returned roots, keyed by trust domain, must be trusted by the TLS server.
+func (c *ChainConfig) BuildX509SVIDAuthenticators(meshTrustDomain string) (
+	[]Authenticator, map[string][]*x509.Certificate, error) {
+	var authenticators []Authenticator
+	roots := map[string][]*x509.Certificate{}
+	for _, cfg := range c.X509SVID {
+		certs, err := loadBundle(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		a, err := NewX509SVIDAuthenticator(cfg.TrustDomain, certs, cfg.Rules, meshTrustDomain)
+		if err != nil {
+			return nil, nil, err
+		}
+		authenticators = append(authenticators, a)
+		roots[cfg.TrustDomain] = append(roots[cfg.TrustDomain], certs...)
+	}
+	return authenticators, roots, nil
+}
+
+// BuildOIDCAuthenticators creates one authenticator per OIDC issuer.
+func (c *ChainConfig) BuildOIDCAuthenticators(meshTrustDomain string) ([]Authenticator, error) {
+	var authenticators []Authenticator
+	for _, cfg := range c.OIDC {
+		a, err := NewOIDCIssuerAuthenticator(cfg, meshTrustDomain)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	return authenticators, nil
+}
+
+func loadBundle(cfg X509SVIDConfig) ([]*x509.Certificate, error) {
+	switch {
+	case cfg.BundleFile != "" && cfg.BundleEndpoint != "":
+		return nil, fmt.Errorf("trust domain %s: only one of bundleFile and bundleEndpoint may be set", cfg.TrustDomain)
+	case cfg.BundleFile != "":
+		b, err := ioutil.ReadFile(cfg.BundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("trust domain %s: %v", cfg.TrustDomain, err)
+		}
+		return parseCertificates(b)
+	case cfg.BundleEndpoint != "":
+		certs, err := spiffe.RetrieveSpiffeBundleRootCerts(map[string]string{cfg.TrustDomain: cfg.BundleEndpoint}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("trust domain %s: %v", cfg.TrustDomain, err)
+		}
+		return certs[cfg.TrustDomain], nil
+	default:
+		return nil, fmt.Errorf("trust domain %s: one of bundleFile and bundleEndpoint is required", cfg.TrustDomain)
+	}
+}
+
+func parseCertificates(in []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(in); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChainConfig(t *testing.T) {
+	root := newTestRoot(t, "vm.example.com")
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "roots.pem")
+	if err := ioutil.WriteFile(bundle, root.certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseChainConfig([]byte(`
+x509Svid:
+- trustDomain: vm.example.com
+  bundleFile: ` + bundle + `
+  rules:
+  - pattern: spiffe://vm.example.com/ns/([^/]+)/sa/([^/]+)
+    namespace: $1
+    serviceAccount: $2
+oidc:
+- issuer: https://issuer.example.com
+  audiences: [istio-ca]
+  jwksUri: https://issuer.example.com/keys
+  rules:
+  - claim: email
+    pattern: (.+)@example.com
+    namespace: vms
+    serviceAccount: $1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svid, roots, err := cfg.BuildX509SVIDAuthenticators("cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svid) != 1 || len(roots["vm.example.com"]) != 1 {
+		t.Fatalf("unexpected X.509 SVID authenticators %v, roots %v", svid, roots)
+	}
+	oidc, err := cfg.BuildOIDCAuthenticators("cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oidc) != 1 || oidc[0].AuthenticatorType() != OIDCIssuerAuthenticatorType {
+		t.Fatalf("unexpected OIDC authenticators %v", oidc)
+	}
+}
+
+func TestChainConfigErrors(t *testing.T) {
+	cases := map[string]struct {
+		config string
+		err    string
+	}{
+		"unknown field": {
+			config: "x509Svid:\n- trustDomain: a\n  bundle: b\n",
+			err:    "failed to parse authenticator config",
+		},
+		"no bundle": {
+			config: "x509Svid:\n- trustDomain: a\n",
+			err:    "one of bundleFile and bundleEndpoint is required",
+		},
+		"both bundles": {
+			config: "x509Svid:\n- trustDomain: a\n  bundleFile: b\n  bundleEndpoint: c\n",
+			err:    "only one of bundleFile and bundleEndpoint may be set",
+		},
+		"missing bundle file": {
+			config: "x509Svid:\n- trustDomain: a\n  bundleFile: /does/not/exist\n",
+			err:    "trust domain a",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := ParseChainConfig([]byte(tc.config))
+			if err == nil {
+				_, _, err = cfg.BuildX509SVIDAuthenticators("cluster.local")
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
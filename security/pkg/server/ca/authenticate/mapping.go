@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// IdentityRule maps an external identity, such as a JWT claim or an X.509 SVID, to a mesh
+// identity. The value is matched against Pattern, and Namespace and ServiceAccount are expanded
+// with the submatches of the pattern, e.g. "$1" or "${ns}".
+type IdentityRule struct {
+	// Claim is the JWT claim holding the value to match. It is ignored for X.509 SVIDs, which
+	// always match the SPIFFE ID of the certificate. Defaults to "sub".
+	Claim string `json:"claim,omitempty"`
+	// Pattern is a regular expression that must match the whole value.
+	Pattern string `json:"pattern"`
+	// Namespace is the template of the namespace of the mesh identity.
+	Namespace string `json:"namespace"`
+	// ServiceAccount is the template of the service account of the mesh identity.
+	ServiceAccount string `json:"serviceAccount"`
+
+	re *regexp.Regexp
+}
+
+const defaultIdentityClaim = "sub"
+
+func (r *IdentityRule) compile() error {
+	if r.Pattern == "" || r.Namespace == "" || r.ServiceAccount == "" {
+		return fmt.Errorf("identity rule requires a pattern, a namespace and a service account")
+	}
+	re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid identity rule pattern %q: %v", r.Pattern, err)
+	}
+	r.re = re
+	if r.Claim == "" {
+		r.Claim = defaultIdentityClaim
+	}
+	return nil
+}
+
+// apply returns the namespace and service account mapped from value, or false if the rule
+// does not match.
+func (r *IdentityRule) apply(value string) (string, string, bool) {
+	m := r.re.FindStringSubmatchIndex(value)
+	if m == nil {
+		return "", "", false
+	}
+	ns := string(r.re.ExpandString(nil, r.Namespace, value, m))
+	sa := string(r.re.ExpandString(nil, r.ServiceAccount, value, m))
+	if ns == "" || sa == "" {
+		return "", "", false
+	}
+	return ns, sa, true
+}
+
+func compileRules(rules []IdentityRule) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("at least one identity rule is required")
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc"
+)
+
+const (
+	OIDCIssuerAuthenticatorType = "OIDCIssuerAuthenticator"
+)
+
+// OIDCIssuerConfig configures the authentication of tokens from one OIDC issuer.
+type OIDCIssuerConfig struct {
+	// Issuer is the expected "iss" claim of the tokens.
+	Issuer string `json:"issuer"`
+	// Audiences lists the accepted "aud" claims. At least one is required.
+	Audiences []string `json:"audiences"`
+	// JwksURI is the location of the issuer's public keys. If empty, it is discovered from
+	// the issuer's OpenID configuration.
+	JwksURI string `json:"jwksUri,omitempty"`
+	// Rules map the claims of a token to a mesh identity. The first matching rule is used.
+	Rules []IdentityRule `json:"rules"`
+}
+
+// OIDCIssuerAuthenticator authenticates tokens from a single OIDC issuer and maps their claims
+// to mesh identities. Unlike JwtAuthenticator it does not assume Kubernetes tokens, so it can be
+// used for tokens issued by cloud providers to VMs. Several can be chained, one per issuer.
+type OIDCIssuerAuthenticator struct {
+	config      OIDCIssuerConfig
+	trustDomain string
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+var _ Authenticator = &OIDCIssuerAuthenticator{}
+
+// NewOIDCIssuerAuthenticator creates an OIDCIssuerAuthenticator. The issuer's keys are fetched
+// lazily, so an issuer that is temporarily unreachable does not prevent startup.
+func NewOIDCIssuerAuthenticator(config OIDCIssuerConfig, trustDomain string) (*OIDCIssuerAuthenticator, error) {
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("OIDC issuer is required")
+	}
+	if len(config.Audiences) == 0 {
+		return nil, fmt.Errorf("OIDC issuer %s requires at least one audience", config.Issuer)
+	}
+	if err := compileRules(config.Rules); err != nil {
+		return nil, fmt.Errorf("OIDC issuer %s: %v", config.Issuer, err)
+	}
+	a := &OIDCIssuerAuthenticator{config: config, trustDomain: trustDomain}
+	if config.JwksURI != "" {
+		keySet := oidc.NewRemoteKeySet(context.Background(), config.JwksURI)
+		a.verifier = oidc.NewVerifier(config.Issuer, keySet, a.oidcConfig())
+	}
+	return a, nil
+}
+
+func (a *OIDCIssuerAuthenticator) oidcConfig() *oidc.Config {
+	// Audiences are checked by Authenticate, since oidc.Config only supports a single one.
+	return &oidc.Config{SkipClientIDCheck: true}
+}
+
+func (a *OIDCIssuerAuthenticator) AuthenticatorType() string {
+	return OIDCIssuerAuthenticatorType
+}
+
+func (a *OIDCIssuerAuthenticator) getVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.verifier != nil {
+		return a.verifier, nil
+	}
+	provider, err := oidc.NewProvider(ctx, a.config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC issuer %s: %v", a.config.Issuer, err)
+	}
+	a.verifier = provider.Verifier(a.oidcConfig())
+	return a.verifier, nil
+}
+
+// Authenticate verifies the bearer token of the request if it was issued by the configured
+// issuer, and maps its claims to a mesh identity.
+func (a *OIDCIssuerAuthenticator) Authenticate(ctx context.Context) (*Caller, error) {
+	bearerToken, err := extractBearerToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ID token extraction error: %v", err)
+	}
+	// Skip tokens from other issuers without fetching keys, since several issuers may be chained.
+	if iss, err := unverifiedIssuer(bearerToken); err != nil || iss != a.config.Issuer {
+		return nil, fmt.Errorf("token is not issued by %s", a.config.Issuer)
+	}
+	verifier, err := a.getVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := verifier.Verify(ctx, bearerToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the ID token (error %v)", err)
+	}
+	if !a.audienceAllowed(idToken.Audience) {
+		return nil, fmt.Errorf("token audience %v is not allowed", idToken.Audience)
+	}
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to extract claims from ID token: %v", err)
+	}
+	for i := range a.config.Rules {
+		rule := &a.config.Rules[i]
+		value, ok := claims[rule.Claim].(string)
+		if !ok {
+			continue
+		}
+		if ns, sa, ok := rule.apply(value); ok {
+			return &Caller{
+				AuthSource: AuthSourceIDToken,
+				Identities: []string{fmt.Sprintf(identityTemplate, a.trustDomain, ns, sa)},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no identity rule of issuer %s matches the token", a.config.Issuer)
+}
+
+func (a *OIDCIssuerAuthenticator) audienceAllowed(audiences []string) bool {
+	for _, aud := range audiences {
+		for _, allowed := range a.config.Audiences {
+			if aud == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unverifiedIssuer returns the "iss" claim of a JWT without verifying its signature.
+func unverifiedIssuer(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed JWT payload: %v", err)
+	}
+	claims := &struct {
+		Iss string `json:"iss"`
+	}{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return "", fmt.Errorf("malformed JWT payload: %v", err)
+	}
+	return claims.Iss, nil
+}
@@ -0,0 +1,194 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(server.Close)
+	return &testIssuer{server: server, key: key}
+}
+
+func (i *testIssuer) token(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: i.key},
+		(&jose.SignerOptions{}).WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestOIDCIssuerAuthenticator(t *testing.T) {
+	issuer := newTestIssuer(t)
+	a, err := NewOIDCIssuerAuthenticator(OIDCIssuerConfig{
+		Issuer:    "https://issuer.example.com",
+		Audiences: []string{"istio-ca", "other"},
+		JwksURI:   issuer.server.URL,
+		Rules: []IdentityRule{
+			{
+				Claim:          "email",
+				Pattern:        "(.+)@my-project.iam.example.com",
+				Namespace:      "vms",
+				ServiceAccount: "$1",
+			},
+			{
+				Pattern:        "vm/(?P<ns>[^/]+)/(?P<sa>[^/]+)",
+				Namespace:      "${ns}",
+				ServiceAccount: "${sa}",
+			},
+		},
+	}, "cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	cases := []struct {
+		name     string
+		claims   map[string]interface{}
+		token    string
+		identity string
+		err      string
+	}{
+		{
+			name: "claim rule",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com", "aud": "istio-ca", "exp": exp,
+				"sub": "123", "email": "db@my-project.iam.example.com",
+			},
+			identity: "spiffe://cluster.local/ns/vms/sa/db",
+		},
+		{
+			name: "subject rule with multiple audiences",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com", "aud": []string{"unrelated", "other"}, "exp": exp,
+				"sub": "vm/foo/bar",
+			},
+			identity: "spiffe://cluster.local/ns/foo/sa/bar",
+		},
+		{
+			name: "other issuer",
+			claims: map[string]interface{}{
+				"iss": "https://other.example.com", "aud": "istio-ca", "exp": exp, "sub": "vm/foo/bar",
+			},
+			err: "token is not issued by https://issuer.example.com",
+		},
+		{
+			name: "audience not allowed",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com", "aud": "unrelated", "exp": exp, "sub": "vm/foo/bar",
+			},
+			err: "audience",
+		},
+		{
+			name: "expired",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com", "aud": "istio-ca", "exp": time.Now().Add(-time.Hour).Unix(),
+				"sub": "vm/foo/bar",
+			},
+			err: "failed to verify the ID token",
+		},
+		{
+			name: "no matching rule",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com", "aud": "istio-ca", "exp": exp, "sub": "someone",
+			},
+			err: "no identity rule",
+		},
+		{
+			name:  "malformed token",
+			token: "not-a-jwt",
+			err:   "token is not issued by",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := tc.token
+			if token == "" {
+				token = issuer.token(t, tc.claims)
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
+				authorizationMeta: []string{bearerTokenPrefix + token},
+			})
+			caller, err := a.Authenticate(ctx)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if caller.AuthSource != AuthSourceIDToken || len(caller.Identities) != 1 || caller.Identities[0] != tc.identity {
+				t.Fatalf("unexpected caller %+v, want identity %s", caller, tc.identity)
+			}
+		})
+	}
+}
+
+func TestNewOIDCIssuerAuthenticatorErrors(t *testing.T) {
+	rules := []IdentityRule{{Pattern: "(.+)", Namespace: "vms", ServiceAccount: "$1"}}
+	cases := map[string]OIDCIssuerConfig{
+		"no issuer":    {Audiences: []string{"a"}, Rules: rules},
+		"no audiences": {Issuer: "https://issuer.example.com", Rules: rules},
+		"no rules":     {Issuer: "https://issuer.example.com", Audiences: []string{"a"}},
+	}
+	for name, cfg := range cases {
+		if _, err := NewOIDCIssuerAuthenticator(cfg, "cluster.local"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
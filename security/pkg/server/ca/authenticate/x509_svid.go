@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	X509SVIDAuthenticatorType = "X509SVIDAuthenticator"
+)
+
+// X509SVIDConfig configures the authentication of X.509 SVIDs issued by an external SPIFFE issuer.
+type X509SVIDConfig struct {
+	// TrustDomain is the trust domain of the external issuer.
+	TrustDomain string `json:"trustDomain"`
+	// BundleFile is a PEM file holding the root certificates of the trust domain.
+	BundleFile string `json:"bundleFile,omitempty"`
+	// BundleEndpoint is a SPIFFE bundle endpoint serving the root certificates of the trust domain.
+	// Exactly one of BundleFile and BundleEndpoint must be set.
+	BundleEndpoint string `json:"bundleEndpoint,omitempty"`
+	// Rules map the SPIFFE ID of a certificate to a mesh identity. The first matching rule is used.
+	Rules []IdentityRule `json:"rules"`
+}
+
+// X509SVIDAuthenticator authenticates client certificates issued by an external SPIFFE issuer,
+// such as the one bootstrapping VMs, and maps their SPIFFE IDs to mesh identities.
+//
+// The roots of the external trust domain must also be trusted by the TLS server, which only
+// checks that the chain is valid for some trusted root; this authenticator verifies it against
+// the roots of its own trust domain.
+type X509SVIDAuthenticator struct {
+	trustDomain     string
+	meshTrustDomain string
+	roots           *x509.CertPool
+	rules           []IdentityRule
+}
+
+var _ Authenticator = &X509SVIDAuthenticator{}
+
+// NewX509SVIDAuthenticator creates an X509SVIDAuthenticator trusting the given roots for the
+// external trust domain.
+func NewX509SVIDAuthenticator(trustDomain string, roots []*x509.Certificate, rules []IdentityRule,
+	meshTrustDomain string) (*X509SVIDAuthenticator, error) {
+	if trustDomain == "" {
+		return nil, fmt.Errorf("X.509 SVID trust domain is required")
+	}
+	if trustDomain == meshTrustDomain {
+		return nil, fmt.Errorf("X.509 SVID trust domain %s is the trust domain of the mesh", trustDomain)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no root certificates for trust domain %s", trustDomain)
+	}
+	if err := compileRules(rules); err != nil {
+		return nil, fmt.Errorf("trust domain %s: %v", trustDomain, err)
+	}
+	pool := x509.NewCertPool()
+	for _, root := range roots {
+		pool.AddCert(root)
+	}
+	return &X509SVIDAuthenticator{
+		trustDomain:     trustDomain,
+		meshTrustDomain: meshTrustDomain,
+		roots:           pool,
+		rules:           rules,
+	}, nil
+}
+
+func (a *X509SVIDAuthenticator) AuthenticatorType() string {
+	return X509SVIDAuthenticatorType
+}
+
+// Authenticate verifies the client certificate against the roots of the external trust domain
+// and maps its SPIFFE ID to a mesh identity.
+func (a *X509SVIDAuthenticator) Authenticate(ctx context.Context) (*Caller, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil, fmt.Errorf("no client certificate is presented")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, fmt.Errorf("unsupported auth type: %q", p.AuthInfo.AuthType())
+	}
+	certs := tlsInfo.State.PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no client certificate is presented")
+	}
+	leaf := certs[0]
+	if len(leaf.URIs) != 1 {
+		return nil, fmt.Errorf("client certificate does not contain 1 URI type SAN, detected %d", len(leaf.URIs))
+	}
+	// External SPIFFE IDs need not follow the mesh's /ns/<ns>/sa/<sa> layout.
+	uri := leaf.URIs[0]
+	if uri.Scheme != spiffe.Scheme {
+		return nil, fmt.Errorf("client certificate SAN %s is not a SPIFFE ID", uri)
+	}
+	id := uri.String()
+	if uri.Host != a.trustDomain {
+		return nil, fmt.Errorf("client certificate is not issued for trust domain %s", a.trustDomain)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         a.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to verify client certificate for trust domain %s: %v", a.trustDomain, err)
+	}
+	for i := range a.rules {
+		if ns, sa, ok := a.rules[i].apply(id); ok {
+			return &Caller{
+				AuthSource: AuthSourceClientCertificate,
+				Identities: []string{fmt.Sprintf(identityTemplate, a.meshTrustDomain, ns, sa)},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no identity rule of trust domain %s matches %s", a.trustDomain, id)
+}
@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+type testSigner struct {
+	cert    *x509.Certificate
+	keyPEM  []byte
+	certPEM []byte
+}
+
+func newTestRoot(t *testing.T, org string) *testSigner {
+	t.Helper()
+	certPEM, keyPEM, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:          org,
+		TTL:          time.Hour,
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{cert: cert, keyPEM: keyPEM, certPEM: certPEM}
+}
+
+func (s *testSigner) issue(t *testing.T, host string) *x509.Certificate {
+	t.Helper()
+	key, err := util.ParsePemEncodedKey(s.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:       host,
+		TTL:        time.Hour,
+		SignerCert: s.cert,
+		SignerPriv: key,
+		RSAKeySize: 2048,
+		IsClient:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestX509SVIDAuthenticator(t *testing.T) {
+	root := newTestRoot(t, "vm.example.com")
+	otherRoot := newTestRoot(t, "other")
+	rules := []IdentityRule{{
+		Pattern:        "spiffe://vm.example.com/ns/([^/]+)/sa/([^/]+)",
+		Namespace:      "$1",
+		ServiceAccount: "vm-$2",
+	}}
+	a, err := NewX509SVIDAuthenticator("vm.example.com", []*x509.Certificate{root.cert}, rules, "cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		certs    []*x509.Certificate
+		authInfo credentials.AuthInfo
+		identity string
+		err      string
+	}{
+		{
+			name:     "mapped identity",
+			certs:    []*x509.Certificate{root.issue(t, "spiffe://vm.example.com/ns/vms/sa/db")},
+			identity: "spiffe://cluster.local/ns/vms/sa/vm-db",
+		},
+		{
+			name:  "no certificate",
+			certs: []*x509.Certificate{},
+			err:   "no client certificate is presented",
+		},
+		{
+			name:     "unsupported auth type",
+			authInfo: mockAuthInfo{"not-tls"},
+			err:      "unsupported auth type",
+		},
+		{
+			name:  "other trust domain",
+			certs: []*x509.Certificate{root.issue(t, "spiffe://cluster.local/ns/vms/sa/db")},
+			err:   "not issued for trust domain vm.example.com",
+		},
+		{
+			name:  "untrusted issuer",
+			certs: []*x509.Certificate{otherRoot.issue(t, "spiffe://vm.example.com/ns/vms/sa/db")},
+			err:   "failed to verify client certificate",
+		},
+		{
+			name:  "no matching rule",
+			certs: []*x509.Certificate{root.issue(t, "spiffe://vm.example.com/host/vm-1")},
+			err:   "no identity rule",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			authInfo := tc.authInfo
+			if authInfo == nil {
+				authInfo = credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: tc.certs}}
+			}
+			ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: authInfo})
+			caller, err := a.Authenticate(ctx)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if caller.AuthSource != AuthSourceClientCertificate || len(caller.Identities) != 1 || caller.Identities[0] != tc.identity {
+				t.Fatalf("unexpected caller %+v, want identity %s", caller, tc.identity)
+			}
+		})
+	}
+}
+
+func TestNewX509SVIDAuthenticatorErrors(t *testing.T) {
+	root := newTestRoot(t, "vm.example.com")
+	rule := IdentityRule{Pattern: "spiffe://vm.example.com/(.+)", Namespace: "vms", ServiceAccount: "$1"}
+	if _, err := NewX509SVIDAuthenticator("", []*x509.Certificate{root.cert}, []IdentityRule{rule}, "cluster.local"); err == nil {
+		t.Error("expected error for empty trust domain")
+	}
+	if _, err := NewX509SVIDAuthenticator("cluster.local", []*x509.Certificate{root.cert}, []IdentityRule{rule}, "cluster.local"); err == nil {
+		t.Error("expected error for the trust domain of the mesh")
+	}
+	if _, err := NewX509SVIDAuthenticator("vm.example.com", nil, []IdentityRule{rule}, "cluster.local"); err == nil {
+		t.Error("expected error for missing roots")
+	}
+	if _, err := NewX509SVIDAuthenticator("vm.example.com", []*x509.Certificate{root.cert}, nil, "cluster.local"); err == nil {
+		t.Error("expected error for missing rules")
+	}
+	bad := IdentityRule{Pattern: "(", Namespace: "vms", ServiceAccount: "sa"}
+	if _, err := NewX509SVIDAuthenticator("vm.example.com", []*x509.Certificate{root.cert}, []IdentityRule{bad}, "cluster.local"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorize
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"istio.io/istio/security/pkg/server/ca/authenticate"
+)
+
+// Request carries the attributes of an authenticated certificate signing request
+// that an Authorizer may base its decision on.
+type Request struct {
+	// Caller is the authenticated caller. It is never nil.
+	Caller *authenticate.Caller
+	// TTL is the validity duration requested by the caller. A non-positive value
+	// means the CA default will be applied.
+	TTL time.Duration
+	// ForCA is true if the request is for an intermediate CA certificate.
+	ForCA bool
+	// PeerAddress is the address of the remote peer, if known.
+	PeerAddress string
+}
+
+// Authorizer decides whether an authenticated caller may be issued a certificate.
+// Authorizers are evaluated by the CA server after authentication succeeds.
+type Authorizer interface {
+	// Authorize returns nil if the request is allowed. A denial should be returned
+	// as a *Denial so that the reason can be audited.
+	Authorize(ctx context.Context, req *Request) error
+	AuthorizerType() string
+}
+
+// Denial is the error returned by an Authorizer that rejects a request.
+type Denial struct {
+	// Rule is a short, stable name for the rule that rejected the request. It is
+	// used as a metric label and must have low cardinality.
+	Rule string
+	// Identity is the caller identity that the rule matched, if any.
+	Identity string
+	// Reason is a human readable explanation of the denial.
+	Reason string
+}
+
+func (d *Denial) Error() string {
+	if d.Identity == "" {
+		return fmt.Sprintf("denied by rule %q: %s", d.Rule, d.Reason)
+	}
+	return fmt.Sprintf("denied by rule %q for %q: %s", d.Rule, d.Identity, d.Reason)
+}
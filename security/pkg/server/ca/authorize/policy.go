@@ -0,0 +1,223 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/configmapwatcher"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/pkg/log"
+)
+
+const (
+	PolicyAuthorizerType = "PolicyAuthorizer"
+
+	// PolicyConfigMapKey is the key in the ConfigMap holding the policy YAML.
+	PolicyConfigMapKey = "policy"
+
+	RuleForCA                  = "for-ca"
+	RuleNamespaceNotAllowed    = "namespace-not-allowed"
+	RuleServiceAccountDenied   = "service-account-denied"
+	RuleTTLExceeded            = "ttl-exceeded"
+	RuleUnrecognizedIdentity   = "unrecognized-identity"
+	wildcard                   = "*"
+	serviceAccountKeySeparator = "/"
+)
+
+var authzLog = log.RegisterScope("caauthz", "CA authorization debugging", 0)
+
+// Policy is the user facing configuration of the PolicyAuthorizer. It is typically
+// stored as YAML in a ConfigMap.
+//
+// An empty Policy allows every authenticated request for a workload certificate. Requests for CA
+// certificates, whose CSR has the CA basic constraint, are only allowed if AllowCA is set.
+type Policy struct {
+	// AllowedNamespaces lists the namespaces whose workloads may be issued certificates.
+	// "*" or an empty list allows all namespaces.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// DeniedServiceAccounts lists service accounts, in "<namespace>/<name>" form, that may
+	// never be issued certificates. The name may be "*" to deny a whole namespace.
+	DeniedServiceAccounts []string `json:"deniedServiceAccounts,omitempty"`
+	// MaxTTL is the largest TTL that may be requested by any workload. Zero means no limit.
+	MaxTTL metav1.Duration `json:"maxTTL,omitempty"`
+	// NamespaceMaxTTL overrides MaxTTL for individual namespaces.
+	NamespaceMaxTTL map[string]metav1.Duration `json:"namespaceMaxTTL,omitempty"`
+	// AllowCA controls whether intermediate CA certificates may be issued.
+	AllowCA bool `json:"allowCA,omitempty"`
+}
+
+// ParsePolicy parses a Policy from YAML or JSON.
+func ParsePolicy(in []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(in, p); err != nil {
+		return nil, fmt.Errorf("failed to parse CA authorization policy: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks that the policy is well formed.
+func (p *Policy) Validate() error {
+	for _, sa := range p.DeniedServiceAccounts {
+		parts := strings.Split(sa, serviceAccountKeySeparator)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid service account %q, expected <namespace>/<name>", sa)
+		}
+	}
+	if p.MaxTTL.Duration < 0 {
+		return fmt.Errorf("invalid maxTTL %v", p.MaxTTL.Duration)
+	}
+	for ns, ttl := range p.NamespaceMaxTTL {
+		if ttl.Duration < 0 {
+			return fmt.Errorf("invalid maxTTL %v for namespace %s", ttl.Duration, ns)
+		}
+	}
+	return nil
+}
+
+// PolicyAuthorizer authorizes requests against a Policy that may be replaced at runtime.
+type PolicyAuthorizer struct {
+	mu     sync.RWMutex
+	policy *Policy
+}
+
+var _ Authorizer = &PolicyAuthorizer{}
+
+// NewPolicyAuthorizer creates a PolicyAuthorizer enforcing the given policy. A nil policy allows
+// all requests.
+func NewPolicyAuthorizer(p *Policy) *PolicyAuthorizer {
+	if p == nil {
+		p = &Policy{}
+	}
+	return &PolicyAuthorizer{policy: p}
+}
+
+// NewConfigMapAuthorizer creates a PolicyAuthorizer whose policy is read from the given key of a
+// ConfigMap and updated when the ConfigMap changes. If the ConfigMap does not exist, all requests
+// are allowed. If the ConfigMap content is invalid, the last valid policy is kept.
+func NewConfigMapAuthorizer(client kube.Client, namespace, name, key string, stop <-chan struct{}) *PolicyAuthorizer {
+	a := NewPolicyAuthorizer(nil)
+	c := configmapwatcher.NewController(client, namespace, name, func(cm *v1.ConfigMap) {
+		if cm == nil {
+			authzLog.Infof("CA authorization ConfigMap %s/%s not found, allowing all requests", namespace, name)
+			a.UpdatePolicy(nil)
+			return
+		}
+		p, err := ParsePolicy([]byte(cm.Data[key]))
+		if err != nil {
+			authzLog.Errorf("failed to load CA authorization policy from ConfigMap %s/%s: %v", namespace, name, err)
+			return
+		}
+		authzLog.Infof("loaded CA authorization policy from ConfigMap %s/%s", namespace, name)
+		a.UpdatePolicy(p)
+	})
+	go c.Run(stop)
+	return a
+}
+
+// UpdatePolicy replaces the policy enforced by the authorizer.
+func (a *PolicyAuthorizer) UpdatePolicy(p *Policy) {
+	if p == nil {
+		p = &Policy{}
+	}
+	a.mu.Lock()
+	a.policy = p
+	a.mu.Unlock()
+}
+
+func (a *PolicyAuthorizer) AuthorizerType() string {
+	return PolicyAuthorizerType
+}
+
+// Authorize checks every identity of the caller against the policy.
+func (a *PolicyAuthorizer) Authorize(_ context.Context, req *Request) error {
+	a.mu.RLock()
+	p := a.policy
+	a.mu.RUnlock()
+
+	if req.ForCA && !p.AllowCA {
+		return &Denial{Rule: RuleForCA, Reason: "issuing CA certificates is not allowed"}
+	}
+	for _, id := range req.Caller.Identities {
+		if err := p.authorizeIdentity(id, req.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Policy) authorizeIdentity(id string, ttl time.Duration) error {
+	if len(p.AllowedNamespaces) == 0 && len(p.DeniedServiceAccounts) == 0 &&
+		p.MaxTTL.Duration == 0 && len(p.NamespaceMaxTTL) == 0 {
+		return nil
+	}
+	identity, err := spiffe.ParseIdentity(id)
+	if err != nil {
+		return &Denial{Rule: RuleUnrecognizedIdentity, Identity: id, Reason: err.Error()}
+	}
+	if !p.namespaceAllowed(identity.Namespace) {
+		return &Denial{
+			Rule:     RuleNamespaceNotAllowed,
+			Identity: id,
+			Reason:   fmt.Sprintf("namespace %s is not allowed to request certificates", identity.Namespace),
+		}
+	}
+	for _, sa := range p.DeniedServiceAccounts {
+		parts := strings.Split(sa, serviceAccountKeySeparator)
+		if parts[0] == identity.Namespace && (parts[1] == wildcard || parts[1] == identity.ServiceAccount) {
+			return &Denial{
+				Rule:     RuleServiceAccountDenied,
+				Identity: id,
+				Reason:   fmt.Sprintf("service account %s/%s is denied", identity.Namespace, identity.ServiceAccount),
+			}
+		}
+	}
+	maxTTL := p.MaxTTL.Duration
+	if nsTTL, f := p.NamespaceMaxTTL[identity.Namespace]; f {
+		maxTTL = nsTTL.Duration
+	}
+	if maxTTL > 0 && ttl > maxTTL {
+		return &Denial{
+			Rule:     RuleTTLExceeded,
+			Identity: id,
+			Reason:   fmt.Sprintf("requested TTL %v exceeds the maximum %v", ttl, maxTTL),
+		}
+	}
+	return nil
+}
+
+func (p *Policy) namespaceAllowed(ns string) bool {
+	if len(p.AllowedNamespaces) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedNamespaces {
+		if allowed == wildcard || allowed == ns {
+			return true
+		}
+	}
+	return false
+}
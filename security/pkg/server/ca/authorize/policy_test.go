@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorize
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"istio.io/istio/security/pkg/server/ca/authenticate"
+)
+
+const testPolicy = `
+allowedNamespaces: [foo, bar]
+deniedServiceAccounts: [foo/admin, bar/*]
+maxTTL: 24h
+namespaceMaxTTL:
+  foo: 1h
+`
+
+func TestParsePolicy(t *testing.T) {
+	cases := map[string]struct {
+		in      string
+		wantErr bool
+	}{
+		"valid":                  {in: testPolicy},
+		"empty":                  {in: ""},
+		"unknown field":          {in: "allowNamespaces: [foo]", wantErr: true},
+		"invalid account":        {in: "deniedServiceAccounts: [foo]", wantErr: true},
+		"negative ttl":           {in: "maxTTL: -1h", wantErr: true},
+		"negative namespace ttl": {in: "namespaceMaxTTL: {foo: -1h}", wantErr: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(tc.in))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got err %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyAuthorizer(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		policy   *Policy
+		identity string
+		ttl      time.Duration
+		forCA    bool
+		rule     string
+	}{
+		"empty policy":       {identity: "spiffe://cluster.local/ns/any/sa/any"},
+		"empty policy ca":    {identity: "spiffe://cluster.local/ns/any/sa/any", forCA: true, rule: RuleForCA},
+		"allowed":            {policy: policy, identity: "spiffe://cluster.local/ns/foo/sa/default", ttl: time.Minute},
+		"default ttl":        {policy: policy, identity: "spiffe://cluster.local/ns/foo/sa/default"},
+		"namespace":          {policy: policy, identity: "spiffe://cluster.local/ns/baz/sa/default", rule: RuleNamespaceNotAllowed},
+		"service account":    {policy: policy, identity: "spiffe://cluster.local/ns/foo/sa/admin", rule: RuleServiceAccountDenied},
+		"wildcard account":   {policy: policy, identity: "spiffe://cluster.local/ns/bar/sa/default", rule: RuleServiceAccountDenied},
+		"namespace ttl":      {policy: policy, identity: "spiffe://cluster.local/ns/foo/sa/default", ttl: 2 * time.Hour, rule: RuleTTLExceeded},
+		"non spiffe":         {policy: policy, identity: "foo.example.com", rule: RuleUnrecognizedIdentity},
+		"non spiffe allowed": {identity: "foo.example.com"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := NewPolicyAuthorizer(tc.policy)
+			err := a.Authorize(context.Background(), &Request{
+				Caller: &authenticate.Caller{Identities: []string{tc.identity}},
+				TTL:    tc.ttl,
+				ForCA:  tc.forCA,
+			})
+			if tc.rule == "" {
+				if err != nil {
+					t.Fatalf("unexpected denial: %v", err)
+				}
+				return
+			}
+			d, ok := err.(*Denial)
+			if !ok {
+				t.Fatalf("expected denial by %s, got %v", tc.rule, err)
+			}
+			if d.Rule != tc.rule {
+				t.Fatalf("expected denial by %s, got %s", tc.rule, d.Rule)
+			}
+		})
+	}
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	a := NewPolicyAuthorizer(&Policy{AllowedNamespaces: []string{"foo"}})
+	req := &Request{Caller: &authenticate.Caller{Identities: []string{"spiffe://cluster.local/ns/bar/sa/default"}}}
+	if err := a.Authorize(context.Background(), req); err == nil {
+		t.Fatal("expected denial")
+	}
+	a.UpdatePolicy(nil)
+	if err := a.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("unexpected denial after update: %v", err)
+	}
+}
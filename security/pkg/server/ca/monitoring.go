@@ -20,10 +20,12 @@ import (
 
 const (
 	errorlabel = "error"
+	rulelabel  = "rule"
 )
 
 var (
 	errorTag = monitoring.MustCreateLabel(errorlabel)
+	ruleTag  = monitoring.MustCreateLabel(rulelabel)
 
 	csrCounts = monitoring.NewSum(
 		"citadel_server_csr_count",
@@ -35,6 +37,12 @@ var (
 		"The number of authentication failures.",
 	)
 
+	authzErrorCounts = monitoring.NewSum(
+		"citadel_server_authorization_failure_count",
+		"The number of authorization failures, by the rule that denied the request.",
+		monitoring.WithLabels(ruleTag),
+	)
+
 	csrParsingErrorCounts = monitoring.NewSum(
 		"citadel_server_csr_parsing_err_count",
 		"The number of errors occurred when parsing the CSR.",
@@ -72,6 +80,7 @@ func init() {
 	monitoring.MustRegister(
 		csrCounts,
 		authnErrorCounts,
+		authzErrorCounts,
 		csrParsingErrorCounts,
 		idExtractionErrorCounts,
 		certSignErrorCounts,
@@ -89,6 +98,7 @@ type monitoringMetrics struct {
 	CSRError          monitoring.Metric
 	IDExtractionError monitoring.Metric
 	certSignErrors    monitoring.Metric
+	authzErrors       monitoring.Metric
 }
 
 // newMonitoringMetrics creates a new monitoringMetrics.
@@ -100,9 +110,14 @@ func newMonitoringMetrics() monitoringMetrics {
 		CSRError:          csrParsingErrorCounts,
 		IDExtractionError: idExtractionErrorCounts,
 		certSignErrors:    certSignErrorCounts,
+		authzErrors:       authzErrorCounts,
 	}
 }
 
 func (m *monitoringMetrics) GetCertSignError(err string) monitoring.Metric {
 	return m.certSignErrors.With(errorTag.Value(err))
 }
+
+func (m *monitoringMetrics) GetAuthzError(rule string) monitoring.Metric {
+	return m.authzErrors.With(ruleTag.Value(rule))
+}
@@ -27,7 +27,9 @@ import (
 	pb "istio.io/api/security/v1alpha1"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/istio/security/pkg/server/ca/audit"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/istio/security/pkg/server/ca/authorize"
 	"istio.io/pkg/log"
 )
 
@@ -49,6 +51,8 @@ type CertificateAuthority interface {
 type Server struct {
 	monitoring     monitoringMetrics
 	Authenticators []authenticate.Authenticator
+	Authorizers    []authorize.Authorizer
+	Auditor        *audit.Auditor
 	ca             CertificateAuthority
 	serverCertTTL  time.Duration
 }
@@ -77,16 +81,27 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
 	}
 
-	// TODO: Call authorizer.
+	ttl := time.Duration(request.ValidityDuration) * time.Second
+	// Without authorizers, every CSR is signed for a workload certificate. CA certificates are only
+	// issued when an authorizer explicitly allows them.
+	forCA := len(s.Authorizers) > 0 && isCARequest(request.Csr)
+	if err := s.authorize(ctx, &authorize.Request{
+		Caller:      caller,
+		TTL:         ttl,
+		ForCA:       forCA,
+		PeerAddress: getConnectionAddress(ctx),
+	}); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 
 	_, _, certChainBytes, rootCertBytes := s.ca.GetCAKeyCertBundle().GetAll()
-	cert, signErr := s.ca.Sign(
-		[]byte(request.Csr), caller.Identities, time.Duration(request.ValidityDuration)*time.Second, false)
+	cert, signErr := s.ca.Sign([]byte(request.Csr), caller.Identities, ttl, forCA)
 	if signErr != nil {
 		serverCaLog.Errorf("CSR signing error (%v)", signErr.Error())
 		s.monitoring.GetCertSignError(signErr.(*caerror.Error).ErrorType()).Increment()
 		return nil, status.Errorf(signErr.(*caerror.Error).HTTPErrorCode(), "CSR signing error (%v)", signErr.(*caerror.Error))
 	}
+	s.recordIssued(ctx, caller, cert, ttl)
 	respCertChain := []string{string(cert)}
 	if len(certChainBytes) != 0 {
 		respCertChain = append(respCertChain, string(certChainBytes))
@@ -156,3 +171,54 @@ func (s *Server) authenticate(ctx context.Context) *authenticate.Caller {
 	serverCaLog.Warnf("Authentication failed for %v: %s", getConnectionAddress(ctx), errMsg)
 	return nil
 }
+
+// isCARequest returns whether the CSR asks for a CA certificate. A CSR that can not be parsed is
+// rejected when it is signed, but one whose basic constraints can not be parsed is treated as
+// asking for a CA certificate, so that it is only signed if CA certificates are allowed.
+func isCARequest(csrPEM string) bool {
+	csr, err := util.ParsePemEncodedCSR([]byte(csrPEM))
+	if err != nil {
+		return false
+	}
+	isCA, err := util.IsCACertificateRequest(csr)
+	return isCA || err != nil
+}
+
+// authorize evaluates the configured authorizers in order and returns the first denial.
+// Every denial is recorded in the audit log and counted by rule.
+func (s *Server) authorize(ctx context.Context, req *authorize.Request) error {
+	for _, authz := range s.Authorizers {
+		err := authz.Authorize(ctx, req)
+		if err == nil {
+			continue
+		}
+		rule := "unknown"
+		if d, ok := err.(*authorize.Denial); ok {
+			rule = d.Rule
+		}
+		s.monitoring.GetAuthzError(rule).Increment()
+		serverCaLog.WithLabels(
+			"audit", "csr-denied",
+			"authorizer", authz.AuthorizerType(),
+			"rule", rule,
+			"identities", req.Caller.Identities,
+			"ttl", req.TTL.String(),
+			"forCA", req.ForCA,
+			"peer", req.PeerAddress,
+		).Warnf("CSR authorization failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// recordIssued writes the audit record of an issued certificate, if auditing is enabled.
+func (s *Server) recordIssued(ctx context.Context, caller *authenticate.Caller, cert []byte, ttl time.Duration) {
+	if s.Auditor == nil {
+		return
+	}
+	r, err := audit.NewRecord(cert, caller.Identities, caller.AuthSource.String(), getConnectionAddress(ctx), ttl)
+	if err != nil {
+		serverCaLog.Warnf("incomplete audit record for certificate issued to %v: %v", caller.Identities, err)
+	}
+	s.Auditor.Record(r)
+}
@@ -15,12 +15,18 @@
 package ca
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
@@ -33,7 +39,9 @@ import (
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
 	mockutil "istio.io/istio/security/pkg/pki/util/mock"
+	"istio.io/istio/security/pkg/server/ca/audit"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/istio/security/pkg/server/ca/authorize"
 )
 
 type mockAuthenticator struct {
@@ -185,9 +193,12 @@ func TestCreateCertificateE2EUsingClientCertAuthenticator(t *testing.T) {
 func TestCreateCertificate(t *testing.T) {
 	testCases := map[string]struct {
 		authenticators []authenticate.Authenticator
+		authorizers    []authorize.Authorizer
 		ca             CertificateAuthority
+		csr            string
 		certChain      []string
 		code           codes.Code
+		forCA          bool
 	}{
 		"No authenticator": {
 			authenticators: nil,
@@ -221,6 +232,67 @@ func TestCreateCertificate(t *testing.T) {
 			ca:             &mockca.FakeCA{SignErr: caerror.NewError(caerror.CertGenError, fmt.Errorf("cannot sign"))},
 			code:           codes.Internal,
 		},
+		"Denied by authorizer": {
+			authenticators: []authenticate.Authenticator{&mockAuthenticator{
+				identities: []string{"spiffe://cluster.local/ns/foo/sa/bar"},
+			}},
+			authorizers: []authorize.Authorizer{authorize.NewPolicyAuthorizer(&authorize.Policy{
+				AllowedNamespaces: []string{"baz"},
+			})},
+			ca:   &mockca.FakeCA{SignedCert: []byte("cert"), KeyCertBundle: &mockutil.FakeKeyCertBundle{}},
+			code: codes.PermissionDenied,
+		},
+		"Allowed by authorizer": {
+			authenticators: []authenticate.Authenticator{&mockAuthenticator{
+				identities: []string{"spiffe://cluster.local/ns/foo/sa/bar"},
+			}},
+			authorizers: []authorize.Authorizer{authorize.NewPolicyAuthorizer(&authorize.Policy{
+				AllowedNamespaces: []string{"foo"},
+			})},
+			ca: &mockca.FakeCA{
+				SignedCert: []byte("cert"),
+				KeyCertBundle: &mockutil.FakeKeyCertBundle{
+					CertChainBytes: []byte("cert_chain"),
+					RootCertBytes:  []byte("root_cert"),
+				},
+			},
+			certChain: []string{"cert", "cert_chain", "root_cert"},
+			code:      codes.OK,
+		},
+		"CA CSR without authorizer": {
+			authenticators: []authenticate.Authenticator{&mockAuthenticator{}},
+			ca:             &mockca.FakeCA{SignedCert: []byte("cert"), KeyCertBundle: &mockutil.FakeKeyCertBundle{}},
+			csr:            generateCSR(t, true),
+			certChain:      []string{"cert", ""},
+			code:           codes.OK,
+		},
+		"CA CSR denied by authorizer": {
+			authenticators: []authenticate.Authenticator{&mockAuthenticator{
+				identities: []string{"spiffe://cluster.local/ns/foo/sa/bar"},
+			}},
+			authorizers: []authorize.Authorizer{authorize.NewPolicyAuthorizer(&authorize.Policy{})},
+			ca:          &mockca.FakeCA{SignedCert: []byte("cert"), KeyCertBundle: &mockutil.FakeKeyCertBundle{}},
+			csr:         generateCSR(t, true),
+			code:        codes.PermissionDenied,
+		},
+		"CA CSR allowed by authorizer": {
+			authenticators: []authenticate.Authenticator{&mockAuthenticator{
+				identities: []string{"spiffe://cluster.local/ns/foo/sa/bar"},
+			}},
+			authorizers: []authorize.Authorizer{authorize.NewPolicyAuthorizer(&authorize.Policy{AllowCA: true})},
+			ca:          &mockca.FakeCA{SignedCert: []byte("cert"), KeyCertBundle: &mockutil.FakeKeyCertBundle{}},
+			csr:         generateCSR(t, true),
+			certChain:   []string{"cert", ""},
+			code:        codes.OK,
+			forCA:       true,
+		},
+		"Workload CSR": {
+			authenticators: []authenticate.Authenticator{&mockAuthenticator{}},
+			ca:             &mockca.FakeCA{SignedCert: []byte("cert"), KeyCertBundle: &mockutil.FakeKeyCertBundle{}},
+			csr:            generateCSR(t, false),
+			certChain:      []string{"cert", ""},
+			code:           codes.OK,
+		},
 		"Successful signing": {
 			authenticators: []authenticate.Authenticator{&mockAuthenticator{}},
 			ca: &mockca.FakeCA{
@@ -239,9 +311,14 @@ func TestCreateCertificate(t *testing.T) {
 		server := &Server{
 			ca:             c.ca,
 			Authenticators: c.authenticators,
+			Authorizers:    c.authorizers,
 			monitoring:     newMonitoringMetrics(),
 		}
-		request := &pb.IstioCertificateRequest{Csr: "dumb CSR"}
+		csr := c.csr
+		if csr == "" {
+			csr = "dumb CSR"
+		}
+		request := &pb.IstioCertificateRequest{Csr: csr}
 
 		response, err := server.CreateCertificate(context.Background(), request)
 		s, _ := status.FromError(err)
@@ -249,6 +326,9 @@ func TestCreateCertificate(t *testing.T) {
 		if c.code != code {
 			t.Errorf("Case %s: expecting code to be (%d) but got (%d): %s", id, c.code, code, s.Message())
 		} else if c.code == codes.OK {
+			if forCA := c.ca.(*mockca.FakeCA).ReceivedForCA; forCA != c.forCA {
+				t.Errorf("Case %s: expecting forCA to be %v but got %v", id, c.forCA, forCA)
+			}
 			if len(response.CertChain) != len(c.certChain) {
 				t.Errorf("Case %s: expecting cert chain length to be (%d) but got (%d)",
 					id, len(c.certChain), len(response.CertChain))
@@ -263,3 +343,151 @@ func TestCreateCertificate(t *testing.T) {
 		}
 	}
 }
+
+// generateCSR returns a PEM encoded CSR, with the CA basic constraint if isCA is set.
+func generateCSR(t *testing.T, isCA bool) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{Subject: pkix.Name{Organization: []string{"istio"}}}
+	if isCA {
+		constraints, err := asn1.Marshal(struct {
+			IsCA       bool `asn1:"optional"`
+			MaxPathLen int  `asn1:"optional,default:-1"`
+		}{IsCA: true, MaxPathLen: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 19}, Critical: true, Value: constraints}}
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+// TestCreateCertificateExternalSVID checks that a certificate of an external trust domain is only
+// authenticated once mapped to a mesh identity, rather than accepted as is by the client certificate
+// authenticator, although the TLS server trusts its root.
+func TestCreateCertificateExternalSVID(t *testing.T) {
+	rootPEM, rootKeyPEM, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:          "vm.example.com",
+		TTL:          time.Hour,
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := util.ParsePemEncodedCertificate(rootPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootKey, err := util.ParsePemEncodedKey(rootKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(id string) *x509.Certificate {
+		certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+			Host:       id,
+			TTL:        time.Hour,
+			SignerCert: root,
+			SignerPriv: rootKey,
+			RSAKeySize: 2048,
+			IsClient:   true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := util.ParsePemEncodedCertificate(certPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	x509SVID, err := authenticate.NewX509SVIDAuthenticator("vm.example.com", []*x509.Certificate{root},
+		[]authenticate.IdentityRule{{
+			Pattern:        "spiffe://vm.example.com/ns/([^/]+)/sa/([^/]+)",
+			Namespace:      "$1",
+			ServiceAccount: "$2",
+		}}, "cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		code     codes.Code
+		identity string
+	}{
+		{
+			name:     "mapped identity",
+			id:       "spiffe://vm.example.com/ns/vms/sa/db",
+			code:     codes.OK,
+			identity: "spiffe://cluster.local/ns/vms/sa/db",
+		},
+		{
+			name: "unmapped identity",
+			id:   "spiffe://vm.example.com/host/vm-1",
+			code: codes.Unauthenticated,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := &mockca.FakeCA{SignedCert: []byte("cert"), KeyCertBundle: &mockutil.FakeKeyCertBundle{}}
+			server := &Server{
+				ca: ca,
+				Authenticators: []authenticate.Authenticator{
+					x509SVID,
+					&authenticate.ClientCertAuthenticator{ExternalTrustDomains: []string{"vm.example.com"}},
+				},
+				monitoring: newMonitoringMetrics(),
+			}
+			// The TLS server trusts the root of the external trust domain, so the chain is verified.
+			leaf := issue(tc.id)
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)}, AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{leaf},
+					VerifiedChains:   [][]*x509.Certificate{{leaf, root}},
+				},
+			}})
+			_, err := server.CreateCertificate(ctx, &pb.IstioCertificateRequest{Csr: "dumb CSR"})
+			if code := status.Code(err); code != tc.code {
+				t.Fatalf("got code %v, want %v: %v", code, tc.code, err)
+			}
+			if tc.code == codes.OK && (len(ca.ReceivedIDs) != 1 || ca.ReceivedIDs[0] != tc.identity) {
+				t.Fatalf("got identities %v, want %s", ca.ReceivedIDs, tc.identity)
+			}
+		})
+	}
+}
+
+func TestCreateCertificateAudit(t *testing.T) {
+	auditor := audit.NewAuditor(10, 10)
+	server := &Server{
+		ca: &mockca.FakeCA{
+			SignedCert:    []byte("cert"),
+			KeyCertBundle: &mockutil.FakeKeyCertBundle{},
+		},
+		Authenticators: []authenticate.Authenticator{&mockAuthenticator{identities: []string{"id"}}},
+		Auditor:        auditor,
+		monitoring:     newMonitoringMetrics(),
+	}
+	request := &pb.IstioCertificateRequest{Csr: "dumb CSR", ValidityDuration: 60}
+	if _, err := server.CreateCertificate(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	records := auditor.Recent(audit.Query{})
+	if len(records) != 1 {
+		t.Fatalf("expected 1 audit record, got %d", len(records))
+	}
+	if records[0].CallerIdentities[0] != "id" || records[0].TTL != "1m0s" {
+		t.Errorf("unexpected audit record %+v", records[0])
+	}
+}
@@ -49,6 +49,30 @@ func IsJwtExpired(token string, now time.Time) (bool, error) {
 
 }
 
+// GetExp returns the claim `exp` from the token, without validating it.
+// Returns the zero time if the token has no expiration.
+func GetExp(token string) (time.Time, error) {
+	claims, err := parseJwtClaims(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch exp := claims["exp"].(type) {
+	case nil:
+		return time.Time{}, nil
+	case float64:
+		return time.Unix(int64(exp), 0), nil
+	case json.Number:
+		v, err := exp.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(v, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid exp claim %v in the token", exp)
+	}
+}
+
 // GetAud returns the claim `aud` from the token. Returns nil if not found.
 func GetAud(token string) ([]string, error) {
 	claims, err := parseJwtClaims(token)
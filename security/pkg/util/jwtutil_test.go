@@ -107,6 +107,39 @@ func TestIsJwtExpired(t *testing.T) {
 	}
 }
 
+func TestGetExp(t *testing.T) {
+	testCases := map[string]struct {
+		jwt    string
+		exp    time.Time
+		expErr bool
+	}{
+		"JWT with expiration": {
+			jwt: thirdPartyJwt,
+			exp: time.Unix(1586106834, 0),
+		},
+		"JWT without expiration": {
+			jwt: firstPartyJwt,
+			exp: time.Time{},
+		},
+		"Invalid JWT": {
+			jwt:    "invalid-token",
+			expErr: true,
+		},
+	}
+
+	for id, tc := range testCases {
+		t.Run(id, func(t *testing.T) {
+			exp, err := GetExp(tc.jwt)
+			if tc.expErr != (err != nil) {
+				t.Fatalf("GetExp() got error %v, expected error: %v", err, tc.expErr)
+			}
+			if !exp.Equal(tc.exp) {
+				t.Errorf("GetExp() got %v, expected %v", exp, tc.exp)
+			}
+		})
+	}
+}
+
 func TestGetAud(t *testing.T) {
 	testCases := map[string]struct {
 		jwt string
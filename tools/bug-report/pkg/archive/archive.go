@@ -17,7 +17,9 @@ package archive
 import (
 	"archive/tar"
 	"compress/gzip"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -106,6 +108,69 @@ func Create(srcDir, outPath string) error {
 	})
 }
 
+// Read returns the files of a bug report, keyed by their path relative to the bug report root, such as
+// cluster/events. path is either an archive written by Create, or a directory it was extracted to.
+func Read(path string) (map[string][]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	if fi.IsDir() {
+		err = filepath.Walk(path, func(file string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			files[archivePath(rel)] = b
+			return nil
+		})
+		return files, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a bug report archive or directory: %v", path, err)
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[archivePath(header.Name)] = b
+	}
+}
+
+// archivePath returns name relative to the bug report root, with forward slashes.
+func archivePath(name string) string {
+	name = filepath.ToSlash(name)
+	return strings.TrimPrefix(name, bugReportSubdir+"/")
+}
+
 func getRootDir(rootDir string) string {
 	if rootDir != "" {
 		return rootDir
@@ -46,6 +46,7 @@ import (
 	"istio.io/istio/tools/bug-report/pkg/kubeclient"
 	"istio.io/istio/tools/bug-report/pkg/kubectlcmd"
 	"istio.io/istio/tools/bug-report/pkg/processlog"
+	"istio.io/istio/tools/bug-report/pkg/redact"
 	"istio.io/pkg/log"
 	"istio.io/pkg/version"
 )
@@ -59,6 +60,15 @@ var (
 	bugReportDefaultIstioNamespace = "istio-system"
 	bugReportDefaultInclude        = []string{""}
 	bugReportDefaultExclude        = []string{strings.Join(analyzer_util.SystemNamespaces, ", ")}
+	bugReportDefaultRedactions     = []string{redactSecrets, redactHeaders}
+	// bugReportDefaultRedactedHeaders are the headers that carry credentials.
+	bugReportDefaultRedactedHeaders = []string{"authorization", "proxy-authorization", "cookie", "set-cookie"}
+)
+
+const (
+	redactSecrets = "secrets"
+	redactHeaders = "headers"
+	redactIPs     = "ips"
 )
 
 // Cmd returns a cobra command for bug-report.
@@ -85,6 +95,7 @@ e.g.
 		},
 	}
 	rootCmd.AddCommand(version.CobraCommand())
+	rootCmd.AddCommand(inspectCmd())
 	addFlags(rootCmd, gConfig)
 
 	return rootCmd
@@ -99,6 +110,8 @@ var (
 	// Aggregated errors for all fetch operations.
 	gErrors util.Errors
 	lock    = sync.RWMutex{}
+	// gRedactor removes sensitive data from every file written to the archive.
+	gRedactor = &redact.Redactor{}
 )
 
 func runBugReportCommand(_ *cobra.Command, logOpts *log.Options) error {
@@ -115,6 +128,10 @@ func runBugReportCommand(_ *cobra.Command, logOpts *log.Options) error {
 		return err
 	}
 
+	if gRedactor, err = redact.New(config.Redaction); err != nil {
+		return err
+	}
+
 	common.LogAndPrintf("\nTarget cluster context: %s\n", clusterCtxStr)
 	common.LogAndPrintf("Running with the following config: \n\n%s\n\n", config)
 
@@ -271,6 +288,7 @@ func gatherInfo(client kube.ExtendedClient, config *config.BugReportConfig, reso
 	getFromCluster(content.GetClusterInfo, params, clusterDir, &mandatoryWg)
 	getFromCluster(content.GetSecrets, params.SetVerbose(config.FullSecrets), clusterDir, &mandatoryWg)
 	getFromCluster(content.GetDescribePods, params.SetIstioNamespace(config.IstioNamespace), clusterDir, &mandatoryWg)
+	getFromCluster(content.GetProxyStatus, params.SetIstioNamespace(config.IstioNamespace), clusterDir, &mandatoryWg)
 
 	// optionalWg is subject to timer.
 	var optionalWg sync.WaitGroup
@@ -391,7 +409,7 @@ func runAnalyze(config *config.BugReportConfig, resources *cluster2.Resources, p
 			continue
 		}
 		common.LogAndPrintf("Running istio analyze on namespace %s.\n", ns)
-		out, err := content.GetAnalyze(params.SetIstioNamespace(config.IstioNamespace))
+		out, err := content.GetAnalyze(params.SetNamespace(ns).SetIstioNamespace(config.IstioNamespace))
 		if err != nil {
 			log.Error(err.Error())
 			continue
@@ -412,6 +430,7 @@ func writeFile(path, text string) {
 		return
 	}
 	mkdirOrExit(path)
+	text = gRedactor.Redact(path, text)
 	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
 		log.Errorf(err.Error())
 	}
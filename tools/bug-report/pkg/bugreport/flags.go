@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
@@ -31,6 +32,7 @@ import (
 var (
 	startTime, endTime, configFile, tempDir string
 	included, excluded                      []string
+	redactions, redactedHeaders             []string
 	commandTimeout, since                   time.Duration
 	gConfig                                 = &config2.BugReportConfig{}
 )
@@ -88,6 +90,13 @@ func addFlags(cmd *cobra.Command, args *config2.BugReportConfig) {
 		"List of comma separated glob patters to match against log error strings. "+
 			"Any error matching these patters is ignored when calculating the log importance heuristic.")
 
+	// redaction
+	cmd.PersistentFlags().StringSliceVar(&redactions, "redact", bugReportDefaultRedactions,
+		"List of comma separated kinds of sensitive data to remove from the archive: secrets (private keys, bearer "+
+			"tokens and secret data), headers (the values of the headers in --redact-headers) and ips.")
+	cmd.PersistentFlags().StringSliceVar(&redactedHeaders, "redact-headers", bugReportDefaultRedactedHeaders,
+		"List of comma separated HTTP header names whose values are removed when headers are redacted.")
+
 	// output/working dir
 	cmd.PersistentFlags().StringVar(&tempDir, "dir", "",
 		"Set a specific directory for temporary artifact storage.")
@@ -130,9 +139,32 @@ func parseConfig() (*config2.BugReportConfig, error) {
 		}
 		gConfig.Exclude = append(gConfig.Exclude, ess)
 	}
+	redaction, err := parseRedaction(redactions, redactedHeaders)
+	if err != nil {
+		return nil, err
+	}
+	gConfig.Redaction = redaction
 	return overlayConfig(fileConfig, gConfig)
 }
 
+func parseRedaction(redactions, headers []string) (*config2.RedactionPolicy, error) {
+	out := &config2.RedactionPolicy{}
+	for _, r := range redactions {
+		switch strings.TrimSpace(r) {
+		case "":
+		case redactSecrets:
+			out.Secrets = true
+		case redactHeaders:
+			out.Headers = headers
+		case redactIPs:
+			out.IPs = true
+		default:
+			return nil, fmt.Errorf("unknown redaction %q, must be one of %s, %s or %s", r, redactSecrets, redactHeaders, redactIPs)
+		}
+	}
+	return out, nil
+}
+
 func parseTimes(config *config2.BugReportConfig, startTime, endTime string) error {
 	config.EndTime = time.Now()
 	if endTime != "" {
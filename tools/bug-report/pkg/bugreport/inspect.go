@@ -0,0 +1,263 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/tools/bug-report/pkg/archive"
+	"istio.io/istio/tools/bug-report/pkg/common"
+	"istio.io/istio/tools/bug-report/pkg/config"
+	"istio.io/istio/tools/bug-report/pkg/processlog"
+)
+
+// maxInspectItems is the maximum number of items listed for each problem found by inspect.
+const maxInspectItems = 10
+
+func inspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <archive>",
+		Short: "Summarizes the problems captured in a bug report archive, without access to the cluster.",
+		Long: `inspect reads a bug report archive, or the directory it was extracted to, and summarizes the problems
+it captured: configuration analysis messages, proxies which are out of sync with Istiod, Istiod push errors and
+the logs with the most errors.`,
+		Example: `  istioctl bug-report inspect bug-report.tgz`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := archive.Read(args[0])
+			if err != nil {
+				return err
+			}
+			return inspect(cmd.OutOrStdout(), files)
+		},
+	}
+}
+
+// analysisMessage is a message from the JSON output of istioctl analyze.
+type analysisMessage struct {
+	Code    string `json:"code"`
+	Level   string `json:"level"`
+	Origin  string `json:"origin"`
+	Message string `json:"message"`
+}
+
+// inspect writes a summary of the problems found in the files of a bug report to w.
+func inspect(w io.Writer, files map[string][]byte) error {
+	if len(files) == 0 {
+		return fmt.Errorf("the bug report is empty")
+	}
+	if versions, ok := files["versions"]; ok {
+		printSection(w, "Versions")
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(string(versions)))
+	}
+	if err := inspectAnalysis(w, files); err != nil {
+		return err
+	}
+	if err := inspectSync(w, files); err != nil {
+		return err
+	}
+	if err := inspectPushStatus(w, files); err != nil {
+		return err
+	}
+	inspectLogs(w, files)
+	return nil
+}
+
+func printSection(w io.Writer, title string) {
+	fmt.Fprintf(w, "%s\n%s\n", title, strings.Repeat("-", len(title)))
+}
+
+// filesMatching returns the names of the files matching the path.Match pattern, sorted.
+func filesMatching(files map[string][]byte, pattern string) []string {
+	var out []string
+	for name := range files {
+		if ok, _ := path.Match(pattern, name); ok {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func printTruncated(w io.Writer, lines []string) {
+	for i, l := range lines {
+		if i == maxInspectItems {
+			fmt.Fprintf(w, "  ... and %d more\n", len(lines)-maxInspectItems)
+			break
+		}
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+}
+
+func inspectAnalysis(w io.Writer, files map[string][]byte) error {
+	seen := map[string]bool{}
+	counts := map[string]int{}
+	var problems []analysisMessage
+	for _, name := range filesMatching(files, "analyze/*/*.json") {
+		var messages []analysisMessage
+		if err := json.Unmarshal(files[name], &messages); err != nil {
+			return fmt.Errorf("could not parse %s: %v", name, err)
+		}
+		for _, m := range messages {
+			key := m.Code + m.Origin + m.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			counts[m.Level]++
+			if m.Level == "Error" || m.Level == "Warning" {
+				problems = append(problems, m)
+			}
+		}
+	}
+	printSection(w, fmt.Sprintf("Configuration analysis: %d errors, %d warnings, %d info messages",
+		counts["Error"], counts["Warning"], counts["Info"]))
+	sort.SliceStable(problems, func(i, j int) bool {
+		// Errors before warnings.
+		return problems[i].Level < problems[j].Level
+	})
+	var lines []string
+	for _, m := range problems {
+		lines = append(lines, fmt.Sprintf("%s [%s] (%s) %s", m.Level, m.Code, m.Origin, m.Message))
+	}
+	printTruncated(w, lines)
+	fmt.Fprintln(w)
+	return nil
+}
+
+func inspectSync(w io.Writer, files map[string][]byte) error {
+	proxies := 0
+	var stale []string
+	for _, name := range filesMatching(files, "istio/*/*/debug/syncz") {
+		var statuses []xds.SyncStatus
+		if err := json.Unmarshal(files[name], &statuses); err != nil {
+			return fmt.Errorf("could not parse %s: %v", name, err)
+		}
+		istiod := strings.Split(name, "/")[2]
+		for _, s := range statuses {
+			proxies++
+			var types []string
+			for _, t := range []struct {
+				name        string
+				sent, acked string
+			}{
+				{"CDS", s.ClusterSent, s.ClusterAcked},
+				{"LDS", s.ListenerSent, s.ListenerAcked},
+				{"EDS", s.EndpointSent, s.EndpointAcked},
+				{"RDS", s.RouteSent, s.RouteAcked},
+			} {
+				if t.sent != "" && t.sent != t.acked {
+					types = append(types, t.name)
+				}
+			}
+			if len(types) > 0 {
+				stale = append(stale, fmt.Sprintf("%s: STALE %s (%s)", s.ProxyID, strings.Join(types, ", "), istiod))
+			}
+		}
+	}
+	printSection(w, fmt.Sprintf("Proxy sync status: %d of %d proxies out of sync", len(stale), proxies))
+	printTruncated(w, stale)
+	fmt.Fprintln(w)
+	return nil
+}
+
+func inspectPushStatus(w io.Writer, files map[string][]byte) error {
+	// Each Istiod reports the same conflicts, so they are merged by metric and key.
+	merged := map[string]map[string]model.ProxyPushStatus{}
+	for _, name := range filesMatching(files, "istio/*/*/debug/push_status") {
+		status := map[string]map[string]model.ProxyPushStatus{}
+		if err := json.Unmarshal(files[name], &status); err != nil {
+			return fmt.Errorf("could not parse %s: %v", name, err)
+		}
+		for metric, entries := range status {
+			if merged[metric] == nil {
+				merged[metric] = map[string]model.ProxyPushStatus{}
+			}
+			for k, v := range entries {
+				merged[metric][k] = v
+			}
+		}
+	}
+	metrics := make([]string, 0, len(merged))
+	for metric := range merged {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	printSection(w, fmt.Sprintf("Push status: %d kinds of problems", len(metrics)))
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "%s: %d\n", metric, len(merged[metric]))
+		keys := make([]string, 0, len(merged[metric]))
+		for k := range merged[metric] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var lines []string
+		for _, k := range keys {
+			s := merged[metric][k]
+			line := k
+			if s.Proxy != "" {
+				line += " on " + s.Proxy
+			}
+			if s.Message != "" {
+				line += ": " + s.Message
+			}
+			lines = append(lines, line)
+		}
+		printTruncated(w, lines)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func inspectLogs(w io.Writer, files map[string][]byte) {
+	type logStats struct {
+		name                     string
+		fatals, errors, warnings int
+	}
+	var withProblems []logStats
+	names := append(filesMatching(files, "istio/*/*/discovery.log"),
+		filesMatching(files, "proxies/*/*/"+common.ProxyContainerName+".log")...)
+	for _, name := range names {
+		fatals, errors, warnings := processlog.GetStats(&config.BugReportConfig{}, string(files[name])).Counts()
+		if fatals+errors+warnings > 0 {
+			withProblems = append(withProblems, logStats{name, fatals, errors, warnings})
+		}
+	}
+	sort.SliceStable(withProblems, func(i, j int) bool {
+		a, b := withProblems[i], withProblems[j]
+		if a.fatals != b.fatals {
+			return a.fatals > b.fatals
+		}
+		if a.errors != b.errors {
+			return a.errors > b.errors
+		}
+		return a.warnings > b.warnings
+	})
+	printSection(w, fmt.Sprintf("Logs: %d of %d logs have errors or warnings", len(withProblems), len(names)))
+	var lines []string
+	for _, l := range withProblems {
+		lines = append(lines, fmt.Sprintf("%s: %d fatal, %d errors, %d warnings", l.name, l.fatals, l.errors, l.warnings))
+	}
+	printTruncated(w, lines)
+}
@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"istio.io/istio/tools/bug-report/pkg/archive"
+)
+
+var inspectFiles = map[string]string{
+	"versions": "The following Istio control plane revisions/versions were found in the cluster:\nRevision default:\n1.8.0\n",
+	"analyze/default/default.json": `[
+	{"code": "IST0101", "level": "Error", "origin": "VirtualService reviews.default",
+	 "message": "Referenced host not found: \"reviews2\""},
+	{"code": "IST0102", "level": "Info", "origin": "Namespace default",
+	 "message": "The namespace is not enabled for Istio injection."}
+]`,
+	"analyze/bookinfo/bookinfo.json": `[
+	{"code": "IST0108", "level": "Warning", "origin": "Pod ratings-v1.bookinfo",
+	 "message": "Unknown annotation: sidecar.istio.io/foo"},
+	{"code": "IST0101", "level": "Error", "origin": "VirtualService reviews.default",
+	 "message": "Referenced host not found: \"reviews2\""}
+]`,
+	"istio/istio-system/istiod-1/debug/syncz": `[
+	{"proxy": "productpage-v1-1.default", "cluster_sent": "a", "cluster_acked": "a", "listener_sent": "b",
+	 "listener_acked": "b", "route_sent": "c", "route_acked": "c"},
+	{"proxy": "reviews-v1-1.default", "cluster_sent": "d", "cluster_acked": "c", "listener_sent": "e",
+	 "route_sent": "f", "route_acked": "f"}
+]`,
+	"istio/istio-system/istiod-1/debug/push_status": `{
+	"pilot_conflict_inbound_listener": {"reviews-v1-1.default": {"proxy": "reviews-v1-1.default",
+	 "message": "Conflicting inbound listener:9080."}}
+}`,
+	"istio/istio-system/istiod-1/discovery.log": "2020-06-29T23:37:27.285053Z\terror\tads\tpush failed\n" +
+		"2020-06-29T23:37:27.285054Z\twarn\tads\tslow push\n",
+	"proxies/default/productpage-v1-1/istio-proxy.log": "2020-06-29T23:37:27.285053Z\tinfo\tEnvoy proxy is ready\n",
+	"proxies/default/reviews-v1-1/istio-proxy.log": "2020-06-29T23:37:27.285053Z\twarn\tsds\tcert expiring\n",
+}
+
+const inspectOutput = `Versions
+--------
+The following Istio control plane revisions/versions were found in the cluster:
+Revision default:
+1.8.0
+
+Configuration analysis: 1 errors, 1 warnings, 1 info messages
+-------------------------------------------------------------
+  Error [IST0101] (VirtualService reviews.default) Referenced host not found: "reviews2"
+  Warning [IST0108] (Pod ratings-v1.bookinfo) Unknown annotation: sidecar.istio.io/foo
+
+Proxy sync status: 1 of 2 proxies out of sync
+---------------------------------------------
+  reviews-v1-1.default: STALE CDS, LDS (istiod-1)
+
+Push status: 1 kinds of problems
+--------------------------------
+pilot_conflict_inbound_listener: 1
+  reviews-v1-1.default on reviews-v1-1.default: Conflicting inbound listener:9080.
+
+Logs: 2 of 3 logs have errors or warnings
+-----------------------------------------
+  istio/istio-system/istiod-1/discovery.log: 0 fatal, 1 errors, 1 warnings
+  proxies/default/reviews-v1-1/istio-proxy.log: 0 fatal, 0 errors, 1 warnings
+`
+
+func TestInspect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bug-report-inspect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "report", "bug-report")
+	for name, content := range inspectFiles {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archivePath := filepath.Join(dir, "bug-report.tgz")
+	if err := archive.Create(filepath.Join(dir, "report"), archivePath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{archivePath, filepath.Join(dir, "report")} {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			files, err := archive.Read(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := inspect(&out, files); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != inspectOutput {
+				t.Errorf("got:\n%s\nwant:\n%s", got, inspectOutput)
+			}
+		})
+	}
+}
+
+func TestParseRedaction(t *testing.T) {
+	got, err := parseRedaction([]string{"secrets", "headers", "ips"}, []string{"authorization"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Secrets || !got.IPs || len(got.Headers) != 1 {
+		t.Errorf("unexpected redaction policy %+v", got)
+	}
+	if _, err := parseRedaction([]string{"passwords"}, nil); err == nil {
+		t.Errorf("expected an error for an unknown redaction")
+	}
+}
@@ -156,6 +156,44 @@ type BugReportConfig struct {
 	// IgnoredErrors are glob error patterns which are ignored when
 	// calculating the error heuristic for a log.
 	IgnoredErrors []string `json:"ignoredErrors,omitempty"`
+
+	// Redaction controls which sensitive data is removed from the captured
+	// files before they are archived.
+	Redaction *RedactionPolicy `json:"redaction,omitempty"`
+}
+
+// RedactionPolicy controls which sensitive data is removed from the archive.
+type RedactionPolicy struct {
+	// Secrets replaces private keys, bearer tokens and the data of secrets
+	// captured with FullSecrets.
+	Secrets bool `json:"secrets,omitempty"`
+	// Headers is a list of HTTP header names whose values are replaced,
+	// such as authorization or cookie. Matching is case insensitive.
+	Headers []string `json:"headers,omitempty"`
+	// IPs replaces IP addresses with a token that is the same for every
+	// occurrence of the address in the archive, but cannot be reversed.
+	// Loopback and unspecified addresses are kept.
+	IPs bool `json:"ips,omitempty"`
+}
+
+func (r *RedactionPolicy) String() string {
+	if r == nil {
+		return "none"
+	}
+	var out []string
+	if r.Secrets {
+		out = append(out, "secrets")
+	}
+	if len(r.Headers) > 0 {
+		out = append(out, fmt.Sprintf("headers (%s)", strings.Join(r.Headers, ",")))
+	}
+	if r.IPs {
+		out = append(out, "ips")
+	}
+	if len(out) == 0 {
+		return "none"
+	}
+	return strings.Join(out, ", ")
 }
 
 func (b *BugReportConfig) String() string {
@@ -178,6 +216,7 @@ func (b *BugReportConfig) String() string {
 	if b.Since != 0 {
 		out += fmt.Sprintf("since: %v\n", b.Since)
 	}
+	out += fmt.Sprintf("redaction: %s\n", b.Redaction)
 	return out
 }
 
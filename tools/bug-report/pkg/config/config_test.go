@@ -46,6 +46,11 @@ criticalErrors:
 ignoredErrors:
   - e3
   - e4
+redaction:
+  secrets: true
+  headers:
+  - authorization
+  ips: true
 `
 	wantTime, err := time.Parse(time.RFC3339, "2002-10-02T10:00:00-05:00")
 	if err != nil {
@@ -109,6 +114,11 @@ ignoredErrors:
 		Since:          Duration(time.Minute),
 		CriticalErrors: []string{"e1", "e2"},
 		IgnoredErrors:  []string{"e3", "e4"},
+		Redaction: &RedactionPolicy{
+			Secrets: true,
+			Headers: []string{"authorization"},
+			IPs:     true,
+		},
 	}
 
 	got := &BugReportConfig{}
@@ -15,6 +15,7 @@
 package content
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -24,6 +25,7 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/local"
 	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
 	"istio.io/istio/istioctl/pkg/util/formatting"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/kube"
@@ -246,9 +248,35 @@ func GetAnalyze(p *Params) (map[string]string, error) {
 		return nil, err
 	}
 	out[p.Namespace] = output
+	// The JSON output is read back by bug-report inspect.
+	jsonOutput, err := formatting.Print(outputMessages, formatting.JSONFormat, false)
+	if err != nil {
+		return nil, err
+	}
+	out[p.Namespace+".json"] = jsonOutput
 	return out, nil
 }
 
+// GetProxyStatus returns the output of istioctl proxy-status, from all Istiod instances.
+func GetProxyStatus(p *Params) (map[string]string, error) {
+	if p.IstioNamespace == "" {
+		return nil, fmt.Errorf("getProxyStatus requires the Istio namespace")
+	}
+	if p.DryRun {
+		return retMap("proxy-status", fmt.Sprintf("Dry run: would be running client.AllDiscoveryDo(%s, /debug/syncz)", p.IstioNamespace), nil)
+	}
+	statuses, err := p.Client.AllDiscoveryDo(context.TODO(), p.IstioNamespace, "/debug/syncz")
+	if err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	sw := pilot.StatusWriter{Writer: &sb}
+	if err := sw.PrintAll(statuses); err != nil {
+		return nil, err
+	}
+	return retMap("proxy-status", sb.String(), nil)
+}
+
 // GetNetfilter returns netfilter for the given container.
 /*func GetNetfilter(p *Params) (map[string]string, error) {
 	if p.Namespace == "" || p.Pod == "" {
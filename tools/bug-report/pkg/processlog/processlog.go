@@ -47,6 +47,19 @@ func (s *Stats) Importance() int {
 	return 1000*s.numFatals + 100*s.numErrors + 10*s.numWarnings
 }
 
+// Counts returns the number of fatal, error and warning entries in the log.
+func (s *Stats) Counts() (fatals, errors, warnings int) {
+	if s == nil {
+		return 0, 0, 0
+	}
+	return s.numFatals, s.numErrors, s.numWarnings
+}
+
+// GetStats returns statistics for all of logStr, which has already been processed.
+func GetStats(config *config.BugReportConfig, logStr string) *Stats {
+	return getStats(config, logStr)
+}
+
 // Process processes logStr based on the supplied config and returns the processed log along with statistics on it.
 func Process(config *config.BugReportConfig, logStr string) (string, *Stats) {
 	out := getTimeRange(logStr, config.StartTime, config.EndTime)
@@ -84,7 +97,7 @@ func getStats(config *config.BugReportConfig, logStr string) *Stats {
 		}
 		switch level {
 		case levelFatal, levelError, levelWarn:
-			if match.MatchesGlobs(text, config.IgnoredErrors) {
+			if len(config.IgnoredErrors) > 0 && match.MatchesGlobs(text, config.IgnoredErrors) {
 				continue
 			}
 			switch level {
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact removes sensitive data from the files captured by bug-report, before they are archived.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"istio.io/istio/tools/bug-report/pkg/config"
+)
+
+const (
+	redacted = "<redacted>"
+	// secretsFile is the name of the file holding the output of GetSecrets.
+	secretsFile = "secrets"
+)
+
+var (
+	privateKeyRegexp  = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+	bearerTokenRegexp = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	ipv4Regexp        = regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}`)
+	ipv6Regexp        = regexp.MustCompile(`[0-9a-fA-F]*:[0-9a-fA-F]*:[0-9a-fA-F:]*`)
+	yamlKeyRegexp     = regexp.MustCompile(`^(\s*)(?:- )?([A-Za-z0-9_.\-]+):(.*)$`)
+)
+
+// Redactor applies a config.RedactionPolicy to captured files.
+type Redactor struct {
+	policy  config.RedactionPolicy
+	headers []*regexp.Regexp
+	// salt keys the tokens that replace IP addresses, so that they cannot be reversed by hashing every address.
+	salt []byte
+}
+
+// New returns a Redactor for the given policy. A nil policy redacts nothing.
+func New(policy *config.RedactionPolicy) (*Redactor, error) {
+	r := &Redactor{}
+	if policy == nil {
+		return r, nil
+	}
+	r.policy = *policy
+	for _, h := range policy.Headers {
+		name := regexp.QuoteMeta(strings.TrimSpace(h))
+		if name == "" {
+			continue
+		}
+		r.headers = append(r.headers,
+			// Envoy debug logs, as in 'authorization', 'Bearer ...'
+			regexp.MustCompile(`(?i)('`+name+`',\s*')[^']*(')`),
+			// JSON, as in "authorization": "Bearer ..."
+			regexp.MustCompile(`(?i)("`+name+`"\s*:\s*")(?:[^"\\]|\\.)*(")`),
+			// HTTP and YAML, as in authorization: Bearer ...
+			regexp.MustCompile(`(?im)^(\s*`+name+`:[ \t]*)\S.*?()$`))
+	}
+	if policy.IPs {
+		r.salt = make([]byte, 32)
+		if _, err := rand.Read(r.salt); err != nil {
+			return nil, fmt.Errorf("could not generate the IP redaction key: %v", err)
+		}
+	}
+	return r, nil
+}
+
+// Redact returns text, the content of the captured file at path, with the data selected by the policy removed.
+func (r *Redactor) Redact(path, text string) string {
+	if r.policy.Secrets {
+		text = privateKeyRegexp.ReplaceAllString(text, "[REDACTED PRIVATE KEY]")
+		text = bearerTokenRegexp.ReplaceAllString(text, "${1}"+redacted)
+		if filepath.Base(path) == secretsFile {
+			text = redactSecretData(text)
+		}
+	}
+	for _, h := range r.headers {
+		text = h.ReplaceAllString(text, "${1}"+redacted+"${2}")
+	}
+	if r.policy.IPs {
+		text = r.redactIPs(text, ipv4Regexp)
+		text = r.redactIPs(text, ipv6Regexp)
+	}
+	return text
+}
+
+// redactIPs replaces the IP addresses matched by re with a keyed hash of them. Matches which are part of a
+// longer word, such as the C++ names in Envoy logs, are not addresses. Loopback and unspecified addresses, and
+// matches which do not parse, such as times, are kept.
+func (r *Redactor) redactIPs(text string, re *regexp.Regexp) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if (start > 0 && (isWordChar(text[start-1]) || text[start-1] == '.')) ||
+			(end < len(text) && isWordChar(text[end])) ||
+			(end+1 < len(text) && text[end] == '.' && isWordChar(text[end+1])) {
+			continue
+		}
+		ip := net.ParseIP(text[start:end])
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+			continue
+		}
+		mac := hmac.New(sha256.New, r.salt)
+		_, _ = mac.Write(ip)
+		sb.WriteString(text[last:start])
+		sb.WriteString("ip-" + hex.EncodeToString(mac.Sum(nil))[:8])
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// redactSecretData replaces the values under the data and stringData keys of the secrets in YAML output.
+func redactSecretData(text string) string {
+	lines := strings.Split(text, "\n")
+	dataIndent := -1
+	for i, line := range lines {
+		m := yamlKeyRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(m[1])
+		if dataIndent >= 0 && indent > dataIndent {
+			if strings.TrimSpace(m[3]) != "" {
+				lines[i] = m[1] + m[2] + ": " + redacted
+			}
+			continue
+		}
+		dataIndent = -1
+		if (m[2] == "data" || m[2] == "stringData") && strings.TrimSpace(m[3]) == "" {
+			dataIndent = indent
+		}
+	}
+	return strings.Join(lines, "\n")
+}